@@ -2,41 +2,160 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/alerting"
 	"github.com/xavesen/search-api/internal/api"
 	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/embedding"
+	"github.com/xavesen/search-api/internal/ingest"
+	"github.com/xavesen/search-api/internal/outbound"
 	"github.com/xavesen/search-api/internal/queue"
 	"github.com/xavesen/search-api/internal/storage"
 	"github.com/xavesen/search-api/internal/utils"
+	"github.com/xavesen/search-api/internal/webhook"
 )
 
+// Time given to requests in progress to finish on shutdown
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	config, err := config.LoadConfig()
 	if err != nil {
 		os.Exit(1)
 	}
 
-	ctx := context.TODO()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	mongoStorage, err := storage.NewMongoStorage(ctx, config.DbAddr, config.Db, config.DbUser, config.DbPass)
 	if err != nil {
 		os.Exit(1)
 	}
 
-	kafkaQueue, err := queue.NewKafkaQueue(context.TODO(), config.KafkaAddrs, config.KafkaTopic)
+	esClient, err := storage.NewElasticSearchClient(config.ElasticSearchURLs, config.ElasticSearchKey)
 	if err != nil {
 		os.Exit(1)
 	}
 
-	esClient, err := storage.NewElasticSearchClient(config.ElasticSearchURLs, config.ElasticSearchKey)
+	err = esClient.EnsureSavedSearchIndexes(ctx)
+	if err != nil {
+		os.Exit(1)
+	}
+	// queued documents are indexed by the built-in consumer only with in-process queue, documents
+	// queued to other backends are indexed outside of the service, so saved searches can't be
+	// evaluated and ingestion events can't be sent for them
+	queuedIngestion := config.QueueBackend == queue.BackendMemory
+	var evaluator *alerting.Evaluator
+	if queuedIngestion {
+		evaluator = alerting.NewEvaluator(esClient, outbound.NewClient(time.Duration(config.SavedSearchWebhookTimeoutMs) * time.Millisecond, config.WebhookAllowPrivateNetworks))
+	} else {
+		log.Infof("Saved searches and ingestion events are disabled, documents queued to %s are indexed outside of the service", config.QueueBackend)
+	}
+
+	webhookClient := outbound.NewClient(time.Duration(config.WebhookTimeoutMs) * time.Millisecond, config.WebhookAllowPrivateNetworks)
+	dispatcher := webhook.NewDispatcher(mongoStorage, webhookClient, config.WebhookMaxAttempts, time.Duration(config.WebhookBackoffMs) * time.Millisecond)
+
+	var consumers sync.WaitGroup
+	messageQueue, err := newQueue(ctx, config, &consumers, esClient, evaluator, dispatcher)
+	if err != nil {
+		os.Exit(1)
+	}
+
+	embedder, err := newEmbedder(config)
 	if err != nil {
 		os.Exit(1)
 	}
 
 	tokenOp := &utils.JwtTokenOperator{}
 
-	server := api.NewServer(config.ListenAddr, kafkaQueue, esClient, mongoStorage, config, tokenOp)
+	server := api.NewServer(
+		config.ListenAddr, messageQueue, esClient, mongoStorage, mongoStorage, config, tokenOp,
+		api.WithEmbedder(embedder),
+		api.WithSavedSearches(esClient, evaluator),
+		api.WithWebhooks(mongoStorage, dispatcher, queuedIngestion),
+	)
+
+	go func() {
+		if err := server.Start(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Info("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Errorf("Error shutting down server: %s", err)
+	}
+
+	// consumer stops with ctx after the message in progress, then webhooks of finished
+	// requests and messages are pushed, deliveries waiting to retry stay pending
+	consumers.Wait()
+	if evaluator != nil {
+		evaluator.Wait()
+	}
+	dispatcher.Stop()
+	dispatcher.Wait()
+}
+
+// newQueue starts built-in consumer for in-process queue, it is added to consumers and
+// stops when ctx is cancelled.
+func newQueue(ctx context.Context, config *config.Config, consumers *sync.WaitGroup, docStorage storage.DocumentStorage, evaluator *alerting.Evaluator, dispatcher *webhook.Dispatcher) (queue.Queue, error) {
+	log.Infof("Initializing %s queue", config.QueueBackend)
+
+	switch config.QueueBackend {
+	case queue.BackendKafka:
+		kafkaOpts := queue.KafkaWriterOptions{
+			BatchSize: config.KafkaBatchSize,
+			BatchBytes: config.KafkaBatchBytes,
+			BatchTimeout: time.Duration(config.KafkaBatchTimeoutMs) * time.Millisecond,
+			Compression: config.KafkaCompression,
+			RequiredAcks: config.KafkaRequiredAcks,
+			Balancer: config.KafkaBalancer,
+		}
+		return queue.NewKafkaQueue(ctx, config.KafkaAddrs, config.KafkaTopic, kafkaOpts)
+	case queue.BackendMemory:
+		memoryQueue := queue.NewMemoryQueue(config.MemoryQueueSize)
+		consumer := &ingest.Consumer{DocStorage: docStorage, Evaluator: evaluator, Dispatcher: dispatcher}
+		consumers.Add(1)
+		go func() {
+			defer consumers.Done()
+			memoryQueue.Consume(ctx, consumer.HandleMessage)
+		}()
+		return memoryQueue, nil
+	case queue.BackendNats:
+		return queue.NewNatsQueue(ctx, config.NatsURL, config.NatsStream, config.NatsSubject, config.NatsConsumer)
+	case queue.BackendRedis:
+		return queue.NewRedisQueue(ctx, config.RedisAddr, config.RedisPassword, config.RedisDb, config.RedisStream, config.RedisGroup, config.RedisConsumer)
+	}
+
+	log.Errorf("Unknown queue backend %s", config.QueueBackend)
+	return nil, fmt.Errorf("unknown queue backend %q", config.QueueBackend)
+}
+
+// newEmbedder returns nil embedder if vector search isn't configured.
+func newEmbedder(config *config.Config) (embedding.Embedder, error) {
+	switch config.Embedder {
+	case "":
+		log.Info("Embedder isn't configured, vector search is disabled")
+		return nil, nil
+	case embedding.BackendHashing:
+		return embedding.NewHashingEmbedder(config.EmbeddingDimensions), nil
+	case embedding.BackendHTTP:
+		if config.EmbedderURL == "" {
+			log.Error("EMBEDDER_URL is required for http embedder")
+			return nil, fmt.Errorf("EMBEDDER_URL is required for http embedder")
+		}
+		return embedding.NewHTTPEmbedder(config.EmbedderURL, config.EmbeddingDimensions, time.Duration(config.EmbedderTimeoutMs) * time.Millisecond), nil
+	}
 
-	log.Fatal(server.Start())
-}
\ No newline at end of file
+	log.Errorf("Unknown embedder %s", config.Embedder)
+	return nil, fmt.Errorf("unknown embedder %q", config.Embedder)
+}
@@ -0,0 +1,144 @@
+package alerting
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+// Evaluator matches newly indexed documents against saved searches, records matches
+// and pushes them to webhooks of saved searches in background.
+type Evaluator struct {
+	storage	storage.SavedSearchStorage
+	client	*http.Client
+	wg		sync.WaitGroup
+}
+
+// NewEvaluator pushes matches with client, it is expected to have timeout and to refuse
+// addresses webhooks must not reach.
+func NewEvaluator(savedSearchStorage storage.SavedSearchStorage, client *http.Client) *Evaluator {
+	return &Evaluator{
+		storage: savedSearchStorage,
+		client: client,
+	}
+}
+
+// Evaluate percolates documents indexed successfully according to results, skipped duplicates
+// were already evaluated when their content was indexed. Webhooks are pushed after
+// matches are recorded without waiting for them, failed ones are only logged, matches
+// stay recorded and can be fetched later.
+func (e *Evaluator) Evaluate(ctx context.Context, indexName string, documents []models.Document, results []models.DocumentIndexingResult) error {
+	indexed := make([]models.Document, 0, len(results))
+	for _, result := range results {
+		if result.Result == models.IndexingResultFailed || result.Result == models.IndexingResultSkipped || result.Position >= len(documents) {
+			continue
+		}
+		document := documents[result.Position]
+		document.Id = result.Id
+		indexed = append(indexed, document)
+	}
+	if len(indexed) == 0 {
+		return nil
+	}
+
+	percolatedSearches, err := e.storage.PercolateDocuments(ctx, indexName, indexed)
+	if err != nil {
+		return err
+	}
+	if len(percolatedSearches) == 0 {
+		return nil
+	}
+
+	matchedAt := time.Now().UTC()
+	allMatches := []models.SavedSearchMatch{}
+	type webhookNotification struct {
+		url				string
+		notification	*models.SavedSearchNotification
+	}
+	notifications := []webhookNotification{}
+	for _, percolatedSearch := range percolatedSearches {
+		matches := make([]models.SavedSearchMatch, 0, len(percolatedSearch.Documents))
+		for _, position := range percolatedSearch.Documents {
+			if position < 0 || position >= len(indexed) {
+				continue
+			}
+			matches = append(matches, models.SavedSearchMatch{
+				SavedSearchId: percolatedSearch.SavedSearch.Id,
+				Index: indexName,
+				DocumentId: indexed[position].Id,
+				Title: indexed[position].Title,
+				MatchedAt: matchedAt,
+			})
+		}
+		allMatches = append(allMatches, matches...)
+
+		if percolatedSearch.SavedSearch.WebhookURL != "" && len(matches) > 0 {
+			notifications = append(notifications, webhookNotification{
+				url: percolatedSearch.SavedSearch.WebhookURL,
+				notification: &models.SavedSearchNotification{
+					SavedSearchId: percolatedSearch.SavedSearch.Id,
+					Name: percolatedSearch.SavedSearch.Name,
+					Index: indexName,
+					Matches: matches,
+				},
+			})
+		}
+	}
+
+	if err := e.storage.SaveMatches(ctx, allMatches); err != nil {
+		return err
+	}
+
+	for _, webhook := range notifications {
+		e.notifyInBackground(context.WithoutCancel(ctx), webhook.url, webhook.notification)
+	}
+	return nil
+}
+
+// Wait blocks until all started webhook pushes finish.
+func (e *Evaluator) Wait() {
+	e.wg.Wait()
+}
+
+// notifyInBackground doesn't stop on cancellation of ctx, pushes are limited by client timeout.
+func (e *Evaluator) notifyInBackground(ctx context.Context, url string, notification *models.SavedSearchNotification) {
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		if err := e.post(ctx, url, notification); err != nil {
+			log.Errorf("Error pushing %d matches of saved search %s to webhook %s: %s", len(notification.Matches), notification.SavedSearchId, url, err)
+		}
+	}()
+}
+
+func (e *Evaluator) post(ctx context.Context, url string, notification *models.SavedSearchNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
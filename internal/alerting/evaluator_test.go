@@ -0,0 +1,96 @@
+package alerting
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/outbound"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+func TestEvaluate(t *testing.T) {
+	notifications := []models.SavedSearchNotification{}
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var notification models.SavedSearchNotification
+		json.NewDecoder(r.Body).Decode(&notification)
+		notifications = append(notifications, notification)
+	}))
+	defer webhook.Close()
+
+	savedSearchStorage := &storage.SavedSearchStorageMock{
+		PercolatedSearches: []models.PercolatedSearch{
+			{
+				SavedSearch: models.SavedSearch{Id: "s1", SavedSearchRequest: models.SavedSearchRequest{Name: "acme", WebhookURL: webhook.URL}},
+				Documents: []int{0, 1},
+			},
+			{
+				SavedSearch: models.SavedSearch{Id: "s2", SavedSearchRequest: models.SavedSearchRequest{Name: "launches"}},
+				Documents: []int{1},
+			},
+		},
+	}
+	evaluator := NewEvaluator(savedSearchStorage, outbound.NewClient(time.Second, true))
+
+	documents := []models.Document{{Title: "Acme"}, {Title: "Broken"}, {Title: "Acme launch"}}
+	results := []models.DocumentIndexingResult{
+		{Position: 0, Id: "d1", Result: models.IndexingResultCreated},
+		{Position: 1, Result: models.IndexingResultFailed},
+		{Position: 2, Id: "d3", Result: models.IndexingResultUpdated},
+	}
+
+	err := evaluator.Evaluate(context.TODO(), "test", documents, results)
+	if err != nil {
+		t.Fatalf("Unable to evaluate saved searches, error: %s\n", err)
+	}
+	evaluator.Wait()
+
+	assert.Equal(t, savedSearchStorage.PercolatedDocuments, []models.Document{{Id: "d1", Title: "Acme"}, {Id: "d3", Title: "Acme launch"}}, "failed documents must not be percolated")
+
+	matches := []string{}
+	for _, match := range savedSearchStorage.Matches {
+		matches = append(matches, match.SavedSearchId+":"+match.DocumentId)
+	}
+	assert.Equal(t, matches, []string{"s1:d1", "s1:d3", "s2:d3"}, "wrong recorded matches")
+
+	assert.Equal(t, len(notifications), 1, "only saved searches with webhook are notified")
+	assert.Equal(t, notifications[0].SavedSearchId, "s1", "wrong notified saved search")
+	assert.Equal(t, len(notifications[0].Matches), 2, "wrong number of notified matches")
+}
+
+func TestEvaluateWithoutIndexedDocuments(t *testing.T) {
+	savedSearchStorage := &storage.SavedSearchStorageMock{PercolateError: errors.New("must not be called")}
+	evaluator := NewEvaluator(savedSearchStorage, outbound.NewClient(time.Second, true))
+
+	results := []models.DocumentIndexingResult{{Position: 0, Result: models.IndexingResultFailed}}
+	err := evaluator.Evaluate(context.TODO(), "test", []models.Document{{Title: "Acme"}}, results)
+
+	assert.Equal(t, err, nil, "documents that failed to index must not be percolated")
+}
+
+func TestEvaluateWebhookFailure(t *testing.T) {
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer webhook.Close()
+
+	savedSearchStorage := &storage.SavedSearchStorageMock{
+		PercolatedSearches: []models.PercolatedSearch{
+			{SavedSearch: models.SavedSearch{Id: "s1", SavedSearchRequest: models.SavedSearchRequest{WebhookURL: webhook.URL}}, Documents: []int{0}},
+		},
+	}
+	evaluator := NewEvaluator(savedSearchStorage, outbound.NewClient(time.Second, true))
+
+	results := []models.DocumentIndexingResult{{Position: 0, Id: "d1", Result: models.IndexingResultCreated}}
+	err := evaluator.Evaluate(context.TODO(), "test", []models.Document{{Title: "Acme"}}, results)
+	evaluator.Wait()
+
+	assert.Equal(t, err, nil, "failed webhook must not fail evaluation")
+	assert.Equal(t, len(savedSearchStorage.Matches), 1, "match must be recorded when webhook fails")
+}
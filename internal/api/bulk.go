@@ -0,0 +1,311 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/ingest"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+// Upper bound of marshaled float32 with comma, used to estimate size of embeddings
+// added to documents before they are queued
+const embeddingValueBytes = 16
+
+var errUnsupportedContentEncoding = utils.NewAPIError(http.StatusUnsupportedMediaType, utils.CodeUnsupportedMediaType, "Unsupported Content-Encoding, expected gzip or identity")
+
+// indexDocumentsBulk queues documents streamed as NDJSON, one document per line. Body is
+// decoded line by line and documents are queued in messages of limited size, so memory
+// doesn't grow with the upload. Invalid lines are reported and skipped.
+func (s *Server) indexDocumentsBulk(w http.ResponseWriter, r *http.Request) {
+	bulkQueue, ok := s.newBulkQueue(w, r)
+	if !ok {
+		return
+	}
+
+	body, err := s.bulkBody(w, r)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	defer body.Close()
+
+	response := &models.BulkIndexingResponse{Errors: []models.BulkLineError{}}
+	abort := func(err error) {
+		response.Queued, response.Messages = bulkQueue.queued, bulkQueue.messages
+		s.writeBulkError(w, r, bulkQueue, err, response)
+	}
+
+	maxLineSize := s.config.MaxDocumentSize
+	if maxLineSize <= 0 {
+		maxLineSize = bulkQueue.maxDocumentSize
+	}
+
+	reader := bufio.NewReader(body)
+	for line := 1; ; line++ {
+		data, tooLong, readErr := readLine(reader, maxLineSize)
+		if readErr != nil && readErr != io.EOF {
+			abort(bulkBodyError(readErr))
+			return
+		}
+
+		if tooLong {
+			response.Lines++
+			addBulkLineError(response, line, []models.FieldError{{Field: "document", Message: fmt.Sprintf("line exceeds %d bytes", maxLineSize)}})
+		} else if len(bytes.TrimSpace(data)) > 0 {
+			response.Lines++
+			document, fieldErrors := decodeBulkLine(data)
+			if len(fieldErrors) == 0 {
+				fieldErrors, err = bulkQueue.Add(document)
+				if err != nil {
+					abort(err)
+					return
+				}
+			}
+			if len(fieldErrors) > 0 {
+				addBulkLineError(response, line, fieldErrors)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if err := bulkQueue.Flush(); err != nil {
+		abort(err)
+		return
+	}
+	response.Queued, response.Messages = bulkQueue.queued, bulkQueue.messages
+
+	if response.Lines == 0 {
+		utils.WriteValidationError(w, r, []models.FieldError{{Field: "documents", Message: "at least one document is required"}})
+		return
+	}
+
+	s.dispatchBulkQueued(bulkQueue)
+	if response.Failed > 0 {
+		utils.WriteJSON(w, r, http.StatusOK, false, "Some lines are invalid and weren't queued", response)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", response)
+}
+
+// bulkQueue validates documents of bulk request and queues them in messages of limited size.
+type bulkQueue struct {
+	server			*Server
+	indexName		string
+	userId			string
+	requestId		string
+	schema			*models.IndexSchema
+	dedupe			string
+	// embeddingSize is estimated size of embedding added to message for each document of
+	// indexes with vector field
+	embeddingSize	int
+	maxDocumentSize	int
+	batcher			*ingest.Batcher
+	queued			int
+	messages		int
+}
+
+// newBulkQueue checks access to index from path, on failure error response is written
+// and false is returned.
+func (s *Server) newBulkQueue(w http.ResponseWriter, r *http.Request) (*bulkQueue, bool) {
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	index, err := s.getIndexMetadata(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return nil, false
+	}
+
+	bulkQueue := &bulkQueue{
+		server: s,
+		indexName: indexName,
+		userId: r.Context().Value(utils.ContextKeyUserId).(string),
+		requestId: utils.RequestIdFromContext(r.Context()),
+	}
+	if index != nil {
+		bulkQueue.schema = index.Schema
+		bulkQueue.dedupe = index.Dedupe
+		if index.Vector != nil {
+			if s.embedder == nil {
+				utils.WriteError(w, r, utils.ErrEmbeddingsUnavailable)
+				return nil, false
+			}
+			bulkQueue.embeddingSize = len(`,"embeddings":[[]]`) + s.embedder.Dimensions()*embeddingValueBytes
+		}
+	}
+
+	// documents are added to the envelope, so its size is subtracted from message size
+	envelope, _ := json.Marshal(&models.DocumentsForIndexing{Index: indexName, UserId: bulkQueue.userId, Dedupe: bulkQueue.dedupe})
+	bulkQueue.maxDocumentSize = s.config.QueueMaxMessageBytes - len(envelope)
+	bulkQueue.batcher = ingest.NewBatcher(s.config.MaxDocumentsPerRequest, bulkQueue.maxDocumentSize, bulkQueue.queue)
+	return bulkQueue, true
+}
+
+// Add validates document and adds it to current message. Field errors mean document is
+// skipped, error means documents can't be queued anymore.
+func (q *bulkQueue) Add(document *models.Document) ([]models.FieldError, error) {
+	validator := q.server.validator
+	fieldErrors := validator.ValidateDocument("document", document)
+	fieldErrors = append(fieldErrors, validator.ValidateDocumentFields("document", q.schema, document)...)
+	if len(fieldErrors) > 0 {
+		return fieldErrors, nil
+	}
+
+	// size of queued document may differ from the source because of whitespace and escaping
+	marshaledDocument, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.batcher.Add(*document, len(marshaledDocument)+q.embeddingSize)
+	if errors.Is(err, ingest.ErrDocumentTooLarge) {
+		return []models.FieldError{{Field: "document", Message: fmt.Sprintf("document doesn't fit into queue message of %d bytes", q.server.config.QueueMaxMessageBytes)}}, nil
+	}
+	return nil, err
+}
+
+// Flush queues the last message, it must be called after all documents are added.
+func (q *bulkQueue) Flush() error {
+	return q.batcher.Flush()
+}
+
+func (q *bulkQueue) queue(documents []models.Document) error {
+	var embeddings [][]float32
+	if q.embeddingSize > 0 {
+		var err error
+		embeddings, err = q.server.embedDocuments(context.TODO(), documents)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := q.server.queueDocuments(context.TODO(), &models.DocumentsForIndexing{Index: q.indexName, UserId: q.userId, Documents: documents, Dedupe: q.dedupe, Embeddings: embeddings}, q.requestId)
+	if err != nil {
+		log.Errorf("Error queueing %d documents of bulk request %s to index %s: %s", len(documents), q.requestId, q.indexName, err)
+		return err
+	}
+
+	q.queued += len(documents)
+	q.messages++
+	return nil
+}
+
+// bulkBody limits and decompresses request body, the limit applies to decompressed body
+// too, so small compressed body can't expand without bound.
+func (s *Server) bulkBody(w http.ResponseWriter, r *http.Request) (io.ReadCloser, error) {
+	body := r.Body
+	if s.config.BulkMaxRequestSize > 0 {
+		body = http.MaxBytesReader(w, body, s.config.BulkMaxRequestSize)
+	}
+
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+		return body, nil
+	case "gzip":
+		gzipReader, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, bulkBodyError(err)
+		}
+		if s.config.BulkMaxRequestSize > 0 {
+			return http.MaxBytesReader(w, gzipReader, s.config.BulkMaxRequestSize), nil
+		}
+		return gzipReader, nil
+	}
+
+	return nil, errUnsupportedContentEncoding
+}
+
+// decodeBulkLine returns document from NDJSON line or errors of the line.
+func decodeBulkLine(data []byte) (*models.Document, []models.FieldError) {
+	document := &models.Document{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	// numbers of fields are kept exact like in decodePayload
+	decoder.UseNumber()
+	if err := decoder.Decode(document); err != nil {
+		if strings.HasPrefix(err.Error(), unknownFieldErrorPrefix) {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldErrorPrefix), "\"")
+			return nil, []models.FieldError{{Field: "document." + field, Message: "unknown field"}}
+		}
+		return nil, []models.FieldError{{Field: "document", Message: "line must be JSON object"}}
+	}
+	if err := decoder.Decode(&json.RawMessage{}); err != io.EOF {
+		return nil, []models.FieldError{{Field: "document", Message: "line must contain single document"}}
+	}
+
+	return document, nil
+}
+
+// readLine returns next line without line ending, lines longer than maxSize are discarded
+// without being buffered and reported with tooLong.
+func readLine(reader *bufio.Reader, maxSize int) ([]byte, bool, error) {
+	var line []byte
+	tooLong := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLong {
+			line = append(line, chunk...)
+			if len(bytes.TrimRight(line, "\r\n")) > maxSize {
+				tooLong = true
+				line = nil
+			}
+		}
+
+		if err != bufio.ErrBufferFull {
+			return bytes.TrimRight(line, "\r\n"), tooLong, err
+		}
+	}
+}
+
+func addBulkLineError(response *models.BulkIndexingResponse, line int, fieldErrors []models.FieldError) {
+	response.Failed++
+	if len(response.Errors) >= models.MaxBulkLineErrors {
+		response.ErrorsTruncated = true
+		return
+	}
+	response.Errors = append(response.Errors, models.BulkLineError{Line: line, Errors: fieldErrors})
+}
+
+// bulkBodyError maps errors of reading request body, everything except exceeded limit
+// means body is malformed or was cut off.
+func bulkBodyError(err error) error {
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return utils.ErrPayloadTooLarge
+	}
+	return utils.ErrInvalidPayload
+}
+
+// writeBulkError writes error that stopped bulk request, documents queued before it stay
+// queued and response summary is returned in error details.
+func (s *Server) writeBulkError(w http.ResponseWriter, r *http.Request, bulkQueue *bulkQueue, err error, response any) {
+	s.dispatchBulkQueued(bulkQueue)
+	utils.WriteError(w, r, utils.MapError(err).WithDetails(response))
+}
+
+func (s *Server) dispatchBulkQueued(bulkQueue *bulkQueue) {
+	if bulkQueue.queued == 0 {
+		return
+	}
+	s.dispatcher.Dispatch(context.TODO(), bulkQueue.indexName, models.EventDocumentsQueued, &models.DocumentsEventData{
+		RequestId: bulkQueue.requestId,
+		Documents: bulkQueue.queued,
+	})
+}
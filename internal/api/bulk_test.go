@@ -0,0 +1,268 @@
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+func gzipped(s string) string {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	writer.Write([]byte(s))
+	writer.Close()
+	return buffer.String()
+}
+
+var indexDocumentsBulkTests = []struct {
+	testName 			string
+	body				string
+	contentEncoding		string
+	queueError			error
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedMessages	[]int
+}{
+	{
+		testName: "Queue documents in messages of limited number of documents",
+		body: "{\"title\": \"One\"}\n{\"title\": \"Two\"}\r\n\n{\"title\": \"Three\"}\n   \n{\"title\": \"Four\"}\n{\"title\": \"Five\"}",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.BulkIndexingResponse{Lines: 5, Queued: 5, Messages: 3, Errors: []models.BulkLineError{}},
+		},
+		expectedMessages: []int{2, 2, 1},
+	},
+	{
+		testName: "Queue documents in messages of limited size",
+		body: fmt.Sprintf("{\"text\": \"%s\"}\n{\"text\": \"%s\"}\n", strings.Repeat("a", 190), strings.Repeat("b", 190)),
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.BulkIndexingResponse{Lines: 2, Queued: 2, Messages: 2, Errors: []models.BulkLineError{}},
+		},
+		expectedMessages: []int{1, 1},
+	},
+	{
+		testName: "Report and skip invalid lines",
+		body: strings.Join([]string{
+			`{"title": "Valid"}`,
+			`{"title": "Unknown", "size": 1}`,
+			`not json`,
+			`{"text": " "}`,
+			`{"title": "One"} {"title": "Two"}`,
+			fmt.Sprintf(`{"text": "%s"}`, strings.Repeat("a", 300)),
+			`{"title": "Price", "fields": {"price": "cheap"}}`,
+		}, "\n"),
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"price": {Type: models.FieldTypeFloat}}}}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Some lines are invalid and weren't queued",
+			Data: &models.BulkIndexingResponse{Lines: 7, Queued: 1, Failed: 6, Messages: 1, Errors: []models.BulkLineError{
+				{Line: 2, Errors: []models.FieldError{{Field: "document.size", Message: "unknown field"}}},
+				{Line: 3, Errors: []models.FieldError{{Field: "document", Message: "line must be JSON object"}}},
+				{Line: 4, Errors: []models.FieldError{{Field: "document", Message: "title or text is required"}}},
+				{Line: 5, Errors: []models.FieldError{{Field: "document", Message: "line must contain single document"}}},
+				{Line: 6, Errors: []models.FieldError{{Field: "document", Message: "line exceeds 256 bytes"}}},
+				{Line: 7, Errors: []models.FieldError{{Field: "document.fields.price", Message: "value must be a number"}}},
+			}},
+		},
+		expectedMessages: []int{1},
+	},
+	{
+		testName: "Decompress gzip body",
+		body: gzipped("{\"title\": \"One\"}\n{\"title\": \"Two\"}\n"),
+		contentEncoding: "gzip",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.BulkIndexingResponse{Lines: 2, Queued: 2, Messages: 1, Errors: []models.BulkLineError{}},
+		},
+		expectedMessages: []int{2},
+	},
+	{
+		testName: "Return 413 when decompressed body exceeds limit",
+		body: gzipped(strings.Repeat("\n", 10000)),
+		contentEncoding: "gzip",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 413,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Request payload is too large",
+			Error: &utils.APIError{Code: utils.CodePayloadTooLarge, Message: "Request payload is too large", Details: &models.BulkIndexingResponse{Errors: []models.BulkLineError{}}, RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on corrupted gzip body",
+		body: "{\"title\": \"One\"}\n",
+		contentEncoding: "gzip",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeInvalidPayload, Message: "Invalid request payload", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 415 on unsupported encoding",
+		body: "{\"title\": \"One\"}\n",
+		contentEncoding: "br",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 415,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Unsupported Content-Encoding, expected gzip or identity",
+			Error: &utils.APIError{Code: utils.CodeUnsupportedMediaType, Message: "Unsupported Content-Encoding, expected gzip or identity", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on body without documents",
+		body: "\n\n",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "documents", Message: "at least one document is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "documents", Message: "at least one document is required"},
+			},
+		},
+	},
+	{
+		testName: "Return summary of queued documents when queue fails",
+		body: "{\"title\": \"One\"}\n",
+		queueError: errors.New("queue unavailable"),
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 500,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", Details: &models.BulkIndexingResponse{Lines: 1, Errors: []models.BulkLineError{}}, RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 503 on index with vector field when embedder isn't configured",
+		body: "{\"title\": \"One\"}\n",
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Dimensions: testDimensions}}},
+		expectedCode: 503,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: utils.ErrEmbeddingsUnavailable.Message,
+			Error: &utils.APIError{Code: utils.CodeEmbeddingsUnavailable, Message: utils.ErrEmbeddingsUnavailable.Message, RequestId: testRequestId},
+		},
+	},
+}
+
+func TestIndexDocumentsBulk(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		MaxDocumentSize: 256,
+		MaxDocumentsPerRequest: 2,
+		BulkMaxRequestSize: 4096,
+		QueueMaxMessageBytes: 400,
+	}
+	for i, test := range indexDocumentsBulkTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		messageQueue := &queue.QueueMock{Error: test.queueError}
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", messageQueue, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/indexes/test/documents/_bulk", bytes.NewBufferString(test.body))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+		req.Header.Add("Content-Type", "application/x-ndjson")
+		if test.contentEncoding != "" {
+			req.Header.Add("Content-Encoding", test.contentEncoding)
+		}
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+
+		if test.expectedMessages != nil {
+			messages := []int{}
+			for _, message := range messageQueue.Messages {
+				var indexingRequest models.DocumentsForIndexing
+				if err := json.Unmarshal(message.Value, &indexingRequest); err != nil {
+					t.Fatalf("Unable to unmarshal queued message, error: %s\n", err)
+				}
+				assert.Equal(t, len(message.Value) <= config.QueueMaxMessageBytes, true, "message exceeds max size")
+				assert.Equal(t, indexingRequest.Index, "test", "wrong queued index")
+				assert.Equal(t, message.Headers[queue.HeaderRequestId], testRequestId, "wrong queued request id")
+				messages = append(messages, len(indexingRequest.Documents))
+			}
+			assert.Equal(t, messages, test.expectedMessages, "wrong queued messages")
+		}
+	}
+}
+
+func TestIndexDocumentsBulkEmbeddings(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		MaxDocumentsPerRequest: 10,
+		QueueMaxMessageBytes: 1 << 20,
+	}
+
+	messageQueue := &queue.QueueMock{}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	indexStorage := &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Dimensions: testDimensions}}}
+	server := NewServer("", messageQueue, &storage.DocStorageMock{EsIndexExists: true}, userStorage, indexStorage, config, &utils.TokenOperatorMock{TokenValid: true}, WithEmbedder(testEmbedder))
+
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/documents/_bulk", bytes.NewBufferString("{\"title\": \"Wireless mouse\", \"text\": \"Quiet clicks\"}\n"))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 200, "wrong response code")
+	assert.Equal(t, len(messageQueue.Messages), 1, "wrong number of queued messages")
+
+	var indexingRequest models.DocumentsForIndexing
+	if err := json.Unmarshal(messageQueue.Messages[0].Value, &indexingRequest); err != nil {
+		t.Fatalf("Unable to unmarshal queued message, error: %s\n", err)
+	}
+	assert.Equal(t, indexingRequest.Documents, []models.Document{{Title: "Wireless mouse", Text: "Quiet clicks"}}, "wrong queued documents")
+	assert.Equal(t, indexingRequest.Embeddings, [][]float32{testEmbedding("Wireless mouse\nQuiet clicks")}, "documents must be queued with embeddings")
+}
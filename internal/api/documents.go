@@ -3,128 +3,462 @@ package api
 import (
 	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
-	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
 	log "github.com/sirupsen/logrus"
 	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/simplequery"
 	"github.com/xavesen/search-api/internal/storage"
 	"github.com/xavesen/search-api/internal/utils"
+	"github.com/xavesen/search-api/internal/validation"
+)
+
+var (
+	errInvalidIndexingMode	= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, "Invalid indexing mode, expected async or sync")
+	errInvalidRefresh		= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, "Invalid refresh, expected true, false or wait_for in sync mode")
 )
 
 func (s *Server) indexDocuments(w http.ResponseWriter, r *http.Request) {
-	var documentsIndexingRequest *models.DocumentsForIndexing
+	documentsIndexingRequest := &models.DocumentsForIndexing{}
+	if !s.decodePayload(w, r, documentsIndexingRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateDocumentsForIndexing(documentsIndexingRequest)) {
+		return
+	}
+
+	mode := r.URL.Query().Get("mode")
+	if mode != "" && mode != models.IndexingModeAsync && mode != models.IndexingModeSync {
+		utils.WriteError(w, r, errInvalidIndexingMode)
+		return
+	}
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&documentsIndexingRequest); err != nil {
-		utils.WriteJSON(w, r, http.StatusBadRequest, false, "Invalid request payload", nil)
+	refreshPolicy := r.URL.Query().Get("refresh")
+	if refreshPolicy != "" && (mode != models.IndexingModeSync || (refreshPolicy != "true" && refreshPolicy != "false" && refreshPolicy != "wait_for")) {
+		utils.WriteError(w, r, errInvalidRefresh)
+		return
+	}
+
+	if mode == models.IndexingModeSync && len(documentsIndexingRequest.Documents) > s.config.SyncIndexingMaxBatch {
+		message := fmt.Sprintf("Sync mode accepts at most %d documents, use async mode for bigger batches", s.config.SyncIndexingMaxBatch)
+		utils.WriteError(w, r, utils.NewAPIError(http.StatusRequestEntityTooLarge, utils.CodePayloadTooLarge, message))
 		return
 	}
-	// TODO: add payload validation
 
 	documentsIndexingRequest.UserId = r.Context().Value(utils.ContextKeyUserId).(string)
 	userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), documentsIndexingRequest.UserId, documentsIndexingRequest.Index)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	indexExists, err := s.docStorage.IndexExists(context.TODO(), documentsIndexingRequest.Index)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if !indexExists || !userHasAccess {
-		utils.WriteJSON(w, r, http.StatusForbidden, false, "Index doesn't exist or you don't have access to it", nil)
+		utils.WriteError(w, r, utils.ErrIndexNotFound)
 		return
 	}
 
-	jsonIndexRequest, err := json.Marshal(documentsIndexingRequest)
+	index, err := s.getIndexMetadata(context.TODO(), documentsIndexingRequest.Index)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
-		log.Error("Error marshalling documents for index request to json after adding adding user_id to original struct from user") // TODO: structured logging with more info
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	err = s.queue.WriteMessage(context.TODO(), jsonIndexRequest)
+	// dedupe policy is set by index and embeddings by server, not by request
+	var schema *models.IndexSchema
+	documentsIndexingRequest.Dedupe = ""
+	documentsIndexingRequest.Embeddings = nil
+	if index != nil {
+		schema = index.Schema
+		documentsIndexingRequest.Dedupe = index.Dedupe
+	}
+	if !checkFieldErrors(w, r, s.validator.ValidateDocumentsFields(schema, documentsIndexingRequest.Documents)) {
+		return
+	}
+
+	// embeddings are computed before queueing, so async indexing doesn't depend on embedder
+	if index != nil && index.Vector != nil {
+		documentsIndexingRequest.Embeddings, err = s.embedDocuments(context.TODO(), documentsIndexingRequest.Documents)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if mode == models.IndexingModeSync {
+		s.indexDocumentsSync(w, r, documentsIndexingRequest, refreshPolicy)
+		return
+	}
+
+	err = s.queueDocuments(context.TODO(), documentsIndexingRequest, utils.RequestIdFromContext(r.Context()))
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
+	s.dispatcher.Dispatch(context.TODO(), documentsIndexingRequest.Index, models.EventDocumentsQueued, &models.DocumentsEventData{
+		RequestId: utils.RequestIdFromContext(r.Context()),
+		Documents: len(documentsIndexingRequest.Documents),
+	})
 
 	utils.WriteJSON(w, r, http.StatusOK, true, "", nil)
 }
 
+// queueDocuments writes indexing request to queue as one message.
+func (s *Server) queueDocuments(ctx context.Context, documentsIndexingRequest *models.DocumentsForIndexing, requestId string) error {
+	jsonIndexRequest, err := json.Marshal(documentsIndexingRequest)
+	if err != nil {
+		log.Error("Error marshalling documents for index request to json after adding adding user_id to original struct from user") // TODO: structured logging with more info
+		return err
+	}
+
+	message := queue.Message{
+		Key: []byte(documentsIndexingRequest.Index),
+		Value: jsonIndexRequest,
+		Headers: map[string]string{
+			queue.HeaderContentType: queue.ContentTypeJSON,
+			queue.HeaderSchemaVersion: queue.IndexingMessageSchemaVersion,
+			queue.HeaderUserId: documentsIndexingRequest.UserId,
+			queue.HeaderRequestId: requestId,
+		},
+	}
+
+	return s.queue.WriteMessage(ctx, message)
+}
+
+func (s *Server) indexDocumentsSync(w http.ResponseWriter, r *http.Request, documentsIndexingRequest *models.DocumentsForIndexing, refreshPolicy string) {
+	requestId := utils.RequestIdFromContext(r.Context())
+	results, err := s.docStorage.IndexDocuments(context.TODO(), documentsIndexingRequest.Index, documentsIndexingRequest.Documents, documentsIndexingRequest.Embeddings, refreshPolicy, documentsIndexingRequest.Dedupe)
+	if err != nil {
+		s.dispatcher.IngestionFailed(context.TODO(), documentsIndexingRequest.Index, requestId, len(documentsIndexingRequest.Documents), err)
+		utils.WriteError(w, r, err)
+		return
+	}
+	s.dispatcher.DocumentsIndexed(context.TODO(), documentsIndexingRequest.Index, requestId, results)
+	s.evaluateSavedSearches(context.TODO(), documentsIndexingRequest.Index, documentsIndexingRequest.Documents, results)
+
+	response := models.SyncIndexingResponse{Documents: results}
+	for _, result := range results {
+		switch result.Result {
+		case models.IndexingResultCreated:
+			response.Created++
+		case models.IndexingResultUpdated:
+			response.Updated++
+		case models.IndexingResultSkipped:
+			response.Skipped++
+		case models.IndexingResultFailed:
+			response.Failed++
+		}
+	}
+
+	if response.Failed > 0 {
+		utils.WriteJSON(w, r, http.StatusOK, false, "Some documents failed to index", response)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", response)
+}
+
 func (s *Server) searchDocuments(w http.ResponseWriter, r *http.Request) {
-	var searchRequest *models.DocumentSearchRequest
+	searchRequest := &models.DocumentSearchRequest{}
+	if !s.decodePayload(w, r, searchRequest) {
+		return
+	}
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&searchRequest); err != nil {
-		utils.WriteJSON(w, r, http.StatusBadRequest, false, "Invalid request payload", nil)
+	if !checkFieldErrors(w, r, s.validator.ValidateDocumentSearchRequest(searchRequest)) {
 		return
 	}
 
-	// TODO: validate payload
+	if !s.checkSearchIndexesAccess(w, r, searchRequest) {
+		return
+	}
 
-	userId := r.Context().Value(utils.ContextKeyUserId).(string)
-	userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), userId, searchRequest.Index)
+	// user searching all indexes has none
+	if len(searchRequest.Indexes) == 0 && searchRequest.Index == "" {
+		writeSearchResponse(w, r, searchRequest, &models.SearchResponse{Documents: []models.SearchHit{}})
+		return
+	}
+
+	indexes, err := s.getSearchIndexesMetadata(context.TODO(), searchRequest)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	indexExists, err := s.docStorage.IndexExists(context.TODO(), searchRequest.Index)
+	for _, indexName := range searchRequest.TargetIndexes() {
+		var schema *models.IndexSchema
+		if index, ok := indexes[indexName]; ok {
+			schema = index.Schema
+		}
+		if !checkFieldErrors(w, r, validation.ValidateSortFields(schema, searchRequest.Sort)) {
+			return
+		}
+	}
+
+	if searchRequest.Mode == models.SearchModeKnn || searchRequest.Mode == models.SearchModeHybrid {
+		if !checkFieldErrors(w, r, s.checkVectorIndexes(searchRequest, indexes)) {
+			return
+		}
+
+		err = s.embedQuery(context.TODO(), searchRequest)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	// index defaults apply only to match queries of single index search, other syntaxes are rejected by validation
+	if index, ok := indexes[searchRequest.Index]; ok && (searchRequest.Search != nil || searchRequest.Syntax == "") {
+		searchRequest.FuzzySettings = models.MergeFuzzySettings(index.Fuzzy, searchRequest.FuzzySettings)
+	}
+
+	var normalized simplequery.Result
+	if searchRequest.Syntax == models.SyntaxSimple {
+		normalized = simplequery.Normalize(searchRequest.Query, s.config.SimpleQueryOperators)
+		// nothing searchable is left, there is no point in asking ES
+		if strings.TrimSpace(normalized.Query) == "" {
+			writeSearchResponse(w, r, searchRequest, &models.SearchResponse{
+				Documents: []models.SearchHit{},
+				IgnoredTerms: normalized.Ignored,
+			})
+			return
+		}
+
+		searchRequest.Query = normalized.Query
+		searchRequest.SimpleQueryFlags = s.config.SimpleQueryOperators.Flags()
+		if searchRequest.DefaultOperator == "" {
+			searchRequest.DefaultOperator = s.config.SimpleQueryDefaultOperator
+		}
+		searchRequest.DefaultOperator = strings.ToLower(searchRequest.DefaultOperator)
+	}
+
+	// suggestion is returned only in envelope, ES isn't asked for it otherwise
+	searchRequest.DidYouMeanMaxHits = -1
+	if searchRequest.UsesEnvelope() {
+		searchRequest.DidYouMeanMaxHits = s.config.DidYouMeanMaxHits
+	}
+	searchRequest.NearDuplicateDistance = s.config.NearDuplicateDistance
+	searchResponse, err := s.docStorage.SearchQuery(context.TODO(), searchRequest)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
-	if !indexExists || !userHasAccess {
-		utils.WriteJSON(w, r, http.StatusForbidden, false, "Index doesn't exist or you don't have access to it", nil)
+	if searchRequest.AutoCorrect && searchResponse.DidYouMean != "" {
+		searchResponse, err = s.searchCorrected(context.TODO(), searchRequest, searchResponse)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+	}
+
+	if searchRequest.Syntax == models.SyntaxSimple {
+		searchResponse.NormalizedQuery = normalized.Query
+		searchResponse.IgnoredTerms = normalized.Ignored
+	}
+
+	writeSearchResponse(w, r, searchRequest, searchResponse)
+}
+
+// writeSearchResponse keeps response of searches not asking for envelope a list of documents.
+func writeSearchResponse(w http.ResponseWriter, r *http.Request, searchRequest *models.DocumentSearchRequest, searchResponse *models.SearchResponse) {
+	if !searchRequest.UsesEnvelope() {
+		utils.WriteJSON(w, r, http.StatusOK, true, "", searchResponse.Documents)
 		return
 	}
+	utils.WriteJSON(w, r, http.StatusOK, true, "", searchResponse)
+}
+
+// checkSearchIndexesAccess checks rights for all searched indexes with one query,
+// AllIndexes is replaced with all indexes of the user.
+func (s *Server) checkSearchIndexesAccess(w http.ResponseWriter, r *http.Request, searchRequest *models.DocumentSearchRequest) bool {
+	userId := r.Context().Value(utils.ContextKeyUserId).(string)
+
+	if len(searchRequest.Indexes) == 0 {
+		userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), userId, searchRequest.Index)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return false
+		}
+
+		indexExists, err := s.docStorage.IndexExists(context.TODO(), searchRequest.Index)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return false
+		}
+
+		if !indexExists || !userHasAccess {
+			utils.WriteError(w, r, utils.ErrIndexNotFound)
+			return false
+		}
+		return true
+	}
 
-	documents, err := s.docStorage.SearchQuery(context.TODO(), searchRequest)
+	accessibleIndexes, err := s.userStorage.FilterUserIndexes(context.TODO(), userId, searchRequest.Indexes)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
-		return
+		utils.WriteError(w, r, err)
+		return false
+	}
+
+	if searchRequest.Indexes[0] == models.AllIndexes {
+		searchRequest.Indexes = accessibleIndexes
+		// user's indexes missing in ES shouldn't fail the search
+		searchRequest.IgnoreUnavailable = true
+		return true
+	}
+
+	if len(accessibleIndexes) != len(searchRequest.Indexes) {
+		utils.WriteError(w, r, utils.ErrIndexNotFound)
+		return false
+	}
+
+	// ES reports that comma separated indexes exist only if all of them do
+	indexesExist, err := s.docStorage.IndexExists(context.TODO(), strings.Join(searchRequest.Indexes, ","))
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return false
+	}
+
+	if !indexesExist {
+		utils.WriteError(w, r, utils.ErrIndexNotFound)
+		return false
+	}
+	return true
+}
+
+// getSearchIndexesMetadata returns metadata of searched indexes by name, indexes created
+// before metadata was stored are missing.
+func (s *Server) getSearchIndexesMetadata(ctx context.Context, searchRequest *models.DocumentSearchRequest) (map[string]*models.IndexMetadata, error) {
+	indexes := map[string]*models.IndexMetadata{}
+
+	if len(searchRequest.Indexes) == 0 {
+		index, err := s.getIndexMetadata(ctx, searchRequest.Index)
+		if err != nil || index == nil {
+			return indexes, err
+		}
+		indexes[searchRequest.Index] = index
+		return indexes, nil
 	}
 
-	utils.WriteJSON(w, r, http.StatusOK, true, "", documents)
+	indexesMetadata, err := s.indexStorage.GetIndexesMetadata(ctx, searchRequest.Indexes)
+	if err != nil {
+		return nil, err
+	}
+	for i := range indexesMetadata {
+		indexes[indexesMetadata[i].Name] = &indexesMetadata[i]
+	}
+	return indexes, nil
+}
+
+// searchCorrected re-runs search with misspelled words of query replaced by did you mean
+// suggestion, original response is kept if corrected query doesn't find more documents.
+func (s *Server) searchCorrected(ctx context.Context, searchRequest *models.DocumentSearchRequest, searchResponse *models.SearchResponse) (*models.SearchResponse, error) {
+	correctedQuery, ok := storage.CorrectQuery(searchRequest.Query, searchResponse.DidYouMean)
+	if !ok {
+		return searchResponse, nil
+	}
+
+	correctedRequest := *searchRequest
+	correctedRequest.Query = correctedQuery
+	correctedRequest.DidYouMeanMaxHits = -1
+
+	correctedResponse, err := s.docStorage.SearchQuery(ctx, &correctedRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	if correctedResponse.Total <= searchResponse.Total {
+		return searchResponse, nil
+	}
+
+	correctedResponse.DidYouMean = searchResponse.DidYouMean
+	correctedResponse.Corrected = true
+	return correctedResponse, nil
 }
 
 func (s *Server) createIndex(w http.ResponseWriter, r *http.Request) {
-	var createIndexRequest *models.CreateIndexRequest
+	createIndexRequest := &models.CreateIndexRequest{}
+	if !s.decodePayload(w, r, createIndexRequest) {
+		return
+	}
 
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&createIndexRequest); err != nil {
-		utils.WriteJSON(w, r, http.StatusBadRequest, false, "Invalid request payload", nil)
+	if !checkFieldErrors(w, r, s.validator.ValidateCreateIndexRequest(createIndexRequest)) {
 		return
 	}
 
 	userId := r.Context().Value(utils.ContextKeyUserId).(string)
 
-	// TODO: validate payload
+	// vector field dimensions are defined by embedder configured on server
+	if createIndexRequest.Vector != nil {
+		if s.embedder == nil {
+			utils.WriteError(w, r, utils.ErrEmbeddingsUnavailable)
+			return
+		}
+		createIndexRequest.Vector.Dimensions = s.embedder.Dimensions()
+	}
+
+	// analyzers are always configured, so synonyms can be added to the index later
+	analysis := createIndexRequest.Analysis
+	if analysis == nil {
+		analysis = &models.IndexAnalysis{}
+	}
 
-	err := s.docStorage.NewIndex(context.TODO(), createIndexRequest.Index)
+	// ES rejects alias named as existing index with invalid alias error, not as existing index
+	indexExists, err := s.docStorage.IndexExists(context.TODO(), createIndexRequest.Index)
 	if err != nil {
-		var esError *types.ElasticsearchError
-		if errors.As(err, &esError) && esError.Status == 400 && esError.ErrorCause.Type == storage.ErrResourceAlreadyExists {
-			utils.WriteJSON(w, r, http.StatusConflict, false, "Index with such name already exists", nil)
-		} else {
-			utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
-		}
+		utils.WriteError(w, r, err)
+		return
+	}
+	if indexExists {
+		utils.WriteError(w, r, utils.ErrIndexAlreadyExists)
+		return
+	}
+
+	// index name is alias of its first version, so it can be reindexed without downtime
+	index := &models.IndexMetadata{
+		Name: createIndexRequest.Index,
+		UserId: userId,
+		Version: 1,
+		Schema: createIndexRequest.Schema,
+		Analysis: analysis,
+		Fuzzy: createIndexRequest.Fuzzy,
+		Vector: createIndexRequest.Vector,
+		Dedupe: createIndexRequest.Dedupe,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err = s.docStorage.NewIndex(context.TODO(), index)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	err = s.indexStorage.CreateIndexMetadata(context.TODO(), index)
+	if err != nil {
+		// index unknown to the service isn't left in ES, deletion error is logged by storage
+		s.docStorage.DeleteIndex(context.TODO(), storage.PhysicalIndexName(index.Name, index.Version))
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	err = s.userStorage.AddIndexToUser(context.TODO(), userId, createIndexRequest.Index)
 	if err != nil {
-		// TODO: delete existing index on error here
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		// metadata is deleted too, otherwise the name stays taken for retries
+		s.docStorage.DeleteIndex(context.TODO(), storage.PhysicalIndexName(index.Name, index.Version))
+		s.indexStorage.DeleteIndexMetadata(context.TODO(), index.Name)
+		utils.WriteError(w, r, err)
 		return
 	}
+	s.dispatcher.Dispatch(context.TODO(), createIndexRequest.Index, models.EventIndexCreated, nil)
 
 	utils.WriteJSON(w, r, http.StatusOK, true, "", nil)
-}
\ No newline at end of file
+}
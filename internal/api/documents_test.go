@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -14,11 +15,15 @@ import (
 	"github.com/magiconair/properties/assert"
 	"github.com/xavesen/search-api/internal/config"
 	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/simplequery"
 	"github.com/xavesen/search-api/internal/queue"
 	"github.com/xavesen/search-api/internal/storage"
 	"github.com/xavesen/search-api/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+const testRequestId = "test-request-id"
+
 var indexDocumentsTests = []struct {
 	testName 			string
 	docStorage 			*storage.DocStorageMock
@@ -93,6 +98,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -126,6 +132,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -159,6 +166,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -192,6 +200,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -226,6 +235,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -259,6 +269,7 @@ var indexDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -273,7 +284,7 @@ func TestIndexDocumentsHandler(t *testing.T) {
 	for i, test := range indexDocumentsTests {
 		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
 
-		server := NewServer("", test.queue, test.docStorage, test.userStorage, config, test.tokenOp)
+		server := NewServer("", test.queue, test.docStorage, test.userStorage, &storage.IndexStorageMock{}, config, test.tokenOp)
 
 		marshaledPayload, err := json.Marshal(test.payload)
 		if err != nil {
@@ -285,6 +296,7 @@ func TestIndexDocumentsHandler(t *testing.T) {
 			t.Fatalf("Unable to create request, error: %s\n", err)
 		}
 		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
 
 		rr := httptest.NewRecorder()
 		server.router.ServeHTTP(rr, req)
@@ -299,6 +311,8 @@ func TestIndexDocumentsHandler(t *testing.T) {
 	}
 }
 
+var queryParseErrorReason = "Failed to parse query [foo AND (bar]"
+
 var searchDocumentsTests = []struct {
 	testName 			string
 	docStorage 			*storage.DocStorageMock
@@ -365,6 +379,7 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -385,6 +400,7 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -406,6 +422,36 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 400 when ES can't parse query",
+		docStorage: &storage.DocStorageMock{
+			SearchError: &types.ElasticsearchError{
+				Status: 400,
+				ErrorCause: types.ErrorCause{
+					Type: "search_phase_execution_exception",
+					RootCause: []types.ErrorCause{{Type: "query_shard_exception", Reason: &queryParseErrorReason}},
+				},
+			},
+			EsIndexExists: true,
+		},
+		userStorage: &storage.UserStorageMock{
+			IndexAccess: true,
+		},
+		payload: &models.DocumentSearchRequest{
+			Index: "test",
+			Query: "foo AND (bar",
+			Syntax: models.SyntaxQueryString,
+		},
+		tokenOp: &utils.TokenOperatorMock{TokenValid: true},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Search query is invalid",
+			Error: &utils.APIError{Code: utils.CodeInvalidQuery, Message: "Search query is invalid", Details: map[string]string{"reason": queryParseErrorReason}, RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -436,6 +482,7 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -467,6 +514,7 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -497,6 +545,7 @@ var searchDocumentsTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -511,7 +560,7 @@ func TestSearchDocumentsHandler(t *testing.T) {
 	for i, test := range searchDocumentsTests {
 		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
 
-		server := NewServer("", nil, test.docStorage, test.userStorage, config, test.tokenOp)
+		server := NewServer("", nil, test.docStorage, test.userStorage, &storage.IndexStorageMock{}, config, test.tokenOp)
 
 		marshaledPayload, err := json.Marshal(test.payload)
 		if err != nil {
@@ -524,6 +573,7 @@ func TestSearchDocumentsHandler(t *testing.T) {
 		}
 
 		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
 
 		rr := httptest.NewRecorder()
 		server.router.ServeHTTP(rr, req)
@@ -550,7 +600,7 @@ var createIndexHandlerTests = []struct {
 	{
 		testName: "Return 200",
 		docStorage: &storage.DocStorageMock{},
-		userStorage: &storage.UserStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
 		payload: &models.CreateIndexRequest{
 			Index: "test",
 		},
@@ -567,7 +617,7 @@ var createIndexHandlerTests = []struct {
 		docStorage: &storage.DocStorageMock{
 			CreateError: errors.New("random error"),
 		},
-		userStorage: &storage.UserStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
 		payload: &models.CreateIndexRequest{
 			Index: "test",
 		},
@@ -576,6 +626,7 @@ var createIndexHandlerTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -584,7 +635,7 @@ var createIndexHandlerTests = []struct {
 		docStorage: &storage.DocStorageMock{
 			CreateError: types.NewElasticsearchError(),
 		},
-		userStorage: &storage.UserStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
 		payload: &models.CreateIndexRequest{
 			Index: "test",
 		},
@@ -593,6 +644,7 @@ var createIndexHandlerTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -601,7 +653,23 @@ var createIndexHandlerTests = []struct {
 		docStorage: &storage.DocStorageMock{
 			CreateError: &types.ElasticsearchError{Status: 400, ErrorCause: types.ErrorCause{Type: storage.ErrResourceAlreadyExists}},
 		},
-		userStorage: &storage.UserStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
+		payload: &models.CreateIndexRequest{
+			Index: "test",
+		},
+		tokenOp: &utils.TokenOperatorMock{TokenValid: true},
+		expectedCode: 409,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index with such name already exists",
+			Error: &utils.APIError{Code: utils.CodeIndexAlreadyExists, Message: "Index with such name already exists", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 409 when index or alias with such name exists",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
 		payload: &models.CreateIndexRequest{
 			Index: "test",
 		},
@@ -610,13 +678,36 @@ var createIndexHandlerTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Index with such name already exists",
+			Error: &utils.APIError{Code: utils.CodeIndexAlreadyExists, Message: "Index with such name already exists", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
+	{
+		testName: "Return 400 on index name with version separator",
+		docStorage: &storage.DocStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
+		payload: &models.CreateIndexRequest{
+			Index: "test~v1",
+		},
+		tokenOp: &utils.TokenOperatorMock{TokenValid: true},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{
+				Code: utils.CodeValidationFailed,
+				Message: "Invalid request payload",
+				Details: []models.FieldError{{Field: "index_name", Message: `index name must not contain "~"`}},
+				RequestId: testRequestId,
+			},
+			Errors: []models.FieldError{{Field: "index_name", Message: `index name must not contain "~"`}},
+		},
+	},
 	{
 		testName: "Return 500 when user storage returns an error",
 		docStorage: &storage.DocStorageMock{},
 		userStorage: &storage.UserStorageMock{
+			User: &models.User{},
 			AddIndexError: errors.New("random error"),
 		},
 		payload: &models.CreateIndexRequest{
@@ -627,13 +718,14 @@ var createIndexHandlerTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
 	{
 		testName: "Return 401 with invalid token",
 		docStorage: &storage.DocStorageMock{},
-		userStorage: &storage.UserStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
 		payload: &models.CreateIndexRequest{
 			Index: "test",
 		},
@@ -642,6 +734,7 @@ var createIndexHandlerTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -656,7 +749,7 @@ func TestCreateIndexHandler(t *testing.T) {
 	for i, test := range createIndexHandlerTests {
 		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
 
-		server := NewServer("", nil, test.docStorage, test.userStorage, config, test.tokenOp)
+		server := NewServer("", nil, test.docStorage, test.userStorage, &storage.IndexStorageMock{}, config, test.tokenOp)
 
 		marshaledPayload, err := json.Marshal(test.payload)
 		if err != nil {
@@ -669,6 +762,324 @@ func TestCreateIndexHandler(t *testing.T) {
 		}
 
 		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+var createIndexCleanupTests = []struct {
+	testName 			string
+	indexStorage		*storage.IndexStorageMock
+	userStorage 		*storage.UserStorageMock
+	expectedDeleted		[]string
+	expectedDeletedMetadata	[]string
+}{
+	{
+		testName: "Keep index when it's created",
+		indexStorage: &storage.IndexStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
+	},
+	{
+		testName: "Delete index when metadata isn't saved",
+		indexStorage: &storage.IndexStorageMock{CreateError: errors.New("random error")},
+		userStorage: &storage.UserStorageMock{User: &models.User{}},
+		expectedDeleted: []string{"test~v1"},
+	},
+	{
+		testName: "Delete index when it isn't added to user",
+		indexStorage: &storage.IndexStorageMock{},
+		userStorage: &storage.UserStorageMock{User: &models.User{}, AddIndexError: errors.New("random error")},
+		expectedDeleted: []string{"test~v1"},
+		expectedDeletedMetadata: []string{"test"},
+	},
+}
+
+func TestCreateIndexCleanup(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range createIndexCleanupTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{}
+		server := NewServer("", nil, docStorage, test.userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/createIndex", bytes.NewBufferString(`{"index_name": "test"}`))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, docStorage.DeletedIndexes, test.expectedDeleted, "wrong deleted indexes")
+		assert.Equal(t, test.indexStorage.DeletedMetadata, test.expectedDeletedMetadata, "wrong deleted index metadata")
+	}
+}
+
+func TestIndexDocumentsQueueMessage(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	queueMock := &queue.QueueMock{}
+	docStorage := &storage.DocStorageMock{EsIndexExists: true}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	tokenOp := &utils.TokenOperatorMock{TokenValid: true}
+
+	server := NewServer("", queueMock, docStorage, userStorage, &storage.IndexStorageMock{}, config, tokenOp)
+
+	payload := &models.DocumentsForIndexing{
+		Index: "test",
+		Documents: []models.Document{{Title: "test", Text: "test test test"}},
+	}
+	marshaledPayload, err := json.Marshal(payload)
+	if err != nil {
+		t.Fatalf("Unable to marshal payload, error: %s\n", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, "/indexDocuments", bytes.NewBuffer(marshaledPayload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", "req-1")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+	assert.Equal(t, len(queueMock.Messages), 1, "wrong number of queued messages")
+
+	message := queueMock.Messages[0]
+	assert.Equal(t, string(message.Key), "test", "wrong message key")
+	assert.Equal(t, message.Headers[queue.HeaderContentType], queue.ContentTypeJSON, "wrong content type header")
+	assert.Equal(t, message.Headers[queue.HeaderSchemaVersion], queue.IndexingMessageSchemaVersion, "wrong schema version header")
+	assert.Equal(t, message.Headers[queue.HeaderUserId], "1", "wrong user id header")
+	assert.Equal(t, message.Headers[queue.HeaderRequestId], "req-1", "wrong request id header")
+}
+
+func TestIndexDocumentsKeepsLargeIntegers(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		QueueMaxMessageBytes: 1 << 20,
+	}
+	schema := &models.IndexSchema{Fields: map[string]models.SchemaField{"views": {Type: models.FieldTypeLong}}}
+	requests := []struct {
+		url		string
+		payload	string
+	}{
+		{url: "/indexDocuments", payload: `{"index_name": "test", "documents": [{"title": "test", "text": "test", "fields": {"views": 9007199254740993}}]}`},
+		{url: "/indexes/test/documents/_bulk", payload: `{"title": "test", "text": "test", "fields": {"views": 9007199254740993}}` + "\n"},
+	}
+
+	for i, request := range requests {
+		fmt.Printf("Running test #%d: %s\n", i+1, request.url)
+
+		queueMock := &queue.QueueMock{}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		indexStorage := &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: schema}}
+		server := NewServer("", queueMock, &storage.DocStorageMock{EsIndexExists: true}, userStorage, indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, request.url, bytes.NewBufferString(request.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+		assert.Equal(t, len(queueMock.Messages), 1, "wrong number of queued messages")
+		assert.Equal(t, strings.Contains(string(queueMock.Messages[0].Value), `"views":9007199254740993`), true, "integer field must be queued exactly")
+	}
+}
+
+var indexDocumentsSyncTests = []struct {
+	testName 			string
+	docStorage 			*storage.DocStorageMock
+	query				string
+	documents			[]models.Document
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Return 200 with per document results",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			IndexingResults: []models.DocumentIndexingResult{
+				{Position: 0, Id: "a", Result: models.IndexingResultCreated},
+				{Position: 1, Id: "b", Result: models.IndexingResultUpdated},
+			},
+		},
+		query: "?mode=sync&refresh=wait_for",
+		documents: []models.Document{{Title: "test", Text: "test"}, {Id: "b", Title: "test1", Text: "test1"}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			ErrorMessage: "",
+			Data: models.SyncIndexingResponse{
+				Created: 1,
+				Updated: 1,
+				Documents: []models.DocumentIndexingResult{
+					{Position: 0, Id: "a", Result: models.IndexingResultCreated},
+					{Position: 1, Id: "b", Result: models.IndexingResultUpdated},
+				},
+			},
+		},
+	},
+	{
+		testName: "Return 200 with failed documents",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			IndexingResults: []models.DocumentIndexingResult{
+				{Position: 0, Id: "a", Result: models.IndexingResultFailed, Error: "failed to parse"},
+			},
+		},
+		query: "?mode=sync",
+		documents: []models.Document{{Title: "test", Text: "test"}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Some documents failed to index",
+			Data: models.SyncIndexingResponse{
+				Failed: 1,
+				Documents: []models.DocumentIndexingResult{
+					{Position: 0, Id: "a", Result: models.IndexingResultFailed, Error: "failed to parse"},
+				},
+			},
+		},
+	},
+	{
+		testName: "Return 200 with skipped duplicates",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			IndexingResults: []models.DocumentIndexingResult{
+				{Position: 0, Id: "a", Result: models.IndexingResultCreated},
+				{Position: 1, Id: "a", Result: models.IndexingResultSkipped},
+			},
+		},
+		query: "?mode=sync",
+		documents: []models.Document{{Title: "test", Text: "test"}, {Title: "Test", Text: " test "}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			ErrorMessage: "",
+			Data: models.SyncIndexingResponse{
+				Created: 1,
+				Skipped: 1,
+				Documents: []models.DocumentIndexingResult{
+					{Position: 0, Id: "a", Result: models.IndexingResultCreated},
+					{Position: 1, Id: "a", Result: models.IndexingResultSkipped},
+				},
+			},
+		},
+	},
+	{
+		testName: "Return 413 when batch is bigger than sync limit",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+		},
+		query: "?mode=sync",
+		documents: []models.Document{{Title: "1"}, {Title: "2"}, {Title: "3"}},
+		expectedCode: 413,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Sync mode accepts at most 2 documents, use async mode for bigger batches",
+			Error: &utils.APIError{Code: utils.CodePayloadTooLarge, Message: "Sync mode accepts at most 2 documents, use async mode for bigger batches", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 400 on invalid refresh",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+		},
+		query: "?mode=sync&refresh=later",
+		documents: []models.Document{{Title: "test", Text: "test"}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid refresh, expected true, false or wait_for in sync mode",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid refresh, expected true, false or wait_for in sync mode", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 400 on invalid mode",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+		},
+		query: "?mode=later",
+		documents: []models.Document{{Title: "test", Text: "test"}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid indexing mode, expected async or sync",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid indexing mode, expected async or sync", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 500 when bulk request fails",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			BulkError: errors.New("random error"),
+		},
+		query: "?mode=sync",
+		documents: []models.Document{{Title: "test", Text: "test"}},
+		expectedCode: 500,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+}
+
+func TestIndexDocumentsSyncHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SyncIndexingMaxBatch: 2,
+	}
+	for i, test := range indexDocumentsSyncTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		queueMock := &queue.QueueMock{}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", queueMock, test.docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		marshaledPayload, err := json.Marshal(&models.DocumentsForIndexing{Index: "test", Documents: test.documents})
+		if err != nil {
+			t.Fatalf("Unable to marshal payload, error: %s\n", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/indexDocuments"+test.query, bytes.NewBuffer(marshaledPayload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
 
 		rr := httptest.NewRecorder()
 		server.router.ServeHTTP(rr, req)
@@ -680,5 +1091,555 @@ func TestCreateIndexHandler(t *testing.T) {
 
 		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
 		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		assert.Equal(t, len(queueMock.Messages), 0, "sync indexing must not write to queue")
+	}
+}
+
+var indexDocumentsDedupeTests = []struct {
+	testName 		string
+	query			string
+	indexStorage	*storage.IndexStorageMock
+	expectedDedupe	string
+}{
+	{
+		testName: "Pass index dedupe policy to sync indexing",
+		query: "?mode=sync",
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Dedupe: models.DedupeSkip}},
+		expectedDedupe: models.DedupeSkip,
+	},
+	{
+		testName: "Pass index dedupe policy to queued message",
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Dedupe: models.DedupeKeepBoth}},
+		expectedDedupe: models.DedupeKeepBoth,
+	},
+	{
+		testName: "Use default policy for indexes without metadata",
+		query: "?mode=sync",
+		indexStorage: &storage.IndexStorageMock{GetError: mongo.ErrNoDocuments},
+		expectedDedupe: "",
+	},
+}
+
+func TestIndexDocumentsDedupePolicy(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SyncIndexingMaxBatch: 2,
+	}
+	for i, test := range indexDocumentsDedupeTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		queueMock := &queue.QueueMock{}
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", queueMock, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		// dedupe of request is replaced with policy of the index
+		payload := `{"index_name": "test", "documents": [{"title": "test", "text": "test"}], "dedupe": "overwrite"}`
+		req, err := http.NewRequest(http.MethodPost, "/indexDocuments"+test.query, bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+		if test.query == "" {
+			assert.Equal(t, len(queueMock.Messages), 1, "wrong number of queued messages")
+			var indexingRequest models.DocumentsForIndexing
+			if err := json.Unmarshal(queueMock.Messages[0].Value, &indexingRequest); err != nil {
+				t.Fatalf("Unable to unmarshal queued message, error: %s\n", err)
+			}
+			assert.Equal(t, indexingRequest.Dedupe, test.expectedDedupe, "wrong dedupe policy of queued message")
+		} else {
+			assert.Equal(t, docStorage.IndexedDedupe, test.expectedDedupe, "wrong dedupe policy")
+		}
 	}
-}
\ No newline at end of file
+}
+
+var searchDocumentsProblemTests = []struct {
+	testName 			string
+	searchError			error
+	expectedCode		int
+	expectedProblem		map[string]any
+}{
+	{
+		testName: "Return 504 problem on search timeout",
+		searchError: context.DeadlineExceeded,
+		expectedCode: 504,
+		expectedProblem: map[string]any{
+			"type": "urn:search-api:error:TIMEOUT",
+			"title": "Gateway Timeout",
+			"status": float64(504),
+			"detail": "Request timed out",
+			"instance": "/searchDocuments",
+			"code": utils.CodeTimeout,
+			"request_id": testRequestId,
+		},
+	},
+	{
+		testName: "Return 403 problem when ES index is missing",
+		searchError: &types.ElasticsearchError{Status: 404, ErrorCause: types.ErrorCause{Type: "index_not_found_exception"}},
+		expectedCode: 403,
+		expectedProblem: map[string]any{
+			"type": "urn:search-api:error:INDEX_NOT_FOUND",
+			"title": "Forbidden",
+			"status": float64(403),
+			"detail": "Index doesn't exist or you don't have access to it",
+			"instance": "/searchDocuments",
+			"code": utils.CodeIndexNotFound,
+			"request_id": testRequestId,
+		},
+	},
+}
+
+func TestSearchDocumentsProblemJSON(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range searchDocumentsProblemTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, SearchError: test.searchError}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		marshaledPayload, err := json.Marshal(&models.DocumentSearchRequest{Index: "test", Query: "search"})
+		if err != nil {
+			t.Fatalf("Unable to marshal payload, error: %s\n", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBuffer(marshaledPayload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+		req.Header.Add("Accept", "application/problem+json, application/json;q=0.9")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		var problem map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &problem); err != nil {
+			t.Fatalf("Unable to unmarshal problem response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, rr.Header().Get("Content-Type"), utils.ContentTypeProblemJSON, "wrong content type")
+		assert.Equal(t, problem, test.expectedProblem, "wrong problem contents")
+	}
+}
+
+var searchDocumentsSimpleSyntaxTests = []struct {
+	testName 				string
+	payload					*models.DocumentSearchRequest
+	documents				[]models.Document
+	expectedSearchRequest	*models.DocumentSearchRequest
+	expectedResponse 		utils.Response
+}{
+	{
+		testName: "Normalize query and report ignored terms",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "title:foo AND (bar", Syntax: models.SyntaxSimple},
+		documents: []models.Document{{Title: "foo", Text: "bar"}},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "foo + bar", Syntax: models.SyntaxSimple, DefaultOperator: "or", SimpleQueryFlags: "WHITESPACE|AND|OR|PRECEDENCE"},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{
+				Total: 1,
+				Documents: []models.SearchHit{{Document: models.Document{Title: "foo", Text: "bar"}}},
+				NormalizedQuery: "foo + bar",
+				IgnoredTerms: []string{"title:", "("},
+			},
+		},
+	},
+	{
+		testName: "Use default operator from request",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "foo -bar", Syntax: models.SyntaxSimple, DefaultOperator: "AND"},
+		documents: []models.Document{},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "foo bar", Syntax: models.SyntaxSimple, DefaultOperator: "and", SimpleQueryFlags: "WHITESPACE|AND|OR|PRECEDENCE"},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{
+				Documents: []models.SearchHit{},
+				NormalizedQuery: "foo bar",
+				IgnoredTerms: []string{"-"},
+			},
+		},
+	},
+	{
+		testName: "Don't search when nothing is left after normalization",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "AND ( )", Syntax: models.SyntaxSimple},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{
+				Documents: []models.SearchHit{},
+				IgnoredTerms: []string{"(", ")", "AND"},
+			},
+		},
+	},
+}
+
+func TestSearchDocumentsSimpleSyntax(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SimpleQueryOperators: simplequery.Operators{And: true, Or: true, Precedence: true},
+		SimpleQueryDefaultOperator: "or",
+	}
+	for i, test := range searchDocumentsSimpleSyntaxTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, Documents: test.documents}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		marshaledPayload, err := json.Marshal(test.payload)
+		if err != nil {
+			t.Fatalf("Unable to marshal payload, error: %s\n", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBuffer(marshaledPayload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		assert.Equal(t, docStorage.SearchRequest, test.expectedSearchRequest, "wrong search request")
+	}
+}
+
+var searchDocumentsDidYouMeanTests = []struct {
+	testName 				string
+	payload					*models.DocumentSearchRequest
+	docStorage				*storage.DocStorageMock
+	expectedSearchRequest	*models.DocumentSearchRequest
+	expectedResponse 		utils.Response
+}{
+	{
+		testName: "Return did you mean suggestion for low result search",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose", Envelope: true},
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}, DidYouMean: "wireless mouse"},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose", Envelope: true, DidYouMeanMaxHits: 5},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{Documents: []models.SearchHit{}, DidYouMean: "wireless mouse"},
+		},
+	},
+	{
+		testName: "Don't ask for suggestion when response has no envelope",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose"},
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}, DidYouMean: "wireless mouse"},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose", DidYouMeanMaxHits: -1},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.SearchHit{},
+		},
+	},
+	{
+		testName: "Re-run search with corrected query",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose", AutoCorrect: true},
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Documents: []models.Document{},
+			DidYouMean: "wireless mouse",
+			CorrectedDocuments: []models.Document{{Id: "1", Title: "Wireless mouse"}},
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless mouse", AutoCorrect: true, DidYouMeanMaxHits: -1},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{
+				Total: 1,
+				Documents: []models.SearchHit{{Document: models.Document{Id: "1", Title: "Wireless mouse"}}},
+				DidYouMean: "wireless mouse",
+				Corrected: true,
+			},
+		},
+	},
+	{
+		testName: "Keep original results when corrected query doesn't find more",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "wireles mose", AutoCorrect: true},
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Documents: []models.Document{{Id: "2", Title: "Wireles mose"}},
+			DidYouMean: "wireless mouse",
+			CorrectedDocuments: []models.Document{},
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless mouse", AutoCorrect: true, DidYouMeanMaxHits: -1},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{
+				Total: 1,
+				Documents: []models.SearchHit{{Document: models.Document{Id: "2", Title: "Wireles mose"}}},
+				DidYouMean: "wireless mouse",
+			},
+		},
+	},
+	{
+		testName: "Keep operators of query when re-running search",
+		payload: &models.DocumentSearchRequest{Index: "test", Query: "wireles AND NOT mose", Syntax: models.SyntaxQueryString, AutoCorrect: true},
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Documents: []models.Document{},
+			DidYouMean: "wireless and not mouse",
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless AND NOT mouse", Syntax: models.SyntaxQueryString, AutoCorrect: true, DidYouMeanMaxHits: -1},
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{Documents: []models.SearchHit{}, DidYouMean: "wireless and not mouse"},
+		},
+	},
+}
+
+func TestSearchDocumentsDidYouMean(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		DidYouMeanMaxHits: 5,
+	}
+	for i, test := range searchDocumentsDidYouMeanTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, test.docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		marshaledPayload, err := json.Marshal(test.payload)
+		if err != nil {
+			t.Fatalf("Unable to marshal payload, error: %s\n", err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBuffer(marshaledPayload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		assert.Equal(t, test.docStorage.SearchRequest, test.expectedSearchRequest, "wrong search request")
+	}
+}
+
+var searchDocumentsSortTests = []struct {
+	testName 			string
+	payload				string
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Sort by sortable schema fields and title",
+		payload: `{"index_name": "test", "query": "go", "sort": [{"field": "fields.published_at", "order": "desc"}, {"field": "title"}]}`,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.Document{},
+		},
+	},
+	{
+		testName: "Return 400 on sort by text, nested and unknown fields",
+		payload: `{"index_name": "test", "query": "go", "sort": [{"field": "text"}, {"field": "fields.authors.name"}, {"field": "fields.rating"}]}`,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "sort[0].field", Message: "field must be _score, title or sortable schema field"},
+				{Field: "sort[1].field", Message: "field must be _score, title or sortable schema field"},
+				{Field: "sort[2].field", Message: "field must be _score, title or sortable schema field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "sort[0].field", Message: "field must be _score, title or sortable schema field"},
+				{Field: "sort[1].field", Message: "field must be _score, title or sortable schema field"},
+				{Field: "sort[2].field", Message: "field must be _score, title or sortable schema field"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on sort by schema field of index without schema",
+		payload: `{"index_name": "test", "query": "go", "sort": [{"field": "_score"}, {"field": "fields.price"}]}`,
+		indexStorage: &storage.IndexStorageMock{GetError: mongo.ErrNoDocuments},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "sort[1].field", Message: "field must be _score, title or sortable schema field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "sort[1].field", Message: "field must be _score, title or sortable schema field"},
+			},
+		},
+	},
+}
+
+func TestSearchDocumentsSort(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range searchDocumentsSortTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+var searchDocumentsMultiIndexTests = []struct {
+	testName 				string
+	payload					string
+	userIndexes				[]string
+	indexStorage			*storage.IndexStorageMock
+	expectedCode			int
+	expectedResponse 		utils.Response
+	expectedSearchRequest	*models.DocumentSearchRequest
+}{
+	{
+		testName: "Search listed indexes at once",
+		payload: `{"index_names": ["books", "articles"], "query": "go"}`,
+		userIndexes: []string{"articles", "books", "notes"},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.SearchHit{{Document: models.Document{Title: "Go"}, Index: "books"}, {Document: models.Document{Title: "Go"}, Index: "articles"}},
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Indexes: []string{"books", "articles"}, Query: "go", DidYouMeanMaxHits: -1},
+	},
+	{
+		testName: "Search all indexes of user",
+		payload: `{"index_names": ["*"], "query": "go"}`,
+		userIndexes: []string{"articles", "books"},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.SearchHit{{Document: models.Document{Title: "Go"}, Index: "books"}, {Document: models.Document{Title: "Go"}, Index: "articles"}},
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Indexes: []string{"articles", "books"}, Query: "go", DidYouMeanMaxHits: -1, IgnoreUnavailable: true},
+	},
+	{
+		testName: "Return no documents when user has no indexes",
+		payload: `{"index_names": ["*"], "query": "go"}`,
+		userIndexes: []string{},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.Document{},
+		},
+	},
+	{
+		testName: "Return 403 if user has no rights for one of indexes",
+		payload: `{"index_names": ["books", "secret"], "query": "go"}`,
+		userIndexes: []string{"books"},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 403,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 if sort field isn't sortable in one of indexes",
+		payload: `{"index_names": ["books", "articles"], "query": "go", "sort": [{"field": "fields.price"}]}`,
+		userIndexes: []string{"articles", "books"},
+		indexStorage: &storage.IndexStorageMock{Indexes: []models.IndexMetadata{{Name: "books", Schema: testSchema}}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "sort[0].field", Message: "field must be _score, title or sortable schema field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "sort[0].field", Message: "field must be _score, title or sortable schema field"},
+			},
+		},
+	},
+}
+
+func TestSearchDocumentsMultiIndex(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range searchDocumentsMultiIndexTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, Hits: []models.SearchHit{{Document: models.Document{Title: "Go"}, Index: "books"}, {Document: models.Document{Title: "Go"}, Index: "articles"}}}
+		userStorage := &storage.UserStorageMock{UserIndexes: test.userIndexes}
+		server := NewServer("", nil, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedSearchRequest != nil {
+			assert.Equal(t, docStorage.SearchRequest, test.expectedSearchRequest, "wrong search request")
+		}
+	}
+}
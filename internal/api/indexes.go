@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/reindex"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+	"github.com/xavesen/search-api/internal/validation"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	errAnalysisNotConfigured	= utils.NewAPIError(http.StatusConflict, utils.CodeIndexNotConfigurable, "Index was created without analysis settings, recreate it to configure synonyms")
+	errInvalidSuggestSize		= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, fmt.Sprintf("Invalid size, expected integer from 1 to %d", models.MaxSuggestSize))
+)
+
+func (s *Server) getIndexSchema(w http.ResponseWriter, r *http.Request) {
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	schema, err := s.getSchema(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if schema == nil {
+		schema = &models.IndexSchema{Fields: map[string]models.SchemaField{}}
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", schema)
+}
+
+// updateIndexSchema adds new fields to index schema, existing fields can't be changed or removed.
+func (s *Server) updateIndexSchema(w http.ResponseWriter, r *http.Request) {
+	schemaUpdate := &models.IndexSchema{}
+	if !s.decodePayload(w, r, schemaUpdate) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, validation.ValidateIndexSchema("schema", schemaUpdate)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	schema, err := s.getSchema(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if schema == nil {
+		schema = &models.IndexSchema{Fields: map[string]models.SchemaField{}}
+	}
+
+	if !checkFieldErrors(w, r, validation.ValidateSchemaUpdate("schema.fields", schema.Fields, schemaUpdate.Fields)) {
+		return
+	}
+
+	mergedSchema := &models.IndexSchema{Fields: models.MergeSchemaFields(schema.Fields, schemaUpdate.Fields)}
+	if !checkFieldErrors(w, r, validation.ValidateIndexSchema("schema", mergedSchema)) {
+		return
+	}
+
+	// reindex would replace schema with the one it started with
+	err = s.reindexer.UnlessRunning(context.TODO(), indexName, func() error {
+		// mapping is updated first, so db never has fields ES doesn't know about
+		if err := s.docStorage.UpdateIndexSchema(context.TODO(), indexName, mergedSchema); err != nil {
+			return err
+		}
+		return s.indexStorage.UpdateIndexSchema(context.TODO(), indexName, mergedSchema)
+	})
+	if errors.Is(err, reindex.ErrAlreadyRunning) {
+		utils.WriteError(w, r, errReindexInProgress)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", mergedSchema)
+}
+
+// updateIndexSynonyms replaces synonyms of the index, they are applied at search time so documents aren't reindexed.
+func (s *Server) updateIndexSynonyms(w http.ResponseWriter, r *http.Request) {
+	synonymsRequest := &models.UpdateSynonymsRequest{}
+	if !s.decodePayload(w, r, synonymsRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateUpdateSynonymsRequest(synonymsRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := s.indexStorage.GetIndexMetadata(context.TODO(), indexName)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// title and text of indexes created before analysis was supported don't use content analyzers
+	if index == nil || index.Analysis == nil {
+		utils.WriteError(w, r, errAnalysisNotConfigured)
+		return
+	}
+
+	analysis := *index.Analysis
+	analysis.Synonyms = synonymsRequest.Synonyms
+
+	// reindex would replace synonyms with analysis it started with
+	err = s.reindexer.UnlessRunning(context.TODO(), indexName, func() error {
+		if err := s.docStorage.UpdateIndexAnalysis(context.TODO(), indexName, &analysis); err != nil {
+			return err
+		}
+		return s.indexStorage.UpdateIndexAnalysis(context.TODO(), indexName, &analysis)
+	})
+	if errors.Is(err, reindex.ErrAlreadyRunning) {
+		utils.WriteError(w, r, errReindexInProgress)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", &analysis)
+}
+
+// updateIndexFuzzy replaces default fuzzy settings applied to match queries of the index.
+func (s *Server) updateIndexFuzzy(w http.ResponseWriter, r *http.Request) {
+	fuzzy := &models.FuzzySettings{}
+	if !s.decodePayload(w, r, fuzzy) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateUpdateFuzzyRequest(fuzzy)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.indexStorage.UpdateIndexFuzzySettings(context.TODO(), indexName, fuzzy)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", fuzzy)
+}
+
+// updateIndexDedupe replaces dedupe policy, it applies to documents indexed afterwards.
+func (s *Server) updateIndexDedupe(w http.ResponseWriter, r *http.Request) {
+	dedupeRequest := &models.UpdateDedupeRequest{}
+	if !s.decodePayload(w, r, dedupeRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateUpdateDedupeRequest(dedupeRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	err := s.indexStorage.UpdateIndexDedupe(context.TODO(), indexName, dedupeRequest.Dedupe)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", dedupeRequest)
+}
+
+// suggestDocuments returns titles matching partially typed query, indexes created before
+// suggestions were supported have no suggest subfield and return no suggestions.
+func (s *Server) suggestDocuments(w http.ResponseWriter, r *http.Request) {
+	suggestRequest := &models.SuggestRequest{
+		Prefix: r.URL.Query().Get("prefix"),
+		Size: models.DefaultSuggestSize,
+	}
+
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		size, err := strconv.Atoi(sizeParam)
+		if err != nil || size < 1 || size > models.MaxSuggestSize {
+			utils.WriteError(w, r, errInvalidSuggestSize)
+			return
+		}
+		suggestRequest.Size = size
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateSuggestRequest(suggestRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+	suggestRequest.Index = indexName
+
+	index, err := s.getIndexMetadata(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	suggestRequest.CollapseField = storage.SuggestCollapseField(index)
+
+	suggestions, err := s.docStorage.Suggest(context.TODO(), suggestRequest)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", &models.SuggestResponse{Suggestions: suggestions})
+}
+
+// checkIndexAccess validates index name from path and checks that it exists and user has rights for it.
+func (s *Server) checkIndexAccess(w http.ResponseWriter, r *http.Request) (string, bool) {
+	indexName := mux.Vars(r)["index"]
+	if !checkFieldErrors(w, r, validation.ValidateIndexName("index", indexName)) {
+		return "", false
+	}
+
+	userId := r.Context().Value(utils.ContextKeyUserId).(string)
+	userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), userId, indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return "", false
+	}
+
+	indexExists, err := s.docStorage.IndexExists(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return "", false
+	}
+
+	if !indexExists || !userHasAccess {
+		utils.WriteError(w, r, utils.ErrIndexNotFound)
+		return "", false
+	}
+
+	return indexName, true
+}
+
+// getSchema returns nil schema for indexes created without one or before schemas were supported.
+func (s *Server) getSchema(ctx context.Context, indexName string) (*models.IndexSchema, error) {
+	index, err := s.getIndexMetadata(ctx, indexName)
+	if err != nil || index == nil {
+		return nil, err
+	}
+	return index.Schema, nil
+}
+
+// getIndexMetadata returns nil metadata for indexes created before metadata was stored.
+func (s *Server) getIndexMetadata(ctx context.Context, indexName string) (*models.IndexMetadata, error) {
+	index, err := s.indexStorage.GetIndexMetadata(ctx, indexName)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return index, nil
+}
@@ -0,0 +1,646 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var testSchema = &models.IndexSchema{
+	Fields: map[string]models.SchemaField{
+		"tags": {Type: models.FieldTypeKeyword},
+		"price": {Type: models.FieldTypeFloat},
+		"published_at": {Type: models.FieldTypeDate},
+		"authors": {Type: models.FieldTypeNested, Properties: map[string]models.SchemaField{
+			"name": {Type: models.FieldTypeText},
+		}},
+	},
+}
+
+var indexSchemaHandlerTests = []struct {
+	testName 			string
+	method				string
+	payload				string
+	docStorage 			*storage.DocStorageMock
+	userStorage 		*storage.UserStorageMock
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Return schema of index",
+		method: http.MethodGet,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{Success: true, Data: testSchema},
+	},
+	{
+		testName: "Return empty schema for index without metadata",
+		method: http.MethodGet,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{GetError: mongo.ErrNoDocuments},
+		expectedCode: 200,
+		expectedResponse: utils.Response{Success: true, Data: &models.IndexSchema{Fields: map[string]models.SchemaField{}}},
+	},
+	{
+		testName: "Return 403 when user has no access to index",
+		method: http.MethodGet,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{IndexAccess: false},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 403,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Add fields to schema",
+		method: http.MethodPatch,
+		payload: `{"fields": {"tags": {"type": "keyword"}, "authors": {"type": "nested", "properties": {"email": {"type": "keyword"}}}, "in_stock": {"type": "boolean"}}}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.IndexSchema{
+				Fields: map[string]models.SchemaField{
+					"tags": {Type: models.FieldTypeKeyword},
+					"price": {Type: models.FieldTypeFloat},
+					"published_at": {Type: models.FieldTypeDate},
+					"in_stock": {Type: models.FieldTypeBoolean},
+					"authors": {Type: models.FieldTypeNested, Properties: map[string]models.SchemaField{
+						"name": {Type: models.FieldTypeText},
+						"email": {Type: models.FieldTypeKeyword},
+					}},
+				},
+			},
+		},
+	},
+	{
+		testName: "Return 400 when type of existing field is changed",
+		method: http.MethodPatch,
+		payload: `{"fields": {"price": {"type": "keyword"}, "authors": {"type": "nested", "properties": {"name": {"type": "keyword"}}}}}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "schema.fields.authors.properties.name.type", Message: "type of existing field can't be changed from text"},
+				{Field: "schema.fields.price.type", Message: "type of existing field can't be changed from float"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "schema.fields.authors.properties.name.type", Message: "type of existing field can't be changed from text"},
+				{Field: "schema.fields.price.type", Message: "type of existing field can't be changed from float"},
+			},
+		},
+	},
+	{
+		testName: "Return 500 when ES mapping update fails",
+		method: http.MethodPatch,
+		payload: `{"fields": {"in_stock": {"type": "boolean"}}}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, UpdateSchemaError: errors.New("random error")},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}},
+		expectedCode: 500,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 409 without updating mapping when index is being reindexed",
+		method: http.MethodPatch,
+		payload: `{"fields": {"in_stock": {"type": "boolean"}}}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, UpdateSchemaError: errors.New("random error")},
+		userStorage: &storage.UserStorageMock{IndexAccess: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}, RunningReindexTasks: []models.ReindexTask{{Id: "running", Index: "test"}}},
+		expectedCode: 409,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index is already being reindexed, wait until the task finishes",
+			Error: &utils.APIError{Code: utils.CodeReindexInProgress, Message: "Index is already being reindexed, wait until the task finishes", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestIndexSchemaHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range indexSchemaHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		server := NewServer("", nil, test.docStorage, test.userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(test.method, "/indexes/test/schema", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+func TestIndexDocumentsSchemaValidation(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	docStorage := &storage.DocStorageMock{EsIndexExists: true}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	indexStorage := &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: testSchema}}
+	queueMock := &queue.QueueMock{}
+	server := NewServer("", queueMock, docStorage, userStorage, indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+	payload := `{"index_name": "test", "documents": [
+		{"title": "a", "fields": {"tags": ["go", "search"], "price": 9.5, "published_at": "2024-05-01", "authors": [{"name": "x"}]}},
+		{"title": "b", "fields": {"tags": [1], "price": "cheap", "published_at": "yesterday", "authors": [{"age": 3}], "color": "red"}}
+	]}`
+	req, err := http.NewRequest(http.MethodPost, "/indexDocuments", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	fieldErrors := []models.FieldError{
+		{Field: "documents[1].fields.authors[0].age", Message: "field is not defined in index schema"},
+		{Field: "documents[1].fields.color", Message: "field is not defined in index schema"},
+		{Field: "documents[1].fields.price", Message: "value must be a number"},
+		{Field: "documents[1].fields.published_at", Message: "value must be a date in ISO 8601 format or milliseconds since epoch"},
+		{Field: "documents[1].fields.tags[0]", Message: "value must be a string"},
+	}
+	expectedResp, err := json.Marshal(utils.Response{
+		Success: false,
+		ErrorMessage: "Invalid request payload",
+		Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: fieldErrors, RequestId: testRequestId},
+		Errors: fieldErrors,
+	})
+	if err != nil {
+		t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusBadRequest, "wrong response code")
+	assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	assert.Equal(t, len(queueMock.Messages), 0, "documents shouldn't be queued")
+}
+
+var indexSynonymsHandlerTests = []struct {
+	testName 			string
+	payload				string
+	docStorage 			*storage.DocStorageMock
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Replace synonyms keeping other analysis settings",
+		payload: `{"synonyms": ["tv, television", "laptop => notebook"]}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Analysis: &models.IndexAnalysis{Language: "english", Synonyms: []string{"a, b"}}}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.IndexAnalysis{Language: "english", Synonyms: []string{"tv, television", "laptop => notebook"}},
+		},
+	},
+	{
+		testName: "Return 400 on invalid synonym rules",
+		payload: `{"synonyms": ["tv", "a => b => c", " => notebook"]}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Analysis: &models.IndexAnalysis{}}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "synonyms[0]", Message: "synonym rule must contain at least two comma separated terms"},
+				{Field: "synonyms[1]", Message: "synonym rule must contain at most one =>"},
+				{Field: "synonyms[2]", Message: "both sides of => must contain terms"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "synonyms[0]", Message: "synonym rule must contain at least two comma separated terms"},
+				{Field: "synonyms[1]", Message: "synonym rule must contain at most one =>"},
+				{Field: "synonyms[2]", Message: "both sides of => must contain terms"},
+			},
+		},
+	},
+	{
+		testName: "Return 409 for index created without analysis",
+		payload: `{"synonyms": ["tv, television"]}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{GetError: mongo.ErrNoDocuments},
+		expectedCode: 409,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index was created without analysis settings, recreate it to configure synonyms",
+			Error: &utils.APIError{Code: utils.CodeIndexNotConfigurable, Message: "Index was created without analysis settings, recreate it to configure synonyms", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 409 without updating analyzers when index is being reindexed",
+		payload: `{"synonyms": ["tv, television"]}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, UpdateAnalysisError: errors.New("random error")},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Analysis: &models.IndexAnalysis{}}, RunningReindexTasks: []models.ReindexTask{{Id: "running", Index: "test"}}},
+		expectedCode: 409,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index is already being reindexed, wait until the task finishes",
+			Error: &utils.APIError{Code: utils.CodeReindexInProgress, Message: "Index is already being reindexed, wait until the task finishes", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestIndexSynonymsHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range indexSynonymsHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, test.docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPut, "/indexes/test/synonyms", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+var suggestDocumentsHandlerTests = []struct {
+	testName 			string
+	query				string
+	docStorage 			*storage.DocStorageMock
+	indexStorage		*storage.IndexStorageMock
+	indexAccess			bool
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedRequest		*models.SuggestRequest
+}{
+	{
+		testName: "Return suggestions with default size",
+		query: "prefix=wir",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, Suggestions: []models.Suggestion{{Id: "1", Title: "Wireless mouse", Score: 2.5}}},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test"}},
+		indexAccess: true,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SuggestResponse{Suggestions: []models.Suggestion{{Id: "1", Title: "Wireless mouse", Score: 2.5}}},
+		},
+		expectedRequest: &models.SuggestRequest{Index: "test", Prefix: "wir", Size: models.DefaultSuggestSize, CollapseField: "title.keyword"},
+	},
+	{
+		testName: "Pass requested size to storage without collapse for index without metadata",
+		query: "prefix=wireless+mo&size=10",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, Suggestions: []models.Suggestion{}},
+		indexStorage: &storage.IndexStorageMock{},
+		indexAccess: true,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SuggestResponse{Suggestions: []models.Suggestion{}},
+		},
+		expectedRequest: &models.SuggestRequest{Index: "test", Prefix: "wireless mo", Size: 10},
+	},
+	{
+		testName: "Return 400 on missing prefix",
+		query: "",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{},
+		indexAccess: true,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "prefix", Message: "prefix is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "prefix", Message: "prefix is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on size out of range",
+		query: "prefix=wir&size=100",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{},
+		indexAccess: true,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid size, expected integer from 1 to 20",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid size, expected integer from 1 to 20", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 403 when user has no rights for index",
+		query: "prefix=wir",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexStorage: &storage.IndexStorageMock{},
+		indexAccess: false,
+		expectedCode: 403,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestSuggestDocumentsHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range suggestDocumentsHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: test.indexAccess}
+		server := NewServer("", nil, test.docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodGet, "/indexes/test/suggest?"+test.query, nil)
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedRequest != nil {
+			assert.Equal(t, test.docStorage.SuggestRequest, test.expectedRequest, "wrong suggest request")
+		}
+	}
+}
+
+var testPrefixLength = 1
+
+var indexFuzzyHandlerTests = []struct {
+	testName 			string
+	payload				string
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Replace default fuzzy settings",
+		payload: `{"fuzziness": "AUTO", "prefix_length": 1}`,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.FuzzySettings{Fuzziness: "AUTO", PrefixLength: &testPrefixLength},
+		},
+	},
+	{
+		testName: "Return 400 on invalid fuzzy settings",
+		payload: `{"fuzziness": "3", "prefix_length": -1, "max_expansions": 1000}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "fuzziness", Message: "fuzziness must be one of AUTO, 0, 1, 2"},
+				{Field: "prefix_length", Message: "prefix_length must be from 0 to 20"},
+				{Field: "max_expansions", Message: "max_expansions must be from 1 to 200"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "fuzziness", Message: "fuzziness must be one of AUTO, 0, 1, 2"},
+				{Field: "prefix_length", Message: "prefix_length must be from 0 to 20"},
+				{Field: "max_expansions", Message: "max_expansions must be from 1 to 200"},
+			},
+		},
+	},
+}
+
+func TestIndexFuzzyHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range indexFuzzyHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPut, "/indexes/test/fuzzy", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+var indexDedupeHandlerTests = []struct {
+	testName 			string
+	payload				string
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Replace dedupe policy",
+		payload: `{"dedupe": "skip"}`,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.UpdateDedupeRequest{Dedupe: models.DedupeSkip},
+		},
+	},
+	{
+		testName: "Return 400 on unknown dedupe policy",
+		payload: `{"dedupe": "keep-both"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "dedupe", Message: "dedupe must be one of skip, overwrite, keep_both"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "dedupe", Message: "dedupe must be one of skip, overwrite, keep_both"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on missing dedupe policy",
+		payload: `{}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "dedupe", Message: "dedupe is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "dedupe", Message: "dedupe is required"},
+			},
+		},
+	},
+}
+
+func TestIndexDedupeHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range indexDedupeHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPut, "/indexes/test/dedupe", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
+
+var searchFuzzyDefaultsTests = []struct {
+	testName 				string
+	payload					string
+	indexStorage			*storage.IndexStorageMock
+	expectedFuzzySettings	models.FuzzySettings
+}{
+	{
+		testName: "Apply index default fuzzy settings",
+		payload: `{"index_name": "test", "query": "iphnoe"}`,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Fuzzy: &models.FuzzySettings{Fuzziness: "AUTO", PrefixLength: &testPrefixLength}}},
+		expectedFuzzySettings: models.FuzzySettings{Fuzziness: "AUTO", PrefixLength: &testPrefixLength},
+	},
+	{
+		testName: "Request settings override index defaults",
+		payload: `{"index_name": "test", "query": "iphnoe", "fuzziness": "0"}`,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Fuzzy: &models.FuzzySettings{Fuzziness: "AUTO", PrefixLength: &testPrefixLength}}},
+		expectedFuzzySettings: models.FuzzySettings{Fuzziness: "0", PrefixLength: &testPrefixLength},
+	},
+	{
+		testName: "Search indexes without metadata exactly",
+		payload: `{"index_name": "test", "query": "iphnoe"}`,
+		indexStorage: &storage.IndexStorageMock{GetError: mongo.ErrNoDocuments},
+		expectedFuzzySettings: models.FuzzySettings{},
+	},
+}
+
+func TestSearchFuzzyDefaults(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range searchFuzzyDefaultsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+		assert.Equal(t, docStorage.SearchRequest.FuzzySettings, test.expectedFuzzySettings, "wrong fuzzy settings")
+	}
+}
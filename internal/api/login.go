@@ -2,7 +2,6 @@ package api
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"time"
@@ -14,40 +13,39 @@ import (
 )
 
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
-	var loginRequest *models.LoginRequest
-
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&loginRequest); err != nil {
-		utils.WriteJSON(w, r, http.StatusBadRequest, false, "Invalid request payload", nil)
+	loginRequest := &models.LoginRequest{}
+	if !s.decodePayload(w, r, loginRequest) {
 		return
 	}
 
-	// TODO: validate payload
+	if !checkFieldErrors(w, r, s.validator.ValidateLoginRequest(loginRequest)) {
+		return
+	}
 
 	user, err := s.userStorage.GetUserInfoByLogin(context.TODO(), loginRequest.Login)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+			utils.WriteError(w, r, utils.ErrUnauthorized)
 		} else {
-			utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+			utils.WriteError(w, r, err)
 		}
 		return
 	}
 
 	if user.Password != loginRequest.Password {
-		utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+		utils.WriteError(w, r, utils.ErrUnauthorized)
 		return
 	}
 
 	accessToken, err := s.tokenOp.GenerateToken(user.Id, time.Now(), s.config.JwtAccessTTL, s.config.JwtKey)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	refreshToken, err := s.tokenOp.GenerateToken(user.Id, time.Now(), s.config.JwtRefreshTTL, s.config.JwtKey)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -55,7 +53,7 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 
 	err = s.userStorage.SetRefreshToken(context.TODO(), user.Id, hashedRefreshToken)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -63,27 +61,26 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
-	var refreshRequest *models.RefreshRequest
-
-	decoder := json.NewDecoder(r.Body)
-	if err := decoder.Decode(&refreshRequest); err != nil {
-		utils.WriteJSON(w, r, http.StatusBadRequest, false, "Invalid request payload", nil)
+	refreshRequest := &models.RefreshRequest{}
+	if !s.decodePayload(w, r, refreshRequest) {
 		return
 	}
 
-	// TODO: validate payload
+	if !checkFieldErrors(w, r, s.validator.ValidateRefreshRequest(refreshRequest)) {
+		return
+	}
 
 	valid, token, err := s.tokenOp.ValidateToken(refreshRequest.RefreshToken, s.config.JwtKey)
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Refresh token has expired", nil)
+			utils.WriteError(w, r, utils.ErrRefreshTokenExpired)
 		} else {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+			utils.WriteError(w, r, utils.ErrUnauthorized)
 		}
 		return
 	}
 	if !valid {
-		utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+		utils.WriteError(w, r, utils.ErrUnauthorized)
 		return
 	}
 
@@ -92,35 +89,35 @@ func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
 
 	blacklisted, err := s.userStorage.CheckIfTokenBlacklisted(context.TODO(), hashedRefreshToken)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if blacklisted {
-		utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Token is blacklisted", nil)
+		utils.WriteError(w, r, utils.ErrTokenBlacklisted)
 		return
 	}
 
 	user, err := s.userStorage.GetUserInfoById(context.TODO(), userId)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	if user.RefreshToken != hashedRefreshToken {
-		utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+		utils.WriteError(w, r, utils.ErrUnauthorized)
 		return
 	}
 
 	accessToken, err := s.tokenOp.GenerateToken(userId, time.Now(), s.config.JwtAccessTTL, s.config.JwtKey)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
 	refreshToken, err := s.tokenOp.GenerateToken(userId, time.Now(), s.config.JwtRefreshTTL, s.config.JwtKey)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -128,7 +125,7 @@ func (s *Server) refresh(w http.ResponseWriter, r *http.Request) {
 
 	err = s.userStorage.SetRefreshToken(context.TODO(), userId, hashedNewRefreshToken)
 	if err != nil {
-		utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+		utils.WriteError(w, r, err)
 		return
 	}
 
@@ -58,6 +58,7 @@ var loginTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -72,6 +73,7 @@ var loginTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -86,6 +88,7 @@ var loginTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -102,6 +105,7 @@ var loginTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -120,6 +124,7 @@ var loginTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -136,7 +141,7 @@ func TestLoginHandler(t *testing.T) {
 	for i, test := range loginTests {
 		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
 
-		server := NewServer("", nil, nil, test.userStorage, config, test.tokenOp)
+		server := NewServer("", nil, nil, test.userStorage, &storage.IndexStorageMock{}, config, test.tokenOp)
 
 		marshaledPayload, err := json.Marshal(test.payload)
 		if err != nil {
@@ -148,6 +153,7 @@ func TestLoginHandler(t *testing.T) {
 			t.Fatalf("Unable to create request, error: %s\n", err)
 		}
 		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
 
 		rr := httptest.NewRecorder()
 		server.router.ServeHTTP(rr, req)
@@ -200,6 +206,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Refresh token has expired",
+			Error: &utils.APIError{Code: utils.CodeTokenExpired, Message: "Refresh token has expired", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -214,6 +221,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -228,6 +236,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -245,6 +254,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -262,6 +272,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Token is blacklisted",
+			Error: &utils.APIError{Code: utils.CodeTokenBlacklisted, Message: "Token is blacklisted", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -279,6 +290,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -296,6 +308,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Unauthorized",
+			Error: &utils.APIError{Code: utils.CodeUnauthorized, Message: "Unauthorized", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -314,6 +327,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -334,6 +348,7 @@ var refreshTests = []struct {
 		expectedResponse: utils.Response{
 			Success: false,
 			ErrorMessage: "Internal server error",
+			Error: &utils.APIError{Code: utils.CodeInternal, Message: "Internal server error", RequestId: testRequestId},
 			Data: nil,
 		},
 	},
@@ -350,7 +365,7 @@ func TestRefreshHandler(t *testing.T) {
 	for i, test := range refreshTests {
 		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
 
-		server := NewServer("", nil, nil, test.userStorage, config, test.tokenOp)
+		server := NewServer("", nil, nil, test.userStorage, &storage.IndexStorageMock{}, config, test.tokenOp)
 
 		marshaledPayload, err := json.Marshal(test.payload)
 		if err != nil {
@@ -362,6 +377,7 @@ func TestRefreshHandler(t *testing.T) {
 			t.Fatalf("Unable to create request, error: %s\n", err)
 		}
 		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
 
 		rr := httptest.NewRecorder()
 		server.router.ServeHTTP(rr, req)
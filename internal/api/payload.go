@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+const unknownFieldErrorPrefix = "json: unknown field "
+
+// decodePayload decodes request body to payload rejecting unknown fields and bodies
+// bigger than configured limit. On failure error response is written and false is returned.
+// Numbers of untyped values like document fields are decoded as json.Number, so long
+// values above 2^53 aren't rounded by float64.
+func (s *Server) decodePayload(w http.ResponseWriter, r *http.Request, payload any) bool {
+	if s.config.MaxRequestSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.MaxRequestSize)
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	decoder.UseNumber()
+	if err := decoder.Decode(payload); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			utils.WriteError(w, r, utils.ErrPayloadTooLarge)
+		} else if strings.HasPrefix(err.Error(), unknownFieldErrorPrefix) {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldErrorPrefix), "\"")
+			utils.WriteValidationError(w, r, []models.FieldError{{Field: field, Message: "unknown field"}})
+		} else {
+			utils.WriteError(w, r, utils.ErrInvalidPayload)
+		}
+		return false
+	}
+
+	return true
+}
+
+// checkFieldErrors writes validation error response if there are any field errors and returns false in that case.
+func checkFieldErrors(w http.ResponseWriter, r *http.Request, fieldErrors []models.FieldError) bool {
+	if len(fieldErrors) > 0 {
+		utils.WriteValidationError(w, r, fieldErrors)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,578 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/alerting"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var payloadValidationTests = []struct {
+	testName 			string
+	url					string
+	payload				string
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Return 400 on unknown field",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "search", "size": 10}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "size", Message: "unknown field"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "size", Message: "unknown field"}},
+		},
+	},
+	{
+		testName: "Return 400 on empty index name and query",
+		url: "/searchDocuments",
+		payload: `{"index_name": "", "query": "  "}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "index_name", Message: "index name is required"},
+				{Field: "query", Message: "query is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "index_name", Message: "index name is required"},
+				{Field: "query", Message: "query is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid search clauses",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "search": {"bool": {"must": [{"match": {"field": "_id", "query": "x"}}, {"term": {"field": "tags"}}], "should": [{}]}}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "search.bool.must[0].match.field", Message: "internal fields can't be searched"},
+				{Field: "search.bool.must[1].term.value", Message: "value must be a string, number or boolean"},
+				{Field: "search.bool.should[0]", Message: "exactly one of match, match_phrase, multi_match, bool, term, range or prefix is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "search.bool.must[0].match.field", Message: "internal fields can't be searched"},
+				{Field: "search.bool.must[1].term.value", Message: "value must be a string, number or boolean"},
+				{Field: "search.bool.should[0]", Message: "exactly one of match, match_phrase, multi_match, bool, term, range or prefix is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 when search is combined with query",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "search": {"prefix": {"field": "title", "value": "sea"}}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "search", Message: "search can't be combined with query, syntax and default_operator"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "search", Message: "search can't be combined with query, syntax and default_operator"}},
+		},
+	},
+	{
+		testName: "Return 400 on unknown syntax",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "syntax": "lucene"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "syntax", Message: "syntax must be query_string or simple"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "syntax", Message: "syntax must be query_string or simple"}},
+		},
+	},
+	{
+		testName: "Return 400 on default operator without simple syntax",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "default_operator": "and"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "default_operator", Message: "default_operator is allowed only with simple syntax"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "default_operator", Message: "default_operator is allowed only with simple syntax"}},
+		},
+	},
+	{
+		testName: "Return 400 on auto correct with search DSL",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "search": {"term": {"field": "tags", "value": "news"}}, "auto_correct": true}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "auto_correct", Message: "auto_correct is allowed only with query"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "auto_correct", Message: "auto_correct is allowed only with query"}},
+		},
+	},
+	{
+		testName: "Return 400 on fuzziness with query_string syntax",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "syntax": "query_string", "fuzziness": "AUTO"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "fuzziness", Message: "fuzziness is not supported with query_string syntax, use ~ operator"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "fuzziness", Message: "fuzziness is not supported with query_string syntax, use ~ operator"}},
+		},
+	},
+	{
+		testName: "Return 400 on invalid sort and text included in snippet only mode",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "sort": [{"field": "title", "order": "up"}, {"field": "title"}], "_source": {"includes": ["text"]}, "snippet_only": true}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "sort[0].order", Message: "order must be asc or desc"},
+				{Field: "sort[1].field", Message: "field is already sorted by"},
+				{Field: "_source.includes", Message: "text can't be included in snippet_only mode"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "sort[0].order", Message: "order must be asc or desc"},
+				{Field: "sort[1].field", Message: "field is already sorted by"},
+				{Field: "_source.includes", Message: "text can't be included in snippet_only mode"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid index names list",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "index_names": ["books", "books", "*"], "query": "x"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "index_names", Message: "index_names can't be combined with index_name"},
+				{Field: "index_names", Message: "* can't be combined with index names"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "index_names", Message: "index_names can't be combined with index_name"},
+				{Field: "index_names", Message: "* can't be combined with index names"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on options not supported in hybrid mode",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "mode": "hybrid", "syntax": "simple", "sort": [{"field": "title"}], "aggregations": {"tags": {"terms": {"field": "tags"}}}, "post_filters": {"tags": {"term": {"field": "tags", "value": "go"}}}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "syntax", Message: "syntax is not supported in hybrid mode"},
+				{Field: "sort", Message: "sort is not supported in hybrid mode"},
+				{Field: "aggregations", Message: "aggregations is not supported in hybrid mode"},
+				{Field: "post_filters", Message: "post_filters is not supported in hybrid mode"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "syntax", Message: "syntax is not supported in hybrid mode"},
+				{Field: "sort", Message: "sort is not supported in hybrid mode"},
+				{Field: "aggregations", Message: "aggregations is not supported in hybrid mode"},
+				{Field: "post_filters", Message: "post_filters is not supported in hybrid mode"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on unknown search mode",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "mode": "semantic"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "mode", Message: "mode must be one of keyword, knn, hybrid"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "mode", Message: "mode must be one of keyword, knn, hybrid"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on unknown default operator",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "syntax": "simple", "default_operator": "xor"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "default_operator", Message: "default_operator must be and or or"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "default_operator", Message: "default_operator must be and or or"}},
+		},
+	},
+	{
+		testName: "Return 400 on invalid aggregations",
+		url: "/searchDocuments",
+		payload: `{"index_name": "test", "query": "x", "aggregations": {"tags": {"terms": {"field": "tags", "size": 0}}, "months": {"date_histogram": {"field": "published_at", "calendar_interval": "fortnight"}}, "empty": {}}, "post_filters": {"author": {"term": {"field": "author", "value": "x"}}}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "aggregations.empty", Message: "exactly one of terms, date_histogram, range or cardinality is required"},
+				{Field: "aggregations.months.date_histogram.calendar_interval", Message: "calendar_interval must be one of minute, 1m, hour, 1h, day, 1d, week, 1w, month, 1M, quarter, 1q, year, 1y"},
+				{Field: "aggregations.tags.terms.size", Message: "size must be between 1 and 1000"},
+				{Field: "post_filters.author", Message: "post filter must belong to one of requested aggregations"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "aggregations.empty", Message: "exactly one of terms, date_histogram, range or cardinality is required"},
+				{Field: "aggregations.months.date_histogram.calendar_interval", Message: "calendar_interval must be one of minute, 1m, hour, 1h, day, 1d, week, 1w, month, 1M, quarter, 1q, year, 1y"},
+				{Field: "aggregations.tags.terms.size", Message: "size must be between 1 and 1000"},
+				{Field: "post_filters.author", Message: "post filter must belong to one of requested aggregations"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid index schema",
+		url: "/createIndex",
+		payload: `{"index_name": "test", "schema": {"fields": {"price": {"type": "money"}, "authors": {"type": "nested"}, "tags": {"type": "keyword", "properties": {"x": {"type": "text"}}}, "a.b": {"type": "text"}}}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "schema.fields.a.b", Message: "field name must start with a letter and contain only letters, digits, _ and - up to 64 characters"},
+				{Field: "schema.fields.authors.properties", Message: "properties are required for nested fields"},
+				{Field: "schema.fields.price.type", Message: "type must be one of keyword, text, date, long, integer, float, double, boolean, object, nested"},
+				{Field: "schema.fields.tags.properties", Message: "properties are allowed only for object and nested fields"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "schema.fields.a.b", Message: "field name must start with a letter and contain only letters, digits, _ and - up to 64 characters"},
+				{Field: "schema.fields.authors.properties", Message: "properties are required for nested fields"},
+				{Field: "schema.fields.price.type", Message: "type must be one of keyword, text, date, long, integer, float, double, boolean, object, nested"},
+				{Field: "schema.fields.tags.properties", Message: "properties are allowed only for object and nested fields"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid index analysis",
+		url: "/createIndex",
+		payload: `{"index_name": "test", "analysis": {"language": "klingon", "stopwords": [" "], "synonyms": ["tv"]}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "analysis.language", Message: "language must be one of english, russian, german, french, spanish, italian, portuguese, dutch"},
+				{Field: "analysis.stopwords[0]", Message: "stopword must not be empty"},
+				{Field: "analysis.synonyms[0]", Message: "synonym rule must contain at least two comma separated terms"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "analysis.language", Message: "language must be one of english, russian, german, french, spanish, italian, portuguese, dutch"},
+				{Field: "analysis.stopwords[0]", Message: "stopword must not be empty"},
+				{Field: "analysis.synonyms[0]", Message: "synonym rule must contain at least two comma separated terms"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid vector settings",
+		url: "/createIndex",
+		payload: `{"index_name": "test", "vector": {"similarity": "jaccard", "dimensions": 3}}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "vector.similarity", Message: "similarity must be one of cosine, dot_product, l2_norm"},
+				{Field: "vector.dimensions", Message: "dimensions are set from embedder and can't be specified"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "vector.similarity", Message: "similarity must be one of cosine, dot_product, l2_norm"},
+				{Field: "vector.dimensions", Message: "dimensions are set from embedder and can't be specified"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on reserved index name",
+		url: "/createIndex",
+		payload: `{"index_name": "saved-searches"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "index_name", Message: "index name is reserved"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "index_name", Message: "index name is reserved"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid saved search",
+		url: "/indexes/test/saved-searches",
+		payload: `{"name": " ", "webhook_url": "ftp://hooks.example.com"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "name", Message: "name is required"},
+				{Field: "webhook_url", Message: "webhook_url must be absolute http or https url"},
+				{Field: "query", Message: "query or search is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "name", Message: "name is required"},
+				{Field: "webhook_url", Message: "webhook_url must be absolute http or https url"},
+				{Field: "query", Message: "query or search is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on saved search webhook to private address",
+		url: "/indexes/test/saved-searches",
+		payload: `{"name": "acme", "query": "acme", "webhook_url": "http://169.254.169.254/latest"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "webhook_url", Message: "webhook_url must not point to loopback, private or link-local address"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "webhook_url", Message: "webhook_url must not point to loopback, private or link-local address"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on invalid webhook",
+		url: "/indexes/test/webhooks",
+		payload: `{"url": "hooks.example.com", "secret": "short", "events": ["documents.indexed", "documents.deleted", "documents.indexed"]}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "url", Message: "url must be absolute http or https url"},
+				{Field: "secret", Message: "secret must be from 16 to 256 bytes"},
+				{Field: "events[1]", Message: "unknown event documents.deleted"},
+				{Field: "events[2]", Message: "duplicate event documents.indexed"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "url", Message: "url must be absolute http or https url"},
+				{Field: "secret", Message: "secret must be from 16 to 256 bytes"},
+				{Field: "events[1]", Message: "unknown event documents.deleted"},
+				{Field: "events[2]", Message: "duplicate event documents.indexed"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on webhook to loopback address",
+		url: "/indexes/test/webhooks",
+		payload: `{"url": "http://localhost:9200/_bulk", "secret": "0123456789abcdef", "events": ["index.created"]}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "url", Message: "url must not point to loopback, private or link-local address"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "url", Message: "url must not point to loopback, private or link-local address"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on webhook without url and events",
+		url: "/indexes/test/webhooks",
+		payload: `{"secret": "0123456789abcdef"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "url", Message: "url is required"},
+				{Field: "events", Message: "at least one event is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "url", Message: "url is required"},
+				{Field: "events", Message: "at least one event is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on uppercase index name",
+		url: "/createIndex",
+		payload: `{"index_name": "Test"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "index_name", Message: "index name must be lowercase"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "index_name", Message: "index name must be lowercase"}},
+		},
+	},
+	{
+		testName: "Return 400 on index name starting with underscore",
+		url: "/createIndex",
+		payload: `{"index_name": "_test"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "index_name", Message: "index name must not start with -, _ or +"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "index_name", Message: "index name must not start with -, _ or +"}},
+		},
+	},
+	{
+		testName: "Return 400 on index name with wildcard or comma",
+		url: "/createIndex",
+		payload: `{"index_name": "test*,other"}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "index_name", Message: `index name must not contain any of "\\/*?\"<>| ,#:"`}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "index_name", Message: `index name must not contain any of "\\/*?\"<>| ,#:"`}},
+		},
+	},
+	{
+		testName: "Return 400 on zero documents",
+		url: "/indexDocuments",
+		payload: `{"index_name": "test", "documents": []}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "documents", Message: "at least one document is required"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "documents", Message: "at least one document is required"}},
+		},
+	},
+	{
+		testName: "Return 400 on too many, empty and too big documents",
+		url: "/indexDocuments",
+		payload: `{"index_name": "test", "documents": [{"title": "t"}, {"title": ""}, {"text": "` + strings.Repeat("a", 100) + `"}]}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "documents", Message: "at most 2 documents are allowed per request"},
+				{Field: "documents[1]", Message: "title or text is required"},
+				{Field: "documents[2]", Message: "document size exceeds 64 bytes"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "documents", Message: "at most 2 documents are allowed per request"},
+				{Field: "documents[1]", Message: "title or text is required"},
+				{Field: "documents[2]", Message: "document size exceeds 64 bytes"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on embedding sent with document",
+		url: "/indexDocuments",
+		payload: `{"index_name": "test", "documents": [{"title": "t", "embedding": [0.5]}]}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "embedding", Message: "unknown field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "embedding", Message: "unknown field"},
+			},
+		},
+	},
+	{
+		testName: "Return 413 when request is bigger than limit",
+		url: "/indexDocuments",
+		payload: `{"index_name": "test", "documents": [{"text": "` + strings.Repeat("a", 1024) + `"}]}`,
+		expectedCode: 413,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Request payload is too large",
+			Error: &utils.APIError{Code: utils.CodePayloadTooLarge, Message: "Request payload is too large", RequestId: testRequestId},
+			Data: nil,
+		},
+	},
+	{
+		testName: "Return 400 on empty login and password",
+		url: "/login",
+		payload: `{}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "login", Message: "login is required"},
+				{Field: "password", Message: "password is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "login", Message: "login is required"},
+				{Field: "password", Message: "password is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on empty refresh token",
+		url: "/refresh",
+		payload: `{"refresh_token": ""}`,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{{Field: "refresh_token", Message: "refresh_token is required"}}, RequestId: testRequestId},
+			Errors: []models.FieldError{{Field: "refresh_token", Message: "refresh_token is required"}},
+		},
+	},
+}
+
+func TestPayloadValidation(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		MaxRequestSize: 1024,
+		MaxDocumentSize: 64,
+		MaxDocumentsPerRequest: 2,
+	}
+	for i, test := range payloadValidationTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", &queue.QueueMock{}, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithSavedSearches(&storage.SavedSearchStorageMock{}, alerting.NewEvaluator(&storage.SavedSearchStorageMock{}, http.DefaultClient)), WithWebhooks(&storage.WebhookStorageMock{}, nil, true))
+
+		req, err := http.NewRequest(http.MethodPost, test.url, bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+	}
+}
@@ -18,7 +18,7 @@ func TestPingHandler(t *testing.T) {
 		TokenHeaderName: "aaa",
 	}
 
-	server := NewServer("", nil, nil, nil, config, nil)
+	server := NewServer("", nil, nil, nil, nil, config, nil)
 
 	req, err := http.NewRequest(http.MethodGet, "/ping", nil)
 	if err != nil {
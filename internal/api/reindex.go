@@ -0,0 +1,75 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/reindex"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var errReindexInProgress = utils.NewAPIError(http.StatusConflict, utils.CodeReindexInProgress, "Index is already being reindexed, wait until the task finishes")
+
+// reindexIndex copies index to its next version with new settings in background, index name
+// points to the new version once documents are copied, so clients don't change anything.
+func (s *Server) reindexIndex(w http.ResponseWriter, r *http.Request) {
+	reindexRequest := &models.ReindexRequest{}
+	if !s.decodePayload(w, r, reindexRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateReindexRequest(reindexRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := s.getIndexMetadata(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	// indexes created before metadata was stored are ES indexes named as the index
+	if index == nil {
+		index = &models.IndexMetadata{Name: indexName}
+	}
+
+	task, err := s.reindexer.Start(context.TODO(), index, reindexRequest)
+	if errors.Is(err, reindex.ErrAlreadyRunning) {
+		utils.WriteError(w, r, errReindexInProgress)
+		return
+	}
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusAccepted, true, "", task)
+}
+
+func (s *Server) getReindexTask(w http.ResponseWriter, r *http.Request) {
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	task, err := s.indexStorage.GetReindexTask(context.TODO(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	if task.Index != indexName {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", task)
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var testReindexConfig = &config.Config{
+	JwtKey: []byte("aaa"),
+	TokenHeaderName: "aaa",
+	JwtSalt: "aaa",
+}
+
+func TestReindexHandler(t *testing.T) {
+	docStorage := &storage.DocStorageMock{EsIndexExists: true, ReindexProgress: &models.ReindexProgress{Completed: true, Total: 2, Copied: 2}}
+	indexStorage := &storage.IndexStorageMock{Index: &models.IndexMetadata{
+		Name: "test",
+		Version: 1,
+		Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"price": {Type: models.FieldTypeInteger}}},
+		Dedupe: models.DedupeSkip,
+	}}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	server := NewServer("", nil, docStorage, userStorage, indexStorage, testReindexConfig, &utils.TokenOperatorMock{TokenValid: true})
+
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/_reindex", strings.NewReader(`{"schema": {"fields": {"price": {"type": "float"}}}}`))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(testReindexConfig.TokenHeaderName, "aaa")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	server.reindexer.Wait()
+
+	var response struct {
+		Data	models.ReindexTask	`json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to unmarshal response, error: %s\n", err)
+	}
+
+	assert.Equal(t, rr.Code, http.StatusAccepted, "wrong response code")
+	assert.Equal(t, response.Data.Id, "task", "wrong task id")
+	assert.Equal(t, response.Data.Status, models.ReindexStatusRunning, "wrong task status")
+	assert.Equal(t, response.Data.SourceIndex, "test~v1", "wrong source index")
+	assert.Equal(t, response.Data.DestIndex, "test~v2", "wrong dest index")
+	assert.Equal(t, docStorage.IndexVersion.Schema.Fields["price"].Type, models.FieldTypeFloat, "new version must use schema of the request")
+	assert.Equal(t, docStorage.IndexVersion.Dedupe, models.DedupeSkip, "new version must keep other settings")
+	assert.Equal(t, docStorage.SwappedIndexes, []string{"test~v1", "test~v2"}, "wrong swapped indexes")
+	assert.Equal(t, indexStorage.UpdatedVersion, 2, "wrong recorded index version")
+}
+
+var reindexErrorHandlerTests = []struct {
+	testName 			string
+	method				string
+	url					string
+	payload				string
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+}{
+	{
+		testName: "Return 409 when index is already being reindexed",
+		method: http.MethodPost,
+		url: "/indexes/test/_reindex",
+		payload: `{}`,
+		indexStorage: &storage.IndexStorageMock{RunningReindexTasks: []models.ReindexTask{{Id: "running", Index: "test"}}},
+		expectedCode: 409,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index is already being reindexed, wait until the task finishes",
+			Error: &utils.APIError{Code: utils.CodeReindexInProgress, Message: "Index is already being reindexed, wait until the task finishes", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on invalid schema",
+		method: http.MethodPost,
+		url: "/indexes/test/_reindex",
+		payload: `{"schema": {"fields": {"price": {"type": "money"}}}}`,
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{
+				Code: utils.CodeValidationFailed,
+				Message: "Invalid request payload",
+				Details: []models.FieldError{{Field: "schema.fields.price.type", Message: "type must be one of keyword, text, date, long, integer, float, double, boolean, object, nested"}},
+				RequestId: testRequestId,
+			},
+			Errors: []models.FieldError{{Field: "schema.fields.price.type", Message: "type must be one of keyword, text, date, long, integer, float, double, boolean, object, nested"}},
+		},
+	},
+	{
+		testName: "Return task status",
+		method: http.MethodGet,
+		url: "/indexes/test/_reindex/task",
+		indexStorage: &storage.IndexStorageMock{ReindexTask: &models.ReindexTask{Id: "task", Index: "test", SourceIndex: "test", DestIndex: "test~v1", Version: 1, Status: models.ReindexStatusRunning, Total: 10, Copied: 4}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.ReindexTask{Id: "task", Index: "test", SourceIndex: "test", DestIndex: "test~v1", Version: 1, Status: models.ReindexStatusRunning, Total: 10, Copied: 4},
+		},
+	},
+	{
+		testName: "Return 404 on task of other index",
+		method: http.MethodGet,
+		url: "/indexes/test/_reindex/task",
+		indexStorage: &storage.IndexStorageMock{ReindexTask: &models.ReindexTask{Id: "task", Index: "other"}},
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestReindexErrorHandler(t *testing.T) {
+	for i, test := range reindexErrorHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, test.indexStorage, testReindexConfig, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(test.method, test.url, strings.NewReader(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(testReindexConfig.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		assert.Equal(t, docStorage.IndexVersion == nil, true, "new index version must not be created")
+	}
+}
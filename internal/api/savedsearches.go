@@ -0,0 +1,107 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var errInvalidMatchesSize = utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, fmt.Sprintf("Invalid size, expected integer from 1 to %d", models.MaxMatchesSize))
+
+// createSavedSearch stores query evaluated against documents indexed to the index later on.
+func (s *Server) createSavedSearch(w http.ResponseWriter, r *http.Request) {
+	if s.evaluator == nil {
+		utils.WriteError(w, r, utils.ErrSavedSearchesUnavailable)
+		return
+	}
+
+	savedSearchRequest := &models.SavedSearchRequest{}
+	if !s.decodePayload(w, r, savedSearchRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateSavedSearchRequest(savedSearchRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	savedSearch := &models.SavedSearch{
+		Index: indexName,
+		UserId: r.Context().Value(utils.ContextKeyUserId).(string),
+		SavedSearchRequest: *savedSearchRequest,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := s.savedSearchStorage.CreateSavedSearch(context.TODO(), savedSearch)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", savedSearch)
+}
+
+// getSavedSearchMatches returns latest documents matched by saved search, saved searches of
+// indexes user has no rights for are reported as missing.
+func (s *Server) getSavedSearchMatches(w http.ResponseWriter, r *http.Request) {
+	size := models.DefaultMatchesSize
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		var err error
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < 1 || size > models.MaxMatchesSize {
+			utils.WriteError(w, r, errInvalidMatchesSize)
+			return
+		}
+	}
+
+	savedSearch, err := s.savedSearchStorage.GetSavedSearch(context.TODO(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if savedSearch == nil {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextKeyUserId).(string)
+	userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), userId, savedSearch.Index)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if !userHasAccess {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return
+	}
+
+	matches, err := s.savedSearchStorage.GetMatches(context.TODO(), savedSearch.Id, size)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", matches)
+}
+
+// evaluateSavedSearches matches documents indexed in sync mode, documents are already
+// indexed, so errors are only logged.
+func (s *Server) evaluateSavedSearches(ctx context.Context, indexName string, documents []models.Document, results []models.DocumentIndexingResult) {
+	if s.evaluator == nil {
+		return
+	}
+	if err := s.evaluator.Evaluate(ctx, indexName, documents, results); err != nil {
+		log.Errorf("Error evaluating saved searches for %d documents indexed to index %s: %s", len(documents), indexName, err)
+	}
+}
@@ -0,0 +1,212 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/alerting"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+func TestCreateSavedSearchHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	docStorage := &storage.DocStorageMock{EsIndexExists: true}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	savedSearchStorage := &storage.SavedSearchStorageMock{SavedSearchId: "s1"}
+	evaluator := alerting.NewEvaluator(savedSearchStorage, http.DefaultClient)
+	server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithSavedSearches(savedSearchStorage, evaluator))
+
+	payload := `{"name": "Product mentions", "query": "acme", "webhook_url": "https://hooks.example.com/acme"}`
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/saved-searches", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	var response struct {
+		Success	bool				`json:"success"`
+		Data	models.SavedSearch	`json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to unmarshal response, error: %s\n", err)
+	}
+
+	assert.Equal(t, rr.Code, 200, "wrong response code")
+	assert.Equal(t, response.Data.Id, "s1", "wrong saved search id")
+	assert.Equal(t, response.Data.Index, "test", "wrong saved search index")
+	assert.Equal(t, savedSearchStorage.CreatedSavedSearch.SavedSearchRequest, models.SavedSearchRequest{Name: "Product mentions", Query: "acme", WebhookURL: "https://hooks.example.com/acme"}, "wrong saved search")
+	assert.Equal(t, savedSearchStorage.CreatedSavedSearch.UserId != "", true, "saved search must belong to user")
+}
+
+var savedSearchMatchesHandlerTests = []struct {
+	testName 			string
+	url					string
+	indexAccess			bool
+	savedSearchStorage	*storage.SavedSearchStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedSize		int
+}{
+	{
+		testName: "Return matches of saved search",
+		url: "/saved-searches/s1/matches?size=5",
+		indexAccess: true,
+		savedSearchStorage: &storage.SavedSearchStorageMock{
+			SavedSearch: &models.SavedSearch{Id: "s1", Index: "test"},
+			Matches: []models.SavedSearchMatch{{SavedSearchId: "s1", Index: "test", DocumentId: "d1", Title: "Acme", MatchedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}},
+		},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SavedSearchMatchesResponse{Total: 1, Matches: []models.SavedSearchMatch{{SavedSearchId: "s1", Index: "test", DocumentId: "d1", Title: "Acme", MatchedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)}}},
+		},
+		expectedSize: 5,
+	},
+	{
+		testName: "Return 404 on unknown saved search",
+		url: "/saved-searches/s1/matches",
+		indexAccess: true,
+		savedSearchStorage: &storage.SavedSearchStorageMock{},
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 404 on saved search of index user has no rights for",
+		url: "/saved-searches/s1/matches",
+		indexAccess: false,
+		savedSearchStorage: &storage.SavedSearchStorageMock{SavedSearch: &models.SavedSearch{Id: "s1", Index: "secret"}},
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on invalid size",
+		url: "/saved-searches/s1/matches?size=1000",
+		indexAccess: true,
+		savedSearchStorage: &storage.SavedSearchStorageMock{SavedSearch: &models.SavedSearch{Id: "s1", Index: "test"}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid size, expected integer from 1 to 100",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid size, expected integer from 1 to 100", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestSavedSearchMatchesHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range savedSearchMatchesHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: test.indexAccess}
+		server := NewServer("", nil, &storage.DocStorageMock{}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithSavedSearches(test.savedSearchStorage, nil))
+
+		req, err := http.NewRequest(http.MethodGet, test.url, nil)
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedSize != 0 {
+			assert.Equal(t, test.savedSearchStorage.MatchesSize, test.expectedSize, "wrong matches size")
+		}
+	}
+}
+
+func TestIndexDocumentsSyncSavedSearchMatches(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SyncIndexingMaxBatch: 10,
+	}
+
+	docStorage := &storage.DocStorageMock{EsIndexExists: true, IndexingResults: []models.DocumentIndexingResult{{Position: 0, Id: "d1", Result: models.IndexingResultCreated}}}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	savedSearchStorage := &storage.SavedSearchStorageMock{
+		PercolatedSearches: []models.PercolatedSearch{{SavedSearch: models.SavedSearch{Id: "s1", Index: "test"}, Documents: []int{0}}},
+	}
+	evaluator := alerting.NewEvaluator(savedSearchStorage, http.DefaultClient)
+	server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithSavedSearches(savedSearchStorage, evaluator))
+
+	payload := `{"index_name": "test", "documents": [{"title": "Acme launch", "text": "Acme ships"}]}`
+	req, err := http.NewRequest(http.MethodPost, "/indexDocuments?mode=sync", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 200, "wrong response code")
+	assert.Equal(t, len(savedSearchStorage.Matches), 1, "wrong number of recorded matches")
+	assert.Equal(t, savedSearchStorage.Matches[0].DocumentId, "d1", "wrong matched document")
+}
+
+func TestCreateSavedSearchWithoutEvaluator(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	savedSearchStorage := &storage.SavedSearchStorageMock{SavedSearchId: "s1"}
+	server := NewServer("", nil, &storage.DocStorageMock{EsIndexExists: true}, &storage.UserStorageMock{IndexAccess: true}, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithSavedSearches(savedSearchStorage, nil))
+
+	payload := `{"name": "Product mentions", "query": "acme"}`
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/saved-searches", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 503, "saved searches must be rejected when queued documents aren't evaluated")
+	assert.Equal(t, savedSearchStorage.CreatedSavedSearch == nil, true, "saved search must not be stored")
+}
@@ -1,28 +1,78 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/alerting"
 	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/embedding"
+	"github.com/xavesen/search-api/internal/extract"
 	"github.com/xavesen/search-api/internal/middleware"
 	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/reindex"
 	"github.com/xavesen/search-api/internal/storage"
 	"github.com/xavesen/search-api/internal/utils"
+	"github.com/xavesen/search-api/internal/validation"
+	"github.com/xavesen/search-api/internal/webhook"
 )
 
 type Server struct {
 	listenAddr string
 	router     	*mux.Router
+	httpServer	*http.Server
 	queue      	queue.Queue
 	docStorage	storage.DocumentStorage
 	userStorage storage.UserStorage
+	indexStorage	storage.IndexStorage
+	savedSearchStorage	storage.SavedSearchStorage
+	evaluator	*alerting.Evaluator
+	webhookStorage	storage.WebhookStorage
+	dispatcher	*webhook.Dispatcher
+	reindexer	*reindex.Reindexer
+	embedder	embedding.Embedder
+	extractors	*extract.Registry
 	config		*config.Config
 	tokenOp 	utils.TokenOperator
+	validator	*validation.Validator
 }
 
-func NewServer(listenAddr string, queue queue.Queue, documentStorage storage.DocumentStorage, userStorage storage.UserStorage, config *config.Config, tokenOp utils.TokenOperator) *Server {
+// ServerOption configures optional features of the server.
+type ServerOption func(*Server)
+
+// WithEmbedder enables vector search, without embedder indexes with vector field can't be created.
+func WithEmbedder(embedder embedding.Embedder) ServerOption {
+	return func(s *Server) {
+		s.embedder = embedder
+	}
+}
+
+// WithSavedSearches enables saved searches evaluated on ingestion by evaluator. Nil evaluator
+// means queued documents are indexed outside of the service and can't be evaluated, so
+// new saved searches are rejected while matches of existing ones can still be fetched.
+func WithSavedSearches(savedSearchStorage storage.SavedSearchStorage, evaluator *alerting.Evaluator) ServerOption {
+	return func(s *Server) {
+		s.savedSearchStorage = savedSearchStorage
+		s.evaluator = evaluator
+	}
+}
+
+// WithWebhooks enables webhooks notified about index events by dispatcher. Without
+// queuedIngestion queued documents are indexed outside of the service, so webhooks can't
+// subscribe to ingestion events which would be sent only for documents indexed in sync mode.
+func WithWebhooks(webhookStorage storage.WebhookStorage, dispatcher *webhook.Dispatcher, queuedIngestion bool) ServerOption {
+	return func(s *Server) {
+		s.webhookStorage = webhookStorage
+		s.dispatcher = dispatcher
+		s.validator.ExternalIngestion = !queuedIngestion
+	}
+}
+
+func NewServer(listenAddr string, queue queue.Queue, documentStorage storage.DocumentStorage, userStorage storage.UserStorage, indexStorage storage.IndexStorage, config *config.Config, tokenOp utils.TokenOperator, options ...ServerOption) *Server {
 	log.Debug("Initializing server")
 
 	server := Server{
@@ -31,17 +81,32 @@ func NewServer(listenAddr string, queue queue.Queue, documentStorage storage.Doc
 		queue:      queue,
 		docStorage: documentStorage,
 		userStorage: userStorage,
+		indexStorage: indexStorage,
+		reindexer: reindex.NewReindexer(documentStorage, indexStorage, time.Duration(config.ReindexPollIntervalMs) * time.Millisecond),
+		extractors: extract.NewDefaultRegistry(),
 		config: config,
 		tokenOp: tokenOp,
+		validator: &validation.Validator{
+			MaxDocumentSize: config.MaxDocumentSize,
+			MaxDocumentsPerRequest: config.MaxDocumentsPerRequest,
+			ReservedIndexNames: storage.ReservedIndexNames,
+			AllowPrivateWebhooks: config.WebhookAllowPrivateNetworks,
+		},
+	}
+	for _, option := range options {
+		option(&server)
 	}
 
 	server.initialiseRoutes()
+	server.httpServer = &http.Server{Addr: listenAddr, Handler: server.router}
 	return &server
 }
 
 func (s *Server) initialiseRoutes() {
 	log.Debug("Initializing routes")
 
+	s.router.Use(middleware.RequestId)
+
 	s.router.HandleFunc("/ping", s.Ping).Methods("GET")
 	s.router.HandleFunc("/login", s.login).Methods("POST")
 	s.router.HandleFunc("/refresh", s.refresh).Methods("POST")
@@ -57,9 +122,48 @@ func (s *Server) initialiseRoutes() {
 	privateRouter.HandleFunc("/indexDocuments", s.indexDocuments).Methods("POST")
 	privateRouter.HandleFunc("/searchDocuments", s.searchDocuments).Methods("POST")
 	privateRouter.HandleFunc("/createIndex", s.createIndex).Methods("POST")
+	privateRouter.HandleFunc("/indexes/{index}/schema", s.getIndexSchema).Methods("GET")
+	privateRouter.HandleFunc("/indexes/{index}/schema", s.updateIndexSchema).Methods("PATCH")
+	privateRouter.HandleFunc("/indexes/{index}/synonyms", s.updateIndexSynonyms).Methods("PUT")
+	privateRouter.HandleFunc("/indexes/{index}/fuzzy", s.updateIndexFuzzy).Methods("PUT")
+	privateRouter.HandleFunc("/indexes/{index}/dedupe", s.updateIndexDedupe).Methods("PUT")
+	privateRouter.HandleFunc("/indexes/{index}/_reindex", s.reindexIndex).Methods("POST")
+	privateRouter.HandleFunc("/indexes/{index}/_reindex/{id}", s.getReindexTask).Methods("GET")
+	privateRouter.HandleFunc("/indexes/{index}/suggest", s.suggestDocuments).Methods("GET")
+	privateRouter.HandleFunc("/indexes/{index}/documents/_bulk", s.indexDocumentsBulk).Methods("POST")
+	privateRouter.HandleFunc("/indexes/{index}/documents/_upload", s.uploadDocuments).Methods("POST")
+	privateRouter.HandleFunc("/indexes/{index}/documents/_more_like_this", s.moreLikeThis).Methods("POST")
+	privateRouter.HandleFunc("/indexes/{index}/documents/{id}/similar", s.similarDocuments).Methods("GET")
+
+	if s.savedSearchStorage != nil {
+		privateRouter.HandleFunc("/indexes/{index}/saved-searches", s.createSavedSearch).Methods("POST")
+		privateRouter.HandleFunc("/saved-searches/{id}/matches", s.getSavedSearchMatches).Methods("GET")
+	}
+	if s.webhookStorage != nil {
+		privateRouter.HandleFunc("/indexes/{index}/webhooks", s.createWebhook).Methods("POST")
+		privateRouter.HandleFunc("/indexes/{index}/webhooks", s.getWebhooks).Methods("GET")
+		privateRouter.HandleFunc("/webhooks/{id}/deliveries", s.getWebhookDeliveries).Methods("GET")
+		privateRouter.HandleFunc("/webhooks/{id}/deliveries/{delivery}/redeliver", s.redeliverWebhook).Methods("POST")
+	}
 }
 
+// Start resumes reindex tasks interrupted by restart and listens until Shutdown.
 func (s *Server) Start() error {
+	s.reindexer.Resume(context.TODO())
+
 	log.Infof("Starting listening on %s", s.listenAddr)
-	return http.ListenAndServe(s.listenAddr, s.router)
+	err := s.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops listening and waits until requests in progress finish or ctx is done.
+// Running reindex tasks are interrupted and resumed after restart.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.httpServer.Shutdown(ctx)
+	s.reindexer.Stop()
+	s.reindexer.Wait()
+	return err
 }
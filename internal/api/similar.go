@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/simhash"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var (
+	errInvalidSimilarSize	= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, fmt.Sprintf("Invalid size, expected integer from 1 to %d", models.MaxSimilarSize))
+	errInvalidDistance		= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, fmt.Sprintf("Invalid distance, expected integer from 0 to %d", simhash.MaxDistance))
+)
+
+// similarDocuments returns near duplicates of the document, documents whose SimHash differs
+// in at most distance bits. Documents indexed before SimHash was supported get it computed
+// here, but they can't be found as near duplicates of others.
+func (s *Server) similarDocuments(w http.ResponseWriter, r *http.Request) {
+	similarRequest := &models.SimilarDocumentsRequest{
+		Id: mux.Vars(r)["id"],
+		Distance: s.config.NearDuplicateDistance,
+		Size: models.DefaultSimilarSize,
+	}
+
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		size, err := strconv.Atoi(sizeParam)
+		if err != nil || size < 1 || size > models.MaxSimilarSize {
+			utils.WriteError(w, r, errInvalidSimilarSize)
+			return
+		}
+		similarRequest.Size = size
+	}
+
+	if distanceParam := r.URL.Query().Get("distance"); distanceParam != "" {
+		distance, err := strconv.Atoi(distanceParam)
+		if err != nil || distance < 0 || distance > simhash.MaxDistance {
+			utils.WriteError(w, r, errInvalidDistance)
+			return
+		}
+		similarRequest.Distance = distance
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+	similarRequest.Index = indexName
+
+	document, err := s.docStorage.GetDocument(context.TODO(), indexName, similarRequest.Id)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if document == nil {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return
+	}
+
+	similarRequest.SimHash, err = simhash.Parse(document.SimHash)
+	if err != nil {
+		similarRequest.SimHash = simhash.Compute(document.Title + "\n" + document.Text)
+	}
+
+	similar, err := s.docStorage.SimilarDocuments(context.TODO(), similarRequest)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", &models.SimilarDocumentsResponse{
+		SimHash: simhash.Format(similarRequest.SimHash),
+		Documents: similar,
+	})
+}
+
+// moreLikeThis returns documents related to the document or free text, unlike
+// similarDocuments they share significant terms instead of being copies.
+func (s *Server) moreLikeThis(w http.ResponseWriter, r *http.Request) {
+	moreLikeThisRequest := &models.MoreLikeThisRequest{}
+	if !s.decodePayload(w, r, moreLikeThisRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateMoreLikeThisRequest(moreLikeThisRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+	moreLikeThisRequest.Index = indexName
+
+	// ES silently ignores missing like documents, so missing id would find nothing
+	if moreLikeThisRequest.Id != "" {
+		document, err := s.docStorage.GetDocument(context.TODO(), indexName, moreLikeThisRequest.Id)
+		if err != nil {
+			utils.WriteError(w, r, err)
+			return
+		}
+		if document == nil {
+			utils.WriteError(w, r, utils.ErrNotFound)
+			return
+		}
+	}
+
+	searchResponse, err := s.docStorage.MoreLikeThis(context.TODO(), moreLikeThisRequest)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", searchResponse)
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/simhash"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var similarDocumentsHandlerTests = []struct {
+	testName 			string
+	query				string
+	docStorage 			*storage.DocStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedRequest		*models.SimilarDocumentsRequest
+}{
+	{
+		testName: "Return similar documents with default distance and size",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Document: &models.SearchHit{Document: models.Document{Id: "a", Title: "test"}, SimHash: "00000000000000ff"},
+			Similar: []models.SimilarDocument{{SearchHit: models.SearchHit{Document: models.Document{Id: "b", Title: "test"}, SimHash: "00000000000000fe"}, Distance: 1}},
+		},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SimilarDocumentsResponse{
+				SimHash: "00000000000000ff",
+				Documents: []models.SimilarDocument{{SearchHit: models.SearchHit{Document: models.Document{Id: "b", Title: "test"}, SimHash: "00000000000000fe"}, Distance: 1}},
+			},
+		},
+		expectedRequest: &models.SimilarDocumentsRequest{Index: "test", Id: "a", SimHash: 0xff, Distance: 4, Size: models.DefaultSimilarSize},
+	},
+	{
+		testName: "Compute simhash of documents indexed without it",
+		query: "?distance=0&size=5",
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Document: &models.SearchHit{Document: models.Document{Id: "a", Title: "test", Text: "old document"}},
+			Similar: []models.SimilarDocument{},
+		},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SimilarDocumentsResponse{
+				SimHash: simhash.Format(simhash.Compute("test\nold document")),
+				Documents: []models.SimilarDocument{},
+			},
+		},
+		expectedRequest: &models.SimilarDocumentsRequest{Index: "test", Id: "a", SimHash: simhash.Compute("test\nold document"), Distance: 0, Size: 5},
+	},
+	{
+		testName: "Return 404 on missing document",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on distance out of range",
+		query: "?distance=8",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid distance, expected integer from 0 to 7",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid distance, expected integer from 0 to 7", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on size out of range",
+		query: "?size=0",
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid size, expected integer from 1 to 100",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid size, expected integer from 1 to 100", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestSimilarDocumentsHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		NearDuplicateDistance: 4,
+	}
+	for i, test := range similarDocumentsHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, test.docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodGet, "/indexes/test/documents/a/similar"+test.query, nil)
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedRequest != nil {
+			assert.Equal(t, test.docStorage.SimilarRequest, test.expectedRequest, "wrong similar request")
+		}
+	}
+}
+
+func TestSearchCollapseNearDuplicates(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		NearDuplicateDistance: 4,
+	}
+	docStorage := &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+	req, err := http.NewRequest(http.MethodPost, "/searchDocuments", strings.NewReader(`{"index_name": "test", "query": "news", "collapse_near_duplicates": true}`))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, http.StatusOK, "wrong response code")
+	assert.Equal(t, docStorage.SearchRequest.CollapseNearDuplicates, true, "collapse isn't passed to storage")
+	assert.Equal(t, docStorage.SearchRequest.NearDuplicateDistance, 4, "wrong near duplicate distance")
+}
+
+var testMaxQueryTerms = 12
+
+var moreLikeThisHandlerTests = []struct {
+	testName 			string
+	payload				string
+	docStorage 			*storage.DocStorageMock
+	indexAccess			bool
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedRequest		*models.MoreLikeThisRequest
+}{
+	{
+		testName: "Return documents like the document",
+		payload: `{"id": "a", "max_query_terms": 12}`,
+		docStorage: &storage.DocStorageMock{
+			EsIndexExists: true,
+			Document: &models.SearchHit{Document: models.Document{Id: "a", Title: "City budget"}},
+			Documents: []models.Document{{Id: "b", Title: "Budget debate"}},
+		},
+		indexAccess: true,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{Total: 1, Documents: []models.SearchHit{{Document: models.Document{Id: "b", Title: "Budget debate"}}}},
+		},
+		expectedRequest: &models.MoreLikeThisRequest{Index: "test", Id: "a", MaxQueryTerms: &testMaxQueryTerms},
+	},
+	{
+		testName: "Return documents like free text",
+		payload: `{"text": "city budget"}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{}},
+		indexAccess: true,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.SearchResponse{Total: 0, Documents: []models.SearchHit{}},
+		},
+		expectedRequest: &models.MoreLikeThisRequest{Index: "test", Text: "city budget"},
+	},
+	{
+		testName: "Return 404 on missing document",
+		payload: `{"id": "a"}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexAccess: true,
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on invalid request",
+		payload: `{"id": "a", "text": "city budget", "min_term_freq": 0, "max_query_terms": 1000}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexAccess: true,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "text", Message: "text can't be used together with id"},
+				{Field: "min_term_freq", Message: "min_term_freq must be from 1 to 100"},
+				{Field: "max_query_terms", Message: "max_query_terms must be from 1 to 100"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "text", Message: "text can't be used together with id"},
+				{Field: "min_term_freq", Message: "min_term_freq must be from 1 to 100"},
+				{Field: "max_query_terms", Message: "max_query_terms must be from 1 to 100"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 without id and text",
+		payload: `{}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexAccess: true,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "id", Message: "id or text is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "id", Message: "id or text is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 403 when user has no rights for index",
+		payload: `{"text": "city budget"}`,
+		docStorage: &storage.DocStorageMock{EsIndexExists: true},
+		indexAccess: false,
+		expectedCode: 403,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Index doesn't exist or you don't have access to it",
+			Error: &utils.APIError{Code: utils.CodeIndexNotFound, Message: "Index doesn't exist or you don't have access to it", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestMoreLikeThisHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range moreLikeThisHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		userStorage := &storage.UserStorageMock{IndexAccess: test.indexAccess}
+		server := NewServer("", nil, test.docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		req, err := http.NewRequest(http.MethodPost, "/indexes/test/documents/_more_like_this", strings.NewReader(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedRequest != nil {
+			assert.Equal(t, test.docStorage.MoreLikeThisRequest, test.expectedRequest, "wrong more like this request")
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/extract"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+// Form field with CSV column mapping, the only non-file field of upload request
+const csvMappingFormField = "csv_mapping"
+
+var (
+	errExpectedMultipart	= utils.NewAPIError(http.StatusUnsupportedMediaType, utils.CodeUnsupportedMediaType, "Expected multipart/form-data body")
+	errFormFieldAfterFiles	= utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidPayload, "Form fields must precede files")
+)
+
+// uploadAbortError wraps error returned from extractor callback, it stops the whole
+// request while other extraction errors only stop the current file.
+type uploadAbortError struct {
+	err	error
+}
+
+func (e *uploadAbortError) Error() string {
+	return e.err.Error()
+}
+
+// bodyReader remembers error of reading request body, so errors of the body aren't
+// mistaken for malformed files.
+type bodyReader struct {
+	reader	io.Reader
+	err		error
+}
+
+func (b *bodyReader) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if err != nil && err != io.EOF {
+		b.err = err
+	}
+	return n, err
+}
+
+// uploadDocuments converts files of multipart request to documents and queues them the same
+// way as bulk request. Format of each file is detected by its content type or extension,
+// files that can't be read are reported and skipped.
+func (s *Server) uploadDocuments(w http.ResponseWriter, r *http.Request) {
+	bulkQueue, ok := s.newBulkQueue(w, r)
+	if !ok {
+		return
+	}
+
+	if s.config.BulkMaxRequestSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.config.BulkMaxRequestSize)
+	}
+	multipartReader, err := r.MultipartReader()
+	if err != nil {
+		utils.WriteError(w, r, errExpectedMultipart)
+		return
+	}
+
+	response := &models.UploadResponse{Files: []models.UploadFileResult{}}
+	abort := func(err error) {
+		response.Queued, response.Messages = bulkQueue.queued, bulkQueue.messages
+		s.writeBulkError(w, r, bulkQueue, err, response)
+	}
+
+	var csvMapping *models.CSVMapping
+	for {
+		part, err := multipartReader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			abort(bulkBodyError(err))
+			return
+		}
+
+		if part.FileName() == "" {
+			if len(response.Files) > 0 {
+				abort(errFormFieldAfterFiles)
+				return
+			}
+			if part.FormName() != csvMappingFormField {
+				utils.WriteValidationError(w, r, []models.FieldError{{Field: part.FormName(), Message: "unknown field"}})
+				return
+			}
+
+			csvMapping, ok = s.decodeCSVMapping(w, r, part)
+			if !ok {
+				return
+			}
+			continue
+		}
+
+		body := &bodyReader{reader: part}
+		result := s.extractFile(bulkQueue, &extract.File{
+			Name: part.FileName(),
+			Reader: body,
+			Options: extract.Options{
+				MaxSize: s.config.MaxDocumentSize,
+				MaxFileSize: s.config.UploadMaxFileSize,
+				MaxPages: s.config.UploadMaxPages,
+				Schema: bulkQueue.schema,
+				CSVMapping: csvMapping,
+			},
+		}, part.Header.Get("Content-Type"))
+
+		var abortError *uploadAbortError
+		if errors.As(result.err, &abortError) {
+			abort(abortError.err)
+			return
+		}
+		if body.err != nil {
+			abort(bulkBodyError(body.err))
+			return
+		}
+
+		if result.err != nil {
+			result.Error = result.err.Error()
+		}
+		response.Failed += result.Failed
+		response.Files = append(response.Files, result.UploadFileResult)
+	}
+
+	if err := bulkQueue.Flush(); err != nil {
+		abort(err)
+		return
+	}
+	response.Queued, response.Messages = bulkQueue.queued, bulkQueue.messages
+
+	if len(response.Files) == 0 {
+		utils.WriteValidationError(w, r, []models.FieldError{{Field: "file", Message: "at least one file is required"}})
+		return
+	}
+
+	s.dispatchBulkQueued(bulkQueue)
+	for _, file := range response.Files {
+		if file.Failed > 0 || file.Error != "" {
+			utils.WriteJSON(w, r, http.StatusOK, false, "Some files or documents weren't queued", response)
+			return
+		}
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", response)
+}
+
+type extractionResult struct {
+	models.UploadFileResult
+	err	error
+}
+
+// extractFile queues documents of one file, error of the result stops extraction of
+// the file, documents queued before it stay queued. Panic of extractor is a bug, not
+// a problem of the file, so it stops the request with internal error.
+func (s *Server) extractFile(bulkQueue *bulkQueue, file *extract.File, contentType string) (result *extractionResult) {
+	result = &extractionResult{UploadFileResult: models.UploadFileResult{File: file.Name, Errors: []models.UploadDocumentError{}}}
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Error extracting file %s of request %s, %s extractor panicked: %v\n%s", file.Name, bulkQueue.requestId, result.Format, r, debug.Stack())
+			result.err = &uploadAbortError{err: utils.ErrInternal}
+		}
+	}()
+
+	format, extractor, ok := s.extractors.Lookup(file.Name, contentType)
+	if !ok {
+		result.err = errors.New("unsupported file format")
+		return result
+	}
+	result.Format = format
+
+	result.err = extractor.Extract(file, func(document models.Document, err error) error {
+		result.Documents++
+		if err != nil {
+			addUploadDocumentError(result, []models.FieldError{{Field: "document", Message: err.Error()}})
+			return nil
+		}
+
+		fieldErrors, err := bulkQueue.Add(&document)
+		if err != nil {
+			return &uploadAbortError{err: err}
+		}
+		if len(fieldErrors) > 0 {
+			addUploadDocumentError(result, fieldErrors)
+		}
+		return nil
+	})
+
+	return result
+}
+
+// decodeCSVMapping decodes and validates mapping form field, on failure error response
+// is written and false is returned.
+func (s *Server) decodeCSVMapping(w http.ResponseWriter, r *http.Request, part io.Reader) (*models.CSVMapping, bool) {
+	csvMapping := &models.CSVMapping{}
+	decoder := json.NewDecoder(part)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(csvMapping); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			utils.WriteError(w, r, utils.ErrPayloadTooLarge)
+		} else if strings.HasPrefix(err.Error(), unknownFieldErrorPrefix) {
+			field := strings.Trim(strings.TrimPrefix(err.Error(), unknownFieldErrorPrefix), "\"")
+			utils.WriteValidationError(w, r, []models.FieldError{{Field: csvMappingFormField + "." + field, Message: "unknown field"}})
+		} else {
+			utils.WriteValidationError(w, r, []models.FieldError{{Field: csvMappingFormField, Message: "csv_mapping must be JSON object"}})
+		}
+		return nil, false
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateCSVMapping(csvMapping)) {
+		return nil, false
+	}
+
+	return csvMapping, true
+}
+
+// addUploadDocumentError records errors of the last extracted document.
+func addUploadDocumentError(result *extractionResult, fieldErrors []models.FieldError) {
+	result.Failed++
+	if len(result.Errors) >= models.MaxBulkLineErrors {
+		result.ErrorsTruncated = true
+		return
+	}
+	result.Errors = append(result.Errors, models.UploadDocumentError{Document: result.Documents, Errors: fieldErrors})
+}
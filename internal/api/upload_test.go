@@ -0,0 +1,377 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/extract"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+type uploadPart struct {
+	name		string
+	fileName	string
+	contentType	string
+	content		string
+}
+
+// multipartBody returns body with parts in the given order and its content type.
+func multipartBody(parts []uploadPart) (string, string) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{}
+		disposition := fmt.Sprintf("form-data; name=%q", part.name)
+		if part.fileName != "" {
+			disposition += fmt.Sprintf("; filename=%q", part.fileName)
+		}
+		header.Set("Content-Disposition", disposition)
+		if part.contentType != "" {
+			header.Set("Content-Type", part.contentType)
+		}
+		partWriter, _ := writer.CreatePart(header)
+		partWriter.Write([]byte(part.content))
+	}
+	writer.Close()
+	return buffer.String(), writer.FormDataContentType()
+}
+
+// extractFixture returns content of fixture file shared with extract package tests.
+func extractFixture(name string) string {
+	data, err := os.ReadFile("../extract/testdata/" + name)
+	if err != nil {
+		panic(err)
+	}
+	return string(data)
+}
+
+var uploadDocumentsTests = []struct {
+	testName 			string
+	parts				[]uploadPart
+	contentType			string
+	indexStorage		*storage.IndexStorageMock
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedDocuments	[]models.Document
+}{
+	{
+		testName: "Queue documents extracted from files of all formats",
+		parts: []uploadPart{
+			{name: "csv_mapping", content: `{"id": "sku", "title": "name", "text": ["summary", "details"], "fields": {"price": "cost"}}`},
+			{name: "file", fileName: "products.csv", content: "sku,name,summary,details,cost\nm1,Mouse,Wireless,\"Quiet, light\",19.5\n"},
+			{name: "file", fileName: "articles", contentType: "application/json", content: `[{"title": "First"}, {"id": "a2", "text": "Second"}]`},
+			{name: "file", fileName: "guide.md", content: "# Guide\n\nSome **bold** [link](http://example.com) text.\n"},
+			{name: "file", fileName: "page.html", content: "<html><head><title>Page</title><style>p {}</style></head><body><p>Hello&amp;welcome</p></body></html>"},
+			{name: "file", fileName: "notes.txt", contentType: "application/octet-stream", content: "Plain   notes\n"},
+		},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"price": {Type: models.FieldTypeFloat}}}}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.UploadResponse{Queued: 6, Messages: 1, Files: []models.UploadFileResult{
+				{File: "products.csv", Format: "csv", Documents: 1, Errors: []models.UploadDocumentError{}},
+				{File: "articles", Format: "json", Documents: 2, Errors: []models.UploadDocumentError{}},
+				{File: "guide.md", Format: "markdown", Documents: 1, Errors: []models.UploadDocumentError{}},
+				{File: "page.html", Format: "html", Documents: 1, Errors: []models.UploadDocumentError{}},
+				{File: "notes.txt", Format: "text", Documents: 1, Errors: []models.UploadDocumentError{}},
+			}},
+		},
+		expectedDocuments: []models.Document{
+			{Id: "m1", Title: "Mouse", Text: "Wireless\n\nQuiet, light", Fields: map[string]any{"price": 19.5}},
+			{Title: "First"},
+			{Id: "a2", Text: "Second"},
+			{Title: "Guide", Text: "Some bold link text."},
+			{Title: "Page", Text: "Hello&welcome"},
+			{Title: "notes", Text: "Plain notes"},
+		},
+	},
+	{
+		testName: "Queue documents extracted from office files with metadata",
+		parts: []uploadPart{
+			{name: "file", fileName: "guide.docx", content: extractFixture("sample.docx")},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.UploadResponse{Queued: 1, Messages: 1, Files: []models.UploadFileResult{
+				{File: "guide.docx", Format: "docx", Documents: 1, Errors: []models.UploadDocumentError{}},
+			}},
+		},
+		expectedDocuments: []models.Document{
+			{Title: "Onboarding guide", Text: "Welcome\nRead the handbook first.\nLine one\nLine two", Fields: map[string]any{"author": "Jane Doe", "created_at": "2024-02-01T09:00:00Z", "page_count": 3.0}},
+		},
+	},
+	{
+		testName: "Report invalid documents and files",
+		parts: []uploadPart{
+			{name: "file", fileName: "rows.csv", content: "title,price\nValid,1\nShort\nPrice,cheap\n"},
+			{name: "file", fileName: "items.json", content: `[{"title": "Valid"}, {"title": "Unknown", "size": 1}, "text"]`},
+			{name: "file", fileName: "broken.json", content: `{"title": "Not array"}`},
+			{name: "file", fileName: "slides.pptx", content: "PK"},
+			{name: "file", fileName: "large.txt", content: strings.Repeat("a", 300)},
+		},
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"price": {Type: models.FieldTypeFloat}}}}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Some files or documents weren't queued",
+			Data: &models.UploadResponse{Queued: 2, Failed: 4, Messages: 1, Files: []models.UploadFileResult{
+				{File: "rows.csv", Format: "csv", Documents: 3, Failed: 2, Errors: []models.UploadDocumentError{
+					{Document: 2, Errors: []models.FieldError{{Field: "document", Message: "row has 1 columns, header has 2"}}},
+					{Document: 3, Errors: []models.FieldError{{Field: "document.fields.price", Message: "value must be a number"}}},
+				}},
+				{File: "items.json", Format: "json", Documents: 3, Failed: 2, Errors: []models.UploadDocumentError{
+					{Document: 2, Errors: []models.FieldError{{Field: "document", Message: "unknown field \"size\""}}},
+					{Document: 3, Errors: []models.FieldError{{Field: "document", Message: "element must be document object"}}},
+				}},
+				{File: "broken.json", Format: "json", Errors: []models.UploadDocumentError{}, Error: "json file must contain array of documents"},
+				{File: "slides.pptx", Errors: []models.UploadDocumentError{}, Error: "unsupported file format"},
+				{File: "large.txt", Format: "text", Errors: []models.UploadDocumentError{}, Error: "file is too large, at most 256 bytes can be converted to one document"},
+			}},
+		},
+		expectedDocuments: []models.Document{
+			{Title: "Valid", Fields: map[string]any{"price": 1.0}},
+			{Title: "Valid"},
+		},
+	},
+	{
+		testName: "Report mapped column missing in csv header",
+		parts: []uploadPart{
+			{name: "csv_mapping", content: `{"title": "name", "text": ["body"]}`},
+			{name: "file", fileName: "products.csv", content: "name,summary\nMouse,Wireless\n"},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Some files or documents weren't queued",
+			Data: &models.UploadResponse{Files: []models.UploadFileResult{
+				{File: "products.csv", Format: "csv", Errors: []models.UploadDocumentError{}, Error: "column body isn't found in csv header"},
+			}},
+		},
+	},
+	{
+		testName: "Return 400 on invalid csv mapping",
+		parts: []uploadPart{
+			{name: "csv_mapping", content: `{"id": "sku", "fields": {"price": ""}}`},
+			{name: "file", fileName: "products.csv", content: "sku,cost\nm1,1\n"},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "csv_mapping", Message: "title or text column is required"},
+				{Field: "csv_mapping.fields.price", Message: "column is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "csv_mapping", Message: "title or text column is required"},
+				{Field: "csv_mapping.fields.price", Message: "column is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on csv mapping with unknown field",
+		parts: []uploadPart{
+			{name: "csv_mapping", content: `{"title": "name", "body": "text"}`},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "csv_mapping.body", Message: "unknown field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "csv_mapping.body", Message: "unknown field"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 on unknown form field",
+		parts: []uploadPart{
+			{name: "mapping", content: `{}`},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "mapping", Message: "unknown field"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "mapping", Message: "unknown field"},
+			},
+		},
+	},
+	{
+		testName: "Return summary of queued documents when form field follows files",
+		parts: []uploadPart{
+			{name: "file", fileName: "notes.txt", content: "Notes"},
+			{name: "csv_mapping", content: `{"title": "name"}`},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Form fields must precede files",
+			Error: &utils.APIError{Code: utils.CodeInvalidPayload, Message: "Form fields must precede files", Details: &models.UploadResponse{Files: []models.UploadFileResult{
+				{File: "notes.txt", Format: "text", Documents: 1, Errors: []models.UploadDocumentError{}},
+			}}, RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 413 when body exceeds limit",
+		parts: []uploadPart{
+			{name: "file", fileName: "items.json", content: "[" + strings.Repeat(`{"title": "Item"},`, 300) + `{"title": "Item"}]`},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 413,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Request payload is too large",
+			Error: &utils.APIError{Code: utils.CodePayloadTooLarge, Message: "Request payload is too large", Details: &models.UploadResponse{Queued: 210, Messages: 21, Files: []models.UploadFileResult{}}, RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on body without files",
+		parts: []uploadPart{
+			{name: "csv_mapping", content: `{"title": "name"}`},
+		},
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "file", Message: "at least one file is required"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "file", Message: "at least one file is required"},
+			},
+		},
+	},
+	{
+		testName: "Return 415 on body that isn't multipart",
+		contentType: "application/json",
+		indexStorage: &storage.IndexStorageMock{},
+		expectedCode: 415,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Expected multipart/form-data body",
+			Error: &utils.APIError{Code: utils.CodeUnsupportedMediaType, Message: "Expected multipart/form-data body", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestUploadDocuments(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		MaxDocumentSize: 256,
+		MaxDocumentsPerRequest: 10,
+		BulkMaxRequestSize: 4096,
+		QueueMaxMessageBytes: 1 << 20,
+	}
+	for i, test := range uploadDocumentsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		messageQueue := &queue.QueueMock{}
+		docStorage := &storage.DocStorageMock{EsIndexExists: true}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", messageQueue, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true})
+
+		body, contentType := multipartBody(test.parts)
+		if test.contentType != "" {
+			contentType = test.contentType
+		}
+		req, err := http.NewRequest(http.MethodPost, "/indexes/test/documents/_upload", bytes.NewBufferString(body))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+		req.Header.Add("Content-Type", contentType)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+
+		if test.expectedDocuments != nil {
+			documents := []models.Document{}
+			for _, message := range messageQueue.Messages {
+				var indexingRequest models.DocumentsForIndexing
+				if err := json.Unmarshal(message.Value, &indexingRequest); err != nil {
+					t.Fatalf("Unable to unmarshal queued message, error: %s\n", err)
+				}
+				documents = append(documents, indexingRequest.Documents...)
+			}
+			assert.Equal(t, documents, test.expectedDocuments, "wrong queued documents")
+		}
+	}
+}
+
+type panicExtractor struct{}
+
+func (e *panicExtractor) Extract(file *extract.File, emit extract.EmitFunc) error {
+	var data []byte
+	_ = data[1]
+	return nil
+}
+
+func TestUploadExtractorPanic(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		QueueMaxMessageBytes: 1 << 20,
+	}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	server := NewServer("", &queue.QueueMock{}, &storage.DocStorageMock{EsIndexExists: true}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true})
+	server.extractors = extract.NewRegistry()
+	server.extractors.Register("broken", &panicExtractor{}, []string{".broken"}, nil)
+
+	body, contentType := multipartBody([]uploadPart{{name: "file", fileName: "file.broken", content: "data"}})
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/documents/_upload", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+	req.Header.Add("Content-Type", contentType)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	var response utils.Response
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to unmarshal response, error: %s\n", err)
+	}
+	assert.Equal(t, rr.Code, 500, "panic of extractor must be internal error")
+	assert.Equal(t, response.Error.Code, utils.CodeInternal, "wrong error code")
+}
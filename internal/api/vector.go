@@ -0,0 +1,60 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+// embedDocuments returns embeddings of title and text of documents computed in one embedder call.
+func (s *Server) embedDocuments(ctx context.Context, documents []models.Document) ([][]float32, error) {
+	if s.embedder == nil {
+		return nil, utils.ErrEmbeddingsUnavailable
+	}
+
+	texts := make([]string, len(documents))
+	for i, document := range documents {
+		texts[i] = document.Title + "\n" + document.Text
+	}
+
+	embeddings, err := s.embedder.Embed(ctx, texts)
+	if err != nil {
+		log.Errorf("Error embedding %d documents: %s", len(documents), err)
+		return nil, utils.ErrEmbeddingsUnavailable
+	}
+
+	return embeddings, nil
+}
+
+// checkVectorIndexes returns field errors if some of searched indexes have no vector
+// field or its dimensions don't match the embedder.
+func (s *Server) checkVectorIndexes(searchRequest *models.DocumentSearchRequest, indexes map[string]*models.IndexMetadata) []models.FieldError {
+	for _, indexName := range searchRequest.TargetIndexes() {
+		index, ok := indexes[indexName]
+		if !ok || index.Vector == nil {
+			return []models.FieldError{{Field: "mode", Message: fmt.Sprintf("index %s has no vector field, %s mode isn't supported", indexName, searchRequest.Mode)}}
+		}
+		if s.embedder != nil && index.Vector.Dimensions != s.embedder.Dimensions() {
+			return []models.FieldError{{Field: "mode", Message: fmt.Sprintf("index %s vector dimensions don't match embedder, %s mode isn't supported", indexName, searchRequest.Mode)}}
+		}
+	}
+	return []models.FieldError{}
+}
+
+func (s *Server) embedQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) error {
+	if s.embedder == nil {
+		return utils.ErrEmbeddingsUnavailable
+	}
+
+	embeddings, err := s.embedder.Embed(ctx, []string{searchRequest.Query})
+	if err != nil {
+		log.Errorf("Error embedding search query %s: %s", searchRequest.Query, err)
+		return utils.ErrEmbeddingsUnavailable
+	}
+
+	searchRequest.QueryVector = embeddings[0]
+	return nil
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/embedding"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+const testDimensions = 8
+
+var testEmbedder = embedding.NewHashingEmbedder(testDimensions)
+
+func testEmbedding(text string) []float32 {
+	vectors, _ := testEmbedder.Embed(context.TODO(), []string{text})
+	return vectors[0]
+}
+
+var searchDocumentsVectorTests = []struct {
+	testName 				string
+	payload					string
+	embedder				embedding.Embedder
+	indexStorage			*storage.IndexStorageMock
+	expectedCode			int
+	expectedResponse 		utils.Response
+	expectedSearchRequest	*models.DocumentSearchRequest
+}{
+	{
+		testName: "Knn search embeds query",
+		payload: `{"index_name": "test", "query": "wireless mouse", "mode": "knn"}`,
+		embedder: testEmbedder,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Similarity: "cosine", Dimensions: testDimensions}}},
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: []models.Document{{Title: "Wireless mouse"}},
+		},
+		expectedSearchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless mouse", Mode: models.SearchModeKnn, DidYouMeanMaxHits: -1, QueryVector: testEmbedding("wireless mouse")},
+	},
+	{
+		testName: "Return 400 on hybrid search of index without vector field",
+		payload: `{"index_name": "test", "query": "wireless mouse", "mode": "hybrid"}`,
+		embedder: testEmbedder,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test"}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "mode", Message: "index test has no vector field, hybrid mode isn't supported"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "mode", Message: "index test has no vector field, hybrid mode isn't supported"},
+			},
+		},
+	},
+	{
+		testName: "Return 400 when index dimensions don't match embedder",
+		payload: `{"index_name": "test", "query": "wireless mouse", "mode": "knn"}`,
+		embedder: testEmbedder,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Dimensions: 384}}},
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid request payload",
+			Error: &utils.APIError{Code: utils.CodeValidationFailed, Message: "Invalid request payload", Details: []models.FieldError{
+				{Field: "mode", Message: "index test vector dimensions don't match embedder, knn mode isn't supported"},
+			}, RequestId: testRequestId},
+			Errors: []models.FieldError{
+				{Field: "mode", Message: "index test vector dimensions don't match embedder, knn mode isn't supported"},
+			},
+		},
+	},
+	{
+		testName: "Return 503 when embedder isn't configured",
+		payload: `{"index_name": "test", "query": "wireless mouse", "mode": "knn"}`,
+		indexStorage: &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Dimensions: testDimensions}}},
+		expectedCode: 503,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: utils.ErrEmbeddingsUnavailable.Message,
+			Error: &utils.APIError{Code: utils.CodeEmbeddingsUnavailable, Message: utils.ErrEmbeddingsUnavailable.Message, RequestId: testRequestId},
+		},
+	},
+}
+
+func TestSearchDocumentsVector(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range searchDocumentsVectorTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{EsIndexExists: true, Documents: []models.Document{{Title: "Wireless mouse"}}}
+		userStorage := &storage.UserStorageMock{IndexAccess: true}
+		server := NewServer("", nil, docStorage, userStorage, test.indexStorage, config, &utils.TokenOperatorMock{TokenValid: true}, WithEmbedder(test.embedder))
+
+		req, err := http.NewRequest(http.MethodPost, "/searchDocuments", bytes.NewBufferString(test.payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedSearchRequest != nil {
+			assert.Equal(t, docStorage.SearchRequest, test.expectedSearchRequest, "wrong search request")
+		}
+	}
+}
+
+var createIndexVectorTests = []struct {
+	testName 			string
+	embedder			embedding.Embedder
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedVector		*models.VectorSettings
+}{
+	{
+		testName: "Vector dimensions are set from embedder",
+		embedder: testEmbedder,
+		expectedCode: 200,
+		expectedResponse: utils.Response{Success: true},
+		expectedVector: &models.VectorSettings{Similarity: "dot_product", Dimensions: testDimensions},
+	},
+	{
+		testName: "Return 503 when embedder isn't configured",
+		expectedCode: 503,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: utils.ErrEmbeddingsUnavailable.Message,
+			Error: &utils.APIError{Code: utils.CodeEmbeddingsUnavailable, Message: utils.ErrEmbeddingsUnavailable.Message, RequestId: testRequestId},
+		},
+	},
+}
+
+func TestCreateIndexVector(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range createIndexVectorTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		docStorage := &storage.DocStorageMock{}
+		userStorage := &storage.UserStorageMock{User: &models.User{}}
+		server := NewServer("", nil, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithEmbedder(test.embedder))
+
+		payload := `{"index_name": "test", "vector": {"similarity": "dot_product"}}`
+		req, err := http.NewRequest(http.MethodPost, "/createIndex", bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedVector != nil {
+			assert.Equal(t, docStorage.CreatedIndex.Vector, test.expectedVector, "wrong vector settings")
+		}
+	}
+}
+
+func TestIndexDocumentsEmbeddings(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SyncIndexingMaxBatch: 10,
+	}
+
+	docStorage := &storage.DocStorageMock{EsIndexExists: true, IndexingResults: []models.DocumentIndexingResult{}}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	indexStorage := &storage.IndexStorageMock{Index: &models.IndexMetadata{Name: "test", Vector: &models.VectorSettings{Dimensions: testDimensions}}}
+	server := NewServer("", &queue.QueueMock{}, docStorage, userStorage, indexStorage, config, &utils.TokenOperatorMock{TokenValid: true}, WithEmbedder(testEmbedder))
+
+	payload := `{"index_name": "test", "documents": [{"title": "Wireless mouse", "text": "Quiet clicks"}, {"text": "Mechanical keyboard"}]}`
+	req, err := http.NewRequest(http.MethodPost, "/indexDocuments?mode=sync", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 200, "wrong response code")
+	assert.Equal(t, docStorage.IndexedDocuments, []models.Document{
+		{Title: "Wireless mouse", Text: "Quiet clicks"},
+		{Text: "Mechanical keyboard"},
+	}, "wrong indexed documents")
+	assert.Equal(t, docStorage.IndexedEmbeddings, [][]float32{
+		testEmbedding("Wireless mouse\nQuiet clicks"),
+		testEmbedding("\nMechanical keyboard"),
+	}, "wrong indexed embeddings")
+}
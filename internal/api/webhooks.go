@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+var errInvalidDeliveriesSize = utils.NewAPIError(http.StatusBadRequest, utils.CodeInvalidParameter, fmt.Sprintf("Invalid size, expected integer from 1 to %d", models.MaxDeliveriesSize))
+
+// createWebhook subscribes url to events of the index, payloads are signed with the secret.
+func (s *Server) createWebhook(w http.ResponseWriter, r *http.Request) {
+	webhookRequest := &models.WebhookRequest{}
+	if !s.decodePayload(w, r, webhookRequest) {
+		return
+	}
+
+	if !checkFieldErrors(w, r, s.validator.ValidateWebhookRequest(webhookRequest)) {
+		return
+	}
+
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	webhook := &models.Webhook{
+		Index: indexName,
+		UserId: r.Context().Value(utils.ContextKeyUserId).(string),
+		URL: webhookRequest.URL,
+		Secret: webhookRequest.Secret,
+		Events: webhookRequest.Events,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	err := s.webhookStorage.CreateWebhook(context.TODO(), webhook)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", webhook)
+}
+
+func (s *Server) getWebhooks(w http.ResponseWriter, r *http.Request) {
+	indexName, ok := s.checkIndexAccess(w, r)
+	if !ok {
+		return
+	}
+
+	webhooks, err := s.webhookStorage.GetIndexWebhooks(context.TODO(), indexName)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", &models.WebhooksResponse{Webhooks: webhooks})
+}
+
+// getWebhookDeliveries returns latest deliveries of the webhook.
+func (s *Server) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	size := models.DefaultDeliveriesSize
+	if sizeParam := r.URL.Query().Get("size"); sizeParam != "" {
+		var err error
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < 1 || size > models.MaxDeliveriesSize {
+			utils.WriteError(w, r, errInvalidDeliveriesSize)
+			return
+		}
+	}
+
+	webhook, ok := s.getUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	deliveries, err := s.webhookStorage.GetDeliveries(context.TODO(), webhook.Id, size)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusOK, true, "", &models.WebhookDeliveriesResponse{Deliveries: deliveries})
+}
+
+// redeliverWebhook sends payload of earlier delivery once again, new delivery is returned
+// before it is attempted.
+func (s *Server) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	webhook, ok := s.getUserWebhook(w, r)
+	if !ok {
+		return
+	}
+
+	delivery, err := s.webhookStorage.GetDelivery(context.TODO(), mux.Vars(r)["delivery"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+	if delivery.WebhookId != webhook.Id {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return
+	}
+
+	redelivery, err := s.dispatcher.Redeliver(context.TODO(), webhook, delivery)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return
+	}
+
+	utils.WriteJSON(w, r, http.StatusAccepted, true, "", redelivery)
+}
+
+// getUserWebhook returns webhook from the path, webhooks of indexes user has no rights for
+// are reported as missing.
+func (s *Server) getUserWebhook(w http.ResponseWriter, r *http.Request) (*models.Webhook, bool) {
+	webhook, err := s.webhookStorage.GetWebhook(context.TODO(), mux.Vars(r)["id"])
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return nil, false
+	}
+
+	userId := r.Context().Value(utils.ContextKeyUserId).(string)
+	userHasAccess, err := s.userStorage.CheckUserIndexRights(context.TODO(), userId, webhook.Index)
+	if err != nil {
+		utils.WriteError(w, r, err)
+		return nil, false
+	}
+	if !userHasAccess {
+		utils.WriteError(w, r, utils.ErrNotFound)
+		return nil, false
+	}
+
+	return webhook, true
+}
@@ -0,0 +1,314 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/config"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/outbound"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/utils"
+	"github.com/xavesen/search-api/internal/webhook"
+)
+
+const testWebhookSecret = "0123456789abcdef"
+
+func newTestDispatcher(webhookStorage storage.WebhookStorage) *webhook.Dispatcher {
+	return webhook.NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 1, time.Millisecond)
+}
+
+func TestCreateWebhookHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	webhookStorage := &storage.WebhookStorageMock{}
+	server := NewServer("", nil, &storage.DocStorageMock{EsIndexExists: true}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithWebhooks(webhookStorage, newTestDispatcher(webhookStorage), true))
+
+	payload := `{"url": "https://hooks.example.com/search", "secret": "0123456789abcdef", "events": ["documents.indexed", "ingestion.failed"]}`
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/webhooks", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 200, "wrong response code")
+	assert.Equal(t, strings.Contains(rr.Body.String(), testWebhookSecret), false, "secret must not be returned")
+	assert.Equal(t, len(webhookStorage.Webhooks), 1, "webhook must be stored")
+
+	created := webhookStorage.Webhooks[0]
+	assert.Equal(t, created.Index, "test", "wrong webhook index")
+	assert.Equal(t, created.URL, "https://hooks.example.com/search", "wrong webhook url")
+	assert.Equal(t, created.Secret, testWebhookSecret, "wrong webhook secret")
+	assert.Equal(t, created.Events, []string{models.EventDocumentsIndexed, models.EventIngestionFailed}, "wrong webhook events")
+	assert.Equal(t, created.UserId != "", true, "webhook must belong to user")
+}
+
+var webhookDeliveriesHandlerTests = []struct {
+	testName 			string
+	method				string
+	url					string
+	indexAccess			bool
+	expectedCode		int
+	expectedResponse 	utils.Response
+	expectedSize		int
+}{
+	{
+		testName: "Return deliveries of webhook",
+		method: http.MethodGet,
+		url: "/webhooks/w1/deliveries?size=5",
+		indexAccess: true,
+		expectedCode: 200,
+		expectedResponse: utils.Response{
+			Success: true,
+			Data: &models.WebhookDeliveriesResponse{Deliveries: []models.WebhookDelivery{
+				{Id: "d1", WebhookId: "w1", EventId: "e1", Event: models.EventIndexCreated, Payload: `{"id":"e1"}`, Status: models.DeliveryStatusFailed, Attempts: 5, CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+			}},
+		},
+		expectedSize: 5,
+	},
+	{
+		testName: "Return 404 on unknown webhook",
+		method: http.MethodGet,
+		url: "/webhooks/w9/deliveries",
+		indexAccess: true,
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 404 on webhook of index user has no rights for",
+		method: http.MethodGet,
+		url: "/webhooks/w1/deliveries",
+		indexAccess: false,
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 400 on invalid size",
+		method: http.MethodGet,
+		url: "/webhooks/w1/deliveries?size=0",
+		indexAccess: true,
+		expectedCode: 400,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Invalid size, expected integer from 1 to 100",
+			Error: &utils.APIError{Code: utils.CodeInvalidParameter, Message: "Invalid size, expected integer from 1 to 100", RequestId: testRequestId},
+		},
+	},
+	{
+		testName: "Return 404 on redelivery of another webhook's delivery",
+		method: http.MethodPost,
+		url: "/webhooks/w2/deliveries/d1/redeliver",
+		indexAccess: true,
+		expectedCode: 404,
+		expectedResponse: utils.Response{
+			Success: false,
+			ErrorMessage: "Resource not found",
+			Error: &utils.APIError{Code: utils.CodeNotFound, Message: "Resource not found", RequestId: testRequestId},
+		},
+	},
+}
+
+func TestWebhookDeliveriesHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+	for i, test := range webhookDeliveriesHandlerTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		webhookStorage := &storage.WebhookStorageMock{
+			Webhooks: []models.Webhook{
+				{Id: "w1", Index: "test", URL: "https://hooks.example.com/1", Events: []string{models.EventIndexCreated}},
+				{Id: "w2", Index: "test", URL: "https://hooks.example.com/2", Events: []string{models.EventIndexCreated}},
+			},
+			Deliveries: []models.WebhookDelivery{
+				{Id: "d1", WebhookId: "w1", EventId: "e1", Event: models.EventIndexCreated, Payload: `{"id":"e1"}`, Status: models.DeliveryStatusFailed, Attempts: 5, CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), UpdatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)},
+			},
+		}
+		userStorage := &storage.UserStorageMock{IndexAccess: test.indexAccess}
+		server := NewServer("", nil, &storage.DocStorageMock{}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithWebhooks(webhookStorage, newTestDispatcher(webhookStorage), true))
+
+		req, err := http.NewRequest(test.method, test.url, nil)
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+
+		expectedResp, err := json.Marshal(test.expectedResponse)
+		if err != nil {
+			t.Fatalf("Unable to marshal expected response, error: %s\n", err)
+		}
+
+		assert.Equal(t, rr.Code, test.expectedCode, "wrong response code")
+		assert.Equal(t, strings.Trim(rr.Body.String(), "\n"), string(expectedResp), "wrong body contents")
+		if test.expectedSize != 0 {
+			assert.Equal(t, webhookStorage.DeliveriesSize, test.expectedSize, "wrong deliveries size")
+		}
+	}
+}
+
+// webhookReceiver records requests delivered to it and verifies their signatures.
+type webhookReceiver struct {
+	mutex		sync.Mutex
+	events		[]models.WebhookEvent
+	signed		bool
+}
+
+func (wr *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var event models.WebhookEvent
+	json.Unmarshal(body, &event)
+
+	wr.mutex.Lock()
+	defer wr.mutex.Unlock()
+	wr.events = append(wr.events, event)
+	wr.signed = r.Header.Get(webhook.HeaderSignature) == webhook.Sign(testWebhookSecret, r.Header.Get(webhook.HeaderTimestamp), body)
+}
+
+func TestRedeliverWebhookHandler(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	receiver := &webhookReceiver{}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{
+		Webhooks: []models.Webhook{{Id: "w1", Index: "test", URL: receiverServer.URL, Secret: testWebhookSecret, Events: []string{models.EventIndexCreated}}},
+		Deliveries: []models.WebhookDelivery{
+			{Id: "d1", WebhookId: "w1", EventId: "e1", Event: models.EventIndexCreated, Payload: `{"id":"e1","type":"index.created"}`, Status: models.DeliveryStatusFailed, Attempts: 5},
+		},
+	}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	server := NewServer("", nil, &storage.DocStorageMock{}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithWebhooks(webhookStorage, newTestDispatcher(webhookStorage), true))
+
+	req, err := http.NewRequest(http.MethodPost, "/webhooks/w1/deliveries/d1/redeliver", nil)
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	server.dispatcher.Wait()
+
+	var response struct {
+		Data	models.WebhookDelivery	`json:"data"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Unable to unmarshal response, error: %s\n", err)
+	}
+
+	assert.Equal(t, rr.Code, 202, "wrong response code")
+	assert.Equal(t, response.Data.RedeliveryOf, "d1", "wrong redelivered delivery")
+	assert.Equal(t, response.Data.Status, models.DeliveryStatusPending, "redelivery must be returned before it is attempted")
+	assert.Equal(t, len(receiver.events), 1, "payload must be redelivered")
+	assert.Equal(t, receiver.events[0].Id, "e1", "wrong redelivered event")
+	assert.Equal(t, receiver.signed, true, "redelivery must be signed")
+	assert.Equal(t, webhookStorage.Deliveries[1].Status, models.DeliveryStatusSucceeded, "wrong redelivery status")
+}
+
+func TestIndexDocumentsWebhookEvents(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+		SyncIndexingMaxBatch: 10,
+	}
+
+	receiver := &webhookReceiver{}
+	receiverServer := httptest.NewServer(receiver)
+	defer receiverServer.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiverServer.URL, Secret: testWebhookSecret, Events: []string{models.EventDocumentsQueued, models.EventDocumentsIndexed}},
+	}}
+	docStorage := &storage.DocStorageMock{EsIndexExists: true, IndexingResults: []models.DocumentIndexingResult{{Position: 0, Id: "d1", Result: models.IndexingResultCreated}}}
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	server := NewServer("", &queue.QueueMock{}, docStorage, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithWebhooks(webhookStorage, newTestDispatcher(webhookStorage), true))
+
+	for _, url := range []string{"/indexDocuments", "/indexDocuments?mode=sync"} {
+		payload := `{"index_name": "test", "documents": [{"title": "Acme launch", "text": "Acme ships"}]}`
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewBufferString(payload))
+		if err != nil {
+			t.Fatalf("Unable to create request, error: %s\n", err)
+		}
+		req.Header.Add(config.TokenHeaderName, "aaa")
+		req.Header.Add("X-Request-Id", testRequestId)
+
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		assert.Equal(t, rr.Code, 200, "wrong response code")
+		server.dispatcher.Wait()
+	}
+
+	eventTypes := []string{}
+	for _, event := range receiver.events {
+		eventTypes = append(eventTypes, event.Type)
+	}
+	assert.Equal(t, eventTypes, []string{models.EventDocumentsQueued, models.EventDocumentsIndexed}, "wrong delivered events")
+	assert.Equal(t, receiver.signed, true, "events must be signed")
+}
+
+func TestCreateWebhookWithExternalIngestion(t *testing.T) {
+	config := &config.Config{
+		JwtKey: []byte("aaa"),
+		TokenHeaderName: "aaa",
+		JwtSalt: "aaa",
+	}
+
+	userStorage := &storage.UserStorageMock{IndexAccess: true}
+	webhookStorage := &storage.WebhookStorageMock{}
+	server := NewServer("", nil, &storage.DocStorageMock{EsIndexExists: true}, userStorage, &storage.IndexStorageMock{}, config, &utils.TokenOperatorMock{TokenValid: true}, WithWebhooks(webhookStorage, newTestDispatcher(webhookStorage), false))
+
+	payload := `{"url": "https://hooks.example.com/search", "secret": "0123456789abcdef", "events": ["documents.queued", "documents.indexed"]}`
+	req, err := http.NewRequest(http.MethodPost, "/indexes/test/webhooks", bytes.NewBufferString(payload))
+	if err != nil {
+		t.Fatalf("Unable to create request, error: %s\n", err)
+	}
+	req.Header.Add(config.TokenHeaderName, "aaa")
+	req.Header.Add("X-Request-Id", testRequestId)
+
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, rr.Code, 400, "ingestion events must be rejected when queued documents are indexed outside of the service")
+	assert.Equal(t, strings.Contains(rr.Body.String(), `"field":"events[1]"`), true, "wrong rejected event")
+	assert.Equal(t, len(webhookStorage.Webhooks), 0, "webhook must not be stored")
+}
@@ -2,11 +2,14 @@ package config
 
 import (
 	"encoding/base64"
+	"fmt"
 	"os"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"github.com/xavesen/search-api/internal/simhash"
+	"github.com/xavesen/search-api/internal/simplequery"
 )
 
 type Config struct {
@@ -16,9 +19,65 @@ type Config struct {
 	ElasticSearchURLs		[]string
 	ElasticSearchKey		string		`mapstructure:"ELASTIC_SEARCH_KEY"`
 
+	QueueBackend			string		`mapstructure:"QUEUE_BACKEND"`
+	MemoryQueueSize			int			`mapstructure:"MEMORY_QUEUE_SIZE"`
+
 	KafkaAddrsStr			string		`mapstructure:"KAFKA_ADDR"`
 	KafkaAddrs 				[]string
 	KafkaTopic				string		`mapstructure:"KAFKA_TOPIC"`
+	KafkaBatchSize			int			`mapstructure:"KAFKA_BATCH_SIZE"`
+	KafkaBatchBytes			int64		`mapstructure:"KAFKA_BATCH_BYTES"`
+	KafkaBatchTimeoutMs		int			`mapstructure:"KAFKA_BATCH_TIMEOUT_MS"`
+	KafkaCompression		string		`mapstructure:"KAFKA_COMPRESSION"`
+	KafkaRequiredAcks		string		`mapstructure:"KAFKA_REQUIRED_ACKS"`
+	KafkaBalancer			string		`mapstructure:"KAFKA_BALANCER"`
+
+	NatsURL					string		`mapstructure:"NATS_URL"`
+	NatsStream				string		`mapstructure:"NATS_STREAM"`
+	NatsSubject				string		`mapstructure:"NATS_SUBJECT"`
+	NatsConsumer			string		`mapstructure:"NATS_CONSUMER"`
+
+	RedisAddr				string		`mapstructure:"REDIS_ADDR"`
+	RedisPassword			string		`mapstructure:"REDIS_PASSWORD"`
+	RedisDb					int			`mapstructure:"REDIS_DB"`
+	RedisStream				string		`mapstructure:"REDIS_STREAM"`
+	RedisGroup				string		`mapstructure:"REDIS_GROUP"`
+	RedisConsumer			string		`mapstructure:"REDIS_CONSUMER"`
+
+	SyncIndexingMaxBatch	int			`mapstructure:"SYNC_INDEXING_MAX_BATCH"`
+
+	MaxRequestSize			int64		`mapstructure:"MAX_REQUEST_SIZE"`
+	MaxDocumentSize			int			`mapstructure:"MAX_DOCUMENT_SIZE"`
+	MaxDocumentsPerRequest	int			`mapstructure:"MAX_DOCUMENTS_PER_REQUEST"`
+	BulkMaxRequestSize		int64		`mapstructure:"BULK_MAX_REQUEST_SIZE"`
+	QueueMaxMessageBytes	int			`mapstructure:"QUEUE_MAX_MESSAGE_BYTES"`
+	UploadMaxFileSize		int			`mapstructure:"UPLOAD_MAX_FILE_SIZE"`
+	UploadMaxPages			int			`mapstructure:"UPLOAD_MAX_PAGES"`
+
+	SimpleQueryOperatorsStr		string		`mapstructure:"SIMPLE_QUERY_OPERATORS"`
+	SimpleQueryOperators		simplequery.Operators
+	SimpleQueryDefaultOperator	string		`mapstructure:"SIMPLE_QUERY_DEFAULT_OPERATOR"`
+
+	DidYouMeanMaxHits		int			`mapstructure:"DID_YOU_MEAN_MAX_HITS"`
+
+	NearDuplicateDistance	int			`mapstructure:"NEAR_DUPLICATE_DISTANCE"`
+
+	Embedder				string		`mapstructure:"EMBEDDER"`
+	EmbedderURL				string		`mapstructure:"EMBEDDER_URL"`
+	EmbedderTimeoutMs		int			`mapstructure:"EMBEDDER_TIMEOUT_MS"`
+	EmbeddingDimensions		int			`mapstructure:"EMBEDDING_DIMENSIONS"`
+
+	// WebhookAllowPrivateNetworks lets saved searches and webhooks push to loopback and private
+	// addresses, it is meant for local setups only
+	WebhookAllowPrivateNetworks	bool	`mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+
+	SavedSearchWebhookTimeoutMs	int		`mapstructure:"SAVED_SEARCH_WEBHOOK_TIMEOUT_MS"`
+
+	WebhookTimeoutMs		int			`mapstructure:"WEBHOOK_TIMEOUT_MS"`
+	WebhookMaxAttempts		int			`mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookBackoffMs		int			`mapstructure:"WEBHOOK_BACKOFF_MS"`
+
+	ReindexPollIntervalMs	int			`mapstructure:"REINDEX_POLL_INTERVAL_MS"`
 
 	DbAddr					string		`mapstructure:"DB_ADDR"`
 	Db						string		`mapstructure:"DB"`
@@ -47,6 +106,80 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if config.QueueBackend == "" {
+		config.QueueBackend = "kafka"
+	}
+	if config.SyncIndexingMaxBatch == 0 {
+		config.SyncIndexingMaxBatch = 100
+	}
+	if config.MaxRequestSize == 0 {
+		config.MaxRequestSize = 10 << 20
+	}
+	if config.MaxDocumentSize == 0 {
+		config.MaxDocumentSize = 1 << 20
+	}
+	if config.MaxDocumentsPerRequest == 0 {
+		config.MaxDocumentsPerRequest = 1000
+	}
+	if config.BulkMaxRequestSize == 0 {
+		config.BulkMaxRequestSize = 1 << 30
+	}
+	// below default max message size of Kafka
+	if config.QueueMaxMessageBytes == 0 {
+		config.QueueMaxMessageBytes = 900 << 10
+	}
+	if config.UploadMaxFileSize == 0 {
+		config.UploadMaxFileSize = 100 << 20
+	}
+	if config.UploadMaxPages == 0 {
+		config.UploadMaxPages = 2000
+	}
+	if config.SimpleQueryOperatorsStr == "" {
+		config.SimpleQueryOperatorsStr = "AND;OR;NOT;PHRASE;PREFIX;PRECEDENCE"
+	}
+	simpleQueryOperators, err := simplequery.ParseOperators(strings.Split(config.SimpleQueryOperatorsStr, ";"))
+	if err != nil {
+		log.Errorf("Error parsing SIMPLE_QUERY_OPERATORS: %s", err)
+		return nil, err
+	}
+	config.SimpleQueryOperators = simpleQueryOperators
+	if config.SimpleQueryDefaultOperator == "" {
+		config.SimpleQueryDefaultOperator = "or"
+	}
+	// zero is valid, suggestions are returned only for searches finding nothing
+	if !viper.IsSet("DID_YOU_MEAN_MAX_HITS") {
+		config.DidYouMeanMaxHits = 5
+	}
+	// zero is valid, only documents with the same SimHash are near duplicates
+	if !viper.IsSet("NEAR_DUPLICATE_DISTANCE") {
+		config.NearDuplicateDistance = 6
+	}
+	if config.NearDuplicateDistance < 0 || config.NearDuplicateDistance > simhash.MaxDistance {
+		err := fmt.Errorf("NEAR_DUPLICATE_DISTANCE must be from 0 to %d", simhash.MaxDistance)
+		log.Errorf("Error parsing NEAR_DUPLICATE_DISTANCE: %s", err)
+		return nil, err
+	}
+	if config.EmbedderTimeoutMs == 0 {
+		config.EmbedderTimeoutMs = 5000
+	}
+	if config.EmbeddingDimensions == 0 {
+		config.EmbeddingDimensions = 384
+	}
+	if config.SavedSearchWebhookTimeoutMs == 0 {
+		config.SavedSearchWebhookTimeoutMs = 5000
+	}
+	if config.WebhookTimeoutMs == 0 {
+		config.WebhookTimeoutMs = 5000
+	}
+	if config.WebhookMaxAttempts == 0 {
+		config.WebhookMaxAttempts = 5
+	}
+	if config.WebhookBackoffMs == 0 {
+		config.WebhookBackoffMs = 1000
+	}
+	if config.ReindexPollIntervalMs == 0 {
+		config.ReindexPollIntervalMs = 1000
+	}
 	config.KafkaAddrs = strings.Split(config.KafkaAddrsStr, ";")
 	config.ElasticSearchURLs = strings.Split(config.ElasticSearchURLsStr, ";")
 	jwtKey, err := base64.StdEncoding.DecodeString(config.JwtKeyStr)
@@ -0,0 +1,14 @@
+package embedding
+
+import "context"
+
+const (
+	BackendHashing	= "hashing"
+	BackendHTTP		= "http"
+)
+
+// Embedder converts texts to vectors of fixed dimensions, vectors are returned in order of texts.
+type Embedder interface {
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	Dimensions() int
+}
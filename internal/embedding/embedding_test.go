@@ -0,0 +1,96 @@
+package embedding
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+func dot(a []float32, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
+
+func TestHashingEmbedder(t *testing.T) {
+	embedder := NewHashingEmbedder(64)
+
+	vectors, err := embedder.Embed(context.TODO(), []string{"Wireless mouse", "wireless MOUSE!", "wireless keyboard", ""})
+	if err != nil {
+		t.Fatalf("Unable to embed texts, error: %s\n", err)
+	}
+
+	assert.Equal(t, len(vectors), 4, "wrong number of vectors")
+	for _, vector := range vectors {
+		assert.Equal(t, len(vector), 64, "wrong vector dimensions")
+	}
+
+	assert.Equal(t, vectors[0], vectors[1], "same words give different vectors")
+	assert.Equal(t, dot(vectors[0], vectors[0]) > 0.999 && dot(vectors[0], vectors[0]) < 1.001, true, "vector isn't normalized")
+	assert.Equal(t, dot(vectors[0], vectors[2]) > 0 && dot(vectors[0], vectors[2]) < 1, true, "texts sharing words aren't similar")
+	assert.Equal(t, dot(vectors[3], vectors[3]), float32(1), "text without words gives zero vector")
+}
+
+var httpEmbedderTests = []struct {
+	testName		string
+	status			int
+	response		string
+	expectedVectors	[][]float32
+	expectError		bool
+}{
+	{
+		testName: "Vectors are returned in order of texts",
+		status: http.StatusOK,
+		response: `[[1,0,0],[0,1,0]]`,
+		expectedVectors: [][]float32{{1, 0, 0}, {0, 1, 0}},
+	},
+	{
+		testName: "Error status",
+		status: http.StatusServiceUnavailable,
+		response: `{"error":"model is loading"}`,
+		expectError: true,
+	},
+	{
+		testName: "Wrong number of vectors",
+		status: http.StatusOK,
+		response: `[[1,0,0]]`,
+		expectError: true,
+	},
+	{
+		testName: "Wrong dimensions",
+		status: http.StatusOK,
+		response: `[[1,0],[0,1]]`,
+		expectError: true,
+	},
+}
+
+func TestHTTPEmbedder(t *testing.T) {
+	for i, test := range httpEmbedderTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		var request httpEmbedRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			json.NewDecoder(r.Body).Decode(&request)
+			w.WriteHeader(test.status)
+			w.Write([]byte(test.response))
+		}))
+
+		embedder := NewHTTPEmbedder(server.URL, 3, time.Second)
+		vectors, err := embedder.Embed(context.TODO(), []string{"first", "second"})
+		server.Close()
+
+		assert.Equal(t, request.Inputs, []string{"first", "second"}, "wrong embedding request")
+		assert.Equal(t, err != nil, test.expectError, "wrong error")
+		if !test.expectError {
+			assert.Equal(t, vectors, test.expectedVectors, "wrong vectors")
+		}
+	}
+}
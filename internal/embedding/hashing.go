@@ -0,0 +1,73 @@
+package embedding
+
+import (
+	"context"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// HashingEmbedder maps words to vector dimensions by their hash, texts sharing words get
+// similar vectors. It is deterministic and needs no model, so it is used in tests and
+// local setups, it doesn't match paraphrases.
+type HashingEmbedder struct {
+	dimensions	int
+}
+
+func NewHashingEmbedder(dimensions int) *HashingEmbedder {
+	return &HashingEmbedder{dimensions: dimensions}
+}
+
+func (e *HashingEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *HashingEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for _, text := range texts {
+		vectors = append(vectors, e.embed(text))
+	}
+	return vectors, nil
+}
+
+func (e *HashingEmbedder) embed(text string) []float32 {
+	vector := make([]float32, e.dimensions)
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	for _, word := range words {
+		hash := fnv.New64a()
+		hash.Write([]byte(word))
+		sum := hash.Sum64()
+		// highest bit chooses sign, so collisions cancel out instead of adding up
+		if sum>>63 == 0 {
+			vector[sum%uint64(e.dimensions)]++
+		} else {
+			vector[sum%uint64(e.dimensions)]--
+		}
+	}
+
+	return normalize(vector)
+}
+
+// normalize scales vector to unit length, zero vector of text without words is replaced
+// with unit vector as ES rejects zero vectors for cosine similarity.
+func normalize(vector []float32) []float32 {
+	var sum float64
+	for _, value := range vector {
+		sum += float64(value) * float64(value)
+	}
+
+	if sum == 0 {
+		vector[0] = 1
+		return vector
+	}
+
+	norm := float32(math.Sqrt(sum))
+	for i := range vector {
+		vector[i] /= norm
+	}
+	return vector
+}
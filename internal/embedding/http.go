@@ -0,0 +1,82 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// HTTPEmbedder calls self-hosted model with text-embeddings-inference compatible API:
+// POST {"inputs": [texts]} returning array of vectors.
+type HTTPEmbedder struct {
+	url			string
+	dimensions	int
+	client		*http.Client
+}
+
+type httpEmbedRequest struct {
+	Inputs	[]string	`json:"inputs"`
+}
+
+func NewHTTPEmbedder(url string, dimensions int, timeout time.Duration) *HTTPEmbedder {
+	return &HTTPEmbedder{
+		url: url,
+		dimensions: dimensions,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (e *HTTPEmbedder) Dimensions() int {
+	return e.dimensions
+}
+
+func (e *HTTPEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(httpEmbedRequest{Inputs: texts})
+	if err != nil {
+		log.Errorf("Error marshalling embedding request: %s", err)
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		log.Errorf("Error creating embedding request to %s: %s", e.url, err)
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Errorf("Error performing embedding request to %s: %s", e.url, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		log.Errorf("Error performing embedding request to %s: status %d", e.url, resp.StatusCode)
+		return nil, fmt.Errorf("embedding request failed with status %d", resp.StatusCode)
+	}
+
+	var vectors [][]float32
+	if err := json.NewDecoder(resp.Body).Decode(&vectors); err != nil {
+		log.Errorf("Error decoding embedding response from %s: %s", e.url, err)
+		return nil, err
+	}
+
+	if len(vectors) != len(texts) {
+		log.Errorf("Error in embedding response from %s: %d vectors for %d texts", e.url, len(vectors), len(texts))
+		return nil, fmt.Errorf("got %d vectors for %d texts", len(vectors), len(texts))
+	}
+	for _, vector := range vectors {
+		if len(vector) != e.dimensions {
+			log.Errorf("Error in embedding response from %s: vector has %d dimensions instead of %d", e.url, len(vector), e.dimensions)
+			return nil, fmt.Errorf("vector has %d dimensions instead of %d", len(vector), e.dimensions)
+		}
+	}
+
+	return vectors, nil
+}
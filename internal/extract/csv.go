@@ -0,0 +1,198 @@
+package extract
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Columns used without mapping
+const (
+	csvColumnId		= "id"
+	csvColumnTitle	= "title"
+	csvColumnText	= "text"
+)
+
+// CSVExtractor converts each row of CSV file with header to document. Without mapping id,
+// title and text columns are used and other columns become fields, columns that aren't
+// fields of index schema are left out like metadata of other formats.
+type CSVExtractor struct{}
+
+func (e *CSVExtractor) Extract(file *File, emit EmitFunc) error {
+	reader := csv.NewReader(file.Reader)
+	// rows with wrong number of columns are reported separately
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return errors.New("csv file must have header")
+	}
+	if err != nil {
+		return err
+	}
+	if len(header) > 0 {
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+	}
+
+	mapping, err := csvColumns(header, file.Options.CSVMapping, file.Options.Schema)
+	if err != nil {
+		return err
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(record) != len(header) {
+			err = emit(models.Document{}, fmt.Errorf("row has %d columns, header has %d", len(record), len(header)))
+		} else {
+			err = emit(mapping.document(record, file.Options.Schema), nil)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// csvMapping is mapping with columns resolved to their positions, -1 means column isn't mapped.
+type csvMapping struct {
+	id		int
+	title	int
+	text	[]int
+	fields	map[string]int
+}
+
+func csvColumns(header []string, mapping *models.CSVMapping, schema *models.IndexSchema) (*csvMapping, error) {
+	positions := map[string]int{}
+	for i, column := range header {
+		if _, ok := positions[column]; !ok {
+			positions[column] = i
+		}
+	}
+
+	if mapping == nil {
+		columns := &csvMapping{id: -1, title: -1, fields: map[string]int{}}
+		for column, position := range positions {
+			switch column {
+			case csvColumnId:
+				columns.id = position
+			case csvColumnTitle:
+				columns.title = position
+			case csvColumnText:
+				columns.text = []int{position}
+			default:
+				if schema != nil {
+					if _, ok := schema.Fields[column]; !ok {
+						continue
+					}
+				}
+				columns.fields[column] = position
+			}
+		}
+		if columns.title == -1 && columns.text == nil {
+			return nil, fmt.Errorf("csv file must have %s or %s column, or mapping", csvColumnTitle, csvColumnText)
+		}
+		return columns, nil
+	}
+
+	position := func(column string) (int, error) {
+		if column == "" {
+			return -1, nil
+		}
+		position, ok := positions[column]
+		if !ok {
+			return -1, fmt.Errorf("column %s isn't found in csv header", column)
+		}
+		return position, nil
+	}
+
+	var err error
+	columns := &csvMapping{fields: map[string]int{}}
+	if columns.id, err = position(mapping.Id); err != nil {
+		return nil, err
+	}
+	if columns.title, err = position(mapping.Title); err != nil {
+		return nil, err
+	}
+	for _, column := range mapping.Text {
+		textPosition, err := position(column)
+		if err != nil {
+			return nil, err
+		}
+		columns.text = append(columns.text, textPosition)
+	}
+	for field, column := range mapping.Fields {
+		if columns.fields[field], err = position(column); err != nil {
+			return nil, err
+		}
+	}
+
+	return columns, nil
+}
+
+func (m *csvMapping) document(record []string, schema *models.IndexSchema) models.Document {
+	document := models.Document{}
+	if m.id >= 0 {
+		document.Id = strings.TrimSpace(record[m.id])
+	}
+	if m.title >= 0 {
+		document.Title = strings.TrimSpace(record[m.title])
+	}
+
+	texts := []string{}
+	for _, position := range m.text {
+		if text := strings.TrimSpace(record[position]); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	document.Text = strings.Join(texts, "\n\n")
+
+	for field, position := range m.fields {
+		value := strings.TrimSpace(record[position])
+		// empty cells are missing values
+		if value == "" {
+			continue
+		}
+		if document.Fields == nil {
+			document.Fields = map[string]any{}
+		}
+		document.Fields[field] = csvValue(schema, field, value)
+	}
+
+	return document
+}
+
+// csvValue converts cell to type of schema field, values that can't be converted are
+// kept as text and rejected by schema validation.
+func csvValue(schema *models.IndexSchema, field string, value string) any {
+	if schema == nil {
+		return value
+	}
+
+	switch schema.Fields[field].Type {
+	case models.FieldTypeLong, models.FieldTypeInteger:
+		// integers are parsed exactly, float64 would round values above 2^53
+		if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return number
+		}
+	case models.FieldTypeFloat, models.FieldTypeDouble:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case models.FieldTypeBoolean:
+		if boolean, err := strconv.ParseBool(value); err == nil {
+			return boolean
+		}
+	}
+	return value
+}
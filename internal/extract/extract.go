@@ -0,0 +1,217 @@
+package extract
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	FormatCSV		= "csv"
+	FormatJSON		= "json"
+	FormatMarkdown	= "markdown"
+	FormatHTML		= "html"
+	FormatText		= "text"
+	FormatPDF		= "pdf"
+	FormatDOCX		= "docx"
+	FormatODT		= "odt"
+)
+
+// Fields metadata of PDF and office files is stored in, fields not defined in index
+// schema are left out.
+const (
+	FieldAuthor		= "author"
+	FieldCreatedAt	= "created_at"
+	FieldPageCount	= "page_count"
+)
+
+// Decompressed content of PDF and office files is limited relative to file size, so
+// small file can't expand without bound
+const maxCompressionRatio = 100
+
+// ErrFileTooLarge is returned by extractors reading whole file into one document
+var ErrFileTooLarge = errors.New("file is too large")
+
+// ErrTooManyPages is returned by extractors of paged formats
+var ErrTooManyPages = errors.New("file has too many pages")
+
+var errNotUTF8 = errors.New("file must be UTF-8 encoded")
+
+// File is uploaded file being extracted, Reader is read only once.
+type File struct {
+	Name	string
+	Reader	io.Reader
+	Options	Options
+}
+
+type Options struct {
+	// MaxSize limits files converted to single document, zero means no limit
+	MaxSize		int
+	// MaxFileSize limits PDF and office files which are much larger than their text
+	MaxFileSize	int
+	// MaxPages limits paged files, zero means no limit
+	MaxPages	int
+	// Schema of the index is used to convert text values to field types
+	Schema		*models.IndexSchema
+	CSVMapping	*models.CSVMapping
+}
+
+// EmitFunc receives documents in order they appear in the file. Non-nil err means element
+// of the file can't be converted to document, extraction goes on after it. Extractors
+// stop and return error returned by EmitFunc.
+type EmitFunc func(document models.Document, err error) error
+
+// Extractor converts files of one format to documents.
+type Extractor interface {
+	Extract(file *File, emit EmitFunc) error
+}
+
+// Registry finds extractor of file by its content type or extension.
+type Registry struct {
+	extractors		map[string]Extractor
+	extensions		map[string]string
+	contentTypes	map[string]string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: map[string]Extractor{},
+		extensions: map[string]string{},
+		contentTypes: map[string]string{},
+	}
+}
+
+// NewDefaultRegistry returns registry with extractors of all built-in formats.
+func NewDefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register(FormatCSV, &CSVExtractor{}, []string{".csv"}, []string{"text/csv"})
+	registry.Register(FormatJSON, &JSONExtractor{}, []string{".json"}, []string{"application/json"})
+	registry.Register(FormatMarkdown, &MarkdownExtractor{}, []string{".md", ".markdown"}, []string{"text/markdown", "text/x-markdown"})
+	registry.Register(FormatHTML, &HTMLExtractor{}, []string{".html", ".htm"}, []string{"text/html"})
+	registry.Register(FormatText, &TextExtractor{}, []string{".txt", ".text"}, []string{"text/plain"})
+	registry.Register(FormatPDF, &PDFExtractor{}, []string{".pdf"}, []string{"application/pdf"})
+	registry.Register(FormatDOCX, &DOCXExtractor{}, []string{".docx"}, []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"})
+	registry.Register(FormatODT, &ODTExtractor{}, []string{".odt"}, []string{"application/vnd.oasis.opendocument.text"})
+	return registry
+}
+
+// Register adds extractor of format replacing extractor registered for it before,
+// extensions include leading dot.
+func (r *Registry) Register(format string, extractor Extractor, extensions []string, contentTypes []string) {
+	r.extractors[format] = extractor
+	for _, extension := range extensions {
+		r.extensions[strings.ToLower(extension)] = format
+	}
+	for _, contentType := range contentTypes {
+		r.contentTypes[strings.ToLower(contentType)] = format
+	}
+}
+
+// Lookup returns format and extractor of file, specific content type takes precedence
+// over extension as clients often send generic one.
+func (r *Registry) Lookup(fileName string, contentType string) (string, Extractor, bool) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		if format, ok := r.contentTypes[strings.ToLower(mediaType)]; ok {
+			return format, r.extractors[format], true
+		}
+	}
+
+	if format, ok := r.extensions[strings.ToLower(filepath.Ext(fileName))]; ok {
+		return format, r.extractors[format], true
+	}
+
+	return "", nil, false
+}
+
+// readAll reads file converted to single document, files over the limit aren't read entirely.
+func readAll(file *File) ([]byte, error) {
+	if file.Options.MaxSize <= 0 {
+		return io.ReadAll(file.Reader)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file.Reader, int64(file.Options.MaxSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > file.Options.MaxSize {
+		return nil, fmt.Errorf("%w, at most %d bytes can be converted to one document", ErrFileTooLarge, file.Options.MaxSize)
+	}
+	return data, nil
+}
+
+// readBinary reads PDF or office file, files over the limit aren't read entirely.
+func readBinary(file *File) ([]byte, error) {
+	if file.Options.MaxFileSize <= 0 {
+		return io.ReadAll(file.Reader)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file.Reader, int64(file.Options.MaxFileSize)+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > file.Options.MaxFileSize {
+		return nil, fmt.Errorf("%w, at most %d bytes can be extracted", ErrFileTooLarge, file.Options.MaxFileSize)
+	}
+	return data, nil
+}
+
+// checkPages returns error if number of pages exceeds the limit, unknown number is zero.
+func checkPages(pages int, options Options) error {
+	if options.MaxPages > 0 && pages > options.MaxPages {
+		return fmt.Errorf("%w, at most %d pages can be extracted", ErrTooManyPages, options.MaxPages)
+	}
+	return nil
+}
+
+// metadata is document information stored in PDF and office files.
+type metadata struct {
+	title	string
+	author	string
+	created	time.Time
+	pages	int
+}
+
+// document returns document with metadata in fields, fields without value are omitted.
+func (m *metadata) document(fileName string, text string, schema *models.IndexSchema) models.Document {
+	document := models.Document{Title: strings.TrimSpace(m.title), Text: normalizeText(text)}
+	if document.Title == "" {
+		document.Title = baseName(fileName)
+	}
+
+	fields := map[string]any{}
+	if author := strings.TrimSpace(m.author); author != "" {
+		fields[FieldAuthor] = author
+	}
+	if !m.created.IsZero() {
+		fields[FieldCreatedAt] = m.created.UTC().Format(time.RFC3339)
+	}
+	if m.pages > 0 {
+		fields[FieldPageCount] = m.pages
+	}
+
+	for field := range fields {
+		if schema != nil {
+			if _, ok := schema.Fields[field]; !ok {
+				delete(fields, field)
+			}
+		}
+	}
+	if len(fields) > 0 {
+		document.Fields = fields
+	}
+
+	return document
+}
+
+// baseName returns file name without directories and extension, it is used as title
+// of documents without one.
+func baseName(fileName string) string {
+	name := filepath.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
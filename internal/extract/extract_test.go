@@ -0,0 +1,520 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+type extracted struct {
+	documents	[]models.Document
+	errors		[]string
+}
+
+func extractFile(extractor Extractor, file *File) (*extracted, error) {
+	result := &extracted{documents: []models.Document{}, errors: []string{}}
+	err := extractor.Extract(file, func(document models.Document, err error) error {
+		if err != nil {
+			result.errors = append(result.errors, err.Error())
+			return nil
+		}
+		result.documents = append(result.documents, document)
+		return nil
+	})
+	return result, err
+}
+
+var extractTests = []struct {
+	testName 			string
+	extractor			Extractor
+	file				*File
+	expectedDocuments	[]models.Document
+	expectedErrors		[]string
+	expectedError		string
+}{
+	{
+		testName: "CSV with default columns",
+		extractor: &CSVExtractor{},
+		file: &File{Name: "rows.csv", Reader: strings.NewReader("\ufeffid,title,text,color\n1, Mouse ,Wireless,\n2,Keyboard,,black\n3,Short\n")},
+		expectedDocuments: []models.Document{
+			{Id: "1", Title: "Mouse", Text: "Wireless"},
+			{Id: "2", Title: "Keyboard", Fields: map[string]any{"color": "black"}},
+		},
+		expectedErrors: []string{"row has 2 columns, header has 4"},
+	},
+	{
+		testName: "CSV with mapping and typed fields",
+		extractor: &CSVExtractor{},
+		file: &File{Name: "rows.csv", Reader: strings.NewReader("name,summary,details,cost,stock\nMouse,Wireless,Quiet,19.5,true\nPad,,,cheap,no\n"), Options: Options{
+			Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"price": {Type: models.FieldTypeFloat}, "in_stock": {Type: models.FieldTypeBoolean}}},
+			CSVMapping: &models.CSVMapping{Title: "name", Text: []string{"summary", "details"}, Fields: map[string]string{"price": "cost", "in_stock": "stock"}},
+		}},
+		expectedDocuments: []models.Document{
+			{Title: "Mouse", Text: "Wireless\n\nQuiet", Fields: map[string]any{"price": 19.5, "in_stock": true}},
+			{Title: "Pad", Fields: map[string]any{"price": "cheap", "in_stock": "no"}},
+		},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "CSV without title and text columns",
+		extractor: &CSVExtractor{},
+		file: &File{Name: "rows.csv", Reader: strings.NewReader("id,color\n1,black\n")},
+		expectedDocuments: []models.Document{},
+		expectedErrors: []string{},
+		expectedError: "csv file must have title or text column, or mapping",
+	},
+	{
+		testName: "CSV without mapping keeps only columns of schema and parses integers exactly",
+		extractor: &CSVExtractor{},
+		file: &File{Name: "rows.csv", Reader: strings.NewReader("title,views,color\nMouse,9007199254740993,black\nPad,1.5,white\n"), Options: Options{
+			Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{"views": {Type: models.FieldTypeLong}}},
+		}},
+		expectedDocuments: []models.Document{
+			{Title: "Mouse", Fields: map[string]any{"views": int64(9007199254740993)}},
+			{Title: "Pad", Fields: map[string]any{"views": "1.5"}},
+		},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "JSON array",
+		extractor: &JSONExtractor{},
+		file: &File{Name: "items.json", Reader: strings.NewReader(`[{"id": "1", "title": "One"}, 5, {"title": "Two", "tags": []}, {"text": "Three"}]`)},
+		expectedDocuments: []models.Document{
+			{Id: "1", Title: "One"},
+			{Text: "Three"},
+		},
+		expectedErrors: []string{"element must be document object", "unknown field \"tags\""},
+	},
+	{
+		testName: "JSON that isn't array",
+		extractor: &JSONExtractor{},
+		file: &File{Name: "items.json", Reader: strings.NewReader(`{"title": "One"}`)},
+		expectedDocuments: []models.Document{},
+		expectedErrors: []string{},
+		expectedError: "json file must contain array of documents",
+	},
+	{
+		testName: "Markdown",
+		extractor: &MarkdownExtractor{},
+		file: &File{Name: "docs/guide.md", Reader: strings.NewReader(strings.Join([]string{
+			"---",
+			"author: someone",
+			"---",
+			"# Getting *started*",
+			"",
+			"Read the [manual](http://example.com) and see ![diagram](d.png).",
+			"",
+			"## Install",
+			"",
+			"- Run `make install`",
+			"> Needs __root__ rights",
+			"",
+			"---",
+			"```",
+			"make   all",
+			"```",
+			"snake_case_name <br/> done",
+		}, "\n"))},
+		expectedDocuments: []models.Document{
+			{Title: "Getting started", Text: "Read the manual and see diagram.\n\nInstall\n\nRun make install\nNeeds root rights\n\nmake all\nsnake_case_name done"},
+		},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "Markdown without heading is titled by file name",
+		extractor: &MarkdownExtractor{},
+		file: &File{Name: "notes.md", Reader: strings.NewReader("Just *text*")},
+		expectedDocuments: []models.Document{{Title: "notes", Text: "Just text"}},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "HTML",
+		extractor: &HTMLExtractor{},
+		file: &File{Name: "page.html", Reader: strings.NewReader(strings.Join([]string{
+			"<!DOCTYPE html>",
+			"<html><head><title>Shop &ndash; Mice</title>",
+			"<script>if (a < b) { document.write('<p>no</p>') }</script>",
+			"<style>p { color: red }</style></head>",
+			"<body><!-- <p>comment</p> --><h1>Mice</h1>",
+			"<p class=\"intro\" data-x=\"a > b\">Wireless   <b>and</b>\nwired</p>",
+			"<ul><li>One</li><li>Two</li></ul></body></html>",
+		}, "\n"))},
+		expectedDocuments: []models.Document{
+			{Title: "Shop – Mice", Text: "Mice\nWireless and wired\nOne\nTwo"},
+		},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "HTML without title is titled by the first heading",
+		extractor: &HTMLExtractor{},
+		file: &File{Name: "page.html", Reader: strings.NewReader("<h1>Main <i>topic</i></h1><p>Body</p><h1>Other</h1>")},
+		expectedDocuments: []models.Document{{Title: "Main topic", Text: "Main topic\nBody\nOther"}},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "Plain text",
+		extractor: &TextExtractor{},
+		file: &File{Name: "C:\\docs\\notes.txt", Reader: strings.NewReader("\ufeff  First   line \r\n\r\n\r\n\tSecond line\n")},
+		expectedDocuments: []models.Document{{Title: "notes", Text: "First line\n\nSecond line"}},
+		expectedErrors: []string{},
+	},
+	{
+		testName: "Plain text that isn't UTF-8",
+		extractor: &TextExtractor{},
+		file: &File{Name: "notes.txt", Reader: strings.NewReader("caf\xe9")},
+		expectedDocuments: []models.Document{},
+		expectedErrors: []string{},
+		expectedError: "file must be UTF-8 encoded",
+	},
+	{
+		testName: "Plain text over the size limit",
+		extractor: &TextExtractor{},
+		file: &File{Name: "notes.txt", Reader: strings.NewReader("0123456789"), Options: Options{MaxSize: 5}},
+		expectedDocuments: []models.Document{},
+		expectedErrors: []string{},
+		expectedError: "file is too large, at most 5 bytes can be converted to one document",
+	},
+}
+
+func TestExtract(t *testing.T) {
+	for i, test := range extractTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		result, err := extractFile(test.extractor, test.file)
+		errorMessage := ""
+		if err != nil {
+			errorMessage = err.Error()
+		}
+
+		assert.Equal(t, errorMessage, test.expectedError, "wrong extraction error")
+		assert.Equal(t, result.documents, test.expectedDocuments, "wrong extracted documents")
+		assert.Equal(t, result.errors, test.expectedErrors, "wrong document errors")
+	}
+}
+
+func TestRegistryLookup(t *testing.T) {
+	registry := NewDefaultRegistry()
+	tests := []struct {
+		fileName		string
+		contentType		string
+		expectedFormat	string
+	}{
+		{fileName: "rows.CSV", expectedFormat: FormatCSV},
+		{fileName: "page.htm", contentType: "application/octet-stream", expectedFormat: FormatHTML},
+		{fileName: "export", contentType: "application/json; charset=utf-8", expectedFormat: FormatJSON},
+		{fileName: "readme.txt", contentType: "text/markdown", expectedFormat: FormatMarkdown},
+		{fileName: "report.pdf", contentType: "application/pdf", expectedFormat: FormatPDF},
+		{fileName: "guide.docx", expectedFormat: FormatDOCX},
+		{fileName: "notes", contentType: "application/vnd.oasis.opendocument.text", expectedFormat: FormatODT},
+		{fileName: "slides.pptx", contentType: "application/octet-stream", expectedFormat: ""},
+	}
+	for _, test := range tests {
+		format, _, ok := registry.Lookup(test.fileName, test.contentType)
+		assert.Equal(t, format, test.expectedFormat, fmt.Sprintf("wrong format of %s", test.fileName))
+		assert.Equal(t, ok, test.expectedFormat != "", fmt.Sprintf("wrong lookup result of %s", test.fileName))
+	}
+
+	registry.Register(FormatText, &TextExtractor{}, []string{".log"}, nil)
+	format, _, _ := registry.Lookup("server.log", "")
+	assert.Equal(t, format, FormatText, "registered extension must be found")
+}
+
+var extractFixtureTests = []struct {
+	testName 			string
+	fileName			string
+	extractor			Extractor
+	options				Options
+	expectedDocuments	[]models.Document
+	expectedError		string
+}{
+	{
+		testName: "PDF with compressed, split and form contents",
+		fileName: "sample.pdf",
+		extractor: &PDFExtractor{},
+		expectedDocuments: []models.Document{{
+			Title: "Q3 report",
+			Text: "Quarterly report\nSales grew by 12%\nDon’t panic\n\nCafé\nFooter note",
+			Fields: map[string]any{FieldAuthor: "Jane Doe", FieldCreatedAt: "2024-01-15T08:30:00Z", FieldPageCount: 2},
+		}},
+	},
+	{
+		testName: "PDF metadata not defined in index schema is left out",
+		fileName: "sample.pdf",
+		extractor: &PDFExtractor{},
+		options: Options{Schema: &models.IndexSchema{Fields: map[string]models.SchemaField{FieldPageCount: {Type: models.FieldTypeInteger}}}},
+		expectedDocuments: []models.Document{{
+			Title: "Q3 report",
+			Text: "Quarterly report\nSales grew by 12%\nDon’t panic\n\nCafé\nFooter note",
+			Fields: map[string]any{FieldPageCount: 2},
+		}},
+	},
+	{
+		testName: "PDF over the page limit",
+		fileName: "sample.pdf",
+		extractor: &PDFExtractor{},
+		options: Options{MaxPages: 1},
+		expectedDocuments: []models.Document{},
+		expectedError: "file has too many pages, at most 1 pages can be extracted",
+	},
+	{
+		testName: "PDF over the size limit",
+		fileName: "sample.pdf",
+		extractor: &PDFExtractor{},
+		options: Options{MaxFileSize: 1024},
+		expectedDocuments: []models.Document{},
+		expectedError: "file is too large, at most 1024 bytes can be extracted",
+	},
+	{
+		testName: "DOCX",
+		fileName: "sample.docx",
+		extractor: &DOCXExtractor{},
+		expectedDocuments: []models.Document{{
+			Title: "Onboarding guide",
+			Text: "Welcome\nRead the handbook first.\nLine one\nLine two",
+			Fields: map[string]any{FieldAuthor: "Jane Doe", FieldCreatedAt: "2024-02-01T09:00:00Z", FieldPageCount: 3},
+		}},
+	},
+	{
+		testName: "DOCX over the page limit",
+		fileName: "sample.docx",
+		extractor: &DOCXExtractor{},
+		options: Options{MaxPages: 2},
+		expectedDocuments: []models.Document{},
+		expectedError: "file has too many pages, at most 2 pages can be extracted",
+	},
+	{
+		testName: "ODT",
+		fileName: "sample.odt",
+		extractor: &ODTExtractor{},
+		expectedDocuments: []models.Document{{
+			Title: "Release notes",
+			Text: "Release notes\nVersion 2.0 is out.\nFirst\nSecond",
+			Fields: map[string]any{FieldAuthor: "Jane Doe", FieldCreatedAt: "2024-03-05T14:30:00Z", FieldPageCount: 2},
+		}},
+	},
+	{
+		testName: "ODT read as DOCX",
+		fileName: "sample.odt",
+		extractor: &DOCXExtractor{},
+		expectedDocuments: []models.Document{},
+		expectedError: "file isn't valid office document, word/document.xml is missing",
+	},
+	{
+		testName: "DOCX that isn't zip archive",
+		fileName: "sample.pdf",
+		extractor: &DOCXExtractor{},
+		expectedDocuments: []models.Document{},
+		expectedError: "file isn't valid office document",
+	},
+}
+
+func TestExtractFixtures(t *testing.T) {
+	for i, test := range extractFixtureTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		reader, err := os.Open("testdata/" + test.fileName)
+		if err != nil {
+			t.Fatalf("Unable to open fixture, error: %s\n", err)
+		}
+		result, err := extractFile(test.extractor, &File{Name: test.fileName, Reader: reader, Options: test.options})
+		reader.Close()
+
+		errorMessage := ""
+		if err != nil {
+			errorMessage = err.Error()
+		}
+		assert.Equal(t, errorMessage, test.expectedError, "wrong extraction error")
+		assert.Equal(t, result.documents, test.expectedDocuments, "wrong extracted documents")
+	}
+}
+
+// testZip returns zip archive with given entries.
+func testZip(entries map[string]string) string {
+	var buffer bytes.Buffer
+	writer := zip.NewWriter(&buffer)
+	for name, content := range entries {
+		entry, _ := writer.Create(name)
+		entry.Write([]byte(content))
+	}
+	writer.Close()
+	return buffer.String()
+}
+
+func TestExtractODTSpacesLimit(t *testing.T) {
+	content := `<office:document-content xmlns:office="o" xmlns:text="t"><office:body><office:text><text:p>a` +
+		strings.Repeat(`<text:s text:c="200000000"/>`, 1000) + `b</text:p></office:text></office:body></office:document-content>`
+
+	_, err := extractFile(&ODTExtractor{}, &File{Name: "spaces.odt", Reader: strings.NewReader(testZip(map[string]string{"content.xml": content}))})
+	if err == nil {
+		t.Fatalf("Text expanding beyond limit must not be extracted\n")
+	}
+	assert.Equal(t, errors.Is(err, ErrFileTooLarge), true, "wrong extraction error")
+}
+
+// testPDF returns PDF with given objects and trailer, objects aren't listed in
+// cross-reference table as extractor doesn't use it.
+func testPDF(trailer string, objects map[int]string) string {
+	var pdf strings.Builder
+	pdf.WriteString("%PDF-1.5\n")
+	for number := 1; number <= len(objects)+10; number++ {
+		if object, ok := objects[number]; ok {
+			fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", number, object)
+		}
+	}
+	if trailer != "" {
+		fmt.Fprintf(&pdf, "trailer\n%s\n", trailer)
+	}
+	pdf.WriteString("%%EOF\n")
+	return pdf.String()
+}
+
+func testPDFStream(dict string, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func compressed(s string) string {
+	var buffer bytes.Buffer
+	writer := zlib.NewWriter(&buffer)
+	writer.Write([]byte(s))
+	writer.Close()
+	return buffer.String()
+}
+
+func TestExtractPDF(t *testing.T) {
+	pages := "<< /Type /Pages /Kids [3 0 R] /Count 1 >>"
+	page := "<< /Type /Page /Parent 2 0 R /Contents 5 0 R /Resources << /Font << /F1 6 0 R >> >> >>"
+	header := fmt.Sprintf("2 0 3 %d ", len(pages)+1)
+	content := "BT /F1 12 Tf 1 0 0 1 72 700 Tm (First) Tj 1 0 0 1 150 700 Tm (line) Tj 1 0 0 1 72 680 Tm <5365636f6e64> Tj ET"
+
+	tests := []struct {
+		testName			string
+		pdf					string
+		expectedDocuments	[]models.Document
+		expectedError		string
+	}{
+		{
+			testName: "Objects compressed into object stream",
+			pdf: testPDF("", map[int]string{
+				1: "<< /Type /Catalog /Pages 2 0 R >>",
+				4: testPDFStream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), compressed(header + pages + " " + page)),
+				5: testPDFStream("", content),
+				6: "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+				7: testPDFStream("/Type /XRef /Root 1 0 R /Size 8", ""),
+			}),
+			expectedDocuments: []models.Document{{Title: "test", Text: "First line\nSecond", Fields: map[string]any{FieldPageCount: 1}}},
+		},
+		{
+			testName: "Pages without page tree, inline image and custom encoding",
+			pdf: testPDF("", map[int]string{
+				1: "<< /Type /Page /Contents 2 0 R /Resources << /Font << /F1 3 0 R >> >> >>",
+				2: testPDFStream("", "BI /W 2 /H 1 /BPC 8 /CS /G ID \x00EI\xff EI BT /F1 12 Tf (caf\\351 \\001) Tj ET"),
+				3: "<< /Type /Font /Subtype /Type1 /Encoding << /Differences [1 /fi] >> >>",
+			}),
+			expectedDocuments: []models.Document{{Title: "test", Text: "café ﬁ", Fields: map[string]any{FieldPageCount: 1}}},
+		},
+		{
+			testName: "Encrypted PDF",
+			pdf: testPDF("<< /Root 1 0 R /Encrypt 2 0 R >>", map[int]string{
+				1: "<< /Type /Catalog /Pages 3 0 R >>",
+				2: "<< /Filter /Standard /V 2 >>",
+			}),
+			expectedDocuments: []models.Document{},
+			expectedError: "encrypted pdf files aren't supported",
+		},
+		{
+			testName: "Content expanding beyond decompression limit",
+			pdf: testPDF("", map[int]string{
+				1: "<< /Type /Page /Contents 2 0 R >>",
+				2: testPDFStream("/Filter /FlateDecode", compressed(strings.Repeat(" ", 1 << 20))),
+			}),
+			expectedDocuments: []models.Document{},
+			expectedError: "file is too large, pdf content expands more than 100 times",
+		},
+		{
+			testName: "Dictionary ended by single bracket at the end of file",
+			pdf: "%PDF-000000000 0 0 obj << /00000 A0000000/00000 0 0 R >",
+			expectedDocuments: []models.Document{},
+			expectedError: "file isn't valid pdf",
+		},
+		{
+			testName: "Hex string without end at the end of file",
+			pdf: "%PDF-1.5\n1 0 obj << /Type /Page /Contents <41",
+			expectedDocuments: []models.Document{},
+			expectedError: "file isn't valid pdf",
+		},
+		{
+			testName: "Unterminated strings read again by every object",
+			pdf: "%PDF-1.5\n" + strings.Repeat("1 0 obj [(", 10000),
+			expectedDocuments: []models.Document{},
+			expectedError: "pdf file is too complex to extract",
+		},
+		{
+			testName: "File that isn't PDF",
+			pdf: "1 0 obj << >> endobj",
+			expectedDocuments: []models.Document{},
+			expectedError: "file isn't valid pdf",
+		},
+	}
+
+	for i, test := range tests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		result, err := extractFile(&PDFExtractor{}, &File{Name: "test.pdf", Reader: strings.NewReader(test.pdf)})
+		errorMessage := ""
+		if err != nil {
+			errorMessage = err.Error()
+		}
+		assert.Equal(t, errorMessage, test.expectedError, "wrong extraction error")
+		assert.Equal(t, result.documents, test.expectedDocuments, "wrong extracted documents")
+	}
+}
+
+// FuzzPDFExtract parses files the way PDFExtractor does.
+func FuzzPDFExtract(f *testing.F) {
+	f.Add([]byte(testPDF("", map[int]string{
+		1: "<< /Type /Page /Contents 2 0 R /Resources << /Font << /F1 3 0 R >> >> >>",
+		2: testPDFStream("", "BI /W 2 /H 1 ID \x00EI EI BT /F1 12 Tf (text) Tj <5365> Tj ET"),
+		3: "<< /Type /Font /Subtype /Type1 /Encoding << /Differences [1 /fi] >> >>",
+	})))
+	f.Add([]byte("%PDF-000000000 0 0 obj << /00000 A0000000/00000 0 0 R >"))
+	if data, err := os.ReadFile("testdata/sample.pdf"); err == nil {
+		f.Add(data)
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		document, err := parsePDF(data)
+		if err != nil {
+			return
+		}
+		var text strings.Builder
+		for _, page := range document.pages() {
+			document.pageText(page, &text)
+		}
+		document.info()
+	})
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := []struct {
+		value		string
+		expected	time.Time
+	}{
+		{value: "D:20240115103000Z", expected: time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{value: "D:20240115103000-05'30'", expected: time.Date(2024, 1, 15, 16, 0, 0, 0, time.UTC)},
+		{value: "D:202401", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "2024", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "yesterday", expected: time.Time{}},
+	}
+	for _, test := range tests {
+		assert.Equal(t, parsePDFDate(test.value), test.expected, fmt.Sprintf("wrong date parsed from %s", test.value))
+	}
+}
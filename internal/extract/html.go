@@ -0,0 +1,205 @@
+package extract
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Elements which content isn't text of the page
+var htmlSkippedElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true,
+}
+
+// Elements starting new line of text
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "br": true, "dd": true,
+	"div": true, "dl": true, "dt": true, "figcaption": true, "figure": true, "footer": true,
+	"form": true, "h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"header": true, "hr": true, "li": true, "main": true, "nav": true, "ol": true, "p": true,
+	"pre": true, "section": true, "table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
+
+// HTMLExtractor converts HTML file to one document with tags removed, title is taken from
+// <title> or the first <h1>.
+type HTMLExtractor struct{}
+
+func (e *HTMLExtractor) Extract(file *File, emit EmitFunc) error {
+	data, err := readAll(file)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(data) {
+		return errNotUTF8
+	}
+
+	title, text := stripHTML(string(data))
+	if title == "" {
+		title = baseName(file.Name)
+	}
+
+	return emit(models.Document{Title: title, Text: text}, nil)
+}
+
+// stripHTML returns title and text of the page, it tolerates malformed markup as browsers do.
+func stripHTML(page string) (string, string) {
+	var text, title, heading strings.Builder
+	inTitle, inHeading, headingDone := false, false, false
+
+	for i := 0; i < len(page); {
+		if page[i] != '<' || !isTagStart(page, i) {
+			end := strings.IndexByte(page[i+1:], '<')
+			if end == -1 {
+				end = len(page)
+			} else {
+				end += i + 1
+			}
+
+			content := collapseSpaces(html.UnescapeString(page[i:end]))
+			switch {
+			case inTitle:
+				title.WriteString(content)
+			default:
+				text.WriteString(content)
+				if inHeading {
+					heading.WriteString(content)
+				}
+			}
+			i = end
+			continue
+		}
+
+		if strings.HasPrefix(page[i:], "<!--") {
+			i = skipPast(page, i+4, "-->")
+			continue
+		}
+		if page[i+1] == '!' || page[i+1] == '?' {
+			i = skipPast(page, i+2, ">")
+			continue
+		}
+
+		name, closing, end := parseTag(page, i)
+		i = end
+
+		if !closing && htmlSkippedElements[name] {
+			i = skipClosingTag(page, i, name)
+			continue
+		}
+
+		switch name {
+		case "title":
+			inTitle = !closing
+		case "h1":
+			if !headingDone {
+				inHeading = !closing
+				headingDone = closing
+			}
+		}
+		if htmlBlockElements[name] {
+			text.WriteString("\n")
+		}
+	}
+
+	pageTitle := strings.TrimSpace(title.String())
+	if pageTitle == "" {
+		pageTitle = strings.TrimSpace(heading.String())
+	}
+	return pageTitle, normalizeText(removeEmptyLines(text.String()))
+}
+
+// removeEmptyLines drops lines left by block elements without text, markup doesn't
+// tell paragraphs apart from other blocks reliably.
+func removeEmptyLines(text string) string {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isTagStart tells whether < at position i starts tag, comment or declaration.
+func isTagStart(page string, i int) bool {
+	if i+1 >= len(page) {
+		return false
+	}
+	next := page[i+1]
+	return next == '/' || next == '!' || next == '?' || (next < utf8.RuneSelf && unicode.IsLetter(rune(next)))
+}
+
+// parseTag returns lowercase name of tag starting at position i and position after it,
+// quoted attribute values may contain >.
+func parseTag(page string, i int) (string, bool, int) {
+	i++
+	closing := i < len(page) && page[i] == '/'
+	if closing {
+		i++
+	}
+
+	start := i
+	for i < len(page) && page[i] != '>' && page[i] != '/' && !unicode.IsSpace(rune(page[i])) {
+		i++
+	}
+	name := strings.ToLower(page[start:i])
+
+	quote := byte(0)
+	for ; i < len(page); i++ {
+		switch {
+		case quote != 0:
+			if page[i] == quote {
+				quote = 0
+			}
+		case page[i] == '"' || page[i] == '\'':
+			quote = page[i]
+		case page[i] == '>':
+			return name, closing, i + 1
+		}
+	}
+	return name, closing, len(page)
+}
+
+// skipClosingTag returns position after closing tag of element, content of skipped elements
+// like script isn't parsed as markup.
+func skipClosingTag(page string, i int, name string) int {
+	closingTag := "</" + name
+	end := strings.Index(strings.ToLower(page[i:]), closingTag)
+	if end == -1 {
+		return len(page)
+	}
+	return skipPast(page, i+end+len(closingTag), ">")
+}
+
+func skipPast(page string, i int, marker string) int {
+	end := strings.Index(page[i:], marker)
+	if end == -1 {
+		return len(page)
+	}
+	return i + end + len(marker)
+}
+
+// collapseSpaces replaces runs of whitespace with single space, line breaks in markup aren't
+// line breaks of the text. Leading and trailing space is kept as it separates words of
+// neighbouring elements.
+func collapseSpaces(content string) string {
+	var builder strings.Builder
+	space := false
+	for _, r := range content {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space {
+			builder.WriteByte(' ')
+		}
+		space = false
+		builder.WriteRune(r)
+	}
+	if space {
+		builder.WriteByte(' ')
+	}
+	return builder.String()
+}
@@ -0,0 +1,59 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const unknownFieldErrorPrefix = "json: unknown field "
+
+var errNotJSONArray = errors.New("json file must contain array of documents")
+
+// JSONExtractor converts elements of JSON array to documents, the array is decoded
+// element by element.
+type JSONExtractor struct{}
+
+func (e *JSONExtractor) Extract(file *File, emit EmitFunc) error {
+	decoder := json.NewDecoder(file.Reader)
+	token, err := decoder.Token()
+	if err != nil {
+		return errNotJSONArray
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return errNotJSONArray
+	}
+
+	for decoder.More() {
+		var element json.RawMessage
+		if err := decoder.Decode(&element); err != nil {
+			return err
+		}
+
+		document, err := decodeDocument(element)
+		if err = emit(document, err); err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+func decodeDocument(element json.RawMessage) (models.Document, error) {
+	var document models.Document
+	decoder := json.NewDecoder(bytes.NewReader(element))
+	decoder.DisallowUnknownFields()
+	// numbers of fields are kept exact, float64 would round integers above 2^53
+	decoder.UseNumber()
+	if err := decoder.Decode(&document); err != nil {
+		if strings.HasPrefix(err.Error(), unknownFieldErrorPrefix) {
+			return models.Document{}, errors.New(strings.TrimPrefix(err.Error(), "json: "))
+		}
+		return models.Document{}, errors.New("element must be document object")
+	}
+	return document, nil
+}
@@ -0,0 +1,106 @@
+package extract
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var (
+	markdownImage		= regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	markdownLink		= regexp.MustCompile(`\[([^\]]*)\]\([^)]*\)`)
+	markdownCode		= regexp.MustCompile("`([^`]*)`")
+	markdownStrong		= regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	markdownEmphasis	= regexp.MustCompile(`\*(\S(?:.*?\S)?)\*|\b_(\S(?:.*?\S)?)_\b`)
+	markdownTag			= regexp.MustCompile(`</?[a-zA-Z][^>]*>`)
+	markdownHeading		= regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*\s*$`)
+	markdownListItem	= regexp.MustCompile(`^\s*(?:[-*+]|\d+[.)])\s+`)
+	markdownRule		= regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+)
+
+// MarkdownExtractor converts markdown file to one document with formatting removed, the
+// first top level heading becomes the title.
+type MarkdownExtractor struct{}
+
+func (e *MarkdownExtractor) Extract(file *File, emit EmitFunc) error {
+	data, err := readAll(file)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(data) {
+		return errNotUTF8
+	}
+
+	title, text := stripMarkdown(string(data))
+	if title == "" {
+		title = baseName(file.Name)
+	}
+
+	return emit(models.Document{Title: title, Text: text}, nil)
+}
+
+func stripMarkdown(markdown string) (string, string) {
+	lines := strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n")
+	lines = skipFrontMatter(lines)
+
+	title := ""
+	text := make([]string, 0, len(lines))
+	inCode := false
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inCode = !inCode
+			continue
+		}
+		// code is kept as written
+		if inCode {
+			text = append(text, line)
+			continue
+		}
+
+		if markdownRule.MatchString(line) {
+			text = append(text, "")
+			continue
+		}
+
+		if heading := markdownHeading.FindStringSubmatch(trimmed); heading != nil {
+			content := stripInlineMarkdown(heading[2])
+			if title == "" && len(heading[1]) == 1 {
+				title = content
+				continue
+			}
+			text = append(text, content)
+			continue
+		}
+
+		line = strings.TrimLeft(trimmed, "> ")
+		line = markdownListItem.ReplaceAllString(line, "")
+		text = append(text, stripInlineMarkdown(line))
+	}
+
+	return title, normalizeText(strings.Join(text, "\n"))
+}
+
+// skipFrontMatter drops YAML metadata block at the beginning of file.
+func skipFrontMatter(lines []string) []string {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return lines
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == "---" {
+			return lines[i+1:]
+		}
+	}
+	return lines
+}
+
+func stripInlineMarkdown(line string) string {
+	line = markdownImage.ReplaceAllString(line, "$1")
+	line = markdownLink.ReplaceAllString(line, "$1")
+	line = markdownCode.ReplaceAllString(line, "$1")
+	line = markdownStrong.ReplaceAllString(line, "$1$2")
+	line = markdownEmphasis.ReplaceAllString(line, "$1$2")
+	return markdownTag.ReplaceAllString(line, "")
+}
@@ -0,0 +1,297 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var errNotOfficeFile = errors.New("file isn't valid office document")
+
+// Spaces element of ODT repeats space by its count, real documents never need more
+const maxODTSpaces = 1024
+
+// DOCXExtractor converts Word document to one document, paragraphs become lines of the
+// text and core properties become title and metadata fields.
+type DOCXExtractor struct{}
+
+func (e *DOCXExtractor) Extract(file *File, emit EmitFunc) error {
+	archive, err := openOffice(file)
+	if err != nil {
+		return err
+	}
+
+	info := &metadata{}
+	if err := archive.parse("docProps/app.xml", false, nil, func(element []string, text string) {
+		if elementPath(element) == "Properties/Pages" {
+			info.pages, _ = strconv.Atoi(strings.TrimSpace(text))
+		}
+	}, nil); err != nil {
+		return err
+	}
+	if err := checkPages(info.pages, file.Options); err != nil {
+		return err
+	}
+
+	if err := archive.parse("docProps/core.xml", false, nil, func(element []string, text string) {
+		switch elementPath(element) {
+		case "coreProperties/title":
+			info.title += text
+		case "coreProperties/creator":
+			info.author += text
+		case "coreProperties/created":
+			info.created = parseOfficeDate(text)
+		}
+	}, nil); err != nil {
+		return err
+	}
+
+	body := archive.newText()
+	err = archive.parse("word/document.xml", true, func(start xml.StartElement) {
+		switch start.Name.Local {
+		case "tab":
+			body.WriteString(" ")
+		case "br", "cr":
+			body.WriteString("\n")
+		}
+	}, func(element []string, text string) {
+		if element[len(element)-1] == "t" {
+			body.WriteString(text)
+		}
+	}, func(end xml.EndElement) {
+		if end.Name.Local == "p" {
+			body.WriteString("\n")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if body.exceeded {
+		return fmt.Errorf("%w, text expands beyond %d bytes", ErrFileTooLarge, body.limit)
+	}
+
+	return emit(info.document(file.Name, body.String(), file.Options.Schema), nil)
+}
+
+// ODTExtractor converts OpenDocument text to one document, paragraphs and headings
+// become lines of the text and meta.xml becomes title and metadata fields.
+type ODTExtractor struct{}
+
+func (e *ODTExtractor) Extract(file *File, emit EmitFunc) error {
+	archive, err := openOffice(file)
+	if err != nil {
+		return err
+	}
+
+	info := &metadata{}
+	creator := ""
+	if err := archive.parse("meta.xml", false, func(start xml.StartElement) {
+		if start.Name.Local != "document-statistic" {
+			return
+		}
+		for _, attr := range start.Attr {
+			if attr.Name.Local == "page-count" {
+				info.pages, _ = strconv.Atoi(attr.Value)
+			}
+		}
+	}, func(element []string, text string) {
+		switch elementPath(element) {
+		case "document-meta/meta/title":
+			info.title += text
+		case "document-meta/meta/initial-creator":
+			info.author += text
+		case "document-meta/meta/creator":
+			creator += text
+		case "document-meta/meta/creation-date":
+			info.created = parseOfficeDate(text)
+		}
+	}, nil); err != nil {
+		return err
+	}
+	// initial creator is the author, creator is who saved the file last
+	if strings.TrimSpace(info.author) == "" {
+		info.author = creator
+	}
+	if err := checkPages(info.pages, file.Options); err != nil {
+		return err
+	}
+
+	// annotations are comments of reviewers, not text of the document
+	body := archive.newText()
+	annotations := 0
+	err = archive.parse("content.xml", true, func(start xml.StartElement) {
+		if annotations > 0 {
+			if start.Name.Local == "annotation" {
+				annotations++
+			}
+			return
+		}
+		switch start.Name.Local {
+		case "annotation":
+			annotations++
+		case "s":
+			count := 1
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "c" {
+					if c, err := strconv.Atoi(attr.Value); err == nil && c > 0 {
+						count = min(c, maxODTSpaces)
+					}
+				}
+			}
+			body.WriteString(strings.Repeat(" ", count))
+		case "tab":
+			body.WriteString(" ")
+		case "line-break":
+			body.WriteString("\n")
+		}
+	}, func(element []string, text string) {
+		if annotations == 0 && contains(element, "body") {
+			body.WriteString(text)
+		}
+	}, func(end xml.EndElement) {
+		switch {
+		case end.Name.Local == "annotation":
+			annotations--
+		case annotations == 0 && (end.Name.Local == "p" || end.Name.Local == "h"):
+			body.WriteString("\n")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if body.exceeded {
+		return fmt.Errorf("%w, text expands beyond %d bytes", ErrFileTooLarge, body.limit)
+	}
+
+	return emit(info.document(file.Name, body.String(), file.Options.Schema), nil)
+}
+
+type officeArchive struct {
+	reader			*zip.Reader
+	maxEntrySize	int64
+}
+
+func openOffice(file *File) (*officeArchive, error) {
+	data, err := readBinary(file)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errNotOfficeFile
+	}
+	return &officeArchive{reader: reader, maxEntrySize: int64(len(data)) * maxCompressionRatio}, nil
+}
+
+// officeText collects text of document up to limit, elements like repeated spaces give
+// text larger than XML they are written in.
+type officeText struct {
+	strings.Builder
+	limit		int
+	exceeded	bool
+}
+
+// newText returns text limited like entries of the archive.
+func (a *officeArchive) newText() *officeText {
+	return &officeText{limit: int(a.maxEntrySize)}
+}
+
+func (t *officeText) WriteString(s string) {
+	if t.exceeded || t.Len()+len(s) > t.limit {
+		t.exceeded = true
+		return
+	}
+	t.Builder.WriteString(s)
+}
+
+// parse streams XML entry calling onText with path of local element names to character
+// data, onStart and onEnd are called for each element, nil callbacks are skipped.
+// Missing entry is an error only if it is required.
+func (a *officeArchive) parse(name string, required bool, onStart func(start xml.StartElement), onText func(element []string, text string), onEnd func(end xml.EndElement)) error {
+	var entry *zip.File
+	for _, file := range a.reader.File {
+		if file.Name == name {
+			entry = file
+			break
+		}
+	}
+	if entry == nil {
+		if required {
+			return fmt.Errorf("%w, %s is missing", errNotOfficeFile, name)
+		}
+		return nil
+	}
+
+	reader, err := entry.Open()
+	if err != nil {
+		return errNotOfficeFile
+	}
+	defer reader.Close()
+
+	limited := &io.LimitedReader{R: reader, N: a.maxEntrySize + 1}
+	decoder := xml.NewDecoder(limited)
+	element := []string{}
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if limited.N <= 0 {
+			return fmt.Errorf("%w, %s expands beyond %d bytes", ErrFileTooLarge, name, a.maxEntrySize)
+		}
+		if err != nil {
+			return fmt.Errorf("%w, %s is malformed", errNotOfficeFile, name)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			element = append(element, token.Name.Local)
+			if onStart != nil {
+				onStart(token)
+			}
+		case xml.EndElement:
+			if len(element) > 0 {
+				element = element[:len(element)-1]
+			}
+			if onEnd != nil {
+				onEnd(token)
+			}
+		case xml.CharData:
+			if onText != nil && len(element) > 0 {
+				onText(element, string(token))
+			}
+		}
+	}
+}
+
+// elementPath joins local names of element and its parents, it matches elements regardless
+// of namespace prefixes files use.
+func elementPath(element []string) string {
+	return strings.Join(element, "/")
+}
+
+func parseOfficeDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date
+		}
+	}
+	return time.Time{}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
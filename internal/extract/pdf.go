@@ -0,0 +1,832 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Nesting limits guard against reference cycles and deeply nested objects
+const (
+	maxPDFDepth		= 32
+	maxPDFFormDepth	= 8
+)
+
+// Bytes lexed per byte of file, it covers decompressed streams and content reused by
+// pages, while broken files making lexer read the same data again and again exceed it
+const pdfWorkPerByte = 2 * maxCompressionRatio
+
+var (
+	errNotPDF		= errors.New("file isn't valid pdf")
+	errEncryptedPDF	= errors.New("encrypted pdf files aren't supported")
+	errComplexPDF	= errors.New("pdf file is too complex to extract")
+)
+
+var pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// PDFExtractor converts PDF file to one document with text of all pages, document
+// information dictionary becomes title and metadata fields. Text is taken from content
+// streams, so scanned pages without text layer give no text.
+type PDFExtractor struct{}
+
+func (e *PDFExtractor) Extract(file *File, emit EmitFunc) error {
+	data, err := readBinary(file)
+	if err != nil {
+		return err
+	}
+
+	// header may follow some garbage, readers look for it in the first kilobyte
+	header := data
+	if len(header) > 1024 {
+		header = header[:1024]
+	}
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return errNotPDF
+	}
+
+	document, err := parsePDF(data)
+	if err != nil {
+		return err
+	}
+	if document.trailer["Encrypt"] != nil {
+		return errEncryptedPDF
+	}
+
+	pages := document.pages()
+	if len(pages) == 0 {
+		return errNotPDF
+	}
+	if err := checkPages(len(pages), file.Options); err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	for _, page := range pages {
+		if err := document.pageText(page, &text); err != nil {
+			return err
+		}
+		text.WriteString("\n\n")
+	}
+
+	info := document.info()
+	info.pages = len(pages)
+	return emit(info.document(file.Name, text.String(), file.Options.Schema), nil)
+}
+
+type pdfName string
+
+// pdfKeyword is bare word of PDF syntax, operators of content streams are keywords
+type pdfKeyword string
+
+type pdfRef struct {
+	number		int
+	generation	int
+}
+
+type pdfDict map[pdfName]any
+
+type pdfStream struct {
+	dict	pdfDict
+	data	[]byte
+}
+
+type pdfPage struct {
+	dict		pdfDict
+	// resources are inherited from parent nodes of page tree
+	resources	pdfDict
+}
+
+// pdfDocument holds objects of PDF file. Objects are found by scanning the file instead of
+// reading cross-reference table, so files with broken offsets are still read.
+type pdfDocument struct {
+	objects	map[int]any
+	trailer	pdfDict
+	fonts	map[pdfRef]*pdfFont
+	// budget is number of bytes streams can still be decompressed to
+	budget	int64
+	// work is number of bytes lexers can still read
+	work	int64
+}
+
+func parsePDF(data []byte) (*pdfDocument, error) {
+	document := &pdfDocument{
+		objects: map[int]any{},
+		fonts: map[pdfRef]*pdfFont{},
+		budget: int64(len(data)) * maxCompressionRatio,
+		work: int64(len(data)) * pdfWorkPerByte,
+	}
+
+	end := 0
+	objectStreams := []*pdfStream{}
+	for _, match := range pdfObjectHeader.FindAllSubmatchIndex(data, -1) {
+		// matches inside streams of objects read before are skipped
+		if match[0] < end || (match[0] > 0 && !isPDFSpace(data[match[0]-1]) && !isPDFDelimiter(data[match[0]-1])) {
+			continue
+		}
+		number, err := strconv.Atoi(string(data[match[2]:match[3]]))
+		if err != nil {
+			continue
+		}
+
+		lexer := &pdfLexer{data: data, pos: match[1], work: &document.work}
+		object, err := lexer.object(0)
+		if errors.Is(err, errComplexPDF) {
+			return nil, err
+		}
+		if err != nil {
+			continue
+		}
+		if dict, ok := object.(pdfDict); ok {
+			if stream, ok := lexer.stream(dict); ok {
+				object = stream
+				switch dict["Type"] {
+				case pdfName("ObjStm"):
+					objectStreams = append(objectStreams, stream)
+				case pdfName("XRef"):
+					// cross-reference stream replaces trailer since PDF 1.5
+					document.trailer = dict
+				}
+			}
+		}
+
+		// later objects with the same number are updates of earlier ones
+		document.objects[number] = object
+		end = lexer.pos
+	}
+	if len(document.objects) == 0 {
+		return nil, errNotPDF
+	}
+
+	// trailer of the last incremental update has all entries of earlier ones
+	if index := bytes.LastIndex(data, []byte("trailer")); index >= 0 {
+		lexer := &pdfLexer{data: data, pos: index + len("trailer"), work: &document.work}
+		if trailer, ok := lexer.objectOrNil().(pdfDict); ok && trailer["Root"] != nil {
+			document.trailer = trailer
+		}
+	}
+	if document.trailer == nil {
+		document.trailer = pdfDict{}
+	}
+
+	for _, stream := range objectStreams {
+		if err := document.loadObjectStream(stream); err != nil {
+			return nil, err
+		}
+	}
+
+	return document, nil
+}
+
+// loadObjectStream adds objects compressed into object stream, objects defined directly
+// in the file take precedence.
+func (d *pdfDocument) loadObjectStream(stream *pdfStream) error {
+	data, err := d.decode(stream)
+	if errors.Is(err, ErrFileTooLarge) {
+		return err
+	}
+	if err != nil {
+		return nil
+	}
+
+	count, _ := d.resolve(stream.dict["N"]).(float64)
+	first, _ := d.resolve(stream.dict["First"]).(float64)
+	if first < 0 || int(first) > len(data) {
+		return nil
+	}
+
+	header := &pdfLexer{data: data[:int(first)], work: &d.work}
+	for i := 0; i < int(count); i++ {
+		number, ok := header.integer()
+		if !ok {
+			return nil
+		}
+		offset, ok := header.integer()
+		if !ok {
+			return nil
+		}
+		// offset is compared with space left, so huge offsets can't overflow
+		if _, exists := d.objects[number]; exists || offset >= len(data)-int(first) {
+			continue
+		}
+
+		lexer := &pdfLexer{data: data, pos: int(first) + offset, work: &d.work}
+		object, err := lexer.object(0)
+		if errors.Is(err, errComplexPDF) {
+			return err
+		}
+		if err == nil {
+			d.objects[number] = object
+		}
+	}
+	return nil
+}
+
+func (d *pdfDocument) resolve(value any) any {
+	for i := 0; i < maxPDFDepth; i++ {
+		ref, ok := value.(pdfRef)
+		if !ok {
+			return value
+		}
+		value = d.objects[ref.number]
+	}
+	return nil
+}
+
+// dict resolves value to dictionary, dictionary of stream is returned for streams.
+func (d *pdfDocument) dict(value any) pdfDict {
+	switch value := d.resolve(value).(type) {
+	case pdfDict:
+		return value
+	case *pdfStream:
+		return value.dict
+	}
+	return nil
+}
+
+// decode applies filters of stream. Data of truncated streams decoded before the error is
+// returned, ErrFileTooLarge is returned once streams of the file exceed decompression budget.
+func (d *pdfDocument) decode(stream *pdfStream) ([]byte, error) {
+	var filters []any
+	switch filter := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []any{filter}
+	case []any:
+		filters = filter
+	}
+
+	data := stream.data
+	for _, filter := range filters {
+		var reader io.Reader
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			zlibReader, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			reader = zlibReader
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			reader = bytes.NewReader((&pdfLexer{data: append([]byte{'<'}, data...)}).hexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			encoded := bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+			if index := bytes.Index(encoded, []byte("~>")); index >= 0 {
+				encoded = encoded[:index]
+			}
+			reader = ascii85.NewDecoder(bytes.NewReader(encoded))
+		default:
+			return nil, fmt.Errorf("unsupported pdf filter %v", filter)
+		}
+
+		limited := &io.LimitedReader{R: reader, N: d.budget + 1}
+		decoded, err := io.ReadAll(limited)
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("%w, pdf content expands more than %d times", ErrFileTooLarge, maxCompressionRatio)
+		}
+		d.budget -= int64(len(decoded))
+		if err != nil && len(decoded) == 0 {
+			return nil, err
+		}
+		data = decoded
+	}
+
+	return data, nil
+}
+
+// pages returns pages in document order, pages of files without page tree are taken in
+// order of their object numbers.
+func (d *pdfDocument) pages() []pdfPage {
+	pages := []pdfPage{}
+	visited := map[pdfRef]bool{}
+
+	var walk func(node any, resources pdfDict, depth int)
+	walk = func(node any, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > maxPDFDepth {
+			return
+		}
+		if own := d.dict(dict["Resources"]); own != nil {
+			resources = own
+		}
+
+		if kids, ok := d.resolve(dict["Kids"]).([]any); ok && d.resolve(dict["Type"]) != pdfName("Page") {
+			for _, kid := range kids {
+				walk(kid, resources, depth+1)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+
+	if root := d.dict(d.trailer["Root"]); root != nil {
+		walk(root["Pages"], nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	numbers := make([]int, 0, len(d.objects))
+	for number := range d.objects {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	for _, number := range numbers {
+		if dict := d.dict(d.objects[number]); dict != nil && d.resolve(dict["Type"]) == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: dict, resources: d.dict(dict["Resources"])})
+		}
+	}
+	return pages
+}
+
+// pageText writes text of page contents, content of page may be split between several
+// streams at any token, so streams are joined before interpreting.
+func (d *pdfDocument) pageText(page pdfPage, text *strings.Builder) error {
+	var streams []any
+	switch contents := d.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = []any{contents}
+	case []any:
+		streams = contents
+	}
+
+	var content []byte
+	for _, value := range streams {
+		stream, ok := d.resolve(value).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.decode(stream)
+		if errors.Is(err, ErrFileTooLarge) {
+			return err
+		}
+		content = append(append(content, data...), '\n')
+	}
+
+	return (&pdfContent{document: d, text: text}).run(content, page.resources, 0)
+}
+
+func (d *pdfDocument) info() *metadata {
+	info := &metadata{}
+	dict := d.dict(d.trailer["Info"])
+	if dict == nil {
+		return info
+	}
+
+	info.title = pdfTextString(d.resolve(dict["Title"]))
+	info.author = pdfTextString(d.resolve(dict["Author"]))
+	info.created = parsePDFDate(pdfTextString(d.resolve(dict["CreationDate"])))
+	return info
+}
+
+// pdfTextString decodes string of document information, it is UTF-16 with byte order
+// mark, UTF-8 with byte order mark since PDF 2.0, or single byte encoded.
+func pdfTextString(value any) string {
+	data, ok := value.([]byte)
+	if !ok {
+		return ""
+	}
+	if bytes.HasPrefix(data, []byte{0xfe, 0xff}) {
+		return decodeUTF16(data[2:])
+	}
+	if bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}) {
+		return string(data[3:])
+	}
+
+	runes := make([]rune, 0, len(data))
+	for _, b := range data {
+		if r := winAnsiEncoding[b]; r != 0 {
+			runes = append(runes, r)
+		}
+	}
+	return string(runes)
+}
+
+// parsePDFDate parses date like D:20240115103000+02'00', every part after year is optional.
+func parsePDFDate(value string) time.Time {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "D:")
+	digits := 0
+	for digits < len(value) && digits < 14 && value[digits] >= '0' && value[digits] <= '9' {
+		digits++
+	}
+	if digits < 4 || digits%2 == 1 {
+		return time.Time{}
+	}
+
+	// missing parts default to the start of the period
+	date, err := time.Parse("20060102150405", value[:digits] + "0101000000"[digits-4:])
+	if err != nil {
+		return time.Time{}
+	}
+
+	zone := value[digits:]
+	if len(zone) >= 3 && (zone[0] == '+' || zone[0] == '-') {
+		hours, err := strconv.Atoi(zone[1:3])
+		if err != nil {
+			return date
+		}
+		minutes := 0
+		if rest := strings.TrimLeft(zone[3:], "'"); len(rest) >= 2 {
+			minutes, _ = strconv.Atoi(rest[:2])
+		}
+		offset := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+		if zone[0] == '-' {
+			offset = -offset
+		}
+		date = date.Add(-offset)
+	}
+	return date
+}
+
+type pdfLexer struct {
+	data	[]byte
+	pos		int
+	// work is shared budget of lexers of the document, nil means data is read once
+	work	*int64
+}
+
+// spend charges bytes read to work budget.
+func (l *pdfLexer) spend(n int) {
+	if l.work != nil {
+		*l.work -= int64(n)
+	}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func (l *pdfLexer) skipSpace() {
+	start := l.pos
+	defer func() { l.spend(l.pos - start) }()
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// object reads next object, io.EOF is returned at the end of data. Position never goes
+// past the end of data, so it can be used to slice data after every read.
+func (l *pdfLexer) object(depth int) (any, error) {
+	if depth > maxPDFDepth {
+		return nil, errNotPDF
+	}
+	// every object costs at least a byte, so empty containers are charged too
+	l.spend(1)
+	if l.work != nil && *l.work < 0 {
+		return nil, errComplexPDF
+	}
+
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+
+	// nested objects of containers are charged by themselves
+	switch c := l.data[l.pos]; {
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		return l.dict(depth)
+	case c == '[':
+		return l.array(depth)
+	}
+
+	start := l.pos
+	object := l.simpleObject()
+	l.spend(l.pos - start)
+	return object, nil
+}
+
+// simpleObject reads object that isn't array or dictionary.
+func (l *pdfLexer) simpleObject() any {
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		return l.name()
+	case c == '(':
+		return l.literalString()
+	case c == '<':
+		return l.hexString()
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.number()
+	case isPDFDelimiter(c):
+		// stray delimiter, content streams of broken files are read as far as possible
+		l.pos++
+		return pdfKeyword(c)
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	switch keyword := string(l.data[start:l.pos]); keyword {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	default:
+		return pdfKeyword(keyword)
+	}
+}
+
+func (l *pdfLexer) objectOrNil() any {
+	object, err := l.object(0)
+	if err != nil {
+		return nil
+	}
+	return object
+}
+
+func (l *pdfLexer) name() pdfName {
+	l.pos++
+	name := []byte{}
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		if l.data[l.pos] == '#' && l.pos+2 < len(l.data) {
+			if decoded, err := hex.DecodeString(string(l.data[l.pos+1 : l.pos+3])); err == nil {
+				name = append(name, decoded[0])
+				l.pos += 3
+				continue
+			}
+		}
+		name = append(name, l.data[l.pos])
+		l.pos++
+	}
+	return pdfName(name)
+}
+
+func (l *pdfLexer) number() float64 {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.data) && (l.data[l.pos] >= '0' && l.data[l.pos] <= '9' || l.data[l.pos] == '.') {
+		l.pos++
+	}
+	token := string(l.data[start:l.pos])
+	// malformed numbers like lone minus are read as zero as readers do
+	number, _ := strconv.ParseFloat(token, 64)
+	return number
+}
+
+// integer reads non-negative integer, position isn't changed if there is none.
+func (l *pdfLexer) integer() (int, bool) {
+	start := l.pos
+	l.skipSpace()
+	digitsStart := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		l.pos++
+	}
+	integer, err := strconv.Atoi(string(l.data[digitsStart:l.pos]))
+	if err != nil || (l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos])) {
+		l.pos = start
+		return 0, false
+	}
+	return integer, true
+}
+
+// reference reads generation and R following object number, position isn't changed if
+// the number isn't part of reference.
+func (l *pdfLexer) reference(number float64) (pdfRef, bool) {
+	start := l.pos
+	if number < 0 || number != float64(int(number)) {
+		return pdfRef{}, false
+	}
+	generation, ok := l.integer()
+	if ok {
+		l.skipSpace()
+		if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isPDFSpace(l.data[l.pos+1]) || isPDFDelimiter(l.data[l.pos+1])) {
+			l.pos++
+			return pdfRef{number: int(number), generation: generation}, true
+		}
+	}
+	l.pos = start
+	return pdfRef{}, false
+}
+
+func (l *pdfLexer) array(depth int) ([]any, error) {
+	l.pos++
+	array := []any{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return nil, errNotPDF
+		}
+		if l.data[l.pos] == ']' {
+			l.pos++
+			return array, nil
+		}
+
+		value, err := l.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+	}
+}
+
+func (l *pdfLexer) dict(depth int) (pdfDict, error) {
+	l.pos += 2
+	dict := pdfDict{}
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) {
+			return nil, errNotPDF
+		}
+		if l.data[l.pos] == '>' {
+			// single > of broken dictionary ends it too
+			l.pos++
+			if l.pos < len(l.data) && l.data[l.pos] == '>' {
+				l.pos++
+			}
+			return dict, nil
+		}
+
+		key, err := l.object(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			return nil, errNotPDF
+		}
+		value, err := l.value(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		dict[name] = value
+	}
+}
+
+// value reads object inside array or dictionary, where numbers may start references.
+func (l *pdfLexer) value(depth int) (any, error) {
+	object, err := l.object(depth)
+	if number, ok := object.(float64); ok && err == nil {
+		if ref, ok := l.reference(number); ok {
+			return ref, nil
+		}
+	}
+	return object, err
+}
+
+func (l *pdfLexer) literalString() []byte {
+	l.pos++
+	value := []byte{}
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return value
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return value
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// escaped line break continues string on the next line
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					code := int(c - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						code = code*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(code)
+				}
+			}
+		}
+		value = append(value, c)
+	}
+	return value
+}
+
+func (l *pdfLexer) hexString() []byte {
+	l.pos++
+	digits := []byte{}
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; !isPDFSpace(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	if l.pos < len(l.data) {
+		l.pos++
+	}
+
+	// odd number of digits means the last one is followed by zero
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	value, _ := hex.DecodeString(string(digits))
+	return value
+}
+
+// stream reads data of stream following dictionary. Length is used only if endstream
+// follows it, as it is often indirect or wrong in files written by broken tools.
+func (l *pdfLexer) stream(dict pdfDict) (*pdfStream, bool) {
+	start := l.pos
+	l.skipSpace()
+	if !bytes.HasPrefix(l.data[l.pos:], []byte("stream")) {
+		l.pos = start
+		return nil, false
+	}
+	l.pos += len("stream")
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	dataStart := l.pos
+	defer func() { l.spend(l.pos - dataStart) }()
+
+	if length, ok := dict["Length"].(float64); ok && length >= 0 && length <= float64(len(l.data)-dataStart) {
+		dataEnd := dataStart + int(length)
+		if bytes.HasPrefix(bytes.TrimLeft(l.data[dataEnd:], "\x00\t\n\f\r "), []byte("endstream")) {
+			l.pos = dataEnd
+			return &pdfStream{dict: dict, data: l.data[dataStart:dataEnd]}, true
+		}
+	}
+
+	index := bytes.Index(l.data[dataStart:], []byte("endstream"))
+	if index == -1 {
+		l.pos = len(l.data)
+		return &pdfStream{dict: dict, data: l.data[dataStart:]}, true
+	}
+	l.pos = dataStart + index + len("endstream")
+	return &pdfStream{dict: dict, data: bytes.TrimRight(l.data[dataStart:dataStart+index], "\r\n")}, true
+}
+
+// skipInlineImage skips data of image embedded in content stream, it is binary data ended
+// by EI surrounded by whitespace.
+func (l *pdfLexer) skipInlineImage() {
+	start := l.pos
+	defer func() { l.spend(l.pos - start) }()
+
+	index := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if index == -1 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += index + len("ID")
+
+	for {
+		index := bytes.Index(l.data[l.pos:], []byte("EI"))
+		if index == -1 {
+			l.pos = len(l.data)
+			return
+		}
+		end := l.pos + index
+		l.pos = end + len("EI")
+		if isPDFSpace(l.data[end-1]) && (l.pos == len(l.data) || isPDFSpace(l.data[l.pos])) {
+			return
+		}
+	}
+}
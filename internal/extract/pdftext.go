@@ -0,0 +1,379 @@
+package extract
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Text offset of TJ array in thousandths of font size, larger gaps separate words
+const pdfWordGap = -200
+
+// Mappings of ToUnicode CMap are limited, so CMap can't allocate without bound
+const maxCMapMappings = 1 << 17
+
+// pdfContent interprets text operators of content streams. Positions of text aren't
+// tracked, moving to another line starts new line and moving along the line separates
+// words, which keeps reading order of most generated files.
+type pdfContent struct {
+	document	*pdfDocument
+	text		*strings.Builder
+	lineBreak	bool
+	space		bool
+	lineY		float64
+}
+
+func (c *pdfContent) run(content []byte, resources pdfDict, depth int) error {
+	lexer := &pdfLexer{data: content, work: &c.document.work}
+	operands := []any{}
+	font := &pdfFont{codeBytes: 1}
+
+	for {
+		object, err := lexer.object(0)
+		if errors.Is(err, errComplexPDF) {
+			return err
+		}
+		if err != nil {
+			// text of malformed content read before the error is kept
+			return nil
+		}
+		operator, ok := object.(pdfKeyword)
+		if !ok {
+			operands = append(operands, object)
+			continue
+		}
+
+		switch operator {
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					font = c.document.font(resources, name)
+				}
+			}
+		case "Tj":
+			if len(operands) > 0 {
+				c.show(font, operands[len(operands)-1])
+			}
+		case "'", "\"":
+			c.lineBreak = true
+			if len(operands) > 0 {
+				c.show(font, operands[len(operands)-1])
+			}
+		case "TJ":
+			if len(operands) > 0 {
+				array, _ := operands[len(operands)-1].([]any)
+				for _, element := range array {
+					if offset, ok := element.(float64); ok {
+						if offset < pdfWordGap {
+							c.space = true
+						}
+						continue
+					}
+					c.show(font, element)
+				}
+			}
+		case "T*":
+			c.lineBreak = true
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				x, _ := operands[len(operands)-2].(float64)
+				y, _ := operands[len(operands)-1].(float64)
+				if y != 0 {
+					c.lineBreak = true
+				} else if x != 0 {
+					c.space = true
+				}
+			}
+		case "Tm":
+			if len(operands) >= 6 {
+				y, _ := operands[len(operands)-1].(float64)
+				if y != c.lineY {
+					c.lineBreak = true
+				} else {
+					c.space = true
+				}
+				c.lineY = y
+			}
+		case "ET":
+			c.space = true
+		case "BI":
+			lexer.skipInlineImage()
+		case "Do":
+			if len(operands) > 0 && depth < maxPDFFormDepth {
+				if err := c.form(resources, operands[len(operands)-1], depth); err != nil {
+					return err
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// form writes text of form XObject, forms are content streams reused by pages.
+func (c *pdfContent) form(resources pdfDict, name any, depth int) error {
+	xObjectName, ok := name.(pdfName)
+	if !ok {
+		return nil
+	}
+	stream, ok := c.document.resolve(c.document.dict(resources["XObject"])[xObjectName]).(*pdfStream)
+	if !ok || c.document.resolve(stream.dict["Subtype"]) != pdfName("Form") {
+		return nil
+	}
+
+	data, err := c.document.decode(stream)
+	if errors.Is(err, ErrFileTooLarge) {
+		return err
+	}
+	if err != nil {
+		return nil
+	}
+
+	formResources := c.document.dict(stream.dict["Resources"])
+	if formResources == nil {
+		formResources = resources
+	}
+	c.lineBreak = true
+	return c.run(data, formResources, depth+1)
+}
+
+func (c *pdfContent) show(font *pdfFont, value any) {
+	data, ok := value.([]byte)
+	if !ok {
+		return
+	}
+	text := font.decode(data)
+	if text == "" {
+		return
+	}
+
+	if c.text.Len() > 0 {
+		if c.lineBreak {
+			c.text.WriteString("\n")
+		} else if c.space && !strings.HasSuffix(c.text.String(), " ") && !strings.HasPrefix(text, " ") {
+			c.text.WriteString(" ")
+		}
+	}
+	c.lineBreak, c.space = false, false
+	c.text.WriteString(text)
+}
+
+// pdfFont maps character codes of shown strings to text.
+type pdfFont struct {
+	// codeBytes is length of character codes, composite fonts usually use two bytes
+	codeBytes	int
+	// composite fonts without ToUnicode map have no text, their codes are glyph ids
+	composite	bool
+	toUnicode	map[uint32]string
+	differences	map[byte]rune
+}
+
+func (d *pdfDocument) font(resources pdfDict, name pdfName) *pdfFont {
+	value := d.dict(resources["Font"])[name]
+	if ref, ok := value.(pdfRef); ok {
+		if font, ok := d.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	font := d.loadFont(d.dict(value))
+	if ref, ok := value.(pdfRef); ok {
+		d.fonts[ref] = font
+	}
+	return font
+}
+
+// loadFont reads encoding of font. Base encodings other than WinAnsi differ from it mostly
+// in rarely used characters, so WinAnsi is used for all of them.
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{codeBytes: 1}
+	if dict == nil {
+		return font
+	}
+	if d.resolve(dict["Subtype"]) == pdfName("Type0") {
+		font.codeBytes, font.composite = 2, true
+	}
+
+	if encoding := d.dict(dict["Encoding"]); encoding != nil && !font.composite {
+		differences, _ := d.resolve(encoding["Differences"]).([]any)
+		font.differences = map[byte]rune{}
+		code := 0
+		for _, difference := range differences {
+			switch difference := d.resolve(difference).(type) {
+			case float64:
+				code = int(difference)
+			case pdfName:
+				if r, ok := glyphRune(string(difference)); ok && code >= 0 && code < 256 {
+					font.differences[byte(code)] = r
+				}
+				code++
+			}
+		}
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.decode(stream); err == nil {
+			font.parseCMap(data, &d.work)
+		}
+	}
+	return font
+}
+
+// parseCMap reads mappings of ToUnicode CMap, targets are UTF-16 encoded.
+func (f *pdfFont) parseCMap(data []byte, work *int64) {
+	f.toUnicode = map[uint32]string{}
+	lexer := &pdfLexer{data: data, work: work}
+	operands := []any{}
+	for len(f.toUnicode) < maxCMapMappings {
+		object, err := lexer.object(0)
+		if err != nil {
+			return
+		}
+		operator, ok := object.(pdfKeyword)
+		if !ok {
+			operands = append(operands, object)
+			continue
+		}
+
+		switch operator {
+		case "endcodespacerange":
+			// files mixing code lengths are rare, the first range defines length of all codes
+			if len(operands) > 0 {
+				if low, ok := operands[0].([]byte); ok && len(low) > 0 && len(low) <= 4 {
+					f.codeBytes = len(low)
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				source, sourceOk := operands[i].([]byte)
+				target, targetOk := operands[i+1].([]byte)
+				if sourceOk && targetOk {
+					f.toUnicode[characterCode(source)] = decodeUTF16(target)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				low, lowOk := operands[i].([]byte)
+				high, highOk := operands[i+1].([]byte)
+				if !lowOk || !highOk {
+					continue
+				}
+				f.addRange(characterCode(low), characterCode(high), operands[i+2])
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// addRange maps codes from start to end, target is either the first mapped text with the
+// last character incremented for following codes or array of texts.
+func (f *pdfFont) addRange(start uint32, end uint32, target any) {
+	if end < start || end-start >= maxCMapMappings {
+		return
+	}
+
+	switch target := target.(type) {
+	case []byte:
+		runes := []rune(decodeUTF16(target))
+		if len(runes) == 0 {
+			return
+		}
+		for code := start; code <= end && len(f.toUnicode) < maxCMapMappings; code++ {
+			mapped := append([]rune{}, runes...)
+			mapped[len(mapped)-1] += rune(code - start)
+			f.toUnicode[code] = string(mapped)
+		}
+	case []any:
+		for i, element := range target {
+			code := start + uint32(i)
+			if text, ok := element.([]byte); ok && code <= end {
+				f.toUnicode[code] = decodeUTF16(text)
+			}
+		}
+	}
+}
+
+func (f *pdfFont) decode(data []byte) string {
+	var text strings.Builder
+	for i := 0; i+f.codeBytes <= len(data); i += f.codeBytes {
+		code := characterCode(data[i : i+f.codeBytes])
+		if mapped, ok := f.toUnicode[code]; ok {
+			text.WriteString(mapped)
+			continue
+		}
+		if f.composite || f.codeBytes != 1 {
+			continue
+		}
+		if r, ok := f.differences[byte(code)]; ok {
+			text.WriteRune(r)
+		} else if r := winAnsiEncoding[code]; r != 0 {
+			text.WriteRune(r)
+		}
+	}
+	return text.String()
+}
+
+func characterCode(data []byte) uint32 {
+	code := uint32(0)
+	for _, b := range data {
+		code = code<<8 | uint32(b)
+	}
+	return code
+}
+
+func decodeUTF16(data []byte) string {
+	units := make([]uint16, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// winAnsiEncoding maps single byte codes to characters, zero means code isn't mapped.
+var winAnsiEncoding = func() [256]rune {
+	var encoding [256]rune
+	encoding['\t'], encoding['\n'], encoding['\r'] = '\t', '\n', '\r'
+	for code := 0x20; code < 0x100; code++ {
+		encoding[code] = rune(code)
+	}
+	encoding[0x7f] = 0
+	upper := []rune{
+		'€', 0, '‚', 'ƒ', '„', '…', '†', '‡',
+		'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+		0, '‘', '’', '“', '”', '•', '–', '—',
+		'˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+	}
+	copy(encoding[0x80:], upper)
+	return encoding
+}()
+
+// Glyph names of Differences arrays which aren't single characters or uniXXXX names
+var glyphNames = map[string]rune{
+	"space": ' ', "exclam": '!', "quotedbl": '"', "numbersign": '#', "dollar": '$',
+	"percent": '%', "ampersand": '&', "quotesingle": '\'', "parenleft": '(', "parenright": ')',
+	"asterisk": '*', "plus": '+', "comma": ',', "hyphen": '-', "period": '.', "slash": '/',
+	"zero": '0', "one": '1', "two": '2', "three": '3', "four": '4',
+	"five": '5', "six": '6', "seven": '7', "eight": '8', "nine": '9',
+	"colon": ':', "semicolon": ';', "less": '<', "equal": '=', "greater": '>', "question": '?',
+	"at": '@', "bracketleft": '[', "backslash": '\\', "bracketright": ']', "underscore": '_',
+	"braceleft": '{', "bar": '|', "braceright": '}', "asciitilde": '~', "asciicircum": '^',
+	"grave": '`', "quoteleft": '‘', "quoteright": '’', "quotedblleft": '“',
+	"quotedblright": '”', "endash": '–', "emdash": '—', "bullet": '•',
+	"ellipsis": '…', "fi": 'ﬁ', "fl": 'ﬂ', "Euro": '€', "copyright": '©',
+	"registered": '®', "trademark": '™', "degree": '°', "nbspace": '\u00a0',
+}
+
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	if strings.HasPrefix(name, "uni") && len(name) == 7 {
+		if code, err := strconv.ParseUint(name[3:], 16, 32); err == nil {
+			return rune(code), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,42 @@
+package extract
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// TextExtractor converts plain text file to one document titled by file name.
+type TextExtractor struct{}
+
+func (e *TextExtractor) Extract(file *File, emit EmitFunc) error {
+	data, err := readAll(file)
+	if err != nil {
+		return err
+	}
+	if !utf8.Valid(data) {
+		return errNotUTF8
+	}
+
+	return emit(models.Document{Title: baseName(file.Name), Text: normalizeText(string(data))}, nil)
+}
+
+// normalizeText trims lines, collapses spaces inside them and keeps at most one empty
+// line between paragraphs.
+func normalizeText(text string) string {
+	text = strings.TrimPrefix(text, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+
+	normalized := make([]string, 0, len(lines))
+	for _, line := range lines {
+		line = strings.Join(strings.FieldsFunc(line, unicode.IsSpace), " ")
+		if line == "" && (len(normalized) == 0 || normalized[len(normalized)-1] == "") {
+			continue
+		}
+		normalized = append(normalized, line)
+	}
+
+	return strings.TrimSpace(strings.Join(normalized, "\n"))
+}
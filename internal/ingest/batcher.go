@@ -0,0 +1,62 @@
+package ingest
+
+import (
+	"errors"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var ErrDocumentTooLarge = errors.New("document doesn't fit into queue message")
+
+// Batcher splits stream of documents into batches limited by number of documents and
+// their size, so each batch fits into one queue message.
+type Batcher struct {
+	maxDocuments	int
+	maxBytes		int
+	flush			func(documents []models.Document) error
+	documents		[]models.Document
+	size			int
+}
+
+// NewBatcher returns batcher passing full batches to flush, maxBytes is the size available
+// for marshaled documents and commas between them, zero maxDocuments means no limit.
+func NewBatcher(maxDocuments int, maxBytes int, flush func(documents []models.Document) error) *Batcher {
+	return &Batcher{
+		maxDocuments: maxDocuments,
+		maxBytes: maxBytes,
+		flush: flush,
+	}
+}
+
+// Add appends document of given marshaled size, current batch is flushed first if the
+// document doesn't fit into it.
+func (b *Batcher) Add(document models.Document, size int) error {
+	if size > b.maxBytes {
+		return ErrDocumentTooLarge
+	}
+
+	if len(b.documents) > 0 && ((b.maxDocuments > 0 && len(b.documents) >= b.maxDocuments) || b.size+1+size > b.maxBytes) {
+		if err := b.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if len(b.documents) > 0 {
+		b.size++
+	}
+	b.documents = append(b.documents, document)
+	b.size += size
+	return nil
+}
+
+// Flush passes incomplete batch to flush, it must be called after the last document.
+func (b *Batcher) Flush() error {
+	if len(b.documents) == 0 {
+		return nil
+	}
+
+	err := b.flush(b.documents)
+	b.documents = nil
+	b.size = 0
+	return err
+}
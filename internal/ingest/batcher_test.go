@@ -0,0 +1,88 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var batcherTests = []struct {
+	testName 			string
+	maxDocuments		int
+	maxBytes			int
+	sizes				[]int
+	expectedBatches		[]int
+}{
+	{
+		testName: "Split by number of documents",
+		maxDocuments: 2,
+		maxBytes: 100,
+		sizes: []int{10, 10, 10, 10, 10},
+		expectedBatches: []int{2, 2, 1},
+	},
+	{
+		testName: "Split by size with commas between documents",
+		maxDocuments: 10,
+		maxBytes: 21,
+		sizes: []int{10, 10, 10, 1},
+		expectedBatches: []int{2, 2},
+	},
+	{
+		testName: "Document of max size is batched alone",
+		maxDocuments: 10,
+		maxBytes: 20,
+		sizes: []int{5, 20, 5},
+		expectedBatches: []int{1, 1, 1},
+	},
+	{
+		testName: "Zero max documents means no limit",
+		maxDocuments: 0,
+		maxBytes: 100,
+		sizes: []int{1, 1, 1, 1},
+		expectedBatches: []int{4},
+	},
+	{
+		testName: "Nothing is flushed without documents",
+		maxDocuments: 10,
+		maxBytes: 100,
+		expectedBatches: []int{},
+	},
+}
+
+func TestBatcher(t *testing.T) {
+	for i, test := range batcherTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		batches := []int{}
+		batcher := NewBatcher(test.maxDocuments, test.maxBytes, func(documents []models.Document) error {
+			batches = append(batches, len(documents))
+			return nil
+		})
+
+		for _, size := range test.sizes {
+			if err := batcher.Add(models.Document{}, size); err != nil {
+				t.Fatalf("Unable to add document, error: %s\n", err)
+			}
+		}
+		if err := batcher.Flush(); err != nil {
+			t.Fatalf("Unable to flush documents, error: %s\n", err)
+		}
+
+		assert.Equal(t, batches, test.expectedBatches, "wrong batches")
+	}
+}
+
+func TestBatcherErrors(t *testing.T) {
+	flushError := errors.New("queue unavailable")
+	batcher := NewBatcher(1, 10, func(documents []models.Document) error {
+		return flushError
+	})
+
+	assert.Equal(t, batcher.Add(models.Document{}, 11), ErrDocumentTooLarge, "document bigger than message must be rejected")
+	assert.Equal(t, batcher.Add(models.Document{}, 5), nil, "first document must not be flushed")
+	assert.Equal(t, batcher.Add(models.Document{}, 5), flushError, "flush error must be returned")
+	assert.Equal(t, batcher.Flush(), nil, "failed batch must not be flushed again")
+}
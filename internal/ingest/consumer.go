@@ -0,0 +1,68 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/alerting"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+	"github.com/xavesen/search-api/internal/webhook"
+)
+
+// Consumer indexes documents from queued indexing requests, it is used as the built-in
+// consumer when the service runs with in-process queue.
+type Consumer struct {
+	DocStorage	storage.DocumentStorage
+	// Evaluator matches indexed documents against saved searches, nil disables alerting
+	Evaluator	*alerting.Evaluator
+	// Dispatcher sends ingestion events to webhooks, nil disables them
+	Dispatcher	*webhook.Dispatcher
+}
+
+func (c *Consumer) HandleMessage(ctx context.Context, message queue.Message) error {
+	var indexingRequest models.DocumentsForIndexing
+	// numbers of fields are kept as they are, float64 would round integers above 2^53
+	decoder := json.NewDecoder(bytes.NewReader(message.Value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&indexingRequest); err != nil {
+		// malformed message will never succeed, so it is dropped instead of being redelivered
+		log.Errorf("Error unmarshalling indexing request from message with key %s: %s", message.Key, err)
+		return nil
+	}
+
+	log.Debugf("Indexing %d documents from queue to index %s", len(indexingRequest.Documents), indexingRequest.Index)
+	requestId := message.Headers[queue.HeaderRequestId]
+	results, err := c.DocStorage.IndexDocuments(ctx, indexingRequest.Index, indexingRequest.Documents, indexingRequest.Embeddings, "", indexingRequest.Dedupe)
+	if err != nil {
+		if c.Dispatcher != nil {
+			c.Dispatcher.IngestionFailed(ctx, indexingRequest.Index, requestId, len(indexingRequest.Documents), err)
+		}
+		return err
+	}
+
+	// documents that failed to index are logged and not retried, because in indexes with
+	// keep_both dedupe policy retrying the whole message would index successful documents
+	// without id once again
+	for _, result := range results {
+		if result.Result == models.IndexingResultFailed {
+			log.Errorf("Error indexing document #%d from message with key %s to index %s: %s", result.Position, message.Key, indexingRequest.Index, result.Error)
+		}
+	}
+
+	if c.Dispatcher != nil {
+		c.Dispatcher.DocumentsIndexed(ctx, indexingRequest.Index, requestId, results)
+	}
+
+	// documents are already indexed, so failed alerting doesn't fail the message
+	if c.Evaluator != nil {
+		if err := c.Evaluator.Evaluate(ctx, indexingRequest.Index, indexingRequest.Documents, results); err != nil {
+			log.Errorf("Error evaluating saved searches for documents from message with key %s: %s", message.Key, err)
+		}
+	}
+
+	return nil
+}
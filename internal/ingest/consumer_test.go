@@ -0,0 +1,28 @@
+package ingest
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/queue"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+func TestHandleMessageKeepsLargeIntegers(t *testing.T) {
+	docStorage := &storage.DocStorageMock{IndexingResults: []models.DocumentIndexingResult{}}
+	consumer := &Consumer{DocStorage: docStorage}
+
+	message := queue.Message{Value: []byte(`{"index_name": "test", "documents": [{"title": "Mouse", "text": "", "fields": {"views": 9007199254740993}}]}`)}
+	if err := consumer.HandleMessage(context.TODO(), message); err != nil {
+		t.Fatalf("Unable to handle message, error: %s\n", err)
+	}
+
+	marshaledFields, err := json.Marshal(docStorage.IndexedDocuments[0].Fields)
+	if err != nil {
+		t.Fatalf("Unable to marshal fields, error: %s\n", err)
+	}
+	assert.Equal(t, string(marshaledFields), `{"views":9007199254740993}`, "integer field must be indexed exactly")
+}
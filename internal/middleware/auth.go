@@ -24,15 +24,15 @@ func (amw *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		valid, token, err := amw.TokenOp.ValidateToken(tokenStr, amw.Config.JwtKey)
 		if err != nil {
 			if errors.Is(err, jwt.ErrTokenExpired) {
-				utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Token has expired, refresh it or login again", nil)
+				utils.WriteError(w, r, utils.ErrTokenExpired)
 			} else {
-				utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+				utils.WriteError(w, r, utils.ErrUnauthorized)
 			}
 			return
 		}
 
 		if !valid {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+			utils.WriteError(w, r, utils.ErrUnauthorized)
 			return
 		}
 
@@ -40,18 +40,18 @@ func (amw *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 
 		blacklisted, err := amw.UserStorage.CheckIfTokenBlacklisted(context.TODO(), hashedToken)
 		if err != nil {
-			utils.WriteJSON(w, r, http.StatusInternalServerError, false, "Internal server error", nil)
+			utils.WriteError(w, r, err)
 			return
 		}
 
 		if blacklisted {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Token is blacklisted", nil)
+			utils.WriteError(w, r, utils.ErrTokenBlacklisted)
 			return
 		}
 
 		userId, err := token.Claims.GetSubject()
 		if err != nil {
-			utils.WriteJSON(w, r, http.StatusUnauthorized, false, "Unauthorized", nil)
+			utils.WriteError(w, r, utils.ErrUnauthorized)
 			return
 		}
 
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/utils"
+)
+
+const RequestIdHeaderName = "X-Request-Id"
+
+// RequestId takes request id from the X-Request-Id header or generates a new one,
+// puts it to the request context and echoes it back in the response headers.
+func RequestId(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(RequestIdHeaderName)
+		if requestId == "" {
+			requestId = newRequestId()
+		}
+
+		w.Header().Set(RequestIdHeaderName, requestId)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), utils.ContextKeyReqId, requestId)))
+	})
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Errorf("Error generating request id: %s", err)
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,56 @@
+package models
+
+const (
+	AggregationTypeTerms			= "terms"
+	AggregationTypeDateHistogram	= "date_histogram"
+	AggregationTypeRange			= "range"
+	AggregationTypeCardinality		= "cardinality"
+)
+
+// Aggregation is a facet requested with search, exactly one of the aggregations must be set.
+type Aggregation struct {
+	Terms			*TermsAggregation			`json:"terms,omitempty"`
+	DateHistogram	*DateHistogramAggregation	`json:"date_histogram,omitempty"`
+	Range			*RangeAggregation			`json:"range,omitempty"`
+	Cardinality		*CardinalityAggregation		`json:"cardinality,omitempty"`
+}
+
+type TermsAggregation struct {
+	Field		string		`json:"field"`
+	Size		*int		`json:"size,omitempty"`
+}
+
+type DateHistogramAggregation struct {
+	Field				string	`json:"field"`
+	CalendarInterval	string	`json:"calendar_interval"`
+	Format				string	`json:"format,omitempty"`
+}
+
+type RangeAggregation struct {
+	Field		string				`json:"field"`
+	Ranges		[]AggregationRange	`json:"ranges"`
+}
+
+type AggregationRange struct {
+	Key		string		`json:"key,omitempty"`
+	From	*float64	`json:"from,omitempty"`
+	To		*float64	`json:"to,omitempty"`
+}
+
+type CardinalityAggregation struct {
+	Field		string		`json:"field"`
+}
+
+// AggregationResult holds buckets for bucket aggregations and value for cardinality.
+type AggregationResult struct {
+	Type		string				`json:"type"`
+	Buckets		[]AggregationBucket	`json:"buckets,omitempty"`
+	Value		*int64				`json:"value,omitempty"`
+}
+
+type AggregationBucket struct {
+	Key			string		`json:"key"`
+	From		*float64	`json:"from,omitempty"`
+	To			*float64	`json:"to,omitempty"`
+	DocCount	int64		`json:"doc_count"`
+}
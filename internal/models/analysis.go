@@ -0,0 +1,16 @@
+package models
+
+// Languages analyzers with stopwords and stemming can be configured for
+var AnalysisLanguages = []string{"english", "russian", "german", "french", "spanish", "italian", "portuguese", "dutch"}
+
+// IndexAnalysis configures analyzers of title and text. Synonyms are applied
+// only at search time, so they can be changed without reindexing documents.
+type IndexAnalysis struct {
+	Language	string		`json:"language,omitempty" bson:"language,omitempty"`
+	Stopwords	[]string	`json:"stopwords,omitempty" bson:"stopwords,omitempty"`
+	Synonyms	[]string	`json:"synonyms,omitempty" bson:"synonyms,omitempty"`
+}
+
+type UpdateSynonymsRequest struct {
+	Synonyms	[]string	`json:"synonyms"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Limit of line errors listed in bulk response, the rest are only counted
+const MaxBulkLineErrors = 100
+
+// BulkLineError lists errors of NDJSON line, lines are numbered from 1.
+type BulkLineError struct {
+	Line	int				`json:"line"`
+	Errors	[]FieldError	`json:"errors"`
+}
+
+type BulkIndexingResponse struct {
+	// Lines is the number of non-empty lines read
+	Lines			int				`json:"lines"`
+	Queued			int				`json:"queued"`
+	Failed			int				`json:"failed"`
+	// Messages is the number of queue messages documents were split into
+	Messages		int				`json:"messages"`
+	Errors			[]BulkLineError	`json:"errors"`
+	// ErrorsTruncated means only first MaxBulkLineErrors errors are listed
+	ErrorsTruncated	bool			`json:"errors_truncated,omitempty"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Dedupe policies decide what happens to documents without id whose content is already
+// indexed, overwrite is used for indexes without policy
+const (
+	DedupeSkip		= "skip"
+	DedupeOverwrite	= "overwrite"
+	DedupeKeepBoth	= "keep_both"
+)
+
+var DedupePolicies = []string{DedupeSkip, DedupeOverwrite, DedupeKeepBoth}
+
+type UpdateDedupeRequest struct {
+	Dedupe	string	`json:"dedupe"`
+}
+
+// ContentHash is hex encoded SHA-256 of normalized title and text, documents differing
+// only in case and whitespace have the same hash.
+func (d *Document) ContentHash() string {
+	hash := sha256.Sum256([]byte(normalizeContent(d.Title) + "\n" + normalizeContent(d.Text)))
+	return hex.EncodeToString(hash[:])
+}
+
+func normalizeContent(content string) string {
+	return strings.Join(strings.Fields(strings.ToLower(content)), " ")
+}
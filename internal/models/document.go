@@ -1,21 +1,137 @@
 package models
 
+const (
+	IndexingModeAsync	= "async"
+	IndexingModeSync	= "sync"
+
+	IndexingResultCreated	= "created"
+	IndexingResultUpdated	= "updated"
+	IndexingResultFailed	= "failed"
+	// Skipped documents are duplicates of indexed content in indexes with skip dedupe policy
+	IndexingResultSkipped	= "skipped"
+
+	AllIndexes	= "*"
+)
+
 type Document struct {
-	Title	string	`json:"title"`
-	Text	string	`json:"text"`
+	Id		string			`json:"id,omitempty"`
+	Title	string			`json:"title"`
+	Text	string			`json:"text"`
+	Fields	map[string]any	`json:"fields,omitempty"`
+}
+
+// SearchHit is document found by search with fields set by server.
+type SearchHit struct {
+	Document
+	// Index the document was found in
+	Index	string			`json:"index,omitempty"`
+	// Highlights are text fragments matching the query
+	Highlights	map[string][]string	`json:"highlights,omitempty"`
+	// SimHash of title and text is computed by server during indexing, documents indexed
+	// before it was supported don't have it
+	SimHash		string			`json:"simhash,omitempty"`
+	// NearDuplicates are ids of hits collapsed into the document by search with
+	// collapse_near_duplicates
+	NearDuplicates	[]string	`json:"near_duplicates,omitempty"`
 }
 
 type DocumentsForIndexing struct {
 	Index		string		`json:"index_name"`
 	UserId		string		`json:"user_id,omitempty"`
 	Documents 	[]Document	`json:"documents"`
+	// Dedupe is policy of the index, set by server so consumer doesn't need index metadata
+	Dedupe		string		`json:"dedupe,omitempty"`
+	// Embeddings of title and text of documents in the same order, set by server for
+	// indexes with vector field, so consumer doesn't need embedder
+	Embeddings	[][]float32	`json:"embeddings,omitempty"`
 }
 
 type DocumentSearchRequest struct {
-	Index 		string	`json:"index_name"`
-	Query		string	`json:"query"`
+	Index 				string			`json:"index_name,omitempty"`
+	// Indexes are searched together instead of single Index, AllIndexes means all indexes of the user
+	Indexes				[]string		`json:"index_names,omitempty"`
+	Query				string			`json:"query,omitempty"`
+	// Mode is keyword by default, knn searches by embeddings and hybrid fuses both
+	Mode				string			`json:"mode,omitempty"`
+	Syntax				string			`json:"syntax,omitempty"`
+	DefaultOperator		string			`json:"default_operator,omitempty"`
+	Search				*SearchClause	`json:"search,omitempty"`
+	Aggregations		map[string]Aggregation	`json:"aggregations,omitempty"`
+	// PostFilters are selected facets keyed by aggregation name, they narrow down documents
+	// but not counts of their own aggregation
+	PostFilters			map[string]SearchClause	`json:"post_filters,omitempty"`
+	// FuzzySettings apply to match queries and override index defaults
+	FuzzySettings
+	Sort				[]SortField		`json:"sort,omitempty"`
+	Source				*SourceFilter	`json:"_source,omitempty"`
+	// SnippetOnly returns highlighted fragments of text instead of the whole text
+	SnippetOnly			bool			`json:"snippet_only,omitempty"`
+	// AutoCorrect re-runs search with did you mean suggestion if it finds more documents
+	AutoCorrect			bool			`json:"auto_correct,omitempty"`
+	// CollapseNearDuplicates groups hits of the same index whose SimHashes are within
+	// NearDuplicateDistance, only the best hit of each group is returned
+	CollapseNearDuplicates	bool		`json:"collapse_near_duplicates,omitempty"`
+	// Envelope returns total and search hints along with documents, without it only documents
+	// are returned
+	Envelope			bool			`json:"envelope,omitempty"`
+	// SimpleQueryFlags are operators allowed in simple syntax, set by server from config
+	SimpleQueryFlags	string			`json:"-"`
+	// DidYouMeanMaxHits is the number of hits up to which spelling suggestion is returned,
+	// set by server from config, negative value disables suggestions
+	DidYouMeanMaxHits	int				`json:"-"`
+	// NearDuplicateDistance is the maximum number of differing SimHash bits, set by server from config
+	NearDuplicateDistance	int			`json:"-"`
+	// IgnoreUnavailable skips indexes missing in ES, set by server when searching all user's indexes
+	IgnoreUnavailable	bool			`json:"-"`
+	// QueryVector is embedding of query, set by server in knn and hybrid modes
+	QueryVector			[]float32		`json:"-"`
+}
+
+// TargetIndexes returns names of indexes the search is run against.
+func (r *DocumentSearchRequest) TargetIndexes() []string {
+	if len(r.Indexes) > 0 {
+		return r.Indexes
+	}
+	return []string{r.Index}
+}
+
+// UsesEnvelope reports if response is SearchResponse instead of documents only, results of
+// simple syntax, aggregations and auto correct are returned only in it.
+func (r *DocumentSearchRequest) UsesEnvelope() bool {
+	return r.Envelope || r.Syntax == SyntaxSimple || len(r.Aggregations) > 0 || r.AutoCorrect
+}
+
+type SearchResponse struct {
+	Total			int64		`json:"total"`
+	Documents		[]SearchHit	`json:"documents"`
+	NormalizedQuery	string		`json:"normalized_query,omitempty"`
+	IgnoredTerms	[]string	`json:"ignored_terms,omitempty"`
+	Aggregations	map[string]AggregationResult	`json:"aggregations,omitempty"`
+	DidYouMean		string		`json:"did_you_mean,omitempty"`
+	// Corrected means documents were found by did_you_mean instead of original query
+	Corrected		bool		`json:"corrected,omitempty"`
 }
 
 type CreateIndexRequest struct {
-	Index 		string	`json:"index_name"`
-}
\ No newline at end of file
+	Index 		string			`json:"index_name"`
+	Schema		*IndexSchema	`json:"schema,omitempty"`
+	Analysis	*IndexAnalysis	`json:"analysis,omitempty"`
+	Fuzzy		*FuzzySettings	`json:"fuzzy,omitempty"`
+	Vector		*VectorSettings	`json:"vector,omitempty"`
+	Dedupe		string			`json:"dedupe,omitempty"`
+}
+
+type DocumentIndexingResult struct {
+	Position	int		`json:"position"`
+	Id			string	`json:"id,omitempty"`
+	Result		string	`json:"result"`
+	Error		string	`json:"error,omitempty"`
+}
+
+type SyncIndexingResponse struct {
+	Created		int							`json:"created"`
+	Updated		int							`json:"updated"`
+	Failed		int							`json:"failed"`
+	Skipped		int							`json:"skipped"`
+	Documents	[]DocumentIndexingResult	`json:"documents"`
+}
@@ -0,0 +1,31 @@
+package models
+
+const FuzzinessAuto = "AUTO"
+
+// FuzzySettings control typo tolerance of match queries, unset values fall back to index
+// defaults and then to ES defaults.
+type FuzzySettings struct {
+	// Fuzziness is AUTO or maximum edit distance from 0 to 2
+	Fuzziness		string	`json:"fuzziness,omitempty" bson:"fuzziness,omitempty"`
+	PrefixLength	*int	`json:"prefix_length,omitempty" bson:"prefixLength,omitempty"`
+	MaxExpansions	*int	`json:"max_expansions,omitempty" bson:"maxExpansions,omitempty"`
+}
+
+// MergeFuzzySettings overrides index defaults with settings from request.
+func MergeFuzzySettings(defaults *FuzzySettings, settings FuzzySettings) FuzzySettings {
+	if defaults == nil {
+		return settings
+	}
+
+	merged := *defaults
+	if settings.Fuzziness != "" {
+		merged.Fuzziness = settings.Fuzziness
+	}
+	if settings.PrefixLength != nil {
+		merged.PrefixLength = settings.PrefixLength
+	}
+	if settings.MaxExpansions != nil {
+		merged.MaxExpansions = settings.MaxExpansions
+	}
+	return merged
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Statuses of reindex tasks, running tasks are resumed after restart
+const (
+	ReindexStatusRunning	= "running"
+	ReindexStatusCompleted	= "completed"
+	ReindexStatusFailed		= "failed"
+)
+
+// ReindexRequest sets schema and analysis of the new index version, settings that aren't
+// set are taken from the current version.
+type ReindexRequest struct {
+	Schema		*IndexSchema	`json:"schema,omitempty"`
+	Analysis	*IndexAnalysis	`json:"analysis,omitempty"`
+}
+
+// ReindexTask tracks copying of index to its next version, SourceIndex and DestIndex are
+// physical ES indexes behind alias named as the index.
+type ReindexTask struct {
+	Id			string			`json:"id" bson:"_id"`
+	Index		string			`json:"index" bson:"index"`
+	SourceIndex	string			`json:"source_index" bson:"sourceIndex"`
+	DestIndex	string			`json:"dest_index" bson:"destIndex"`
+	Version		int				`json:"version" bson:"version"`
+	EsTaskId	string			`json:"-" bson:"esTaskId,omitempty"`
+	// Swapped is set once alias points to DestIndex, after that SourceIndex can't be restored
+	Swapped		bool			`json:"-" bson:"swapped"`
+	Status		string			`json:"status" bson:"status"`
+	Total		int64			`json:"total" bson:"total"`
+	Copied		int64			`json:"copied" bson:"copied"`
+	Error		string			`json:"error,omitempty" bson:"error,omitempty"`
+	Schema		*IndexSchema	`json:"schema,omitempty" bson:"schema,omitempty"`
+	Analysis	*IndexAnalysis	`json:"analysis,omitempty" bson:"analysis,omitempty"`
+	CreatedAt	time.Time		`json:"created_at" bson:"createdAt"`
+	CompletedAt	*time.Time		`json:"completed_at,omitempty" bson:"completedAt,omitempty"`
+}
+
+// ReindexProgress is the state of ES reindex task copying documents.
+type ReindexProgress struct {
+	Completed	bool
+	Total		int64
+	Copied		int64
+	// Error is set if task completed with failures
+	Error		string
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+const (
+	DefaultMatchesSize		= 20
+	MaxMatchesSize			= 100
+	MaxSavedSearchNameLen	= 100
+)
+
+type SavedSearchRequest struct {
+	Name		string			`json:"name"`
+	Query		string			`json:"query,omitempty"`
+	Search		*SearchClause	`json:"search,omitempty"`
+	// WebhookURL receives matches of newly indexed documents, matches are recorded anyway
+	WebhookURL	string			`json:"webhook_url,omitempty"`
+}
+
+type SavedSearch struct {
+	Id			string		`json:"id"`
+	Index		string		`json:"index_name"`
+	UserId		string		`json:"-"`
+	SavedSearchRequest
+	CreatedAt	time.Time	`json:"created_at"`
+}
+
+// PercolatedSearch is a saved search matching some of percolated documents,
+// documents are identified by their positions.
+type PercolatedSearch struct {
+	SavedSearch	SavedSearch
+	Documents	[]int
+}
+
+type SavedSearchMatch struct {
+	SavedSearchId	string		`json:"saved_search_id"`
+	Index			string		`json:"index_name"`
+	DocumentId		string		`json:"document_id"`
+	Title			string		`json:"title"`
+	MatchedAt		time.Time	`json:"matched_at"`
+}
+
+type SavedSearchMatchesResponse struct {
+	Total	int64				`json:"total"`
+	Matches	[]SavedSearchMatch	`json:"matches"`
+}
+
+// SavedSearchNotification is pushed to webhook of saved search after ingestion.
+type SavedSearchNotification struct {
+	SavedSearchId	string				`json:"saved_search_id"`
+	Name			string				`json:"name"`
+	Index			string				`json:"index_name"`
+	Matches			[]SavedSearchMatch	`json:"matches"`
+}
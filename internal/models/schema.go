@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+const (
+	FieldTypeKeyword	= "keyword"
+	FieldTypeText		= "text"
+	FieldTypeDate		= "date"
+	FieldTypeLong		= "long"
+	FieldTypeInteger	= "integer"
+	FieldTypeFloat		= "float"
+	FieldTypeDouble		= "double"
+	FieldTypeBoolean	= "boolean"
+	FieldTypeObject		= "object"
+	FieldTypeNested		= "nested"
+)
+
+// IndexSchema describes typed metadata fields documents of the index carry in their fields map.
+type IndexSchema struct {
+	Fields		map[string]SchemaField	`json:"fields" bson:"fields"`
+}
+
+type SchemaField struct {
+	Type		string					`json:"type" bson:"type"`
+	// Properties are set only for object and nested fields
+	Properties	map[string]SchemaField	`json:"properties,omitempty" bson:"properties,omitempty"`
+}
+
+// IndexMetadata is kept in db next to ES index, ES mapping is built from it.
+type IndexMetadata struct {
+	Name		string			`json:"index_name" bson:"_id"`
+	UserId		string			`json:"user_id" bson:"userId"`
+	Schema		*IndexSchema	`json:"schema,omitempty" bson:"schema,omitempty"`
+	Analysis	*IndexAnalysis	`json:"analysis,omitempty" bson:"analysis,omitempty"`
+	Fuzzy		*FuzzySettings	`json:"fuzzy,omitempty" bson:"fuzzy,omitempty"`
+	Vector		*VectorSettings	`json:"vector,omitempty" bson:"vector,omitempty"`
+	Dedupe		string			`json:"dedupe,omitempty" bson:"dedupe,omitempty"`
+	// Version of physical ES index behind alias named as the index, zero for indexes
+	// created before aliases were used, they are ES indexes named as the index
+	Version		int				`json:"version,omitempty" bson:"version,omitempty"`
+	CreatedAt	time.Time		`json:"created_at" bson:"createdAt"`
+}
+
+// MergeSchemaFields adds fields from update to current ones recursively, types of existing
+// fields are taken from current, so update must be validated before merging.
+func MergeSchemaFields(current map[string]SchemaField, update map[string]SchemaField) map[string]SchemaField {
+	merged := make(map[string]SchemaField, len(current)+len(update))
+	for name, field := range current {
+		merged[name] = field
+	}
+
+	for name, field := range update {
+		existing, ok := merged[name]
+		if !ok {
+			merged[name] = field
+			continue
+		}
+		if len(field.Properties) > 0 {
+			existing.Properties = MergeSchemaFields(existing.Properties, field.Properties)
+			merged[name] = existing
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,84 @@
+package models
+
+import "encoding/json"
+
+const (
+	SyntaxQueryString	= "query_string"
+	SyntaxSimple		= "simple"
+
+	DefaultOperatorAnd	= "and"
+	DefaultOperatorOr	= "or"
+
+	SortFieldScore		= "_score"
+	SortOrderAsc		= "asc"
+	SortOrderDesc		= "desc"
+)
+
+// SortField orders results by _score, title or sortable schema field, order defaults to
+// desc for _score and asc for fields.
+type SortField struct {
+	Field		string		`json:"field"`
+	Order		string		`json:"order,omitempty"`
+}
+
+// SourceFilter selects document fields returned by search, id is always returned.
+type SourceFilter struct {
+	Includes	[]string	`json:"includes,omitempty"`
+	Excludes	[]string	`json:"excludes,omitempty"`
+}
+
+// SearchClause is a node of search DSL, exactly one of the clauses must be set.
+type SearchClause struct {
+	Match			*MatchClause		`json:"match,omitempty"`
+	MatchPhrase		*MatchClause		`json:"match_phrase,omitempty"`
+	MultiMatch		*MultiMatchClause	`json:"multi_match,omitempty"`
+	Bool			*BoolClause			`json:"bool,omitempty"`
+	Term			*TermClause			`json:"term,omitempty"`
+	Range			*RangeClause		`json:"range,omitempty"`
+	Prefix			*PrefixClause		`json:"prefix,omitempty"`
+}
+
+type MatchClause struct {
+	Field		string		`json:"field"`
+	Query		string		`json:"query"`
+	Operator	string		`json:"operator,omitempty"`
+	Boost		*float32	`json:"boost,omitempty"`
+}
+
+type FieldBoost struct {
+	Field		string		`json:"field"`
+	Boost		*float32	`json:"boost,omitempty"`
+}
+
+type MultiMatchClause struct {
+	Query		string			`json:"query"`
+	Fields		[]FieldBoost	`json:"fields"`
+	Type		string			`json:"type,omitempty"`
+	Operator	string			`json:"operator,omitempty"`
+}
+
+type BoolClause struct {
+	Must				[]SearchClause	`json:"must,omitempty"`
+	Should				[]SearchClause	`json:"should,omitempty"`
+	MustNot				[]SearchClause	`json:"must_not,omitempty"`
+	Filter				[]SearchClause	`json:"filter,omitempty"`
+	MinimumShouldMatch	*int			`json:"minimum_should_match,omitempty"`
+}
+
+type TermClause struct {
+	Field		string		`json:"field"`
+	Value		any			`json:"value"`
+}
+
+type RangeClause struct {
+	Field		string			`json:"field"`
+	Gt			json.RawMessage	`json:"gt,omitempty"`
+	Gte			json.RawMessage	`json:"gte,omitempty"`
+	Lt			json.RawMessage	`json:"lt,omitempty"`
+	Lte			json.RawMessage	`json:"lte,omitempty"`
+}
+
+type PrefixClause struct {
+	Field		string		`json:"field"`
+	Value		string		`json:"value"`
+}
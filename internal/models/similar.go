@@ -0,0 +1,39 @@
+package models
+
+const (
+	DefaultSimilarSize	= 10
+	MaxSimilarSize		= 100
+)
+
+// SimilarDocumentsRequest finds documents with SimHash within Distance bits of the document
+// with Id, the document itself isn't returned.
+type SimilarDocumentsRequest struct {
+	Index		string
+	Id			string
+	SimHash		uint64
+	Distance	int
+	Size		int
+}
+
+type SimilarDocument struct {
+	SearchHit
+	// Distance is the number of bits SimHash of the document differs in
+	Distance	int	`json:"distance"`
+}
+
+type SimilarDocumentsResponse struct {
+	SimHash		string				`json:"simhash"`
+	Documents	[]SimilarDocument	`json:"documents"`
+}
+
+// MoreLikeThisRequest finds documents sharing significant terms of title and text with the
+// document with Id or with Text, unset tuning parameters fall back to ES defaults.
+type MoreLikeThisRequest struct {
+	Index			string	`json:"-"`
+	Id				string	`json:"id,omitempty"`
+	Text			string	`json:"text,omitempty"`
+	// MinTermFreq is how many times term must occur in the document or text to be used
+	MinTermFreq		*int	`json:"min_term_freq,omitempty"`
+	// MaxQueryTerms is the number of the most significant terms used
+	MaxQueryTerms	*int	`json:"max_query_terms,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+const (
+	DefaultSuggestSize	= 5
+	MaxSuggestSize		= 20
+	MaxSuggestPrefixLen	= 100
+)
+
+type SuggestRequest struct {
+	Index	string
+	Prefix	string
+	Size	int
+	// CollapseField groups suggestions with equal titles, set by server from index metadata,
+	// empty when title mapping isn't known
+	CollapseField	string
+}
+
+type Suggestion struct {
+	Id		string	`json:"id"`
+	Title	string	`json:"title"`
+	Score	float64	`json:"score"`
+}
+
+type SuggestResponse struct {
+	Suggestions	[]Suggestion	`json:"suggestions"`
+}
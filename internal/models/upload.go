@@ -0,0 +1,39 @@
+package models
+
+// CSVMapping maps CSV columns to document, columns are referenced by header names.
+type CSVMapping struct {
+	Id		string				`json:"id,omitempty"`
+	Title	string				`json:"title,omitempty"`
+	// Text columns are joined with empty line between them
+	Text	[]string			`json:"text,omitempty"`
+	// Fields map document fields to columns
+	Fields	map[string]string	`json:"fields,omitempty"`
+}
+
+// UploadDocumentError lists errors of document extracted from file, documents are
+// numbered from 1 in order they appear in the file.
+type UploadDocumentError struct {
+	Document	int				`json:"document"`
+	Errors		[]FieldError	`json:"errors"`
+}
+
+type UploadFileResult struct {
+	File		string					`json:"file"`
+	Format		string					`json:"format,omitempty"`
+	// Documents is the number of documents extracted from the file
+	Documents	int						`json:"documents"`
+	Failed		int						`json:"failed"`
+	Errors		[]UploadDocumentError	`json:"errors"`
+	// ErrorsTruncated means only first MaxBulkLineErrors errors are listed
+	ErrorsTruncated	bool				`json:"errors_truncated,omitempty"`
+	// Error means file couldn't be read entirely, documents extracted before it are queued
+	Error		string					`json:"error,omitempty"`
+}
+
+type UploadResponse struct {
+	Queued		int					`json:"queued"`
+	Failed		int					`json:"failed"`
+	// Messages is the number of queue messages documents were split into
+	Messages	int					`json:"messages"`
+	Files		[]UploadFileResult	`json:"files"`
+}
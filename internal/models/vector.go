@@ -0,0 +1,18 @@
+package models
+
+const (
+	SearchModeKeyword	= "keyword"
+	SearchModeKnn		= "knn"
+	SearchModeHybrid	= "hybrid"
+
+	VectorSimilarityCosine	= "cosine"
+)
+
+var VectorSimilarities = []string{VectorSimilarityCosine, "dot_product", "l2_norm"}
+
+// VectorSettings enable dense vector field with embeddings of title and text,
+// dimensions are set by server from embedder.
+type VectorSettings struct {
+	Similarity	string	`json:"similarity,omitempty" bson:"similarity"`
+	Dimensions	int		`json:"dimensions,omitempty" bson:"dimensions"`
+}
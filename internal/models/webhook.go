@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+const (
+	EventIndexCreated		= "index.created"
+	EventIndexDeleted		= "index.deleted"
+	EventDocumentsQueued	= "documents.queued"
+	EventDocumentsIndexed	= "documents.indexed"
+	EventIngestionFailed	= "ingestion.failed"
+
+	DeliveryStatusPending	= "pending"
+	DeliveryStatusSucceeded	= "succeeded"
+	DeliveryStatusFailed	= "failed"
+
+	DefaultDeliveriesSize	= 20
+	MaxDeliveriesSize		= 100
+	MinWebhookSecretLen		= 16
+	MaxWebhookSecretLen		= 256
+)
+
+var WebhookEvents = []string{EventIndexCreated, EventIndexDeleted, EventDocumentsQueued, EventDocumentsIndexed, EventIngestionFailed}
+
+// IngestionEvents report indexing of documents, so they are sent only by the one who indexes them
+var IngestionEvents = []string{EventDocumentsIndexed, EventIngestionFailed}
+
+type WebhookRequest struct {
+	URL		string		`json:"url"`
+	// Secret signs payloads with HMAC-SHA256, it is never returned
+	Secret	string		`json:"secret"`
+	Events	[]string	`json:"events"`
+}
+
+type Webhook struct {
+	Id			string		`json:"id" bson:"_id"`
+	Index		string		`json:"index_name" bson:"index"`
+	UserId		string		`json:"-" bson:"userId"`
+	URL			string		`json:"url" bson:"url"`
+	Secret		string		`json:"-" bson:"secret"`
+	Events		[]string	`json:"events" bson:"events"`
+	CreatedAt	time.Time	`json:"created_at" bson:"createdAt"`
+}
+
+type WebhooksResponse struct {
+	Webhooks	[]Webhook	`json:"webhooks"`
+}
+
+// WebhookEvent is the payload delivered to webhooks.
+type WebhookEvent struct {
+	Id			string		`json:"id"`
+	Type		string		`json:"type"`
+	Index		string		`json:"index_name"`
+	CreatedAt	time.Time	`json:"created_at"`
+	Data		any			`json:"data,omitempty"`
+}
+
+// DocumentsEventData describes ingestion in documents.* and ingestion.failed events, Error is
+// set when the whole batch failed and Failures list documents that failed separately.
+type DocumentsEventData struct {
+	RequestId	string						`json:"request_id,omitempty"`
+	Documents	int							`json:"documents"`
+	Created		int							`json:"created,omitempty"`
+	Updated		int							`json:"updated,omitempty"`
+	Failed		int							`json:"failed,omitempty"`
+	Skipped		int							`json:"skipped,omitempty"`
+	Failures	[]DocumentIndexingResult	`json:"failures,omitempty"`
+	Error		string						`json:"error,omitempty"`
+}
+
+// WebhookDelivery keeps payload as it was sent, so redelivery sends the same event.
+type WebhookDelivery struct {
+	Id				string		`json:"id" bson:"_id"`
+	WebhookId		string		`json:"webhook_id" bson:"webhookId"`
+	EventId			string		`json:"event_id" bson:"eventId"`
+	Event			string		`json:"event" bson:"event"`
+	Payload			string		`json:"payload" bson:"payload"`
+	Status			string		`json:"status" bson:"status"`
+	Attempts		int			`json:"attempts" bson:"attempts"`
+	ResponseStatus	int			`json:"response_status,omitempty" bson:"responseStatus,omitempty"`
+	Error			string		`json:"error,omitempty" bson:"error,omitempty"`
+	RedeliveryOf	string		`json:"redelivery_of,omitempty" bson:"redeliveryOf,omitempty"`
+	CreatedAt		time.Time	`json:"created_at" bson:"createdAt"`
+	UpdatedAt		time.Time	`json:"updated_at" bson:"updatedAt"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries	[]WebhookDelivery	`json:"deliveries"`
+}
@@ -0,0 +1,63 @@
+// Package outbound sends requests to URLs provided by users, such as webhooks, without
+// letting them reach the host and its internal networks.
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("connections to loopback, private and link-local addresses aren't allowed")
+
+// IsForbiddenIP reports addresses of the host and its internal networks, user provided
+// URLs must not reach them.
+func IsForbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsUnspecified()
+}
+
+// IsForbiddenHost reports hosts of URLs that point to forbidden addresses without resolving
+// them, names resolved to such addresses are refused by client at dial time.
+func IsForbiddenHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && IsForbiddenIP(ip)
+}
+
+// NewClient returns client for user provided URLs. Unless private addresses are allowed,
+// every address is checked after resolving right before connecting, so DNS names and
+// redirects can't lead it to internal services. Proxy isn't used, because the checked
+// address would be address of proxy.
+func NewClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = controlAddress
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+func controlAddress(network string, address string, conn syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || IsForbiddenIP(ip) {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package outbound
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+var isForbiddenHostTests = []struct {
+	host		string
+	forbidden	bool
+}{
+	{host: "localhost", forbidden: true},
+	{host: "api.localhost.", forbidden: true},
+	{host: "127.0.0.1", forbidden: true},
+	{host: "::1", forbidden: true},
+	{host: "10.1.2.3", forbidden: true},
+	{host: "172.16.0.1", forbidden: true},
+	{host: "192.168.1.1", forbidden: true},
+	{host: "169.254.169.254", forbidden: true},
+	{host: "fe80::1", forbidden: true},
+	{host: "fd00::1", forbidden: true},
+	{host: "0.0.0.0", forbidden: true},
+	{host: "::ffff:127.0.0.1", forbidden: true},
+	{host: "93.184.216.34", forbidden: false},
+	{host: "hooks.example.com", forbidden: false},
+}
+
+func TestIsForbiddenHost(t *testing.T) {
+	for i, test := range isForbiddenHostTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.host)
+
+		assert.Equal(t, IsForbiddenHost(test.host), test.forbidden, "wrong result for "+test.host)
+	}
+}
+
+func TestClientRefusesLoopback(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer receiver.Close()
+
+	_, err := NewClient(time.Second, false).Get(receiver.URL)
+	assert.Equal(t, errors.Is(err, ErrForbiddenAddress), true, "loopback address must be refused at dial time")
+
+	resp, err := NewClient(time.Second, true).Get(receiver.URL)
+	if err != nil {
+		t.Fatalf("Unable to reach receiver with private addresses allowed, error: %s\n", err)
+	}
+	resp.Body.Close()
+}
@@ -0,0 +1,160 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+const conformanceTimeout = 10 * time.Second
+
+type conformanceQueue interface {
+	Queue
+	Consumer
+}
+
+// runConformanceTests checks the behaviour every queue backend is expected to have,
+// newQueue must return a queue backed by a fresh stream or topic.
+func runConformanceTests(t *testing.T, newQueue func(t *testing.T) conformanceQueue) {
+	t.Run("Delivers key, value and headers", func(t *testing.T) {
+		q := newQueue(t)
+		sent := Message{
+			Key: []byte("index"),
+			Value: []byte(`{"index_name":"index"}`),
+			Headers: map[string]string{
+				HeaderContentType: ContentTypeJSON,
+				HeaderUserId: "1",
+			},
+		}
+		if err := q.WriteMessage(context.Background(), sent); err != nil {
+			t.Fatalf("Unable to write message, error: %s\n", err)
+		}
+
+		received := consumeMessages(t, q, 1, nil)
+
+		assert.Equal(t, string(received[0].Key), string(sent.Key), "wrong message key")
+		assert.Equal(t, string(received[0].Value), string(sent.Value), "wrong message value")
+		assert.Equal(t, received[0].Headers, sent.Headers, "wrong message headers")
+	})
+
+	t.Run("Preserves order of messages with the same key", func(t *testing.T) {
+		q := newQueue(t)
+		for i := 0; i < 20; i++ {
+			message := Message{Key: []byte("index"), Value: []byte(fmt.Sprint(i)), Headers: map[string]string{}}
+			if err := q.WriteMessage(context.Background(), message); err != nil {
+				t.Fatalf("Unable to write message, error: %s\n", err)
+			}
+		}
+
+		received := consumeMessages(t, q, 20, nil)
+
+		for i, message := range received {
+			assert.Equal(t, string(message.Value), fmt.Sprint(i), "wrong message order")
+		}
+	})
+
+	t.Run("Redelivers message when handler fails", func(t *testing.T) {
+		q := newQueue(t)
+		message := Message{Key: []byte("index"), Value: []byte("value"), Headers: map[string]string{}}
+		if err := q.WriteMessage(context.Background(), message); err != nil {
+			t.Fatalf("Unable to write message, error: %s\n", err)
+		}
+
+		failed := false
+		received := consumeMessages(t, q, 1, func() error {
+			if !failed {
+				failed = true
+				return errors.New("random error")
+			}
+			return nil
+		})
+
+		assert.Equal(t, failed, true, "handler wasn't called before redelivery")
+		assert.Equal(t, string(received[0].Value), "value", "wrong redelivered message")
+	})
+
+	t.Run("Drops message after max attempts", func(t *testing.T) {
+		q := newQueue(t)
+		for _, key := range []string{"failing", "next"} {
+			message := Message{Key: []byte(key), Value: []byte(key), Headers: map[string]string{}}
+			if err := q.WriteMessage(context.Background(), message); err != nil {
+				t.Fatalf("Unable to write message, error: %s\n", err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+		defer cancel()
+
+		var mu sync.Mutex
+		attempts := map[string]int{}
+		go q.Consume(ctx, func(ctx context.Context, message Message) error {
+			mu.Lock()
+			defer mu.Unlock()
+			attempts[string(message.Key)]++
+			if string(message.Key) == "failing" {
+				return errors.New("random error")
+			}
+			cancel()
+			return nil
+		})
+
+		<-ctx.Done()
+		mu.Lock()
+		defer mu.Unlock()
+		assert.Equal(t, attempts, map[string]int{"failing": maxDeliveryAttempts, "next": 1}, "wrong number of attempts")
+	})
+
+	t.Run("Stops consuming when context is cancelled", func(t *testing.T) {
+		q := newQueue(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- q.Consume(ctx, func(ctx context.Context, message Message) error { return nil })
+		}()
+
+		cancel()
+		select {
+		case <-done:
+		case <-time.After(conformanceTimeout):
+			t.Fatal("Consume didn't return after context cancellation")
+		}
+	})
+}
+
+// consumeMessages consumes until count messages were successfully handled, fail is
+// called before handling each message and its error is returned from the handler.
+func consumeMessages(t *testing.T, q conformanceQueue, count int, fail func() error) []Message {
+	ctx, cancel := context.WithTimeout(context.Background(), conformanceTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	received := []Message{}
+	go q.Consume(ctx, func(ctx context.Context, message Message) error {
+		if fail != nil {
+			if err := fail(); err != nil {
+				return err
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, message)
+		if len(received) == count {
+			cancel()
+		}
+		return nil
+	})
+
+	<-ctx.Done()
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) < count {
+		t.Fatalf("Received %d of %d messages before timeout\n", len(received), count)
+	}
+	return received
+}
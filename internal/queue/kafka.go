@@ -2,32 +2,99 @@ package queue
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/segmentio/kafka-go"
 )
 
+const (
+	BalancerHash		= "hash"
+	BalancerMurmur2		= "murmur2"
+	BalancerCRC32		= "crc32"
+	BalancerRoundRobin	= "round_robin"
+	BalancerLeastBytes	= "least_bytes"
+)
+
+type KafkaWriterOptions struct {
+	BatchSize		int
+	BatchBytes		int64
+	BatchTimeout	time.Duration
+	Compression		string
+	RequiredAcks	string
+	Balancer		string
+}
+
 type KafkaQueue struct {
 	Writer	*kafka.Writer
 }
 
-func NewKafkaQueue(ctx context.Context, addr []string, topic string) (*KafkaQueue, error) {
+func NewKafkaQueue(ctx context.Context, addr []string, topic string, opts KafkaWriterOptions) (*KafkaQueue, error) {
 	writer := &kafka.Writer{
 		Addr: kafka.TCP(addr...),
 		Topic: topic,
 		AllowAutoTopicCreation: true,
+		BatchSize: opts.BatchSize,
+		BatchBytes: opts.BatchBytes,
+		BatchTimeout: opts.BatchTimeout,
+	}
+
+	if opts.Compression != "" {
+		if err := writer.Compression.UnmarshalText([]byte(opts.Compression)); err != nil {
+			log.Errorf("Error configuring kafka writer compression: %s", err)
+			return nil, err
+		}
+	}
+
+	if opts.RequiredAcks != "" {
+		if err := writer.RequiredAcks.UnmarshalText([]byte(opts.RequiredAcks)); err != nil {
+			log.Errorf("Error configuring kafka writer required acks: %s", err)
+			return nil, err
+		}
+	}
+
+	balancer, err := newKafkaBalancer(opts.Balancer)
+	if err != nil {
+		log.Errorf("Error configuring kafka writer balancer: %s", err)
+		return nil, err
 	}
+	writer.Balancer = balancer
 
 	return &KafkaQueue{Writer: writer}, nil
 }
 
-func (s *KafkaQueue) WriteMessage(ctx context.Context, message []byte) error {
-	err := s.Writer.WriteMessages(ctx,
-		kafka.Message{Value: message},
-	)
+// Messages are keyed by index name, so the balancer must take the key into account
+// to keep messages for the same index on one partition. Hash is used by default for that reason.
+func newKafkaBalancer(name string) (kafka.Balancer, error) {
+	switch name {
+	case "", BalancerHash:
+		return &kafka.Hash{}, nil
+	case BalancerMurmur2:
+		return kafka.Murmur2Balancer{}, nil
+	case BalancerCRC32:
+		return kafka.CRC32Balancer{}, nil
+	case BalancerRoundRobin:
+		return &kafka.RoundRobin{}, nil
+	case BalancerLeastBytes:
+		return &kafka.LeastBytes{}, nil
+	}
+	return nil, fmt.Errorf("unknown balancer %q", name)
+}
+
+func (s *KafkaQueue) WriteMessage(ctx context.Context, message Message) error {
+	kafkaMessage := kafka.Message{
+		Key: message.Key,
+		Value: message.Value,
+	}
+	for key, value := range message.Headers {
+		kafkaMessage.Headers = append(kafkaMessage.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+
+	err := s.Writer.WriteMessages(ctx, kafkaMessage)
 	if err != nil {
-		log.Errorf("Error writing message to kafka queue: %s", err)
+		log.Errorf("Error writing message with key %s to kafka queue: %s", message.Key, err)
 	}
 
 	return err
-}
\ No newline at end of file
+}
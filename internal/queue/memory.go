@@ -0,0 +1,66 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const memoryQueueRetryDelay = time.Second
+
+// MemoryQueue is a channel backed queue for single binary setups where
+// messages are consumed by the same process that produces them.
+type MemoryQueue struct {
+	messages	chan Message
+	retryDelay	time.Duration
+}
+
+func NewMemoryQueue(size int) *MemoryQueue {
+	return &MemoryQueue{messages: make(chan Message, size), retryDelay: memoryQueueRetryDelay}
+}
+
+func (q *MemoryQueue) WriteMessage(ctx context.Context, message Message) error {
+	select {
+	case q.messages <- message:
+		return nil
+	case <-ctx.Done():
+		log.Errorf("Error writing message with key %s to memory queue: %s", message.Key, ctx.Err())
+		return ctx.Err()
+	}
+}
+
+func (q *MemoryQueue) Consume(ctx context.Context, handler Handler) error {
+	for {
+		select {
+		case message := <-q.messages:
+			if err := q.handle(ctx, message, handler); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handle retries message until it is handled or attempts run out, only cancelled
+// context is returned as error.
+func (q *MemoryQueue) handle(ctx context.Context, message Message, handler Handler) error {
+	for attempt := 1; ; attempt++ {
+		err := handler(ctx, message)
+		if err == nil {
+			return nil
+		}
+		if attempt >= maxDeliveryAttempts {
+			log.Errorf("Error handling message with key %s from memory queue, dropping it after %d attempts: %s", message.Key, attempt, err)
+			return nil
+		}
+
+		log.Errorf("Error handling message with key %s from memory queue, retrying: %s", message.Key, err)
+		select {
+		case <-time.After(q.retryDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package queue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMemoryQueueConformance(t *testing.T) {
+	runConformanceTests(t, func(t *testing.T) conformanceQueue {
+		q := NewMemoryQueue(100)
+		q.retryDelay = time.Millisecond
+		return q
+	})
+}
@@ -0,0 +1,134 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	log "github.com/sirupsen/logrus"
+)
+
+// NATS has no notion of message keys, so the key travels in a dedicated header.
+const natsKeyHeader = "Nats-Msg-Key"
+
+const natsQueueRetryDelay = time.Second
+
+type NatsQueue struct {
+	conn		*nats.Conn
+	js			jetstream.JetStream
+	stream		string
+	subject		string
+	durable		string
+	retryDelay	time.Duration
+}
+
+func NewNatsQueue(ctx context.Context, url string, stream string, subject string, durable string) (*NatsQueue, error) {
+	log.Infof("Connecting to nats on %s", url)
+	conn, err := nats.Connect(url)
+	if err != nil {
+		log.Errorf("Error connecting to nats on %s: %s", url, err)
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		log.Errorf("Error initializing nats jetstream context: %s", err)
+		conn.Close()
+		return nil, err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name: stream,
+		Subjects: []string{subject},
+	})
+	if err != nil {
+		log.Errorf("Error creating nats jetstream stream %s for subject %s: %s", stream, subject, err)
+		conn.Close()
+		return nil, err
+	}
+
+	return &NatsQueue{conn: conn, js: js, stream: stream, subject: subject, durable: durable, retryDelay: natsQueueRetryDelay}, nil
+}
+
+func (q *NatsQueue) WriteMessage(ctx context.Context, message Message) error {
+	natsMessage := nats.NewMsg(q.subject)
+	natsMessage.Data = message.Value
+	natsMessage.Header.Set(natsKeyHeader, string(message.Key))
+	for key, value := range message.Headers {
+		natsMessage.Header.Set(key, value)
+	}
+
+	_, err := q.js.PublishMsg(ctx, natsMessage)
+	if err != nil {
+		log.Errorf("Error publishing message with key %s to nats subject %s: %s", message.Key, q.subject, err)
+	}
+
+	return err
+}
+
+func (q *NatsQueue) Consume(ctx context.Context, handler Handler) error {
+	consumer, err := q.js.CreateOrUpdateConsumer(ctx, q.stream, jetstream.ConsumerConfig{
+		Durable: q.durable,
+		AckPolicy: jetstream.AckExplicitPolicy,
+		// one message in flight at a time keeps messages with the same key in order
+		MaxAckPending: 1,
+	})
+	if err != nil {
+		log.Errorf("Error creating nats consumer %s on stream %s: %s", q.durable, q.stream, err)
+		return err
+	}
+
+	messages, err := consumer.Messages()
+	if err != nil {
+		log.Errorf("Error subscribing to nats consumer %s on stream %s: %s", q.durable, q.stream, err)
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		messages.Stop()
+	}()
+
+	for {
+		natsMessage, err := messages.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				return ctx.Err()
+			}
+			log.Errorf("Error receiving message from nats consumer %s: %s", q.durable, err)
+			if err := sleep(ctx, q.retryDelay); err != nil {
+				return err
+			}
+			continue
+		}
+
+		message := Message{
+			Key: []byte(natsMessage.Headers().Get(natsKeyHeader)),
+			Value: natsMessage.Data(),
+			Headers: map[string]string{},
+		}
+		for key := range natsMessage.Headers() {
+			if key != natsKeyHeader {
+				message.Headers[key] = natsMessage.Headers().Get(key)
+			}
+		}
+
+		if err := handler(ctx, message); err != nil {
+			metadata, metadataErr := natsMessage.Metadata()
+			if metadataErr == nil && metadata.NumDelivered >= maxDeliveryAttempts {
+				log.Errorf("Error handling message with key %s from nats consumer %s, dropping it after %d attempts: %s", message.Key, q.durable, metadata.NumDelivered, err)
+				natsMessage.Term()
+				continue
+			}
+			log.Errorf("Error handling message with key %s from nats consumer %s, retrying: %s", message.Key, q.durable, err)
+			natsMessage.NakWithDelay(q.retryDelay)
+			continue
+		}
+		natsMessage.Ack()
+	}
+}
+
+func (q *NatsQueue) Close() {
+	q.conn.Close()
+}
@@ -0,0 +1,42 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+)
+
+func TestNatsQueueConformance(t *testing.T) {
+	natsServer, err := server.NewServer(&server.Options{
+		Host: "127.0.0.1",
+		Port: server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir: t.TempDir(),
+		NoLog: true,
+		NoSigs: true,
+	})
+	if err != nil {
+		t.Fatalf("Unable to create nats server, error: %s\n", err)
+	}
+	go natsServer.Start()
+	defer natsServer.Shutdown()
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		t.Fatal("Nats server isn't ready for connections")
+	}
+
+	streams := 0
+	runConformanceTests(t, func(t *testing.T) conformanceQueue {
+		streams++
+		stream := fmt.Sprintf("documents-%d", streams)
+		q, err := NewNatsQueue(context.Background(), natsServer.ClientURL(), stream, stream, "search-api")
+		if err != nil {
+			t.Fatalf("Unable to create nats queue, error: %s\n", err)
+		}
+		q.retryDelay = time.Millisecond
+		t.Cleanup(q.Close)
+		return q
+	})
+}
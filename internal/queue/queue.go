@@ -2,6 +2,41 @@ package queue
 
 import "context"
 
+const (
+	HeaderContentType		= "content-type"
+	HeaderSchemaVersion		= "schema-version"
+	HeaderUserId			= "user-id"
+	HeaderRequestId			= "request-id"
+
+	ContentTypeJSON					= "application/json"
+	IndexingMessageSchemaVersion	= "1"
+)
+
+const (
+	BackendKafka	= "kafka"
+	BackendMemory	= "memory"
+	BackendNats		= "nats"
+	BackendRedis	= "redis"
+)
+
+// Messages failing this many times are dropped so they don't block messages behind them
+const maxDeliveryAttempts = 5
+
+type Message struct {
+	Key			[]byte
+	Value		[]byte
+	Headers		map[string]string
+}
+
+// Handler processes a single consumed message. Returning an error means the message
+// wasn't processed and backends that support it will redeliver it.
+type Handler func(ctx context.Context, message Message) error
+
 type Queue interface {
-	WriteMessage(ctx context.Context, message []byte) error
-}
\ No newline at end of file
+	WriteMessage(ctx context.Context, message Message) error
+}
+
+type Consumer interface {
+	// Consume blocks delivering messages to handler until ctx is cancelled.
+	Consume(ctx context.Context, handler Handler) error
+}
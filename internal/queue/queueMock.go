@@ -4,8 +4,10 @@ import "context"
 
 type QueueMock struct {
 	Error		error
+	Messages	[]Message
 }
 
-func (qm *QueueMock) WriteMessage(ctx context.Context, message []byte) error {
+func (qm *QueueMock) WriteMessage(ctx context.Context, message Message) error {
+	qm.Messages = append(qm.Messages, message)
 	return qm.Error
-}
\ No newline at end of file
+}
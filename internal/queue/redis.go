@@ -0,0 +1,157 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	redisKeyField		= "key"
+	redisValueField		= "value"
+	redisHeadersField	= "headers"
+
+	redisQueueRetryDelay	= time.Second
+)
+
+type RedisQueue struct {
+	client		*redis.Client
+	stream		string
+	group		string
+	consumer	string
+	retryDelay	time.Duration
+}
+
+func NewRedisQueue(ctx context.Context, addr string, password string, db int, stream string, group string, consumer string) (*RedisQueue, error) {
+	log.Infof("Connecting to redis on %s", addr)
+	client := redis.NewClient(&redis.Options{
+		Addr: addr,
+		Password: password,
+		DB: db,
+	})
+
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Errorf("Error connecting to redis on %s: %s", addr, err)
+		return nil, err
+	}
+
+	return &RedisQueue{client: client, stream: stream, group: group, consumer: consumer, retryDelay: redisQueueRetryDelay}, nil
+}
+
+func (q *RedisQueue) WriteMessage(ctx context.Context, message Message) error {
+	headers, err := json.Marshal(message.Headers)
+	if err != nil {
+		log.Errorf("Error marshalling headers of message with key %s: %s", message.Key, err)
+		return err
+	}
+
+	err = q.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: q.stream,
+		Values: map[string]any{
+			redisKeyField: message.Key,
+			redisValueField: message.Value,
+			redisHeadersField: headers,
+		},
+	}).Err()
+	if err != nil {
+		log.Errorf("Error adding message with key %s to redis stream %s: %s", message.Key, q.stream, err)
+	}
+
+	return err
+}
+
+func (q *RedisQueue) Consume(ctx context.Context, handler Handler) error {
+	err := q.client.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		log.Errorf("Error creating consumer group %s on redis stream %s: %s", q.group, q.stream, err)
+		return err
+	}
+
+	// Pending messages of this consumer (read but not acknowledged before a restart
+	// or a handler error) are processed first, then new ones.
+	lastId := "0"
+	// failed attempts by message id, counting starts over after a restart
+	attempts := map[string]int{}
+	for {
+		streams, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group: q.group,
+			Consumer: q.consumer,
+			Streams: []string{q.stream, lastId},
+			Count: 1,
+			Block: time.Second,
+		}).Result()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			if !errors.Is(err, redis.Nil) {
+				log.Errorf("Error reading from redis stream %s as consumer %s: %s", q.stream, q.consumer, err)
+				if err := sleep(ctx, time.Second); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if lastId == "0" && len(streams[0].Messages) == 0 {
+			lastId = ">"
+			continue
+		}
+
+		for _, redisMessage := range streams[0].Messages {
+			message, err := redisStreamMessage(redisMessage)
+			if err != nil {
+				log.Errorf("Error decoding message %s from redis stream %s: %s", redisMessage.ID, q.stream, err)
+			} else if err := handler(ctx, message); err != nil {
+				attempts[redisMessage.ID]++
+				if attempts[redisMessage.ID] < maxDeliveryAttempts {
+					log.Errorf("Error handling message with key %s from redis stream %s, retrying: %s", message.Key, q.stream, err)
+					// keep message pending and retry it from the pending list
+					lastId = "0"
+					if err := sleep(ctx, q.retryDelay); err != nil {
+						return err
+					}
+					continue
+				}
+				log.Errorf("Error handling message with key %s from redis stream %s, dropping it after %d attempts: %s", message.Key, q.stream, attempts[redisMessage.ID], err)
+			}
+			delete(attempts, redisMessage.ID)
+
+			if err := q.client.XAck(ctx, q.stream, q.group, redisMessage.ID).Err(); err != nil {
+				log.Errorf("Error acknowledging message %s in redis stream %s: %s", redisMessage.ID, q.stream, err)
+			}
+		}
+	}
+}
+
+// sleep pauses consuming, it returns early with error when context is cancelled.
+func sleep(ctx context.Context, duration time.Duration) error {
+	select {
+	case <-time.After(duration):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func redisStreamMessage(redisMessage redis.XMessage) (Message, error) {
+	key, _ := redisMessage.Values[redisKeyField].(string)
+	value, _ := redisMessage.Values[redisValueField].(string)
+	headers, _ := redisMessage.Values[redisHeadersField].(string)
+
+	message := Message{Key: []byte(key), Value: []byte(value)}
+	if err := json.Unmarshal([]byte(headers), &message.Headers); err != nil {
+		return Message{}, err
+	}
+
+	return message, nil
+}
+
+func (q *RedisQueue) Close() error {
+	return q.client.Close()
+}
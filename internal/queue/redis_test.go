@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestRedisQueueConformance(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+
+	streams := 0
+	runConformanceTests(t, func(t *testing.T) conformanceQueue {
+		streams++
+		stream := fmt.Sprintf("documents-%d", streams)
+		q, err := NewRedisQueue(context.Background(), redisServer.Addr(), "", 0, stream, "search-api", "consumer-1")
+		if err != nil {
+			t.Fatalf("Unable to create redis queue, error: %s\n", err)
+		}
+		q.retryDelay = time.Millisecond
+		t.Cleanup(func() { q.Close() })
+		return q
+	})
+}
@@ -0,0 +1,315 @@
+// Package reindex copies indexes to their next physical versions in background and points
+// aliases of indexes to new versions once documents are copied, so indexes stay searchable
+// and writable while they are reindexed.
+package reindex
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+var ErrAlreadyRunning = errors.New("index is already being reindexed")
+
+// Documents are copied by indexing time set by ingestion, margin covers clock differences
+// between ingestion and the service, copying document once more is harmless
+const catchUpMargin = time.Minute
+
+// Steps after the swap are retried with growing delay up to maxRetryDelay
+const maxRetryDelay = time.Minute
+
+// Reindexer runs reindex tasks. Task is recorded before documents are copied and updated
+// after each step, tasks interrupted by restart are resumed.
+type Reindexer struct {
+	docStorage		storage.DocumentStorage
+	indexStorage	storage.IndexStorage
+	pollInterval	time.Duration
+	// mutex makes check for running task and start of new one atomic
+	mutex			sync.Mutex
+	wg				sync.WaitGroup
+	// ctx of background tasks is cancelled by Stop
+	ctx				context.Context
+	cancel			context.CancelFunc
+}
+
+func NewReindexer(docStorage storage.DocumentStorage, indexStorage storage.IndexStorage, pollInterval time.Duration) *Reindexer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Reindexer{
+		docStorage: docStorage,
+		indexStorage: indexStorage,
+		pollInterval: pollInterval,
+		ctx: ctx,
+		cancel: cancel,
+	}
+}
+
+// Start creates next version of the index with settings of the request and starts copying
+// documents to it, the rest of reindex runs in background.
+func (r *Reindexer) Start(ctx context.Context, index *models.IndexMetadata, request *models.ReindexRequest) (*models.ReindexTask, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	running, err := r.indexStorage.GetRunningReindexTasks(ctx, index.Name)
+	if err != nil {
+		return nil, err
+	}
+	if len(running) > 0 {
+		return nil, ErrAlreadyRunning
+	}
+
+	next := *index
+	next.Version = index.Version + 1
+	if request.Schema != nil {
+		next.Schema = request.Schema
+	}
+	if request.Analysis != nil {
+		next.Analysis = request.Analysis
+	}
+	// analyzers are always configured like for new indexes, so synonyms can be added later
+	if next.Analysis == nil {
+		next.Analysis = &models.IndexAnalysis{}
+	}
+
+	task := &models.ReindexTask{
+		Index: index.Name,
+		SourceIndex: storage.PhysicalIndexName(index.Name, index.Version),
+		DestIndex: storage.PhysicalIndexName(next.Name, next.Version),
+		Version: next.Version,
+		Status: models.ReindexStatusRunning,
+		Schema: next.Schema,
+		Analysis: next.Analysis,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := r.docStorage.NewIndexVersion(ctx, &next); err != nil {
+		return nil, err
+	}
+
+	// task is recorded before copying starts, so dest index is never left untracked
+	if err := r.indexStorage.CreateReindexTask(ctx, task); err != nil {
+		r.docStorage.DeleteIndex(ctx, task.DestIndex)
+		return nil, err
+	}
+
+	task.EsTaskId, err = r.docStorage.StartReindex(ctx, task.SourceIndex, task.DestIndex)
+	if err != nil {
+		r.fail(ctx, task, err)
+		return nil, err
+	}
+	r.update(ctx, task)
+
+	r.runInBackground(*task)
+	return task, nil
+}
+
+// UnlessRunning runs update of index settings that reindex would overwrite with settings
+// it started with. ErrAlreadyRunning is returned while the index is reindexed, and reindex
+// doesn't start until update finishes.
+func (r *Reindexer) UnlessRunning(ctx context.Context, indexName string, update func() error) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	running, err := r.indexStorage.GetRunningReindexTasks(ctx, indexName)
+	if err != nil {
+		return err
+	}
+	if len(running) > 0 {
+		return ErrAlreadyRunning
+	}
+	return update()
+}
+
+// Resume continues tasks interrupted by restart, tasks interrupted before copying started fail.
+func (r *Reindexer) Resume(ctx context.Context) {
+	tasks, err := r.indexStorage.GetRunningReindexTasks(ctx, "")
+	if err != nil {
+		log.Errorf("Error getting running reindex tasks to resume: %s", err)
+		return
+	}
+
+	for _, task := range tasks {
+		if task.EsTaskId == "" && !task.Swapped {
+			r.fail(ctx, &task, errors.New("reindex was interrupted before copying started"))
+			continue
+		}
+		log.Infof("Resuming reindex of index %s to %s", task.Index, task.DestIndex)
+		r.runInBackground(task)
+	}
+}
+
+// Wait blocks until all started tasks finish.
+func (r *Reindexer) Wait() {
+	r.wg.Wait()
+}
+
+// Stop interrupts running tasks, they stay running and are resumed after restart.
+func (r *Reindexer) Stop() {
+	r.cancel()
+}
+
+// runInBackground doesn't use request context, reindex outlives request that started it.
+func (r *Reindexer) runInBackground(task models.ReindexTask) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		err := r.run(r.ctx, &task)
+		if err != nil && r.ctx.Err() != nil {
+			log.Infof("Reindex of index %s to %s is interrupted, it will be resumed after restart", task.Index, task.DestIndex)
+			return
+		}
+		if err != nil {
+			r.fail(context.Background(), &task, err)
+		}
+	}()
+}
+
+func (r *Reindexer) run(ctx context.Context, task *models.ReindexTask) error {
+	if !task.Swapped {
+		if err := r.waitForCopy(ctx, task); err != nil {
+			return err
+		}
+
+		// documents indexed while copying aren't in dest index or are outdated there
+		catchUpStart := time.Now().UTC()
+		if err := r.docStorage.CatchUpReindex(ctx, task.SourceIndex, task.DestIndex, task.CreatedAt.Add(-catchUpMargin), true); err != nil {
+			return err
+		}
+
+		if err := r.swap(ctx, task, catchUpStart.Add(-catchUpMargin)); err != nil {
+			return err
+		}
+	}
+
+	// alias already points to dest index, so the task can't fail anymore and steps left
+	// are retried until they succeed or the task is interrupted
+	if err := r.retry(ctx, task, func() error {
+		return r.indexStorage.UpdateIndexVersion(ctx, task.Index, task.Version, task.Schema, task.Analysis)
+	}); err != nil {
+		return err
+	}
+
+	// unversioned source index was deleted by the swap
+	if task.SourceIndex != task.Index {
+		// writes to source index made between catch up and the swap, documents written
+		// to dest index after the swap are newer, so they are kept
+		if err := r.retry(ctx, task, func() error {
+			return r.docStorage.CatchUpReindex(ctx, task.SourceIndex, task.DestIndex, task.CreatedAt.Add(-catchUpMargin), false)
+		}); err != nil {
+			return err
+		}
+		if err := r.docStorage.DeleteIndex(ctx, task.SourceIndex); err != nil {
+			log.Warningf("Old version %s of reindexed index %s wasn't deleted: %s", task.SourceIndex, task.Index, err)
+		}
+	}
+
+	completedAt := time.Now().UTC()
+	task.Status, task.CompletedAt = models.ReindexStatusCompleted, &completedAt
+	r.update(ctx, task)
+	log.Infof("Index %s is reindexed to %s", task.Index, task.DestIndex)
+	return nil
+}
+
+// retry runs step until it succeeds, error is returned only if ctx is done.
+func (r *Reindexer) retry(ctx context.Context, task *models.ReindexTask, step func() error) error {
+	delay := r.pollInterval
+	for {
+		err := step()
+		if err == nil {
+			return nil
+		}
+		log.Errorf("Error finishing reindex of index %s to %s, retrying in %s: %s", task.Index, task.DestIndex, delay, err)
+
+		if !pause(ctx, delay) {
+			return ctx.Err()
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+}
+
+// waitForCopy polls ES task copying documents and records its progress.
+func (r *Reindexer) waitForCopy(ctx context.Context, task *models.ReindexTask) error {
+	for {
+		progress, err := r.docStorage.GetReindexProgress(ctx, task.EsTaskId)
+		if err != nil {
+			return err
+		}
+
+		if progress.Total != task.Total || progress.Copied != task.Copied {
+			task.Total, task.Copied = progress.Total, progress.Copied
+			r.update(ctx, task)
+		}
+
+		if progress.Completed {
+			if progress.Error != "" {
+				return errors.New(progress.Error)
+			}
+			return nil
+		}
+		if !pause(ctx, r.pollInterval) {
+			return ctx.Err()
+		}
+	}
+}
+
+// pause waits for duration, false is returned if ctx is done before it passes.
+func pause(ctx context.Context, duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// swap points alias to dest index. Alias can't be added while unversioned source index
+// named as it exists, so the swap deletes that index and writes to it are blocked while
+// documents indexed since catch up are copied, otherwise they would be lost.
+func (r *Reindexer) swap(ctx context.Context, task *models.ReindexTask, since time.Time) error {
+	if task.SourceIndex == task.Index {
+		if err := r.docStorage.SetIndexWriteBlock(ctx, task.SourceIndex, true); err != nil {
+			return err
+		}
+		if err := r.docStorage.CatchUpReindex(ctx, task.SourceIndex, task.DestIndex, since, true); err != nil {
+			return err
+		}
+	}
+
+	if err := r.docStorage.SwapIndexAlias(ctx, task.Index, task.SourceIndex, task.DestIndex); err != nil {
+		return err
+	}
+
+	task.Swapped = true
+	r.update(ctx, task)
+	return nil
+}
+
+// fail records error of the task. Until alias is swapped index keeps using source index,
+// so dest index is deleted and writes to source are allowed again.
+func (r *Reindexer) fail(ctx context.Context, task *models.ReindexTask, err error) {
+	log.Errorf("Error reindexing index %s to %s: %s", task.Index, task.DestIndex, err)
+
+	if !task.Swapped {
+		if task.SourceIndex == task.Index {
+			r.docStorage.SetIndexWriteBlock(ctx, task.SourceIndex, false)
+		}
+		r.docStorage.DeleteIndex(ctx, task.DestIndex)
+	}
+
+	completedAt := time.Now().UTC()
+	task.Status, task.Error, task.CompletedAt = models.ReindexStatusFailed, err.Error(), &completedAt
+	r.update(ctx, task)
+}
+
+func (r *Reindexer) update(ctx context.Context, task *models.ReindexTask) {
+	if err := r.indexStorage.UpdateReindexTask(ctx, task); err != nil {
+		log.Errorf("Error recording state of reindex task %s of index %s: %s", task.Id, task.Index, err)
+	}
+}
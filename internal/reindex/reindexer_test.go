@@ -0,0 +1,166 @@
+package reindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+var reindexTests = []struct {
+	testName				string
+	index					*models.IndexMetadata
+	docStorage				*storage.DocStorageMock
+	expectedStatus			string
+	expectedError			string
+	expectedCatchUps		[]bool
+	expectedWriteBlocks		[]bool
+	expectedSwapped			[]string
+	expectedDeleted			[]string
+	expectedVersion			int
+}{
+	{
+		testName: "Swap alias and delete old version",
+		index: &models.IndexMetadata{Name: "test", Version: 1},
+		docStorage: &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true, Total: 3, Copied: 3}},
+		expectedStatus: models.ReindexStatusCompleted,
+		expectedCatchUps: []bool{true, false},
+		expectedSwapped: []string{"test~v1", "test~v2"},
+		expectedDeleted: []string{"test~v1"},
+		expectedVersion: 2,
+	},
+	{
+		testName: "Block writes to index without version until it's replaced by alias",
+		index: &models.IndexMetadata{Name: "test"},
+		docStorage: &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true, Total: 3, Copied: 3}},
+		expectedStatus: models.ReindexStatusCompleted,
+		expectedCatchUps: []bool{true, true},
+		expectedWriteBlocks: []bool{true},
+		expectedSwapped: []string{"test", "test~v1"},
+		expectedVersion: 1,
+	},
+	{
+		testName: "Delete new version if copying fails",
+		index: &models.IndexMetadata{Name: "test", Version: 1},
+		docStorage: &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true, Total: 3, Copied: 1, Error: "document a: failed to parse field"}},
+		expectedStatus: models.ReindexStatusFailed,
+		expectedError: "document a: failed to parse field",
+		expectedDeleted: []string{"test~v2"},
+	},
+	{
+		testName: "Allow writes to index without version if swap fails",
+		index: &models.IndexMetadata{Name: "test"},
+		docStorage: &storage.DocStorageMock{
+			ReindexProgress: &models.ReindexProgress{Completed: true, Total: 3, Copied: 3},
+			SwapError: errors.New("random error"),
+		},
+		expectedStatus: models.ReindexStatusFailed,
+		expectedError: "random error",
+		expectedCatchUps: []bool{true, true},
+		expectedWriteBlocks: []bool{true, false},
+		expectedDeleted: []string{"test~v1"},
+	},
+}
+
+func TestReindex(t *testing.T) {
+	for i, test := range reindexTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		indexStorage := &storage.IndexStorageMock{}
+		reindexer := NewReindexer(test.docStorage, indexStorage, time.Millisecond)
+
+		task, err := reindexer.Start(context.TODO(), test.index, &models.ReindexRequest{})
+		if err != nil {
+			t.Fatalf("Unable to start reindex, error: %s\n", err)
+		}
+		reindexer.Wait()
+
+		final := indexStorage.ReindexTaskUpdates[len(indexStorage.ReindexTaskUpdates)-1]
+		assert.Equal(t, task.Status, models.ReindexStatusRunning, "started task must be running")
+		assert.Equal(t, final.Status, test.expectedStatus, "wrong final task status")
+		assert.Equal(t, final.Error, test.expectedError, "wrong task error")
+		assert.Equal(t, final.Copied, test.docStorage.ReindexProgress.Copied, "wrong copied documents")
+		assert.Equal(t, test.docStorage.CatchUps, test.expectedCatchUps, "wrong catch ups")
+		assert.Equal(t, test.docStorage.WriteBlocks, test.expectedWriteBlocks, "wrong write blocks")
+		assert.Equal(t, test.docStorage.SwappedIndexes, test.expectedSwapped, "wrong swapped indexes")
+		assert.Equal(t, test.docStorage.DeletedIndexes, test.expectedDeleted, "wrong deleted indexes")
+		assert.Equal(t, indexStorage.UpdatedVersion, test.expectedVersion, "wrong recorded index version")
+	}
+}
+
+func TestReindexKeepsSwappedTaskRunning(t *testing.T) {
+	docStorage := &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true, Total: 3, Copied: 3}}
+	indexStorage := &storage.IndexStorageMock{UpdateVersionError: errors.New("random error")}
+	reindexer := NewReindexer(docStorage, indexStorage, time.Millisecond)
+
+	_, err := reindexer.Start(context.TODO(), &models.IndexMetadata{Name: "test", Version: 1}, &models.ReindexRequest{})
+	if err != nil {
+		t.Fatalf("Unable to start reindex, error: %s\n", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	reindexer.Stop()
+	reindexer.Wait()
+
+	final := indexStorage.ReindexTaskUpdates[len(indexStorage.ReindexTaskUpdates)-1]
+	assert.Equal(t, final.Status, models.ReindexStatusRunning, "swapped task must stay running until it's resumed")
+	assert.Equal(t, final.Swapped, true, "task must be swapped")
+	assert.Equal(t, docStorage.DeletedIndexes == nil, true, "no version of swapped index must be deleted")
+}
+
+func TestReindexKeepsSettings(t *testing.T) {
+	docStorage := &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true}}
+	reindexer := NewReindexer(docStorage, &storage.IndexStorageMock{}, time.Millisecond)
+
+	index := &models.IndexMetadata{Name: "test", Version: 3, Fuzzy: &models.FuzzySettings{Fuzziness: "AUTO"}}
+	analysis := &models.IndexAnalysis{Language: "english"}
+	task, err := reindexer.Start(context.TODO(), index, &models.ReindexRequest{Analysis: analysis})
+	if err != nil {
+		t.Fatalf("Unable to start reindex, error: %s\n", err)
+	}
+	reindexer.Wait()
+
+	assert.Equal(t, task.Version, 4, "wrong task version")
+	assert.Equal(t, docStorage.IndexVersion.Version, 4, "wrong version of new index")
+	assert.Equal(t, docStorage.IndexVersion.Analysis, analysis, "new version must use analysis of the request")
+	assert.Equal(t, docStorage.IndexVersion.Fuzzy, index.Fuzzy, "new version must keep other settings")
+	assert.Equal(t, index.Version, 3, "current index metadata must not be changed")
+}
+
+func TestReindexAlreadyRunning(t *testing.T) {
+	docStorage := &storage.DocStorageMock{}
+	indexStorage := &storage.IndexStorageMock{RunningReindexTasks: []models.ReindexTask{{Id: "running", Index: "test"}}}
+	reindexer := NewReindexer(docStorage, indexStorage, time.Millisecond)
+
+	_, err := reindexer.Start(context.TODO(), &models.IndexMetadata{Name: "test", Version: 1}, &models.ReindexRequest{})
+	assert.Equal(t, err, ErrAlreadyRunning, "wrong error")
+	assert.Equal(t, docStorage.IndexVersion == nil, true, "new index version must not be created")
+}
+
+func TestResume(t *testing.T) {
+	docStorage := &storage.DocStorageMock{ReindexProgress: &models.ReindexProgress{Completed: true}}
+	indexStorage := &storage.IndexStorageMock{RunningReindexTasks: []models.ReindexTask{
+		{Id: "copying", Index: "a", SourceIndex: "a~v1", DestIndex: "a~v2", Version: 2, EsTaskId: "node:1", Status: models.ReindexStatusRunning},
+		{Id: "swapped", Index: "b", SourceIndex: "b~v1", DestIndex: "b~v2", Version: 2, EsTaskId: "node:2", Swapped: true, Status: models.ReindexStatusRunning},
+		{Id: "untracked", Index: "c", SourceIndex: "c~v1", DestIndex: "c~v2", Version: 2, Status: models.ReindexStatusRunning},
+	}}
+	reindexer := NewReindexer(docStorage, indexStorage, time.Millisecond)
+
+	reindexer.Resume(context.TODO())
+	reindexer.Wait()
+
+	statuses := map[string]string{}
+	for _, task := range indexStorage.ReindexTaskUpdates {
+		statuses[task.Id] = task.Status
+	}
+	assert.Equal(t, statuses, map[string]string{
+		"copying": models.ReindexStatusCompleted,
+		"swapped": models.ReindexStatusCompleted,
+		"untracked": models.ReindexStatusFailed,
+	}, "wrong task statuses")
+	assert.Equal(t, docStorage.SwappedIndexes, []string{"a~v1", "a~v2"}, "swapped task must not be swapped again")
+}
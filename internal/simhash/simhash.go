@@ -0,0 +1,104 @@
+// Package simhash computes locality sensitive signatures of texts, lightly edited copies of
+// the same text get signatures differing in few bits while unrelated texts differ in about
+// half of them.
+package simhash
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Number of consecutive words hashed together, single changed word affects only shingles
+// containing it
+const shingleSize = 2
+
+// Bands split signature into 8 bit parts, signatures within distance less than Bands share
+// at least one band, so bands find candidates for any distance up to MaxDistance
+const (
+	Bands		= 8
+	bandBits	= 64 / Bands
+	MaxDistance	= Bands - 1
+)
+
+// Compute returns signature of words of the text, case and punctuation are ignored.
+// Text without words has zero signature.
+func Compute(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	var weights [64]int
+	add := func(feature []string) {
+		hash := fnv.New64a()
+		hash.Write([]byte(strings.Join(feature, " ")))
+		sum := mix(hash.Sum64())
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	if len(words) < shingleSize {
+		add(words)
+	}
+	for i := 0; i+shingleSize <= len(words); i++ {
+		add(words[i : i+shingleSize])
+	}
+
+	var signature uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			signature |= 1 << bit
+		}
+	}
+	return signature
+}
+
+// mix spreads bits of FNV hash, FNV of similar short strings differs mostly in low bits.
+// It is the finalizer of splitmix64.
+func mix(hash uint64) uint64 {
+	hash ^= hash >> 30
+	hash *= 0xbf58476d1ce4e5b9
+	hash ^= hash >> 27
+	hash *= 0x94d049bb133111eb
+	hash ^= hash >> 31
+	return hash
+}
+
+// Distance is the number of bits signatures differ in.
+func Distance(a uint64, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Format returns signature as 16 hex digits.
+func Format(signature uint64) string {
+	return fmt.Sprintf("%016x", signature)
+}
+
+func Parse(value string) (uint64, error) {
+	if len(value) != 16 {
+		return 0, fmt.Errorf("signature %q isn't 16 hex digits", value)
+	}
+	return strconv.ParseUint(value, 16, 64)
+}
+
+// BandTokens returns bands of signature prefixed with their position, tokens are
+// alphanumeric, so they stay single terms even in analyzed text fields.
+func BandTokens(signature uint64) []string {
+	tokens := make([]string, 0, Bands)
+	for band := 0; band < Bands; band++ {
+		value := (signature >> (band * bandBits)) & (1<<bandBits - 1)
+		tokens = append(tokens, fmt.Sprintf("%d%02x", band, value))
+	}
+	return tokens
+}
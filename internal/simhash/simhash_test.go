@@ -0,0 +1,89 @@
+package simhash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+const testArticle = `City council approved the new budget on Tuesday after a long debate about public transport.
+The plan adds two bus lines connecting northern districts with the central station and extends
+night service on weekends. Council members also agreed to repair bridges over the river, which
+were closed for heavy trucks last winter. Opposition criticized rising parking fees, saying that
+small shops in the old town lose customers, while the mayor answered that fees fund cleaner
+streets and new bicycle lanes. The budget will be published next week and takes effect in March.`
+
+var distanceTests = []struct {
+	testName		string
+	a				string
+	b				string
+	maxDistance		int
+	minDistance		int
+}{
+	{
+		testName: "Ignore case, punctuation and whitespace",
+		a: testArticle,
+		b: strings.ToUpper(strings.ReplaceAll(testArticle, ",", " ")),
+		maxDistance: 0,
+		minDistance: 0,
+	},
+	{
+		testName: "Keep lightly edited copy close",
+		a: testArticle,
+		b: strings.NewReplacer("Tuesday", "Wednesday", "two bus", "three bus", "March", "April").Replace(testArticle),
+		maxDistance: MaxDistance,
+		minDistance: 1,
+	},
+	{
+		testName: "Keep unrelated text far",
+		a: testArticle,
+		b: `Researchers trained a small language model on recipes and found that it invents dishes
+combining chocolate with pickled fish. The team published the dataset, so other groups can
+check whether larger models repeat the mistake or learn which flavors actually go together.`,
+		maxDistance: 64,
+		minDistance: 16,
+	},
+}
+
+func TestDistance(t *testing.T) {
+	for i, test := range distanceTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		distance := Distance(Compute(test.a), Compute(test.b))
+		assert.Equal(t, distance <= test.maxDistance && distance >= test.minDistance, true, fmt.Sprintf("distance %d out of range", distance))
+	}
+}
+
+func TestComputeEmptyText(t *testing.T) {
+	assert.Equal(t, Compute(" ,. "), uint64(0), "wrong signature of text without words")
+	assert.Equal(t, Compute("one") != 0, true, "single word has no signature")
+}
+
+func TestFormatParse(t *testing.T) {
+	signature := Compute(testArticle)
+	parsed, err := Parse(Format(signature))
+	assert.Equal(t, err, nil, "unexpected error")
+	assert.Equal(t, parsed, signature, "wrong parsed signature")
+
+	_, err = Parse("abc")
+	assert.Equal(t, err != nil, true, "short signature is parsed")
+}
+
+func TestBandTokens(t *testing.T) {
+	tokens := BandTokens(0x0123456789abcdef)
+	assert.Equal(t, tokens, []string{"0ef", "1cd", "2ab", "389", "467", "545", "623", "701"}, "wrong band tokens")
+
+	// signatures within MaxDistance share at least one band
+	a := Compute(testArticle)
+	b := a ^ 0x0101010101010100
+	shared := 0
+	bTokens := BandTokens(b)
+	for i, token := range BandTokens(a) {
+		if token == bTokens[i] {
+			shared++
+		}
+	}
+	assert.Equal(t, shared, 1, "wrong number of shared bands")
+}
@@ -0,0 +1,385 @@
+// Package simplequery normalizes search box input to Elasticsearch simple_query_string
+// syntax, so that whatever users type produces a valid query limited to allowed operators.
+package simplequery
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	OperatorAnd			= "AND"
+	OperatorOr			= "OR"
+	OperatorNot			= "NOT"
+	OperatorPhrase		= "PHRASE"
+	OperatorPrefix		= "PREFIX"
+	OperatorPrecedence	= "PRECEDENCE"
+)
+
+// Characters that have special meaning in ES query syntaxes and are never passed through
+const specialChars = "\\/:{}[]^~<>=!&|+\"()"
+
+type Operators struct {
+	And			bool
+	Or			bool
+	Not			bool
+	Phrase		bool
+	Prefix		bool
+	Precedence	bool
+}
+
+// ParseOperators builds allowed operators from their simple_query_string flag names.
+func ParseOperators(names []string) (Operators, error) {
+	var operators Operators
+	for _, name := range names {
+		switch strings.ToUpper(strings.TrimSpace(name)) {
+		case OperatorAnd:
+			operators.And = true
+		case OperatorOr:
+			operators.Or = true
+		case OperatorNot:
+			operators.Not = true
+		case OperatorPhrase:
+			operators.Phrase = true
+		case OperatorPrefix:
+			operators.Prefix = true
+		case OperatorPrecedence:
+			operators.Precedence = true
+		case "":
+		default:
+			return Operators{}, fmt.Errorf("unknown simple query operator %q", name)
+		}
+	}
+	return operators, nil
+}
+
+// Flags returns simple_query_string flags matching allowed operators, whitespace is always allowed.
+func (o Operators) Flags() string {
+	flags := []string{"WHITESPACE"}
+	for _, operator := range []struct {
+		allowed	bool
+		name	string
+	}{
+		{o.And, OperatorAnd},
+		{o.Or, OperatorOr},
+		{o.Not, OperatorNot},
+		{o.Phrase, OperatorPhrase},
+		{o.Prefix, OperatorPrefix},
+		{o.Precedence, OperatorPrecedence},
+	} {
+		if operator.allowed {
+			flags = append(flags, operator.name)
+		}
+	}
+	return strings.Join(flags, "|")
+}
+
+type Result struct {
+	Query		string
+	Ignored		[]string
+}
+
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenPhrase
+	tokenOpen
+	tokenClose
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind	tokenKind
+	text	string
+	// original is what user typed, it is reported when token is ignored
+	original	string
+}
+
+type normalizer struct {
+	operators	Operators
+	ignored		[]string
+	seen		map[string]bool
+}
+
+func (n *normalizer) ignore(text string) {
+	if text != "" && !n.seen[text] {
+		n.seen[text] = true
+		n.ignored = append(n.ignored, text)
+	}
+}
+
+// Normalize rewrites input to simple_query_string syntax using only allowed operators.
+// Everything that can't be used (field qualifiers, unbalanced quotes and parentheses,
+// dangling or disallowed operators, special characters) is dropped and reported as ignored.
+func Normalize(input string, operators Operators) Result {
+	n := &normalizer{operators: operators, seen: map[string]bool{}}
+	tokens := n.balance(n.lex(input))
+	query := n.emit(tokens)
+	return Result{Query: query, Ignored: n.ignored}
+}
+
+func (n *normalizer) lex(input string) []token {
+	tokens := []token{}
+	runes := []rune(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '"':
+			end := indexRune(runes, '"', i+1)
+			if end < 0 {
+				n.ignore(`"`)
+				i++
+				continue
+			}
+			phrase := strings.Join(n.words(string(runes[i+1:end])), " ")
+			if phrase != "" {
+				if n.operators.Phrase {
+					tokens = append(tokens, token{kind: tokenPhrase, text: `"` + phrase + `"`})
+				} else {
+					n.ignore(`"`)
+					for _, word := range strings.Fields(phrase) {
+						tokens = append(tokens, token{kind: tokenTerm, text: word})
+					}
+				}
+			}
+			i = end + 1
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen, text: "(", original: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose, text: ")", original: ")"})
+			i++
+		case r == '+':
+			tokens = append(tokens, token{kind: tokenAnd, original: "+"})
+			i++
+		case r == '|':
+			tokens = append(tokens, token{kind: tokenOr, original: "|"})
+			i++
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, token{kind: tokenNot, original: "-"})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune(`"()`, runes[i]) {
+				i++
+			}
+			tokens = append(tokens, n.wordToken(string(runes[start:i]))...)
+		}
+	}
+
+	return tokens
+}
+
+func (n *normalizer) wordToken(word string) []token {
+	switch word {
+	case "AND", "&&":
+		return []token{{kind: tokenAnd, original: word}}
+	case "OR", "||":
+		return []token{{kind: tokenOr, original: word}}
+	case "NOT", "!":
+		return []token{{kind: tokenNot, original: word}}
+	}
+
+	// field qualifiers like title:foo would let users query fields they shouldn't
+	if colon := strings.LastIndex(word, ":"); colon >= 0 {
+		n.ignore(word[:colon+1])
+		word = word[colon+1:]
+	}
+
+	prefix := strings.HasSuffix(word, "*")
+	words := n.words(word)
+	if len(words) == 0 {
+		return nil
+	}
+
+	term := strings.Join(words, " ")
+	if prefix {
+		if n.operators.Prefix && len(words) == 1 {
+			term += "*"
+		} else {
+			n.ignore("*")
+		}
+	}
+
+	tokens := []token{}
+	for _, w := range strings.Fields(term) {
+		tokens = append(tokens, token{kind: tokenTerm, text: w})
+	}
+	return tokens
+}
+
+// words splits text by special characters removing them, hyphens are kept only inside words.
+func (n *normalizer) words(text string) []string {
+	words := []string{}
+	for _, word := range n.split(text) {
+		trimmed := strings.Trim(word, "-")
+		if trimmed != word {
+			n.ignore("-")
+		}
+		if trimmed != "" {
+			words = append(words, trimmed)
+		}
+	}
+	return words
+}
+
+func (n *normalizer) split(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		if unicode.IsSpace(r) {
+			return true
+		}
+		if r == '*' {
+			return true
+		}
+		if strings.ContainsRune(specialChars, r) {
+			n.ignore(string(r))
+			return true
+		}
+		return false
+	})
+}
+
+// balance drops unmatched and empty parentheses, or all of them when precedence isn't allowed.
+func (n *normalizer) balance(tokens []token) []token {
+	keep := make([]bool, len(tokens))
+	open := []int{}
+	for i, t := range tokens {
+		switch t.kind {
+		case tokenOpen:
+			open = append(open, i)
+		case tokenClose:
+			if len(open) == 0 {
+				n.ignore(")")
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if !n.operators.Precedence {
+				n.ignore("(")
+				n.ignore(")")
+				continue
+			}
+			if hasOperand(tokens[start+1 : i]) {
+				keep[start] = true
+				keep[i] = true
+			} else {
+				n.ignore("(")
+				n.ignore(")")
+			}
+		default:
+			keep[i] = true
+		}
+	}
+	if len(open) > 0 {
+		n.ignore("(")
+	}
+
+	balanced := []token{}
+	for i, t := range tokens {
+		if keep[i] {
+			balanced = append(balanced, t)
+		}
+	}
+	return balanced
+}
+
+func hasOperand(tokens []token) bool {
+	for _, t := range tokens {
+		if t.kind == tokenTerm || t.kind == tokenPhrase {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *normalizer) emit(tokens []token) string {
+	var query strings.Builder
+	// afterOperand is true when last emitted token can be followed by a binary operator
+	afterOperand := false
+	var pending *token
+	var negate *token
+
+	writeOperand := func(text string) {
+		if afterOperand {
+			switch {
+			case pending == nil:
+				query.WriteString(" ")
+			case pending.kind == tokenAnd:
+				query.WriteString(" + ")
+			case pending.kind == tokenOr:
+				query.WriteString(" | ")
+			}
+		} else if pending != nil {
+			n.ignore(pending.original)
+		}
+		if negate != nil {
+			query.WriteString("-")
+		}
+		query.WriteString(text)
+		pending, negate = nil, nil
+	}
+
+	for i := range tokens {
+		t := tokens[i]
+		switch t.kind {
+		case tokenAnd, tokenOr:
+			allowed := (t.kind == tokenAnd && n.operators.And) || (t.kind == tokenOr && n.operators.Or)
+			if !allowed || !afterOperand || negate != nil {
+				n.ignore(t.original)
+				continue
+			}
+			if pending != nil {
+				n.ignore(pending.original)
+			}
+			pending = &tokens[i]
+		case tokenNot:
+			if !n.operators.Not || negate != nil {
+				n.ignore(t.original)
+				continue
+			}
+			negate = &tokens[i]
+		case tokenOpen:
+			writeOperand("(")
+			afterOperand = false
+		case tokenClose:
+			if pending != nil {
+				n.ignore(pending.original)
+				pending = nil
+			}
+			if negate != nil {
+				n.ignore(negate.original)
+				negate = nil
+			}
+			query.WriteString(")")
+			afterOperand = true
+		default:
+			writeOperand(t.text)
+			afterOperand = true
+		}
+	}
+
+	if pending != nil {
+		n.ignore(pending.original)
+	}
+	if negate != nil {
+		n.ignore(negate.original)
+	}
+
+	return query.String()
+}
+
+func indexRune(runes []rune, r rune, from int) int {
+	for i := from; i < len(runes); i++ {
+		if runes[i] == r {
+			return i
+		}
+	}
+	return -1
+}
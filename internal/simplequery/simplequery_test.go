@@ -0,0 +1,104 @@
+package simplequery
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+)
+
+var allOperators = Operators{And: true, Or: true, Not: true, Phrase: true, Prefix: true, Precedence: true}
+
+var normalizeTests = []struct {
+	testName		string
+	input			string
+	operators		Operators
+	expectedResult	Result
+}{
+	{
+		testName: "Keep valid query as is",
+		input: `(foo | bar) + "quick brown" -baz sea*`,
+		operators: allOperators,
+		expectedResult: Result{Query: `(foo | bar) + "quick brown" -baz sea*`},
+	},
+	{
+		testName: "Convert operator words",
+		input: "foo AND bar OR baz NOT qux",
+		operators: allOperators,
+		expectedResult: Result{Query: "foo + bar | baz -qux"},
+	},
+	{
+		testName: "Drop unbalanced parenthesis",
+		input: "foo AND (bar",
+		operators: allOperators,
+		expectedResult: Result{Query: "foo + bar", Ignored: []string{"("}},
+	},
+	{
+		testName: "Drop extra closing and empty parentheses",
+		input: "foo (bar baz)) ()",
+		operators: allOperators,
+		expectedResult: Result{Query: "foo (bar baz)", Ignored: []string{")", "("}},
+	},
+	{
+		testName: "Drop unclosed quote",
+		input: `"unclosed phrase`,
+		operators: allOperators,
+		expectedResult: Result{Query: "unclosed phrase", Ignored: []string{`"`}},
+	},
+	{
+		testName: "Strip field qualifiers",
+		input: "title:foo _id:1",
+		operators: allOperators,
+		expectedResult: Result{Query: "foo 1", Ignored: []string{"title:", "_id:"}},
+	},
+	{
+		testName: "Strip special characters",
+		input: "a/b [c] ^~ C++",
+		operators: allOperators,
+		expectedResult: Result{Query: "a b c C", Ignored: []string{"/", "[", "]", "^", "~", "+"}},
+	},
+	{
+		testName: "Drop dangling and repeated operators",
+		input: "OR foo OR OR bar AND NOT",
+		operators: allOperators,
+		expectedResult: Result{Query: "foo | bar", Ignored: []string{"OR", "AND", "NOT"}},
+	},
+	{
+		testName: "Keep hyphens inside words only",
+		input: "e-mail -",
+		operators: allOperators,
+		expectedResult: Result{Query: "e-mail", Ignored: []string{"-"}},
+	},
+	{
+		testName: "Return empty query when only operators are given",
+		input: "AND OR ( )",
+		operators: allOperators,
+		expectedResult: Result{Query: "", Ignored: []string{"(", ")", "AND", "OR"}},
+	},
+	{
+		testName: "Drop disallowed operators",
+		input: `foo AND (bar | "x y") -baz sea*`,
+		operators: Operators{Or: true},
+		expectedResult: Result{Query: "foo bar | x y baz sea", Ignored: []string{`"`, "*", "(", ")", "AND", "-"}},
+	},
+}
+
+func TestNormalize(t *testing.T) {
+	for i, test := range normalizeTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		result := Normalize(test.input, test.operators)
+
+		assert.Equal(t, result.Query, test.expectedResult.Query, "wrong normalized query")
+		assert.Equal(t, fmt.Sprint(result.Ignored), fmt.Sprint(test.expectedResult.Ignored), "wrong ignored terms")
+	}
+}
+
+func TestParseOperators(t *testing.T) {
+	operators, err := ParseOperators([]string{"and", " NOT", "prefix"})
+	assert.Equal(t, err, nil, "unexpected error")
+	assert.Equal(t, operators.Flags(), "WHITESPACE|AND|NOT|PREFIX", "wrong flags")
+
+	_, err = ParseOperators([]string{"FUZZY"})
+	assert.Equal(t, err != nil, true, "expected error on unknown operator")
+}
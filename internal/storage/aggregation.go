@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/calendarinterval"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var ErrEmptyAggregation = errors.New("aggregation has no type set")
+
+// buildPostFilter combines all selected facets, so hits are narrowed down after aggregations are counted.
+func buildPostFilter(searchRequest *models.DocumentSearchRequest) (*types.Query, error) {
+	if len(searchRequest.PostFilters) == 0 {
+		return nil, nil
+	}
+
+	filters, err := buildPostFilterQueries(searchRequest.PostFilters, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Query{Bool: &types.BoolQuery{Filter: filters}}, nil
+}
+
+// buildAggregations translates requested facets into ES aggregations. Each aggregation is
+// counted with every selected facet except its own, so selecting a value keeps its siblings' counts.
+func buildAggregations(searchRequest *models.DocumentSearchRequest) (map[string]types.Aggregations, error) {
+	if len(searchRequest.Aggregations) == 0 {
+		return nil, nil
+	}
+
+	aggregations := make(map[string]types.Aggregations, len(searchRequest.Aggregations))
+	for name, aggregation := range searchRequest.Aggregations {
+		esAggregation, err := buildAggregation(&aggregation)
+		if err != nil {
+			return nil, err
+		}
+
+		otherFilters, err := buildPostFilterQueries(searchRequest.PostFilters, name)
+		if err != nil {
+			return nil, err
+		}
+		if len(otherFilters) == 0 {
+			aggregations[name] = *esAggregation
+			continue
+		}
+
+		aggregations[name] = types.Aggregations{
+			Filter: &types.Query{Bool: &types.BoolQuery{Filter: otherFilters}},
+			Aggregations: map[string]types.Aggregations{name: *esAggregation},
+		}
+	}
+
+	return aggregations, nil
+}
+
+// buildPostFilterQueries returns post filters sorted by aggregation name skipping excluded one.
+func buildPostFilterQueries(postFilters map[string]models.SearchClause, exclude string) ([]types.Query, error) {
+	names := make([]string, 0, len(postFilters))
+	for name := range postFilters {
+		if name != exclude {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	queries := make([]types.Query, 0, len(names))
+	for _, name := range names {
+		clause := postFilters[name]
+		query, err := buildClauseQuery(&clause, nil)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, *query)
+	}
+
+	return queries, nil
+}
+
+func buildAggregation(aggregation *models.Aggregation) (*types.Aggregations, error) {
+	switch {
+	case aggregation.Terms != nil:
+		return &types.Aggregations{
+			Terms: &types.TermsAggregation{
+				Field: &aggregation.Terms.Field,
+				Size: aggregation.Terms.Size,
+			},
+		}, nil
+	case aggregation.DateHistogram != nil:
+		dateHistogram := &types.DateHistogramAggregation{
+			Field: &aggregation.DateHistogram.Field,
+			CalendarInterval: &calendarinterval.CalendarInterval{Name: aggregation.DateHistogram.CalendarInterval},
+		}
+		if aggregation.DateHistogram.Format != "" {
+			dateHistogram.Format = &aggregation.DateHistogram.Format
+		}
+		return &types.Aggregations{DateHistogram: dateHistogram}, nil
+	case aggregation.Range != nil:
+		ranges := make([]types.AggregationRange, 0, len(aggregation.Range.Ranges))
+		for _, aggregationRange := range aggregation.Range.Ranges {
+			esRange := types.AggregationRange{
+				From: (*types.Float64)(aggregationRange.From),
+				To: (*types.Float64)(aggregationRange.To),
+			}
+			if aggregationRange.Key != "" {
+				key := aggregationRange.Key
+				esRange.Key = &key
+			}
+			ranges = append(ranges, esRange)
+		}
+		return &types.Aggregations{
+			Range: &types.RangeAggregation{
+				Field: &aggregation.Range.Field,
+				Ranges: ranges,
+			},
+		}, nil
+	case aggregation.Cardinality != nil:
+		return &types.Aggregations{
+			Cardinality: &types.CardinalityAggregation{
+				Field: &aggregation.Cardinality.Field,
+			},
+		}, nil
+	}
+
+	return nil, ErrEmptyAggregation
+}
+
+// parseAggregations converts typed ES aggregates into response aggregations,
+// filter wrappers added by buildAggregations are unwrapped.
+func parseAggregations(aggregates map[string]types.Aggregate) map[string]models.AggregationResult {
+	if len(aggregates) == 0 {
+		return nil
+	}
+
+	results := make(map[string]models.AggregationResult, len(aggregates))
+	for name, aggregate := range aggregates {
+		if filter, ok := aggregate.(*types.FilterAggregate); ok {
+			aggregate = filter.Aggregations[name]
+		}
+
+		result, ok := parseAggregate(aggregate)
+		if ok {
+			results[name] = result
+		}
+	}
+
+	return results
+}
+
+func parseAggregate(aggregate types.Aggregate) (models.AggregationResult, bool) {
+	switch aggregate := aggregate.(type) {
+	case *types.StringTermsAggregate:
+		result := models.AggregationResult{Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{}}
+		if buckets, ok := aggregate.Buckets.([]types.StringTermsBucket); ok {
+			for _, bucket := range buckets {
+				result.Buckets = append(result.Buckets, models.AggregationBucket{Key: fmt.Sprint(bucket.Key), DocCount: bucket.DocCount})
+			}
+		}
+		return result, true
+	case *types.LongTermsAggregate:
+		result := models.AggregationResult{Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{}}
+		if buckets, ok := aggregate.Buckets.([]types.LongTermsBucket); ok {
+			for _, bucket := range buckets {
+				key := strconv.FormatInt(bucket.Key, 10)
+				if bucket.KeyAsString != nil {
+					key = *bucket.KeyAsString
+				}
+				result.Buckets = append(result.Buckets, models.AggregationBucket{Key: key, DocCount: bucket.DocCount})
+			}
+		}
+		return result, true
+	case *types.DoubleTermsAggregate:
+		result := models.AggregationResult{Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{}}
+		if buckets, ok := aggregate.Buckets.([]types.DoubleTermsBucket); ok {
+			for _, bucket := range buckets {
+				key := strconv.FormatFloat(float64(bucket.Key), 'f', -1, 64)
+				if bucket.KeyAsString != nil {
+					key = *bucket.KeyAsString
+				}
+				result.Buckets = append(result.Buckets, models.AggregationBucket{Key: key, DocCount: bucket.DocCount})
+			}
+		}
+		return result, true
+	case *types.UnmappedTermsAggregate:
+		// field doesn't exist in index mapping yet
+		return models.AggregationResult{Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{}}, true
+	case *types.DateHistogramAggregate:
+		result := models.AggregationResult{Type: models.AggregationTypeDateHistogram, Buckets: []models.AggregationBucket{}}
+		if buckets, ok := aggregate.Buckets.([]types.DateHistogramBucket); ok {
+			for _, bucket := range buckets {
+				key := strconv.FormatInt(bucket.Key, 10)
+				if bucket.KeyAsString != nil {
+					key = *bucket.KeyAsString
+				}
+				result.Buckets = append(result.Buckets, models.AggregationBucket{Key: key, DocCount: bucket.DocCount})
+			}
+		}
+		return result, true
+	case *types.RangeAggregate:
+		result := models.AggregationResult{Type: models.AggregationTypeRange, Buckets: []models.AggregationBucket{}}
+		if buckets, ok := aggregate.Buckets.([]types.RangeBucket); ok {
+			for _, bucket := range buckets {
+				resultBucket := models.AggregationBucket{
+					From: (*float64)(bucket.From),
+					To: (*float64)(bucket.To),
+					DocCount: bucket.DocCount,
+				}
+				if bucket.Key != nil {
+					resultBucket.Key = *bucket.Key
+				}
+				result.Buckets = append(result.Buckets, resultBucket)
+			}
+		}
+		return result, true
+	case *types.CardinalityAggregate:
+		value := aggregate.Value
+		return models.AggregationResult{Type: models.AggregationTypeCardinality, Value: &value}, true
+	}
+
+	return models.AggregationResult{}, false
+}
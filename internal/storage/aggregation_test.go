@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var termsSize = 5
+var rangeTo = float64(10)
+
+var buildAggregationsTests = []struct {
+	testName				string
+	request					*models.DocumentSearchRequest
+	expectedAggregations	string
+	expectedPostFilter		string
+}{
+	{
+		testName: "Aggregations without post filters",
+		request: &models.DocumentSearchRequest{
+			Aggregations: map[string]models.Aggregation{
+				"tags": {Terms: &models.TermsAggregation{Field: "tags", Size: &termsSize}},
+				"by_month": {DateHistogram: &models.DateHistogramAggregation{Field: "published_at", CalendarInterval: "month", Format: "yyyy-MM"}},
+				"price": {Range: &models.RangeAggregation{Field: "price", Ranges: []models.AggregationRange{{Key: "cheap", To: &rangeTo}}}},
+				"authors": {Cardinality: &models.CardinalityAggregation{Field: "author"}},
+			},
+		},
+		expectedAggregations: `{"authors":{"cardinality":{"field":"author"}},"by_month":{"date_histogram":{"calendar_interval":"month","field":"published_at","format":"yyyy-MM"}},"price":{"range":{"field":"price","ranges":[{"key":"cheap","to":10}]}},"tags":{"terms":{"field":"tags","size":5}}}`,
+		expectedPostFilter: `null`,
+	},
+	{
+		testName: "Aggregation is counted without its own post filter",
+		request: &models.DocumentSearchRequest{
+			Aggregations: map[string]models.Aggregation{
+				"tags": {Terms: &models.TermsAggregation{Field: "tags"}},
+				"author": {Terms: &models.TermsAggregation{Field: "author"}},
+			},
+			PostFilters: map[string]models.SearchClause{
+				"tags": {Term: &models.TermClause{Field: "tags", Value: "news"}},
+			},
+		},
+		expectedAggregations: `{"author":{"aggregations":{"author":{"terms":{"field":"author"}}},"filter":{"bool":{"filter":[{"term":{"tags":{"value":"news"}}}]}}},"tags":{"terms":{"field":"tags"}}}`,
+		expectedPostFilter: `{"bool":{"filter":[{"term":{"tags":{"value":"news"}}}]}}`,
+	},
+}
+
+func TestBuildAggregations(t *testing.T) {
+	for i, test := range buildAggregationsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		aggregations, err := buildAggregations(test.request)
+		if err != nil {
+			t.Fatalf("Unable to build aggregations, error: %s\n", err)
+		}
+		postFilter, err := buildPostFilter(test.request)
+		if err != nil {
+			t.Fatalf("Unable to build post filter, error: %s\n", err)
+		}
+
+		marshaledAggregations, err := json.Marshal(aggregations)
+		if err != nil {
+			t.Fatalf("Unable to marshal aggregations, error: %s\n", err)
+		}
+		marshaledPostFilter, err := json.Marshal(postFilter)
+		if err != nil {
+			t.Fatalf("Unable to marshal post filter, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledAggregations), test.expectedAggregations, "wrong aggregations")
+		assert.Equal(t, string(marshaledPostFilter), test.expectedPostFilter, "wrong post filter")
+	}
+}
+
+func TestParseAggregations(t *testing.T) {
+	esResponse := `{
+		"took": 1, "timed_out": false, "_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+		"hits": {"total": {"value": 0, "relation": "eq"}, "hits": []},
+		"aggregations": {
+			"sterms#tags": {"doc_count_error_upper_bound": 0, "sum_other_doc_count": 0, "buckets": [{"key": "news", "doc_count": 3}]},
+			"filter#author": {"doc_count": 2, "lterms#author": {"doc_count_error_upper_bound": 0, "sum_other_doc_count": 0, "buckets": [{"key": 7, "doc_count": 2}]}},
+			"date_histogram#by_month": {"buckets": [{"key_as_string": "2024-01", "key": 1704067200000, "doc_count": 1}]},
+			"range#price": {"buckets": [{"key": "cheap", "to": 10.0, "doc_count": 4}]},
+			"cardinality#authors": {"value": 2}
+		}
+	}`
+
+	response := search.NewResponse()
+	if err := json.Unmarshal([]byte(esResponse), response); err != nil {
+		t.Fatalf("Unable to unmarshal ES response, error: %s\n", err)
+	}
+
+	cardinality := int64(2)
+	expectedAggregations := map[string]models.AggregationResult{
+		"tags": {Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{{Key: "news", DocCount: 3}}},
+		"author": {Type: models.AggregationTypeTerms, Buckets: []models.AggregationBucket{{Key: "7", DocCount: 2}}},
+		"by_month": {Type: models.AggregationTypeDateHistogram, Buckets: []models.AggregationBucket{{Key: "2024-01", DocCount: 1}}},
+		"price": {Type: models.AggregationTypeRange, Buckets: []models.AggregationBucket{{Key: "cheap", To: &rangeTo, DocCount: 4}}},
+		"authors": {Type: models.AggregationTypeCardinality, Value: &cardinality},
+	}
+
+	marshaledAggregations, err := json.Marshal(parseAggregations(response.Aggregations))
+	if err != nil {
+		t.Fatalf("Unable to marshal aggregations, error: %s\n", err)
+	}
+	marshaledExpectedAggregations, err := json.Marshal(expectedAggregations)
+	if err != nil {
+		t.Fatalf("Unable to marshal expected aggregations, error: %s\n", err)
+	}
+
+	assert.Equal(t, string(marshaledAggregations), string(marshaledExpectedAggregations), "wrong aggregations")
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Analyzers title and text are indexed and searched with
+const (
+	ContentAnalyzer			= "content"
+	ContentSearchAnalyzer	= "content_search"
+
+	contentStopFilter		= "content_stop"
+	contentStemmerFilter	= "content_stemmer"
+	contentSynonymsFilter	= "content_synonyms"
+)
+
+// Stemmers ES recommends for models.AnalysisLanguages
+var languageStemmers = map[string]string{
+	"english": "english",
+	"russian": "russian",
+	"german": "light_german",
+	"french": "light_french",
+	"spanish": "light_spanish",
+	"italian": "light_italian",
+	"portuguese": "light_portuguese",
+	"dutch": "dutch",
+}
+
+// buildAnalysisSettings defines content analyzers: lowercase, stopwords and stemming
+// for indexing, search analyzer additionally expands synonyms.
+func buildAnalysisSettings(analysis *models.IndexAnalysis) *types.IndexSettingsAnalysis {
+	filters := map[string]types.TokenFilter{}
+	indexFilters := []string{"lowercase"}
+
+	stopwords := []string{}
+	if analysis.Language != "" {
+		// named stopwords list like _english_ is expanded by ES
+		stopwords = append(stopwords, "_"+analysis.Language+"_")
+	}
+	stopwords = append(stopwords, analysis.Stopwords...)
+	if len(stopwords) > 0 {
+		stopFilter := types.NewStopTokenFilter()
+		stopFilter.Stopwords = stopwords
+		filters[contentStopFilter] = stopFilter
+		indexFilters = append(indexFilters, contentStopFilter)
+	}
+
+	if stemmer, ok := languageStemmers[analysis.Language]; ok {
+		stemmerFilter := types.NewStemmerTokenFilter()
+		stemmerFilter.Language = &stemmer
+		filters[contentStemmerFilter] = stemmerFilter
+		indexFilters = append(indexFilters, contentStemmerFilter)
+	}
+
+	// synonyms go right after lowercase, so rules match words as users type them
+	searchFilters := indexFilters
+	if len(analysis.Synonyms) > 0 {
+		synonymsFilter := types.NewSynonymGraphTokenFilter()
+		synonymsFilter.Synonyms = analysis.Synonyms
+		filters[contentSynonymsFilter] = synonymsFilter
+		searchFilters = append([]string{"lowercase", contentSynonymsFilter}, indexFilters[1:]...)
+	}
+
+	contentAnalyzer := types.NewCustomAnalyzer()
+	contentAnalyzer.Tokenizer = "standard"
+	contentAnalyzer.Filter = indexFilters
+
+	contentSearchAnalyzer := types.NewCustomAnalyzer()
+	contentSearchAnalyzer.Tokenizer = "standard"
+	contentSearchAnalyzer.Filter = searchFilters
+
+	return &types.IndexSettingsAnalysis{
+		Analyzer: map[string]types.Analyzer{
+			ContentAnalyzer: contentAnalyzer,
+			ContentSearchAnalyzer: contentSearchAnalyzer,
+		},
+		Filter: filters,
+	}
+}
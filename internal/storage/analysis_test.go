@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildAnalysisSettingsTests = []struct {
+	testName			string
+	analysis			*models.IndexAnalysis
+	expectedSettings	string
+}{
+	{
+		testName: "Default analysis is equal to standard analyzer",
+		analysis: &models.IndexAnalysis{},
+		expectedSettings: `{"analyzer":{"content":{"filter":["lowercase"],"tokenizer":"standard","type":"custom"},"content_search":{"filter":["lowercase"],"tokenizer":"standard","type":"custom"}}}`,
+	},
+	{
+		testName: "Language stopwords, stemming and synonyms",
+		analysis: &models.IndexAnalysis{Language: "german", Stopwords: []string{"gmbh"}, Synonyms: []string{"auto, wagen"}},
+		expectedSettings: `{"analyzer":{"content":{"filter":["lowercase","content_stop","content_stemmer"],"tokenizer":"standard","type":"custom"},"content_search":{"filter":["lowercase","content_synonyms","content_stop","content_stemmer"],"tokenizer":"standard","type":"custom"}},"filter":{"content_stemmer":{"language":"light_german","type":"stemmer"},"content_stop":{"stopwords":["_german_","gmbh"],"type":"stop"},"content_synonyms":{"synonyms":["auto, wagen"],"type":"synonym_graph"}}}`,
+	},
+}
+
+func TestBuildAnalysisSettings(t *testing.T) {
+	for i, test := range buildAnalysisSettingsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledSettings, err := json.Marshal(buildAnalysisSettings(test.analysis))
+		if err != nil {
+			t.Fatalf("Unable to marshal settings, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledSettings), test.expectedSettings, "wrong analysis settings")
+	}
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"sync"
+	"time"
 
 	"github.com/xavesen/search-api/internal/models"
 )
@@ -9,17 +11,83 @@ import (
 type DocStorageMock struct {
 	IndexError 		error
 	SearchError		error
+	SuggestError	error
 	CreateError		error
+	UpdateAnalysisError	error
+	UpdateSchemaError	error
+	BulkError		error
+	IndexingResults	[]models.DocumentIndexingResult
 	Documents 		[]models.Document
+	// Hits are returned by search instead of Documents when set
+	Hits			[]models.SearchHit
+	SearchRequest	*models.DocumentSearchRequest
+	DidYouMean		string
+	CorrectedDocuments	[]models.Document
+	Suggestions		[]models.Suggestion
+	SuggestRequest	*models.SuggestRequest
+	CreatedIndex	*models.IndexMetadata
+	IndexedDocuments	[]models.Document
+	IndexedEmbeddings	[][]float32
+	IndexedDedupe	string
+	GetError		error
+	Document		*models.SearchHit
+	SimilarError	error
+	Similar			[]models.SimilarDocument
+	SimilarRequest	*models.SimilarDocumentsRequest
+	MoreLikeThisRequest	*models.MoreLikeThisRequest
 	EsIndexExists 	bool
+	ReindexError	error
+	CatchUpError	error
+	SwapError		error
+	IndexVersion	*models.IndexMetadata
+	ReindexProgress	*models.ReindexProgress
+	CatchUps		[]bool
+	WriteBlocks		[]bool
+	SwappedIndexes	[]string
+	DeletedIndexes	[]string
+	mutex			sync.Mutex
 }
 
-func (ds *DocStorageMock) SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) ([]models.Document, error) {
+func (ds *DocStorageMock) SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) (*models.SearchResponse, error) {
+	ds.SearchRequest = searchRequest
 	if ds.SearchError != nil {
-		return []models.Document{}, ds.SearchError
+		return nil, ds.SearchError
 	}
 
-	return ds.Documents, nil
+	if ds.DidYouMean != "" && searchRequest.Query == ds.DidYouMean {
+		return &models.SearchResponse{Total: int64(len(ds.CorrectedDocuments)), Documents: searchHits(ds.CorrectedDocuments)}, nil
+	}
+
+	hits := ds.Hits
+	if hits == nil {
+		hits = searchHits(ds.Documents)
+	}
+	searchResponse := &models.SearchResponse{Total: int64(len(hits)), Documents: hits}
+	if searchRequest.DidYouMeanMaxHits >= 0 && len(ds.Documents) <= searchRequest.DidYouMeanMaxHits {
+		searchResponse.DidYouMean = ds.DidYouMean
+	}
+	return searchResponse, nil
+}
+
+func searchHits(documents []models.Document) []models.SearchHit {
+	if documents == nil {
+		return nil
+	}
+
+	hits := make([]models.SearchHit, 0, len(documents))
+	for _, document := range documents {
+		hits = append(hits, models.SearchHit{Document: document})
+	}
+	return hits
+}
+
+func (ds *DocStorageMock) Suggest(ctx context.Context, suggestRequest *models.SuggestRequest) ([]models.Suggestion, error) {
+	ds.SuggestRequest = suggestRequest
+	if ds.SuggestError != nil {
+		return nil, ds.SuggestError
+	}
+
+	return ds.Suggestions, nil
 }
 
 func (ds *DocStorageMock) IndexExists(ctx context.Context, indexName string) (bool, error) {
@@ -30,6 +98,100 @@ func (ds *DocStorageMock) IndexExists(ctx context.Context, indexName string) (bo
 	return ds.EsIndexExists, nil
 }
 
-func (ds *DocStorageMock) NewIndex(ctx context.Context, indexName string) error {
+func (ds *DocStorageMock) NewIndex(ctx context.Context, index *models.IndexMetadata) error {
+	ds.CreatedIndex = index
+	return ds.CreateError
+}
+
+func (ds *DocStorageMock) UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error {
+	return ds.UpdateSchemaError
+}
+
+func (ds *DocStorageMock) IndexDocuments(ctx context.Context, indexName string, documents []models.Document, embeddings [][]float32, refreshPolicy string, dedupe string) ([]models.DocumentIndexingResult, error) {
+	ds.IndexedDocuments = documents
+	ds.IndexedEmbeddings = embeddings
+	ds.IndexedDedupe = dedupe
+	if ds.BulkError != nil {
+		return nil, ds.BulkError
+	}
+
+	return ds.IndexingResults, nil
+}
+
+func (ds *DocStorageMock) UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error {
+	return ds.UpdateAnalysisError
+}
+
+func (ds *DocStorageMock) GetDocument(ctx context.Context, indexName string, id string) (*models.SearchHit, error) {
+	return ds.Document, ds.GetError
+}
+
+func (ds *DocStorageMock) SimilarDocuments(ctx context.Context, similarRequest *models.SimilarDocumentsRequest) ([]models.SimilarDocument, error) {
+	ds.SimilarRequest = similarRequest
+	if ds.SimilarError != nil {
+		return nil, ds.SimilarError
+	}
+
+	return ds.Similar, nil
+}
+
+func (ds *DocStorageMock) MoreLikeThis(ctx context.Context, moreLikeThisRequest *models.MoreLikeThisRequest) (*models.SearchResponse, error) {
+	ds.MoreLikeThisRequest = moreLikeThisRequest
+	if ds.SearchError != nil {
+		return nil, ds.SearchError
+	}
+
+	return &models.SearchResponse{Total: int64(len(ds.Documents)), Documents: searchHits(ds.Documents)}, nil
+}
+
+func (ds *DocStorageMock) NewIndexVersion(ctx context.Context, index *models.IndexMetadata) error {
+	ds.IndexVersion = index
 	return ds.CreateError
-}
\ No newline at end of file
+}
+
+func (ds *DocStorageMock) StartReindex(ctx context.Context, sourceIndex string, destIndex string) (string, error) {
+	if ds.ReindexError != nil {
+		return "", ds.ReindexError
+	}
+
+	return "node:1", nil
+}
+
+func (ds *DocStorageMock) GetReindexProgress(ctx context.Context, esTaskId string) (*models.ReindexProgress, error) {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	return ds.ReindexProgress, nil
+}
+
+// CatchUpReindex records overwrite flag of each catch up
+func (ds *DocStorageMock) CatchUpReindex(ctx context.Context, sourceIndex string, destIndex string, since time.Time, overwrite bool) error {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	ds.CatchUps = append(ds.CatchUps, overwrite)
+	return ds.CatchUpError
+}
+
+func (ds *DocStorageMock) SetIndexWriteBlock(ctx context.Context, physicalName string, blocked bool) error {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	ds.WriteBlocks = append(ds.WriteBlocks, blocked)
+	return nil
+}
+
+func (ds *DocStorageMock) SwapIndexAlias(ctx context.Context, alias string, oldIndex string, newIndex string) error {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	if ds.SwapError != nil {
+		return ds.SwapError
+	}
+
+	ds.SwappedIndexes = []string{oldIndex, newIndex}
+	return nil
+}
+
+func (ds *DocStorageMock) DeleteIndex(ctx context.Context, physicalName string) error {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	ds.DeletedIndexes = append(ds.DeletedIndexes, physicalName)
+	return nil
+}
@@ -3,11 +3,14 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
 	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/refresh"
 	log "github.com/sirupsen/logrus"
 	"github.com/xavesen/search-api/internal/models"
 )
@@ -41,23 +44,185 @@ func NewElasticSearchClient(addr []string, apiKey string) (*ElasticSearchClient,
 	return &ElasticSearchClient{Client: es}, nil
 }
 
-func (es *ElasticSearchClient) SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) ([]models.Document, error) {
-	documents := []models.Document{}
+func (es *ElasticSearchClient) SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) (*models.SearchResponse, error) {
+	if searchRequest.Mode == models.SearchModeHybrid {
+		return es.hybridSearch(ctx, searchRequest)
+	}
+
+	// more hits are fetched when near duplicates are collapsed, so collapsed ones are replaced
+	var size *int
+	if searchRequest.CollapseNearDuplicates {
+		windowSize := nearDuplicateWindowSize
+		size = &windowSize
+	}
+
+	request, err := buildSearchRequest(searchRequest, size)
+	if err != nil {
+		log.Errorf("Error building search request for index %s: %s", strings.Join(searchRequest.TargetIndexes(), ","), err)
+		return nil, err
+	}
+
+	searchResult, err := es.search(ctx, searchRequest, request)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	if searchResult.Hits.Total != nil {
+		total = searchResult.Hits.Total.Value
+	}
+
+	documents := parseHits(searchResult.Hits.Hits)
+	if searchRequest.CollapseNearDuplicates {
+		resultsSize := keywordResultsSize
+		if searchRequest.Mode == models.SearchModeKnn {
+			resultsSize = vectorResultsSize
+		}
+		documents = collapseNearDuplicates(documents, searchRequest.NearDuplicateDistance)
+		if len(documents) > resultsSize {
+			documents = documents[:resultsSize]
+		}
+	}
+
+	return &models.SearchResponse{
+		Total: total,
+		Documents: documents,
+		Aggregations: parseAggregations(searchResult.Aggregations),
+		DidYouMean: parseDidYouMean(searchResult.Suggest, total, searchRequest.DidYouMeanMaxHits),
+	}, nil
+}
+
+// hybridSearch runs keyword and knn searches and fuses their rankings, total is the number
+// of documents matching keyword query or fused documents if there are more of them.
+func (es *ElasticSearchClient) hybridSearch(ctx context.Context, searchRequest *models.DocumentSearchRequest) (*models.SearchResponse, error) {
+	windowSize := rrfWindowSize
+	keywordRequest, knnRequest := *searchRequest, *searchRequest
+	keywordRequest.Mode = models.SearchModeKeyword
+	keywordRequest.DidYouMeanMaxHits = -1
+	knnRequest.Mode = models.SearchModeKnn
+
+	rankings := make([][]models.SearchHit, 0, 2)
+	var keywordTotal int64
+	for _, modeRequest := range []*models.DocumentSearchRequest{&keywordRequest, &knnRequest} {
+		request, err := buildSearchRequest(modeRequest, &windowSize)
+		if err != nil {
+			log.Errorf("Error building %s search request for index %s: %s", modeRequest.Mode, strings.Join(searchRequest.TargetIndexes(), ","), err)
+			return nil, err
+		}
+
+		searchResult, err := es.search(ctx, modeRequest, request)
+		if err != nil {
+			return nil, err
+		}
+
+		if modeRequest.Mode == models.SearchModeKeyword && searchResult.Hits.Total != nil {
+			keywordTotal = searchResult.Hits.Total.Value
+		}
+		rankings = append(rankings, parseHits(searchResult.Hits.Hits))
+	}
+
+	documents := fuseRankings(rankings...)
+	if searchRequest.CollapseNearDuplicates {
+		documents = collapseNearDuplicates(documents, searchRequest.NearDuplicateDistance)
+	}
+	total := keywordTotal
+	if int64(len(documents)) > total {
+		total = int64(len(documents))
+	}
+	if len(documents) > vectorResultsSize {
+		documents = documents[:vectorResultsSize]
+	}
+
+	return &models.SearchResponse{Total: total, Documents: documents}, nil
+}
+
+// buildSearchRequest translates search request to ES one, nil size means ES default
+// for keyword search and vectorResultsSize for knn search.
+func buildSearchRequest(searchRequest *models.DocumentSearchRequest, size *int) (*search.Request, error) {
+	postFilter, err := buildPostFilter(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	aggregations, err := buildAggregations(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &search.Request{
+		PostFilter: postFilter,
+		Aggregations: aggregations,
+		Suggest: buildDidYouMeanSuggester(searchRequest),
+		Sort: buildSort(searchRequest),
+		Source_: buildSourceFilter(searchRequest),
+		Highlight: buildHighlight(searchRequest),
+		Size: size,
+	}
+
+	if searchRequest.Mode == models.SearchModeKnn {
+		k := vectorResultsSize
+		if size != nil {
+			k = *size
+		}
+		request.Knn = buildKnnSearch(searchRequest, k)
+		return request, nil
+	}
+
+	request.Query, err = buildSearchQuery(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+func (es *ElasticSearchClient) search(ctx context.Context, searchRequest *models.DocumentSearchRequest, request *search.Request) (*search.Response, error) {
+	indexNames := strings.Join(searchRequest.TargetIndexes(), ",")
+	searchQuery := es.Client.Search().
+	Index(indexNames).
+	TypedKeys(true)
+	if searchRequest.IgnoreUnavailable {
+		searchQuery.IgnoreUnavailable(true)
+	}
+
+	searchResult, err := searchQuery.Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("Error performing search request with query %s in index %s: %s", searchRequest.Query, indexNames, err)
+		return nil, err
+	}
+	return searchResult, nil
+}
+
+func parseHits(hits []types.Hit) []models.SearchHit {
+	documents := []models.SearchHit{}
+	for _, hit := range hits {
+		var document models.SearchHit
+		err := json.Unmarshal(hit.Source_, &document)
+		if err != nil {
+			log.Errorf("Error unmarshalling hit from ES to document struct: %s", err)
+			continue
+		}
+		if hit.Id_ != nil {
+			document.Id = *hit.Id_
+		}
+		document.Index = LogicalIndexName(hit.Index_)
+		if len(hit.Highlight) > 0 {
+			document.Highlights = hit.Highlight
+		}
+		documents = append(documents, document)
+	}
+	return documents
+}
+
+func (es *ElasticSearchClient) Suggest(ctx context.Context, suggestRequest *models.SuggestRequest) ([]models.Suggestion, error) {
+	suggestions := []models.Suggestion{}
 
 	searchResult, err := es.Client.Search().
-	Index(searchRequest.Index).
-	Request(
-		&search.Request{
-			Query: &types.Query{
-				QueryString: &types.QueryStringQuery{
-					Query: searchRequest.Query,
-				},
-			},
-		},
-	).Do(ctx)
+	Index(suggestRequest.Index).
+	Request(buildSuggestRequest(suggestRequest)).
+	Do(ctx)
 	if err != nil {
-		log.Errorf("Error performing search request with query %s in index %s: %s", searchRequest.Query, searchRequest.Index, err)
-		return []models.Document{}, err
+		log.Errorf("Error performing suggest request with prefix %s in index %s: %s", suggestRequest.Prefix, suggestRequest.Index, err)
+		return nil, err
 	}
 
 	for _, hit := range searchResult.Hits.Hits {
@@ -67,10 +232,18 @@ func (es *ElasticSearchClient) SearchQuery(ctx context.Context, searchRequest *m
 			log.Errorf("Error unmarshalling hit from ES to document struct: %s", err)
 			continue
 		}
-		documents = append(documents, document)
+
+		suggestion := models.Suggestion{Title: document.Title}
+		if hit.Id_ != nil {
+			suggestion.Id = *hit.Id_
+		}
+		if hit.Score_ != nil {
+			suggestion.Score = float64(*hit.Score_)
+		}
+		suggestions = append(suggestions, suggestion)
 	}
 
-	return documents, nil
+	return suggestions, nil
 }
 
 func (es *ElasticSearchClient) IndexExists(ctx context.Context, indexName string) (bool, error) {
@@ -82,11 +255,130 @@ func (es *ElasticSearchClient) IndexExists(ctx context.Context, indexName string
 	return exists, nil
 }
 
-func (es *ElasticSearchClient) NewIndex(ctx context.Context, indexName string) error {
-	_, err := es.Client.Indices.Create(indexName).Do(ctx)
+// NewIndex creates physical index of index.Version, versioned indexes get alias named as the
+// index, so clients use the name regardless of version.
+func (es *ElasticSearchClient) NewIndex(ctx context.Context, index *models.IndexMetadata) error {
+	var aliases map[string]types.Alias
+	if index.Version > 0 {
+		aliases = map[string]types.Alias{index.Name: {}}
+	}
+	return es.createPhysicalIndex(ctx, index, aliases)
+}
+
+// UpdateIndexSchema puts schema fields to index mapping, ES rejects changes of existing fields types.
+func (es *ElasticSearchClient) UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error {
+	properties := map[string]types.Property{FieldsProperty: buildFieldsProperty(schema)}
+	_, err := es.Client.Indices.PutMapping(indexName).Properties(properties).Do(ctx)
 	if err != nil {
-		log.Errorf("Error creating index '%s' in ES: %s", indexName, err)
+		log.Errorf("Error updating mapping of index '%s' in ES: %s", indexName, err)
 		return err
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// UpdateIndexAnalysis replaces analysis settings, they can be changed only on closed index,
+// so index is unavailable until it's reopened.
+func (es *ElasticSearchClient) UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error {
+	_, err := es.Client.Indices.Close(indexName).Do(ctx)
+	if err != nil {
+		log.Errorf("Error closing index '%s' in ES to update analysis: %s", indexName, err)
+		return err
+	}
+
+	_, err = es.Client.Indices.PutSettings().Indices(indexName).Analysis(buildAnalysisSettings(analysis)).Do(ctx)
+	if err != nil {
+		log.Errorf("Error updating analysis settings of index '%s' in ES: %s", indexName, err)
+	}
+
+	// index is reopened even if update failed, otherwise it stays unavailable
+	_, openErr := es.Client.Indices.Open(indexName).Do(ctx)
+	if openErr != nil {
+		log.Errorf("Error reopening index '%s' in ES after analysis update: %s", indexName, openErr)
+		if err == nil {
+			err = openErr
+		}
+	}
+
+	return err
+}
+
+// IndexDocuments uses content hash as _id of documents without id, unless dedupe policy is
+// keep_both. With skip policy they are created only if the hash isn't indexed yet.
+func (es *ElasticSearchClient) IndexDocuments(ctx context.Context, indexName string, documents []models.Document, embeddings [][]float32, refreshPolicy string, dedupe string) ([]models.DocumentIndexingResult, error) {
+	bulkRequest := es.Client.Bulk().Index(indexName)
+	if refreshPolicy != "" {
+		var refreshValue refresh.Refresh
+		refreshValue.UnmarshalText([]byte(refreshPolicy))
+		bulkRequest.Refresh(refreshValue)
+	}
+
+	indexedAt := time.Now().UTC()
+	for i, document := range documents {
+		id := document.Id
+		// id is stored as ES document _id, not in the document source
+		document.Id = ""
+		var embedding []float32
+		if i < len(embeddings) {
+			embedding = embeddings[i]
+		}
+
+		var err error
+		if id == "" && dedupe == models.DedupeSkip {
+			id = document.ContentHash()
+			err = bulkRequest.CreateOp(types.CreateOperation{Id_: &id}, newStoredDocument(document, embedding, indexedAt))
+		} else {
+			if id == "" && dedupe != models.DedupeKeepBoth {
+				id = document.ContentHash()
+			}
+			operation := types.IndexOperation{}
+			if id != "" {
+				operation.Id_ = &id
+			}
+			err = bulkRequest.IndexOp(operation, newStoredDocument(document, embedding, indexedAt))
+		}
+		if err != nil {
+			log.Errorf("Error adding document to bulk request for index %s: %s", indexName, err)
+			return nil, err
+		}
+	}
+
+	bulkResult, err := bulkRequest.Do(ctx)
+	if err != nil {
+		log.Errorf("Error performing bulk request with %d documents in index %s: %s", len(documents), indexName, err)
+		return nil, err
+	}
+
+	results := make([]models.DocumentIndexingResult, 0, len(bulkResult.Items))
+	for position, item := range bulkResult.Items {
+		for _, responseItem := range item {
+			result := models.DocumentIndexingResult{Position: position}
+			if responseItem.Id_ != nil {
+				result.Id = *responseItem.Id_
+			}
+
+			// only create operations of skip policy conflict with existing documents
+			if responseItem.Status == http.StatusConflict {
+				result.Result = models.IndexingResultSkipped
+			} else if responseItem.Error != nil {
+				result.Result = models.IndexingResultFailed
+				if responseItem.Error.Reason != nil {
+					result.Error = *responseItem.Error.Reason
+				} else {
+					result.Error = responseItem.Error.Type
+				}
+			} else if responseItem.Result != nil && *responseItem.Result == models.IndexingResultUpdated {
+				result.Result = models.IndexingResultUpdated
+			} else {
+				result.Result = models.IndexingResultCreated
+			}
+
+			results = append(results, result)
+		}
+	}
+
+	if bulkResult.Errors {
+		log.Warningf("Bulk request in index %s finished with failed documents", indexName)
+	}
+
+	return results, nil
+}
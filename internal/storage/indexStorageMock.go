@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+type IndexStorageMock struct {
+	CreateError			error
+	GetError			error
+	UpdateAnalysisError	error
+	UpdateSchemaError	error
+	UpdateFuzzyError	error
+	UpdateDedupeError	error
+	UpdateVersionError	error
+	ReindexTaskError	error
+	Index				*models.IndexMetadata
+	Indexes				[]models.IndexMetadata
+	UpdatedVersion		int
+	DeletedMetadata		[]string
+	ReindexTask			*models.ReindexTask
+	RunningReindexTasks	[]models.ReindexTask
+	ReindexTaskUpdates	[]models.ReindexTask
+	mutex				sync.Mutex
+}
+
+func (is *IndexStorageMock) CreateIndexMetadata(ctx context.Context, index *models.IndexMetadata) error {
+	return is.CreateError
+}
+
+func (is *IndexStorageMock) DeleteIndexMetadata(ctx context.Context, indexName string) error {
+	is.DeletedMetadata = append(is.DeletedMetadata, indexName)
+	return nil
+}
+
+func (is *IndexStorageMock) GetIndexMetadata(ctx context.Context, indexName string) (*models.IndexMetadata, error) {
+	return is.Index, is.GetError
+}
+
+func (is *IndexStorageMock) GetIndexesMetadata(ctx context.Context, indexNames []string) ([]models.IndexMetadata, error) {
+	if is.GetError != nil {
+		return nil, is.GetError
+	}
+
+	return is.Indexes, nil
+}
+
+func (is *IndexStorageMock) UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error {
+	return is.UpdateSchemaError
+}
+
+func (is *IndexStorageMock) UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error {
+	return is.UpdateAnalysisError
+}
+
+func (is *IndexStorageMock) UpdateIndexFuzzySettings(ctx context.Context, indexName string, fuzzy *models.FuzzySettings) error {
+	return is.UpdateFuzzyError
+}
+
+func (is *IndexStorageMock) UpdateIndexDedupe(ctx context.Context, indexName string, dedupe string) error {
+	return is.UpdateDedupeError
+}
+
+func (is *IndexStorageMock) UpdateIndexVersion(ctx context.Context, indexName string, version int, schema *models.IndexSchema, analysis *models.IndexAnalysis) error {
+	is.mutex.Lock()
+	defer is.mutex.Unlock()
+	is.UpdatedVersion = version
+	return is.UpdateVersionError
+}
+
+func (is *IndexStorageMock) CreateReindexTask(ctx context.Context, task *models.ReindexTask) error {
+	if is.ReindexTaskError != nil {
+		return is.ReindexTaskError
+	}
+
+	task.Id = "task"
+	is.ReindexTask = task
+	return nil
+}
+
+func (is *IndexStorageMock) GetReindexTask(ctx context.Context, id string) (*models.ReindexTask, error) {
+	return is.ReindexTask, is.GetError
+}
+
+func (is *IndexStorageMock) GetRunningReindexTasks(ctx context.Context, indexName string) ([]models.ReindexTask, error) {
+	if is.GetError != nil {
+		return nil, is.GetError
+	}
+
+	return is.RunningReindexTasks, nil
+}
+
+// UpdateReindexTask records copies of task states, so tests can check all of them
+func (is *IndexStorageMock) UpdateReindexTask(ctx context.Context, task *models.ReindexTask) error {
+	is.mutex.Lock()
+	defer is.mutex.Unlock()
+	is.ReindexTaskUpdates = append(is.ReindexTaskUpdates, *task)
+	return nil
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/dynamicmapping"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Name of the object documents' typed metadata is stored in
+const FieldsProperty = "fields"
+
+// Same limit ES uses for keyword subfields of dynamically mapped strings
+const keywordIgnoreAbove = 256
+
+// Subfield of title prefix suggestions are searched in
+const SuggestSubfield = "suggest"
+
+// Subfield of title and text with exact values
+const KeywordSubfield = "keyword"
+
+// Field embeddings of title and text are stored in
+const EmbeddingProperty = "embedding"
+
+// Fields SimHash of title and text and its bands are stored in
+const (
+	SimHashProperty			= "simhash"
+	SimHashBandsProperty	= "simhash_bands"
+)
+
+// Field time of indexing is stored in, documents indexed during reindex are copied by it
+const IndexedAtProperty = "indexed_at"
+
+// buildMapping returns explicit mapping for title and text, schema fields are mapped inside
+// fields object. Without schema fields object stays dynamic like the whole index used to be.
+func buildMapping(index *models.IndexMetadata) *types.TypeMapping {
+	mapping := &types.TypeMapping{
+		Properties: map[string]types.Property{
+			"title": titleProperty(index.Analysis),
+			"text": contentProperty(index.Analysis),
+			FieldsProperty: buildFieldsProperty(index.Schema),
+			SimHashProperty: types.NewKeywordProperty(),
+			SimHashBandsProperty: types.NewKeywordProperty(),
+			IndexedAtProperty: types.NewDateProperty(),
+		},
+	}
+	if index.Vector != nil {
+		mapping.Properties[EmbeddingProperty] = embeddingProperty(index.Vector)
+	}
+	return mapping
+}
+
+// embeddingProperty is indexed for approximate kNN search with HNSW.
+func embeddingProperty(vector *models.VectorSettings) *types.DenseVectorProperty {
+	dims, indexed, similarity := vector.Dimensions, true, vector.Similarity
+	if similarity == "" {
+		similarity = models.VectorSimilarityCosine
+	}
+
+	embedding := types.NewDenseVectorProperty()
+	embedding.Dims = &dims
+	embedding.Index = &indexed
+	embedding.Similarity = &similarity
+	return embedding
+}
+
+func buildFieldsProperty(schema *models.IndexSchema) *types.ObjectProperty {
+	fieldsProperty := types.NewObjectProperty()
+	if schema == nil {
+		return fieldsProperty
+	}
+
+	fieldsProperty.Dynamic = &dynamicmapping.Strict
+	fieldsProperty.Properties = buildSchemaProperties(schema.Fields)
+	return fieldsProperty
+}
+
+func buildSchemaProperties(fields map[string]models.SchemaField) map[string]types.Property {
+	properties := make(map[string]types.Property, len(fields))
+	for name, field := range fields {
+		properties[name] = buildSchemaProperty(field)
+	}
+	return properties
+}
+
+func buildSchemaProperty(field models.SchemaField) types.Property {
+	switch field.Type {
+	case models.FieldTypeKeyword:
+		return types.NewKeywordProperty()
+	case models.FieldTypeText:
+		return types.NewTextProperty()
+	case models.FieldTypeDate:
+		return types.NewDateProperty()
+	case models.FieldTypeLong:
+		return types.NewLongNumberProperty()
+	case models.FieldTypeInteger:
+		return types.NewIntegerNumberProperty()
+	case models.FieldTypeFloat:
+		return types.NewFloatNumberProperty()
+	case models.FieldTypeDouble:
+		return types.NewDoubleNumberProperty()
+	case models.FieldTypeBoolean:
+		return types.NewBooleanProperty()
+	case models.FieldTypeNested:
+		nested := types.NewNestedProperty()
+		nested.Dynamic = &dynamicmapping.Strict
+		nested.Properties = buildSchemaProperties(field.Properties)
+		return nested
+	}
+
+	object := types.NewObjectProperty()
+	object.Dynamic = &dynamicmapping.Strict
+	object.Properties = buildSchemaProperties(field.Properties)
+	return object
+}
+
+// titleProperty adds search_as_you_type subfield to title, it keeps standard analyzer
+// because stemming of partially typed words breaks prefix matching.
+func titleProperty(analysis *models.IndexAnalysis) *types.TextProperty {
+	title := contentProperty(analysis)
+	title.Fields[SuggestSubfield] = types.NewSearchAsYouTypeProperty()
+	return title
+}
+
+// contentProperty maps title and text with content analyzers if analysis is configured.
+func contentProperty(analysis *models.IndexAnalysis) *types.TextProperty {
+	ignoreAbove := keywordIgnoreAbove
+	keyword := types.NewKeywordProperty()
+	keyword.IgnoreAbove = &ignoreAbove
+
+	text := types.NewTextProperty()
+	text.Fields = map[string]types.Property{KeywordSubfield: keyword}
+	if analysis != nil {
+		analyzer, searchAnalyzer := ContentAnalyzer, ContentSearchAnalyzer
+		text.Analyzer = &analyzer
+		text.SearchAnalyzer = &searchAnalyzer
+	}
+	return text
+}
@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildMappingTests = []struct {
+	testName		string
+	index			*models.IndexMetadata
+	expectedMapping	string
+}{
+	{
+		testName: "Fields object stays dynamic without schema",
+		index: &models.IndexMetadata{},
+		expectedMapping: `{"properties":{"fields":{"type":"object"},"indexed_at":{"type":"date"},"simhash":{"type":"keyword"},"simhash_bands":{"type":"keyword"},"text":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"}},"type":"text"},"title":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"},"suggest":{"type":"search_as_you_type"}},"type":"text"}}}`,
+	},
+	{
+		testName: "Schema fields are mapped strictly",
+		index: &models.IndexMetadata{
+			Schema: &models.IndexSchema{
+				Fields: map[string]models.SchemaField{
+					"tags": {Type: models.FieldTypeKeyword},
+					"price": {Type: models.FieldTypeFloat},
+					"authors": {Type: models.FieldTypeNested, Properties: map[string]models.SchemaField{
+						"name": {Type: models.FieldTypeText},
+					}},
+				},
+			},
+		},
+		expectedMapping: `{"properties":{"fields":{"dynamic":"strict","properties":{"authors":{"dynamic":"strict","properties":{"name":{"type":"text"}},"type":"nested"},"price":{"type":"float"},"tags":{"type":"keyword"}},"type":"object"},"indexed_at":{"type":"date"},"simhash":{"type":"keyword"},"simhash_bands":{"type":"keyword"},"text":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"}},"type":"text"},"title":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"},"suggest":{"type":"search_as_you_type"}},"type":"text"}}}`,
+	},
+	{
+		testName: "Title and text use content analyzers when analysis is configured",
+		index: &models.IndexMetadata{Analysis: &models.IndexAnalysis{Language: "english"}},
+		expectedMapping: `{"properties":{"fields":{"type":"object"},"indexed_at":{"type":"date"},"simhash":{"type":"keyword"},"simhash_bands":{"type":"keyword"},"text":{"analyzer":"content","fields":{"keyword":{"ignore_above":256,"type":"keyword"}},"search_analyzer":"content_search","type":"text"},"title":{"analyzer":"content","fields":{"keyword":{"ignore_above":256,"type":"keyword"},"suggest":{"type":"search_as_you_type"}},"search_analyzer":"content_search","type":"text"}}}`,
+	},
+	{
+		testName: "Embedding is mapped as dense vector when vector is configured",
+		index: &models.IndexMetadata{Vector: &models.VectorSettings{Dimensions: 384}},
+		expectedMapping: `{"properties":{"embedding":{"dims":384,"index":true,"similarity":"cosine","type":"dense_vector"},"fields":{"type":"object"},"indexed_at":{"type":"date"},"simhash":{"type":"keyword"},"simhash_bands":{"type":"keyword"},"text":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"}},"type":"text"},"title":{"fields":{"keyword":{"ignore_above":256,"type":"keyword"},"suggest":{"type":"search_as_you_type"}},"type":"text"}}}`,
+	},
+}
+
+func TestBuildMapping(t *testing.T) {
+	for i, test := range buildMappingTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledMapping, err := json.Marshal(buildMapping(test.index))
+		if err != nil {
+			t.Fatalf("Unable to marshal mapping, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledMapping), test.expectedMapping, "wrong mapping")
+	}
+}
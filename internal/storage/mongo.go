@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/xavesen/search-api/internal/models"
@@ -16,6 +18,10 @@ type MongoStorage struct {
 	database 			*mongo.Database
 	usersCollection		*mongo.Collection
 	blacklistCollection	*mongo.Collection
+	indexesCollection	*mongo.Collection
+	webhooksCollection	*mongo.Collection
+	deliveriesCollection	*mongo.Collection
+	reindexTasksCollection	*mongo.Collection
 }
 
 func NewMongoStorage(ctx context.Context, addr string, db string, user string, password string) (*MongoStorage, error) {
@@ -47,12 +53,20 @@ func NewMongoStorage(ctx context.Context, addr string, db string, user string, p
 	appDb := newClient.Database(db)
 	usersCol := appDb.Collection("users")
 	blacklistCol := appDb.Collection("blacklist")
+	indexesCol := appDb.Collection("indexes")
+	webhooksCol := appDb.Collection("webhooks")
+	deliveriesCol := appDb.Collection("webhook_deliveries")
+	reindexTasksCol := appDb.Collection("reindex_tasks")
 
 	newStorage := &MongoStorage{
 		client: newClient,
 		database: appDb,
 		usersCollection: usersCol,
 		blacklistCollection: blacklistCol,
+		indexesCollection: indexesCol,
+		webhooksCollection: webhooksCol,
+		deliveriesCollection: deliveriesCol,
+		reindexTasksCollection: reindexTasksCol,
 	}
 
 	log.Info("Successfully initialized and connected mongo db")
@@ -87,6 +101,45 @@ func (s *MongoStorage) CheckUserIndexRights(ctx context.Context, userId string,
 	return false, nil
 }
 
+// FilterUserIndexes returns those of indexNames user has rights for, models.AllIndexes
+// returns all indexes of the user.
+func (s *MongoStorage) FilterUserIndexes(ctx context.Context, userId string, indexNames []string) ([]string, error) {
+	oid, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		log.Errorf("Error converting userId string %s to object id while filtering user indexes: %s", userId, err.Error())
+		return nil, err
+	}
+
+	var user models.User
+	projection := options.FindOne().SetProjection(bson.D{{Key: "indexes", Value: 1}})
+	if err := s.usersCollection.FindOne(ctx, bson.D{{Key: "_id", Value: oid}}, projection).Decode(&user); err != nil {
+		log.Errorf("Error finding in db indexes of user with id %s: %s", userId, err)
+		return nil, err
+	}
+
+	return filterIndexes(user.Indexes, indexNames), nil
+}
+
+// filterIndexes keeps order of requested index names.
+func filterIndexes(userIndexes []string, indexNames []string) []string {
+	if len(indexNames) == 1 && indexNames[0] == models.AllIndexes {
+		return append([]string{}, userIndexes...)
+	}
+
+	owned := make(map[string]bool, len(userIndexes))
+	for _, index := range userIndexes {
+		owned[index] = true
+	}
+
+	filtered := []string{}
+	for _, index := range indexNames {
+		if owned[index] {
+			filtered = append(filtered, index)
+		}
+	}
+	return filtered
+}
+
 func (s *MongoStorage) AddIndexToUser(ctx context.Context, userId string, indexName string) error {
 	oid, err := primitive.ObjectIDFromHex(userId)
 	if err != nil {
@@ -196,4 +249,352 @@ func (s *MongoStorage) CheckIfTokenBlacklisted(ctx context.Context, token string
 	}
 
 	return false, nil
-}
\ No newline at end of file
+}
+
+func (s *MongoStorage) CreateIndexMetadata(ctx context.Context, index *models.IndexMetadata) error {
+	_, err := s.indexesCollection.InsertOne(ctx, index)
+	if err != nil {
+		log.Errorf("Error inserting metadata of index %s to db: %s", index.Name, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) DeleteIndexMetadata(ctx context.Context, indexName string) error {
+	filter := bson.D{
+		{Key: "_id", Value: indexName},
+	}
+
+	_, err := s.indexesCollection.DeleteOne(ctx, filter)
+	if err != nil {
+		log.Errorf("Error deleting metadata of index %s from db: %s", indexName, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetIndexMetadata(ctx context.Context, indexName string) (*models.IndexMetadata, error) {
+	var index *models.IndexMetadata
+	filter := bson.D{
+		{Key: "_id", Value: indexName},
+	}
+
+	if err := s.indexesCollection.FindOne(ctx, filter).Decode(&index); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Debugf("No metadata for index %s in db", indexName)
+		} else {
+			log.Errorf("Error searching for metadata of index %s in db: %s", indexName, err)
+		}
+		return nil, err
+	}
+
+	return index, nil
+}
+
+// GetIndexesMetadata returns metadata of indexes in one query, indexes without metadata are skipped.
+func (s *MongoStorage) GetIndexesMetadata(ctx context.Context, indexNames []string) ([]models.IndexMetadata, error) {
+	filter := bson.D{
+		{Key: "_id", Value: bson.D{{Key: "$in", Value: indexNames}}},
+	}
+
+	cursor, err := s.indexesCollection.Find(ctx, filter)
+	if err != nil {
+		log.Errorf("Error searching for metadata of indexes %s in db: %s", strings.Join(indexNames, ", "), err)
+		return nil, err
+	}
+
+	indexes := []models.IndexMetadata{}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		log.Errorf("Error decoding metadata of indexes %s from db: %s", strings.Join(indexNames, ", "), err)
+		return nil, err
+	}
+
+	return indexes, nil
+}
+
+// UpdateIndexSchema replaces stored schema, indexes created before schemas were supported get metadata document here.
+func (s *MongoStorage) UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "schema", Value: schema},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "createdAt", Value: time.Now().UTC()},
+		}},
+	}
+
+	_, err := s.indexesCollection.UpdateByID(ctx, indexName, update, options.Update().SetUpsert(true))
+	if err != nil {
+		log.Errorf("Error updating schema of index %s in db: %s", indexName, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateIndexFuzzySettings replaces default fuzzy settings, like schema they don't depend on
+// index settings, so indexes without metadata get it here.
+func (s *MongoStorage) UpdateIndexFuzzySettings(ctx context.Context, indexName string, fuzzy *models.FuzzySettings) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "fuzzy", Value: fuzzy},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "createdAt", Value: time.Now().UTC()},
+		}},
+	}
+
+	_, err := s.indexesCollection.UpdateByID(ctx, indexName, update, options.Update().SetUpsert(true))
+	if err != nil {
+		log.Errorf("Error updating fuzzy settings of index %s in db: %s", indexName, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateIndexDedupe sets dedupe policy, it is applied by ingestion only, so indexes without
+// metadata get it here like fuzzy settings.
+func (s *MongoStorage) UpdateIndexDedupe(ctx context.Context, indexName string, dedupe string) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "dedupe", Value: dedupe},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "createdAt", Value: time.Now().UTC()},
+		}},
+	}
+
+	_, err := s.indexesCollection.UpdateByID(ctx, indexName, update, options.Update().SetUpsert(true))
+	if err != nil {
+		log.Errorf("Error updating dedupe policy of index %s in db: %s", indexName, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "analysis", Value: analysis},
+		}},
+	}
+
+	result, err := s.indexesCollection.UpdateByID(ctx, indexName, update)
+	if err != nil {
+		log.Errorf("Error updating analysis of index %s in db: %s", indexName, err)
+		return err
+	} else if result.MatchedCount == 0 {
+		log.Errorf("Error updating analysis of index %s in db: index metadata doesn't exist", indexName)
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+// UpdateIndexVersion records version index alias points to along with settings it was
+// created with, indexes without metadata get it here like with schema updates.
+func (s *MongoStorage) UpdateIndexVersion(ctx context.Context, indexName string, version int, schema *models.IndexSchema, analysis *models.IndexAnalysis) error {
+	update := bson.D{
+		{Key: "$set", Value: bson.D{
+			{Key: "version", Value: version},
+			{Key: "schema", Value: schema},
+			{Key: "analysis", Value: analysis},
+		}},
+		{Key: "$setOnInsert", Value: bson.D{
+			{Key: "createdAt", Value: time.Now().UTC()},
+		}},
+	}
+
+	_, err := s.indexesCollection.UpdateByID(ctx, indexName, update, options.Update().SetUpsert(true))
+	if err != nil {
+		log.Errorf("Error updating version of index %s in db: %s", indexName, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) CreateReindexTask(ctx context.Context, task *models.ReindexTask) error {
+	task.Id = primitive.NewObjectID().Hex()
+	_, err := s.reindexTasksCollection.InsertOne(ctx, task)
+	if err != nil {
+		log.Errorf("Error inserting reindex task of index %s to db: %s", task.Index, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetReindexTask(ctx context.Context, id string) (*models.ReindexTask, error) {
+	var task *models.ReindexTask
+	filter := bson.D{
+		{Key: "_id", Value: id},
+	}
+
+	if err := s.reindexTasksCollection.FindOne(ctx, filter).Decode(&task); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Debugf("No reindex task with id %s in db", id)
+		} else {
+			log.Errorf("Error searching for reindex task %s in db: %s", id, err)
+		}
+		return nil, err
+	}
+
+	return task, nil
+}
+
+// GetRunningReindexTasks returns running tasks of the index, or of all indexes if index name is empty.
+func (s *MongoStorage) GetRunningReindexTasks(ctx context.Context, indexName string) ([]models.ReindexTask, error) {
+	filter := bson.D{
+		{Key: "status", Value: models.ReindexStatusRunning},
+	}
+	if indexName != "" {
+		filter = append(filter, bson.E{Key: "index", Value: indexName})
+	}
+
+	cursor, err := s.reindexTasksCollection.Find(ctx, filter)
+	if err != nil {
+		log.Errorf("Error searching for running reindex tasks of index %s in db: %s", indexName, err)
+		return nil, err
+	}
+
+	tasks := []models.ReindexTask{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		log.Errorf("Error decoding running reindex tasks of index %s from db: %s", indexName, err)
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+// UpdateReindexTask replaces task with its current state.
+func (s *MongoStorage) UpdateReindexTask(ctx context.Context, task *models.ReindexTask) error {
+	result, err := s.reindexTasksCollection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: task.Id}}, task)
+	if err != nil {
+		log.Errorf("Error updating reindex task %s of index %s in db: %s", task.Id, task.Index, err)
+		return err
+	} else if result.MatchedCount == 0 {
+		log.Errorf("Error updating reindex task %s of index %s in db: No task with such id", task.Id, task.Index)
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	webhook.Id = primitive.NewObjectID().Hex()
+	_, err := s.webhooksCollection.InsertOne(ctx, webhook)
+	if err != nil {
+		log.Errorf("Error inserting webhook of index %s to db: %s", webhook.Index, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	var webhook *models.Webhook
+	filter := bson.D{
+		{Key: "_id", Value: id},
+	}
+
+	if err := s.webhooksCollection.FindOne(ctx, filter).Decode(&webhook); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Debugf("No webhook with id %s in db", id)
+		} else {
+			log.Errorf("Error searching for webhook %s in db: %s", id, err)
+		}
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+func (s *MongoStorage) GetIndexWebhooks(ctx context.Context, indexName string) ([]models.Webhook, error) {
+	filter := bson.D{
+		{Key: "index", Value: indexName},
+	}
+
+	cursor, err := s.webhooksCollection.Find(ctx, filter)
+	if err != nil {
+		log.Errorf("Error searching for webhooks of index %s in db: %s", indexName, err)
+		return nil, err
+	}
+
+	webhooks := []models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		log.Errorf("Error decoding webhooks of index %s from db: %s", indexName, err)
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+func (s *MongoStorage) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.Id = primitive.NewObjectID().Hex()
+	_, err := s.deliveriesCollection.InsertOne(ctx, delivery)
+	if err != nil {
+		log.Errorf("Error inserting delivery of webhook %s to db: %s", delivery.WebhookId, err)
+		return err
+	}
+
+	return nil
+}
+
+// UpdateDelivery replaces delivery with its current state after delivery attempt.
+func (s *MongoStorage) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	result, err := s.deliveriesCollection.ReplaceOne(ctx, bson.D{{Key: "_id", Value: delivery.Id}}, delivery)
+	if err != nil {
+		log.Errorf("Error updating delivery %s of webhook %s in db: %s", delivery.Id, delivery.WebhookId, err)
+		return err
+	} else if result.MatchedCount == 0 {
+		log.Errorf("Error updating delivery %s of webhook %s in db: No delivery with such id", delivery.Id, delivery.WebhookId)
+		return mongo.ErrNoDocuments
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	var delivery *models.WebhookDelivery
+	filter := bson.D{
+		{Key: "_id", Value: id},
+	}
+
+	if err := s.deliveriesCollection.FindOne(ctx, filter).Decode(&delivery); err != nil {
+		if err == mongo.ErrNoDocuments {
+			log.Debugf("No webhook delivery with id %s in db", id)
+		} else {
+			log.Errorf("Error searching for webhook delivery %s in db: %s", id, err)
+		}
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// GetDeliveries returns latest deliveries of webhook.
+func (s *MongoStorage) GetDeliveries(ctx context.Context, webhookId string, size int) ([]models.WebhookDelivery, error) {
+	filter := bson.D{
+		{Key: "webhookId", Value: webhookId},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}).SetLimit(int64(size))
+
+	cursor, err := s.deliveriesCollection.Find(ctx, filter, opts)
+	if err != nil {
+		log.Errorf("Error searching for deliveries of webhook %s in db: %s", webhookId, err)
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		log.Errorf("Error decoding deliveries of webhook %s from db: %s", webhookId, err)
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/operator"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/textquerytype"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Fields plain text queries are run against, internal fields are never searched.
+var DefaultSearchFields = []string{"title", "text"}
+
+var ErrEmptySearchClause = errors.New("search clause has no query set")
+
+// buildSearchQuery translates search request into ES query. Search DSL takes priority,
+// raw query_string and simple_query_string are used only when explicitly asked for,
+// otherwise query is matched against default fields.
+func buildSearchQuery(searchRequest *models.DocumentSearchRequest) (*types.Query, error) {
+	if searchRequest.Search != nil {
+		return buildClauseQuery(searchRequest.Search, &searchRequest.FuzzySettings)
+	}
+
+	if searchRequest.Syntax == models.SyntaxQueryString {
+		return &types.Query{
+			QueryString: &types.QueryStringQuery{
+				Query: searchRequest.Query,
+			},
+		}, nil
+	}
+
+	if searchRequest.Syntax == models.SyntaxSimple {
+		simpleQuery := &types.SimpleQueryStringQuery{
+			Query: searchRequest.Query,
+			Fields: DefaultSearchFields,
+			DefaultOperator: queryOperator(searchRequest.DefaultOperator),
+		}
+		if searchRequest.SimpleQueryFlags != "" {
+			simpleQuery.Flags = searchRequest.SimpleQueryFlags
+		}
+		return &types.Query{SimpleQueryString: simpleQuery}, nil
+	}
+
+	multiMatch := &types.MultiMatchQuery{
+		Query: searchRequest.Query,
+		Fields: DefaultSearchFields,
+	}
+	applyMultiMatchFuzziness(multiMatch, &searchRequest.FuzzySettings)
+	return &types.Query{MultiMatch: multiMatch}, nil
+}
+
+// buildClauseQuery translates search DSL clause, fuzzy settings are applied to match and
+// multi_match clauses, nil fuzzy settings leave queries exact.
+func buildClauseQuery(clause *models.SearchClause, fuzzy *models.FuzzySettings) (*types.Query, error) {
+	switch {
+	case clause.Match != nil:
+		match := types.MatchQuery{
+			Query: clause.Match.Query,
+			Operator: queryOperator(clause.Match.Operator),
+			Boost: clause.Match.Boost,
+		}
+		if fuzzy != nil {
+			if fuzzy.Fuzziness != "" {
+				match.Fuzziness = strings.ToUpper(fuzzy.Fuzziness)
+			}
+			match.PrefixLength = fuzzy.PrefixLength
+			match.MaxExpansions = fuzzy.MaxExpansions
+		}
+		return &types.Query{
+			Match: map[string]types.MatchQuery{clause.Match.Field: match},
+		}, nil
+	case clause.MatchPhrase != nil:
+		return &types.Query{
+			MatchPhrase: map[string]types.MatchPhraseQuery{
+				clause.MatchPhrase.Field: {
+					Query: clause.MatchPhrase.Query,
+					Boost: clause.MatchPhrase.Boost,
+				},
+			},
+		}, nil
+	case clause.MultiMatch != nil:
+		return buildMultiMatchQuery(clause.MultiMatch, fuzzy), nil
+	case clause.Bool != nil:
+		return buildBoolQuery(clause.Bool, fuzzy)
+	case clause.Term != nil:
+		return &types.Query{
+			Term: map[string]types.TermQuery{
+				clause.Term.Field: {Value: clause.Term.Value},
+			},
+		}, nil
+	case clause.Range != nil:
+		return &types.Query{
+			Range: map[string]types.RangeQuery{
+				clause.Range.Field: types.UntypedRangeQuery{
+					Gt: clause.Range.Gt,
+					Gte: clause.Range.Gte,
+					Lt: clause.Range.Lt,
+					Lte: clause.Range.Lte,
+				},
+			},
+		}, nil
+	case clause.Prefix != nil:
+		return &types.Query{
+			Prefix: map[string]types.PrefixQuery{
+				clause.Prefix.Field: {Value: clause.Prefix.Value},
+			},
+		}, nil
+	}
+
+	return nil, ErrEmptySearchClause
+}
+
+func buildMultiMatchQuery(clause *models.MultiMatchClause, fuzzy *models.FuzzySettings) *types.Query {
+	fields := make([]string, 0, len(clause.Fields))
+	for _, field := range clause.Fields {
+		if field.Boost != nil {
+			fields = append(fields, fmt.Sprintf("%s^%g", field.Field, *field.Boost))
+		} else {
+			fields = append(fields, field.Field)
+		}
+	}
+
+	multiMatch := &types.MultiMatchQuery{
+		Query: clause.Query,
+		Fields: fields,
+		Operator: queryOperator(clause.Operator),
+	}
+	if clause.Type != "" {
+		multiMatch.Type = &textquerytype.TextQueryType{Name: clause.Type}
+	}
+	applyMultiMatchFuzziness(multiMatch, fuzzy)
+
+	return &types.Query{MultiMatch: multiMatch}
+}
+
+// applyMultiMatchFuzziness skips cross_fields and phrase types, ES rejects fuzziness for them.
+func applyMultiMatchFuzziness(multiMatch *types.MultiMatchQuery, fuzzy *models.FuzzySettings) {
+	if fuzzy == nil {
+		return
+	}
+	if multiMatch.Type != nil && multiMatch.Type.Name != "best_fields" && multiMatch.Type.Name != "most_fields" {
+		return
+	}
+
+	if fuzzy.Fuzziness != "" {
+		multiMatch.Fuzziness = strings.ToUpper(fuzzy.Fuzziness)
+	}
+	multiMatch.PrefixLength = fuzzy.PrefixLength
+	multiMatch.MaxExpansions = fuzzy.MaxExpansions
+}
+
+func buildBoolQuery(clause *models.BoolClause, fuzzy *models.FuzzySettings) (*types.Query, error) {
+	boolQuery := &types.BoolQuery{}
+	if clause.MinimumShouldMatch != nil {
+		boolQuery.MinimumShouldMatch = *clause.MinimumShouldMatch
+	}
+
+	var err error
+	if boolQuery.Must, err = buildClauseQueries(clause.Must, fuzzy); err != nil {
+		return nil, err
+	}
+	if boolQuery.Should, err = buildClauseQueries(clause.Should, fuzzy); err != nil {
+		return nil, err
+	}
+	if boolQuery.MustNot, err = buildClauseQueries(clause.MustNot, fuzzy); err != nil {
+		return nil, err
+	}
+	if boolQuery.Filter, err = buildClauseQueries(clause.Filter, fuzzy); err != nil {
+		return nil, err
+	}
+
+	return &types.Query{Bool: boolQuery}, nil
+}
+
+func buildClauseQueries(clauses []models.SearchClause, fuzzy *models.FuzzySettings) ([]types.Query, error) {
+	queries := make([]types.Query, 0, len(clauses))
+	for i := range clauses {
+		query, err := buildClauseQuery(&clauses[i], fuzzy)
+		if err != nil {
+			return nil, err
+		}
+		queries = append(queries, *query)
+	}
+
+	return queries, nil
+}
+
+func queryOperator(name string) *operator.Operator {
+	if name == "" {
+		return nil
+	}
+	return &operator.Operator{Name: name}
+}
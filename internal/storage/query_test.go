@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var boost = float32(3)
+var minimumShouldMatch = 1
+var prefixLength = 1
+var maxExpansions = 20
+
+var buildSearchQueryTests = []struct {
+	testName 		string
+	request			*models.DocumentSearchRequest
+	expectedQuery	string
+}{
+	{
+		testName: "Plain query is matched against title and text",
+		request: &models.DocumentSearchRequest{Query: "foo AND (bar"},
+		expectedQuery: `{"multi_match":{"fields":["title","text"],"query":"foo AND (bar"}}`,
+	},
+	{
+		testName: "Raw query_string is used only when asked for",
+		request: &models.DocumentSearchRequest{Query: "title:foo", Syntax: models.SyntaxQueryString},
+		expectedQuery: `{"query_string":{"query":"title:foo"}}`,
+	},
+	{
+		testName: "Simple syntax is restricted to title and text",
+		request: &models.DocumentSearchRequest{Query: "foo + bar", Syntax: models.SyntaxSimple, DefaultOperator: "and", SimpleQueryFlags: "WHITESPACE|AND"},
+		expectedQuery: `{"simple_query_string":{"default_operator":"and","fields":["title","text"],"flags":"WHITESPACE|AND","query":"foo + bar"}}`,
+	},
+	{
+		testName: "Bool clause with nested clauses",
+		request: &models.DocumentSearchRequest{
+			Search: &models.SearchClause{
+				Bool: &models.BoolClause{
+					Must: []models.SearchClause{{Match: &models.MatchClause{Field: "title", Query: "go", Operator: "and"}}},
+					Should: []models.SearchClause{{MatchPhrase: &models.MatchClause{Field: "text", Query: "search api"}}},
+					MustNot: []models.SearchClause{{Prefix: &models.PrefixClause{Field: "title", Value: "draft"}}},
+					Filter: []models.SearchClause{
+						{Term: &models.TermClause{Field: "tags", Value: "news"}},
+						{Range: &models.RangeClause{Field: "price", Gte: json.RawMessage("10"), Lt: json.RawMessage(`"20"`)}},
+					},
+					MinimumShouldMatch: &minimumShouldMatch,
+				},
+			},
+		},
+		expectedQuery: `{"bool":{"filter":[{"term":{"tags":{"value":"news"}}},{"range":{"price":{"gte":10,"lt":"20"}}}],"minimum_should_match":1,"must":[{"match":{"title":{"operator":"and","query":"go"}}}],"must_not":[{"prefix":{"title":{"value":"draft"}}}],"should":[{"match_phrase":{"text":{"query":"search api"}}}]}}`,
+	},
+	{
+		testName: "Multi match with per field boosts",
+		request: &models.DocumentSearchRequest{
+			Search: &models.SearchClause{
+				MultiMatch: &models.MultiMatchClause{
+					Query: "go",
+					Fields: []models.FieldBoost{{Field: "title", Boost: &boost}, {Field: "text"}},
+					Type: "best_fields",
+				},
+			},
+		},
+		expectedQuery: `{"multi_match":{"fields":["title^3","text"],"query":"go","type":"best_fields"}}`,
+	},
+	{
+		testName: "Fuzzy settings apply to plain query",
+		request: &models.DocumentSearchRequest{Query: "iphnoe", FuzzySettings: models.FuzzySettings{Fuzziness: "auto", PrefixLength: &prefixLength, MaxExpansions: &maxExpansions}},
+		expectedQuery: `{"multi_match":{"fields":["title","text"],"fuzziness":"AUTO","max_expansions":20,"prefix_length":1,"query":"iphnoe"}}`,
+	},
+	{
+		testName: "Fuzzy settings apply to match clauses but not to phrase multi match",
+		request: &models.DocumentSearchRequest{
+			Search: &models.SearchClause{
+				Bool: &models.BoolClause{
+					Should: []models.SearchClause{
+						{Match: &models.MatchClause{Field: "title", Query: "iphnoe"}},
+						{MultiMatch: &models.MultiMatchClause{Query: "iphnoe case", Fields: []models.FieldBoost{{Field: "text"}}, Type: "phrase"}},
+					},
+				},
+			},
+			FuzzySettings: models.FuzzySettings{Fuzziness: "1"},
+		},
+		expectedQuery: `{"bool":{"should":[{"match":{"title":{"fuzziness":"1","query":"iphnoe"}}},{"multi_match":{"fields":["text"],"query":"iphnoe case","type":"phrase"}}]}}`,
+	},
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	for i, test := range buildSearchQueryTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		query, err := buildSearchQuery(test.request)
+		if err != nil {
+			t.Fatalf("Unable to build query, error: %s\n", err)
+		}
+
+		marshaledQuery, err := json.Marshal(query)
+		if err != nil {
+			t.Fatalf("Unable to marshal query, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledQuery), test.expectedQuery, "wrong query")
+	}
+}
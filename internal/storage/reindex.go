@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/reindex"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/conflicts"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/optype"
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Separates index name and version in names of physical indexes, it isn't allowed in
+// names of new indexes, so physical names never clash with them
+const indexVersionSeparator = "~v"
+
+// PhysicalIndexName returns name of ES index holding version of the index, indexes created
+// before aliases were used have version zero and are ES indexes named as the index.
+func PhysicalIndexName(indexName string, version int) string {
+	if version == 0 {
+		return indexName
+	}
+	return indexName + indexVersionSeparator + strconv.Itoa(version)
+}
+
+// LogicalIndexName returns name of the index physical ES index belongs to.
+func LogicalIndexName(physicalName string) string {
+	name, version, found := strings.Cut(physicalName, indexVersionSeparator)
+	if !found {
+		return physicalName
+	}
+	if _, err := strconv.Atoi(version); err != nil {
+		return physicalName
+	}
+	return name
+}
+
+// NewIndexVersion creates physical index of index.Version without alias, it is pointed to
+// the index once documents are copied.
+func (es *ElasticSearchClient) NewIndexVersion(ctx context.Context, index *models.IndexMetadata) error {
+	return es.createPhysicalIndex(ctx, index, nil)
+}
+
+func (es *ElasticSearchClient) createPhysicalIndex(ctx context.Context, index *models.IndexMetadata, aliases map[string]types.Alias) error {
+	physicalName := PhysicalIndexName(index.Name, index.Version)
+	createRequest := es.Client.Indices.Create(physicalName).Mappings(buildMapping(index))
+	if index.Analysis != nil {
+		createRequest.Settings(&types.IndexSettings{Analysis: buildAnalysisSettings(index.Analysis)})
+	}
+	if aliases != nil {
+		createRequest.Aliases(aliases)
+	}
+
+	_, err := createRequest.Do(ctx)
+	if err != nil {
+		log.Errorf("Error creating index '%s' in ES: %s", physicalName, err)
+		return err
+	}
+	return nil
+}
+
+// StartReindex starts copying all documents of source index to dest index in background
+// and returns id of ES task doing it.
+func (es *ElasticSearchClient) StartReindex(ctx context.Context, sourceIndex string, destIndex string) (string, error) {
+	result, err := es.Client.Reindex().
+	Source(&types.ReindexSource{Index: []string{sourceIndex}}).
+	Dest(&types.ReindexDestination{Index: destIndex}).
+	WaitForCompletion(false).
+	Do(ctx)
+	if err != nil {
+		log.Errorf("Error starting reindex of index '%s' to '%s' in ES: %s", sourceIndex, destIndex, err)
+		return "", err
+	}
+
+	return fmt.Sprint(result.Task), nil
+}
+
+// reindexTaskStatus is the part of ES reindex task status progress is taken from
+type reindexTaskStatus struct {
+	Total	int64	`json:"total"`
+	Created	int64	`json:"created"`
+	Updated	int64	`json:"updated"`
+}
+
+func (es *ElasticSearchClient) GetReindexProgress(ctx context.Context, esTaskId string) (*models.ReindexProgress, error) {
+	result, err := es.Client.Tasks.Get(esTaskId).Do(ctx)
+	if err != nil {
+		log.Errorf("Error getting reindex task %s from ES: %s", esTaskId, err)
+		return nil, err
+	}
+
+	progress := &models.ReindexProgress{Completed: result.Completed}
+	if len(result.Task.Status) > 0 {
+		var status reindexTaskStatus
+		if err := json.Unmarshal(result.Task.Status, &status); err != nil {
+			log.Errorf("Error unmarshalling status of reindex task %s: %s", esTaskId, err)
+			return nil, err
+		}
+		progress.Total, progress.Copied = status.Total, status.Created+status.Updated
+	}
+
+	if result.Error != nil {
+		progress.Error = errorCauseMessage(result.Error)
+	} else if len(result.Response) > 0 {
+		var response reindex.Response
+		if err := json.Unmarshal(result.Response, &response); err != nil {
+			log.Errorf("Error unmarshalling response of reindex task %s: %s", esTaskId, err)
+			return nil, err
+		}
+		progress.Error = reindexFailure(&response)
+	}
+
+	return progress, nil
+}
+
+// CatchUpReindex synchronously copies documents indexed in source index since the time.
+// Without overwrite documents already existing in dest index are kept.
+func (es *ElasticSearchClient) CatchUpReindex(ctx context.Context, sourceIndex string, destIndex string, since time.Time, overwrite bool) error {
+	source := &types.ReindexSource{Index: []string{sourceIndex}, Query: buildIndexedSinceQuery(since)}
+	dest := &types.ReindexDestination{Index: destIndex}
+	reindexRequest := es.Client.Reindex().Source(source)
+	if !overwrite {
+		dest.OpType = &optype.Create
+		reindexRequest.Conflicts(conflicts.Proceed)
+	}
+
+	result, err := reindexRequest.Dest(dest).Do(ctx)
+	if err != nil {
+		log.Errorf("Error copying documents indexed since %s from index '%s' to '%s' in ES: %s", since, sourceIndex, destIndex, err)
+		return err
+	}
+	if failure := reindexFailure(result); failure != "" {
+		log.Errorf("Error copying documents indexed since %s from index '%s' to '%s' in ES: %s", since, sourceIndex, destIndex, failure)
+		return fmt.Errorf("copying documents failed: %s", failure)
+	}
+
+	return nil
+}
+
+func buildIndexedSinceQuery(since time.Time) *types.Query {
+	from := since.UTC().Format(time.RFC3339Nano)
+	return &types.Query{Range: map[string]types.RangeQuery{
+		IndexedAtProperty: types.DateRangeQuery{Gte: &from},
+	}}
+}
+
+// SetIndexWriteBlock rejects or allows writes to physical index.
+func (es *ElasticSearchClient) SetIndexWriteBlock(ctx context.Context, physicalName string, blocked bool) error {
+	_, err := es.Client.Indices.PutSettings().Indices(physicalName).Blocks(&types.IndexSettingBlocks{Write: blocked}).Do(ctx)
+	if err != nil {
+		log.Errorf("Error setting write block of index '%s' to %t in ES: %s", physicalName, blocked, err)
+		return err
+	}
+	return nil
+}
+
+// SwapIndexAlias atomically points alias from old physical index to new one. Alias can't be
+// named as existing index, so index without version is deleted in the same request.
+func (es *ElasticSearchClient) SwapIndexAlias(ctx context.Context, alias string, oldIndex string, newIndex string) error {
+	_, err := es.Client.Indices.UpdateAliases().Actions(buildAliasSwapActions(alias, oldIndex, newIndex)...).Do(ctx)
+	if err != nil {
+		log.Errorf("Error swapping alias '%s' from index '%s' to '%s' in ES: %s", alias, oldIndex, newIndex, err)
+		return err
+	}
+	return nil
+}
+
+func buildAliasSwapActions(alias string, oldIndex string, newIndex string) []types.IndicesAction {
+	remove := types.IndicesAction{Remove: &types.RemoveAction{Index: &oldIndex, Alias: &alias}}
+	if oldIndex == alias {
+		remove = types.IndicesAction{RemoveIndex: &types.RemoveIndexAction{Index: &oldIndex}}
+	}
+
+	return []types.IndicesAction{
+		remove,
+		{Add: &types.AddAction{Index: &newIndex, Alias: &alias}},
+	}
+}
+
+// DeleteIndex deletes physical index, missing index isn't an error.
+func (es *ElasticSearchClient) DeleteIndex(ctx context.Context, physicalName string) error {
+	_, err := es.Client.Indices.Delete(physicalName).IgnoreUnavailable(true).Do(ctx)
+	if err != nil {
+		log.Errorf("Error deleting index '%s' in ES: %s", physicalName, err)
+		return err
+	}
+	return nil
+}
+
+// reindexFailure returns reason of the first failed document, ES stops reindex on it.
+func reindexFailure(response *reindex.Response) string {
+	if len(response.Failures) == 0 {
+		return ""
+	}
+	failure := response.Failures[0]
+	return fmt.Sprintf("document %s: %s", failure.Id, errorCauseMessage(&failure.Cause))
+}
+
+func errorCauseMessage(cause *types.ErrorCause) string {
+	if cause.Reason != nil {
+		return *cause.Reason
+	}
+	return cause.Type
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+)
+
+var indexNameTests = []struct {
+	testName		string
+	indexName		string
+	version			int
+	physicalName	string
+}{
+	{
+		testName: "Use index name for index without version",
+		indexName: "test",
+		version: 0,
+		physicalName: "test",
+	},
+	{
+		testName: "Add version to index name",
+		indexName: "test",
+		version: 12,
+		physicalName: "test~v12",
+	},
+	{
+		testName: "Keep separator without version number",
+		indexName: "test~vx",
+		version: 0,
+		physicalName: "test~vx",
+	},
+}
+
+func TestIndexNames(t *testing.T) {
+	for i, test := range indexNameTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		assert.Equal(t, PhysicalIndexName(test.indexName, test.version), test.physicalName, "wrong physical index name")
+		assert.Equal(t, LogicalIndexName(test.physicalName), test.indexName, "wrong logical index name")
+	}
+}
+
+func TestBuildAliasSwapActions(t *testing.T) {
+	marshaledActions, err := json.Marshal(buildAliasSwapActions("test", "test~v1", "test~v2"))
+	if err != nil {
+		t.Fatalf("Unable to marshal actions, error: %s\n", err)
+	}
+	assert.Equal(t, string(marshaledActions), `[{"remove":{"alias":"test","index":"test~v1"}},{"add":{"alias":"test","index":"test~v2"}}]`, "wrong swap of versioned index")
+
+	marshaledActions, err = json.Marshal(buildAliasSwapActions("test", "test", "test~v1"))
+	if err != nil {
+		t.Fatalf("Unable to marshal actions, error: %s\n", err)
+	}
+	assert.Equal(t, string(marshaledActions), `[{"remove_index":{"index":"test"}},{"add":{"alias":"test","index":"test~v1"}}]`, "wrong swap of index without version")
+}
+
+func TestBuildIndexedSinceQuery(t *testing.T) {
+	since := time.Date(2024, 5, 1, 12, 0, 0, 500, time.FixedZone("", 3600))
+	marshaledQuery, err := json.Marshal(buildIndexedSinceQuery(since))
+	if err != nil {
+		t.Fatalf("Unable to marshal query, error: %s\n", err)
+	}
+	assert.Equal(t, string(marshaledQuery), `{"range":{"indexed_at":{"gte":"2024-05-01T11:00:00.0000005Z"}}}`, "wrong catch up query")
+}
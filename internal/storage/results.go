@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// Snippets are fragments of text of about this size in characters
+const (
+	snippetSize			= 150
+	snippetsPerDocument	= 3
+)
+
+// buildSort translates sort fields, documents with equal values are ordered by relevance
+// and then by position in index, or by id across indexes, so pages don't overlap.
+// Title is sorted by its keyword subfield.
+func buildSort(searchRequest *models.DocumentSearchRequest) []types.SortCombinations {
+	if len(searchRequest.Sort) == 0 {
+		return nil
+	}
+
+	sort := make([]types.SortCombinations, 0, len(searchRequest.Sort)+2)
+	scoreSorted := false
+	for _, sortField := range searchRequest.Sort {
+		var order *sortorder.SortOrder
+		if sortField.Order != "" {
+			order = &sortorder.SortOrder{Name: sortField.Order}
+		}
+
+		switch sortField.Field {
+		case models.SortFieldScore:
+			scoreSorted = true
+			sort = append(sort, types.SortOptions{Score_: &types.ScoreSort{Order: order}})
+		case "title":
+			sort = append(sort, types.SortOptions{SortOptions: map[string]types.FieldSort{"title.keyword": {Order: order}}})
+		default:
+			sort = append(sort, types.SortOptions{SortOptions: map[string]types.FieldSort{sortField.Field: {Order: order}}})
+		}
+	}
+
+	if !scoreSorted {
+		sort = append(sort, types.SortOptions{Score_: &types.ScoreSort{Order: &sortorder.Desc}})
+	}
+	if len(searchRequest.Indexes) > 0 {
+		sort = append(sort, types.SortOptions{SortOptions: map[string]types.FieldSort{"_id": {}}})
+	} else {
+		sort = append(sort, types.SortOptions{Doc_: &types.ScoreSort{}})
+	}
+	return sort
+}
+
+// buildSourceFilter always excludes embeddings and SimHash bands, they are only needed by
+// ES, text is excluded in snippet only mode. SimHash is included when near duplicates are
+// collapsed, as they are compared by it.
+func buildSourceFilter(searchRequest *models.DocumentSearchRequest) types.SourceConfig {
+	sourceFilter := &types.SourceFilter{}
+	if searchRequest.Source != nil {
+		sourceFilter.Includes = searchRequest.Source.Includes
+		sourceFilter.Excludes = searchRequest.Source.Excludes
+	}
+
+	if searchRequest.CollapseNearDuplicates && len(sourceFilter.Includes) > 0 {
+		sourceFilter.Includes = append(append([]string{}, sourceFilter.Includes...), SimHashProperty)
+	}
+	sourceFilter.Excludes = append(append([]string{}, sourceFilter.Excludes...), EmbeddingProperty, SimHashBandsProperty)
+	if searchRequest.SnippetOnly {
+		sourceFilter.Excludes = append(sourceFilter.Excludes, "text")
+	}
+	return sourceFilter
+}
+
+// buildHighlight requests snippets of text in snippet only mode, documents not matching
+// in text get its beginning.
+func buildHighlight(searchRequest *models.DocumentSearchRequest) *types.Highlight {
+	if !searchRequest.SnippetOnly {
+		return nil
+	}
+
+	fragmentSize, numberOfFragments, noMatchSize := snippetSize, snippetsPerDocument, snippetSize
+	return &types.Highlight{
+		Fields: map[string]types.HighlightField{
+			"text": {
+				FragmentSize: &fragmentSize,
+				NumberOfFragments: &numberOfFragments,
+				NoMatchSize: &noMatchSize,
+			},
+		},
+	}
+}
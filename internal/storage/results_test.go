@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildResultsOptionsTests = []struct {
+	testName			string
+	request				*models.DocumentSearchRequest
+	expectedSort		string
+	expectedSource		string
+	expectedHighlight	string
+}{
+	{
+		testName: "Relevance order and whole documents by default",
+		request: &models.DocumentSearchRequest{Query: "go"},
+		expectedSort: `null`,
+		expectedSource: `{"excludes":["embedding","simhash_bands"]}`,
+		expectedHighlight: `null`,
+	},
+	{
+		testName: "Sort by fields with relevance as tie breaker",
+		request: &models.DocumentSearchRequest{
+			Query: "go",
+			Sort: []models.SortField{{Field: "fields.price", Order: "desc"}, {Field: "title"}},
+			Source: &models.SourceFilter{Includes: []string{"title"}},
+		},
+		expectedSort: `[{"fields.price":{"order":"desc"}},{"title.keyword":{}},{"_score":{"order":"desc"}},{"_doc":{}}]`,
+		expectedSource: `{"excludes":["embedding","simhash_bands"],"includes":["title"]}`,
+		expectedHighlight: `null`,
+	},
+	{
+		testName: "Explicit score sort isn't duplicated",
+		request: &models.DocumentSearchRequest{Query: "go", Sort: []models.SortField{{Field: "_score", Order: "asc"}, {Field: "fields.created"}}},
+		expectedSort: `[{"_score":{"order":"asc"}},{"fields.created":{}},{"_doc":{}}]`,
+		expectedSource: `{"excludes":["embedding","simhash_bands"]}`,
+		expectedHighlight: `null`,
+	},
+	{
+		testName: "Sort of multi index search ends with id",
+		request: &models.DocumentSearchRequest{Indexes: []string{"books", "articles"}, Query: "go", Sort: []models.SortField{{Field: "fields.created"}}},
+		expectedSort: `[{"fields.created":{}},{"_score":{"order":"desc"}},{"_id":{}}]`,
+		expectedSource: `{"excludes":["embedding","simhash_bands"]}`,
+		expectedHighlight: `null`,
+	},
+	{
+		testName: "Include simhash when near duplicates are collapsed",
+		request: &models.DocumentSearchRequest{Query: "go", CollapseNearDuplicates: true, Source: &models.SourceFilter{Includes: []string{"title"}}},
+		expectedSort: `null`,
+		expectedSource: `{"excludes":["embedding","simhash_bands"],"includes":["title","simhash"]}`,
+		expectedHighlight: `null`,
+	},
+	{
+		testName: "Snippet only mode excludes text and highlights it",
+		request: &models.DocumentSearchRequest{Query: "go", SnippetOnly: true, Source: &models.SourceFilter{Excludes: []string{"fields"}}},
+		expectedSort: `null`,
+		expectedSource: `{"excludes":["fields","embedding","simhash_bands","text"]}`,
+		expectedHighlight: `{"fields":{"text":{"fragment_size":150,"no_match_size":150,"number_of_fragments":3}}}`,
+	},
+}
+
+func TestBuildResultsOptions(t *testing.T) {
+	for i, test := range buildResultsOptionsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledSort, err := json.Marshal(buildSort(test.request))
+		if err != nil {
+			t.Fatalf("Unable to marshal sort, error: %s\n", err)
+		}
+		marshaledSource, err := json.Marshal(buildSourceFilter(test.request))
+		if err != nil {
+			t.Fatalf("Unable to marshal source filter, error: %s\n", err)
+		}
+		marshaledHighlight, err := json.Marshal(buildHighlight(test.request))
+		if err != nil {
+			t.Fatalf("Unable to marshal highlight, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledSort), test.expectedSort, "wrong sort")
+		assert.Equal(t, string(marshaledSource), test.expectedSource, "wrong source filter")
+		assert.Equal(t, string(marshaledHighlight), test.expectedHighlight, "wrong highlight")
+	}
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+type SavedSearchStorageMock struct {
+	CreateError			error
+	GetError			error
+	PercolateError		error
+	SaveMatchesError	error
+	GetMatchesError		error
+	SavedSearchId		string
+	SavedSearch			*models.SavedSearch
+	CreatedSavedSearch	*models.SavedSearch
+	PercolatedSearches	[]models.PercolatedSearch
+	PercolatedDocuments	[]models.Document
+	Matches				[]models.SavedSearchMatch
+	MatchesSize			int
+}
+
+func (ss *SavedSearchStorageMock) CreateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch) error {
+	ss.CreatedSavedSearch = savedSearch
+	if ss.CreateError != nil {
+		return ss.CreateError
+	}
+
+	savedSearch.Id = ss.SavedSearchId
+	return nil
+}
+
+func (ss *SavedSearchStorageMock) GetSavedSearch(ctx context.Context, id string) (*models.SavedSearch, error) {
+	return ss.SavedSearch, ss.GetError
+}
+
+func (ss *SavedSearchStorageMock) PercolateDocuments(ctx context.Context, indexName string, documents []models.Document) ([]models.PercolatedSearch, error) {
+	ss.PercolatedDocuments = documents
+	if ss.PercolateError != nil {
+		return nil, ss.PercolateError
+	}
+
+	return ss.PercolatedSearches, nil
+}
+
+func (ss *SavedSearchStorageMock) SaveMatches(ctx context.Context, matches []models.SavedSearchMatch) error {
+	ss.Matches = append(ss.Matches, matches...)
+	return ss.SaveMatchesError
+}
+
+func (ss *SavedSearchStorageMock) GetMatches(ctx context.Context, savedSearchId string, size int) (*models.SavedSearchMatchesResponse, error) {
+	ss.MatchesSize = size
+	if ss.GetMatchesError != nil {
+		return nil, ss.GetMatchesError
+	}
+
+	return &models.SavedSearchMatchesResponse{Total: int64(len(ss.Matches)), Matches: ss.Matches}, nil
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/sortorder"
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	// Percolator index with queries of saved searches of all user indexes
+	SavedSearchesIndex		= "saved-searches"
+	// Index with documents matched by saved searches on ingestion
+	SavedSearchMatchesIndex	= "saved-search-matches"
+)
+
+// Field percolator queries are stored in
+const percolatorQueryProperty = "query"
+
+// Field percolator puts positions of matched documents to
+const percolatorSlotField = "_percolator_document_slot"
+
+// Limit of saved searches matched by one batch of documents
+const maxPercolatedSearches = 1000
+
+var ReservedIndexNames = []string{SavedSearchesIndex, SavedSearchMatchesIndex}
+
+// savedSearchDocument is stored in percolator index, query is built once when search is saved.
+type savedSearchDocument struct {
+	Query		*types.Query		`json:"query"`
+	Index		string				`json:"index"`
+	UserId		string				`json:"user_id"`
+	SavedSearch	models.SavedSearch	`json:"saved_search"`
+}
+
+// percolatedDocument has only fields percolated queries can match, embeddings aren't mapped
+// in percolator index.
+type percolatedDocument struct {
+	Title	string			`json:"title"`
+	Text	string			`json:"text"`
+	Fields	map[string]any	`json:"fields,omitempty"`
+}
+
+// EnsureSavedSearchIndexes creates percolator and matches indexes if they don't exist yet.
+// Percolator index maps title and text like user indexes without custom analysis, fields
+// of schemas are mapped as text when query is saved, so range queries on them compare strings.
+func (es *ElasticSearchClient) EnsureSavedSearchIndexes(ctx context.Context) error {
+	mapUnmappedFieldsAsText, _ := json.Marshal(true)
+	percolatorMapping := buildMapping(&models.IndexMetadata{})
+	percolatorMapping.Properties[percolatorQueryProperty] = types.NewPercolatorProperty()
+	percolatorMapping.Properties["index"] = types.NewKeywordProperty()
+	percolatorMapping.Properties["user_id"] = types.NewKeywordProperty()
+	percolatorMapping.Properties["saved_search"] = &types.ObjectProperty{Enabled: &[]bool{false}[0]}
+
+	_, err := es.Client.Indices.Create(SavedSearchesIndex).
+	Mappings(percolatorMapping).
+	Settings(&types.IndexSettings{IndexSettings: map[string]json.RawMessage{"percolator.map_unmapped_fields_as_text": mapUnmappedFieldsAsText}}).
+	Do(ctx)
+	if err != nil && !isResourceAlreadyExists(err) {
+		log.Errorf("Error creating saved searches index in ES: %s", err)
+		return err
+	}
+
+	matchesMapping := &types.TypeMapping{
+		Properties: map[string]types.Property{
+			"saved_search_id": types.NewKeywordProperty(),
+			"index_name": types.NewKeywordProperty(),
+			"document_id": types.NewKeywordProperty(),
+			"title": types.NewTextProperty(),
+			"matched_at": types.NewDateProperty(),
+		},
+	}
+	_, err = es.Client.Indices.Create(SavedSearchMatchesIndex).Mappings(matchesMapping).Do(ctx)
+	if err != nil && !isResourceAlreadyExists(err) {
+		log.Errorf("Error creating saved search matches index in ES: %s", err)
+		return err
+	}
+	return nil
+}
+
+func isResourceAlreadyExists(err error) bool {
+	var esError *types.ElasticsearchError
+	return errors.As(err, &esError) && esError.ErrorCause.Type == ErrResourceAlreadyExists
+}
+
+func buildSavedSearchQuery(savedSearch *models.SavedSearch) (*types.Query, error) {
+	return buildSearchQuery(&models.DocumentSearchRequest{Query: savedSearch.Query, Search: savedSearch.Search})
+}
+
+// CreateSavedSearch stores query of saved search in percolator index and sets id of saved search.
+func (es *ElasticSearchClient) CreateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch) error {
+	query, err := buildSavedSearchQuery(savedSearch)
+	if err != nil {
+		log.Errorf("Error building query of saved search for index %s: %s", savedSearch.Index, err)
+		return err
+	}
+
+	document := savedSearchDocument{
+		Query: query,
+		Index: savedSearch.Index,
+		UserId: savedSearch.UserId,
+		SavedSearch: *savedSearch,
+	}
+	result, err := es.Client.Index(SavedSearchesIndex).Request(document).Do(ctx)
+	if err != nil {
+		log.Errorf("Error saving search for index %s to ES: %s", savedSearch.Index, err)
+		return err
+	}
+
+	savedSearch.Id = result.Id_
+	return nil
+}
+
+// GetSavedSearch returns nil saved search if there is no saved search with such id.
+func (es *ElasticSearchClient) GetSavedSearch(ctx context.Context, id string) (*models.SavedSearch, error) {
+	result, err := es.Client.Get(SavedSearchesIndex, id).Do(ctx)
+	if err != nil {
+		var esError *types.ElasticsearchError
+		if errors.As(err, &esError) && esError.Status == http.StatusNotFound {
+			return nil, nil
+		}
+		log.Errorf("Error getting saved search %s from ES: %s", id, err)
+		return nil, err
+	}
+	if !result.Found {
+		return nil, nil
+	}
+
+	return parseSavedSearch(result.Id_, result.Source_)
+}
+
+func parseSavedSearch(id string, source json.RawMessage) (*models.SavedSearch, error) {
+	var document savedSearchDocument
+	if err := json.Unmarshal(source, &document); err != nil {
+		log.Errorf("Error unmarshalling saved search %s from ES: %s", id, err)
+		return nil, err
+	}
+
+	savedSearch := document.SavedSearch
+	savedSearch.Id = id
+	savedSearch.Index = document.Index
+	savedSearch.UserId = document.UserId
+	return &savedSearch, nil
+}
+
+func buildPercolateRequest(indexName string, documents []models.Document) (*search.Request, error) {
+	percolatedDocuments := make([]json.RawMessage, 0, len(documents))
+	for _, document := range documents {
+		percolated, err := json.Marshal(percolatedDocument{Title: document.Title, Text: document.Text, Fields: document.Fields})
+		if err != nil {
+			return nil, err
+		}
+		percolatedDocuments = append(percolatedDocuments, percolated)
+	}
+
+	size := maxPercolatedSearches
+	return &search.Request{
+		Query: &types.Query{
+			Bool: &types.BoolQuery{
+				Must: []types.Query{{Percolate: &types.PercolateQuery{Field: percolatorQueryProperty, Documents: percolatedDocuments}}},
+				Filter: []types.Query{{Term: map[string]types.TermQuery{"index": {Value: indexName}}}},
+			},
+		},
+		Source_: &types.SourceFilter{Excludes: []string{percolatorQueryProperty}},
+		Size: &size,
+	}, nil
+}
+
+// PercolateDocuments returns saved searches of the index matching any of documents.
+func (es *ElasticSearchClient) PercolateDocuments(ctx context.Context, indexName string, documents []models.Document) ([]models.PercolatedSearch, error) {
+	request, err := buildPercolateRequest(indexName, documents)
+	if err != nil {
+		log.Errorf("Error building percolate request for index %s: %s", indexName, err)
+		return nil, err
+	}
+
+	searchResult, err := es.Client.Search().Index(SavedSearchesIndex).Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("Error percolating %d documents of index %s: %s", len(documents), indexName, err)
+		return nil, err
+	}
+
+	return parsePercolatedSearches(searchResult.Hits.Hits), nil
+}
+
+func parsePercolatedSearches(hits []types.Hit) []models.PercolatedSearch {
+	percolatedSearches := []models.PercolatedSearch{}
+	for _, hit := range hits {
+		if hit.Id_ == nil {
+			continue
+		}
+
+		savedSearch, err := parseSavedSearch(*hit.Id_, hit.Source_)
+		if err != nil {
+			continue
+		}
+
+		var slots []int
+		if err := json.Unmarshal(hit.Fields[percolatorSlotField], &slots); err != nil {
+			log.Errorf("Error unmarshalling matched documents slots of saved search %s: %s", *hit.Id_, err)
+			continue
+		}
+
+		percolatedSearches = append(percolatedSearches, models.PercolatedSearch{SavedSearch: *savedSearch, Documents: slots})
+	}
+	return percolatedSearches
+}
+
+// SaveMatches records matched documents, document matched again by the same saved search
+// replaces previous match.
+func (es *ElasticSearchClient) SaveMatches(ctx context.Context, matches []models.SavedSearchMatch) error {
+	bulkRequest := es.Client.Bulk().Index(SavedSearchMatchesIndex)
+	for _, match := range matches {
+		id := match.SavedSearchId + "/" + match.DocumentId
+		if err := bulkRequest.IndexOp(types.IndexOperation{Id_: &id}, match); err != nil {
+			log.Errorf("Error adding match of saved search %s to bulk request: %s", match.SavedSearchId, err)
+			return err
+		}
+	}
+
+	bulkResult, err := bulkRequest.Do(ctx)
+	if err != nil {
+		log.Errorf("Error saving %d saved search matches to ES: %s", len(matches), err)
+		return err
+	}
+	if bulkResult.Errors {
+		log.Warning("Bulk request saving saved search matches finished with failed matches")
+	}
+	return nil
+}
+
+// GetMatches returns latest matches of saved search.
+func (es *ElasticSearchClient) GetMatches(ctx context.Context, savedSearchId string, size int) (*models.SavedSearchMatchesResponse, error) {
+	request := &search.Request{
+		Query: &types.Query{Term: map[string]types.TermQuery{"saved_search_id": {Value: savedSearchId}}},
+		Sort: []types.SortCombinations{types.SortOptions{SortOptions: map[string]types.FieldSort{"matched_at": {Order: &sortorder.Desc}}}},
+		Size: &size,
+	}
+
+	searchResult, err := es.Client.Search().Index(SavedSearchMatchesIndex).Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("Error getting matches of saved search %s: %s", savedSearchId, err)
+		return nil, err
+	}
+
+	response := &models.SavedSearchMatchesResponse{Matches: []models.SavedSearchMatch{}}
+	if searchResult.Hits.Total != nil {
+		response.Total = searchResult.Hits.Total.Value
+	}
+	for _, hit := range searchResult.Hits.Hits {
+		var match models.SavedSearchMatch
+		if err := json.Unmarshal(hit.Source_, &match); err != nil {
+			log.Errorf("Error unmarshalling match of saved search %s: %s", savedSearchId, err)
+			continue
+		}
+		response.Matches = append(response.Matches, match)
+	}
+	return response, nil
+}
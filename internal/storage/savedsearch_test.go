@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+func TestBuildPercolateRequest(t *testing.T) {
+	documents := []models.Document{
+		{Id: "1", Title: "Acme launch", Text: "Acme ships"},
+		{Title: "Other", Text: "news", Fields: map[string]any{"tags": "go"}},
+	}
+
+	request, err := buildPercolateRequest("test", documents)
+	if err != nil {
+		t.Fatalf("Unable to build percolate request, error: %s\n", err)
+	}
+
+	marshaledRequest, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Unable to marshal percolate request, error: %s\n", err)
+	}
+
+	expectedRequest := `{"query":{"bool":{"filter":[{"term":{"index":{"value":"test"}}}],"must":[{"percolate":{"documents":[{"title":"Acme launch","text":"Acme ships"},{"title":"Other","text":"news","fields":{"tags":"go"}}],"field":"query"}}]}},"size":1000,"_source":{"excludes":["query"]}}`
+	assert.Equal(t, string(marshaledRequest), expectedRequest, "wrong percolate request")
+}
+
+var parsePercolatedSearchesTests = []struct {
+	testName			string
+	hits				[]types.Hit
+	expectedSearches	[]models.PercolatedSearch
+}{
+	{
+		testName: "Saved searches with matched document slots",
+		hits: []types.Hit{
+			{
+				Id_: &[]string{"s1"}[0],
+				Source_: json.RawMessage(`{"index":"test","user_id":"u1","saved_search":{"id":"","index_name":"","name":"acme","query":"acme","webhook_url":"http://hooks.local/acme","created_at":"2026-01-02T00:00:00Z"}}`),
+				Fields: map[string]json.RawMessage{percolatorSlotField: json.RawMessage(`[0,2]`)},
+			},
+		},
+		expectedSearches: []models.PercolatedSearch{
+			{
+				SavedSearch: models.SavedSearch{
+					Id: "s1",
+					Index: "test",
+					UserId: "u1",
+					SavedSearchRequest: models.SavedSearchRequest{Name: "acme", Query: "acme", WebhookURL: "http://hooks.local/acme"},
+					CreatedAt: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC),
+				},
+				Documents: []int{0, 2},
+			},
+		},
+	},
+	{
+		testName: "Hits without slots are skipped",
+		hits: []types.Hit{
+			{Id_: &[]string{"s1"}[0], Source_: json.RawMessage(`{"index":"test","saved_search":{}}`)},
+		},
+		expectedSearches: []models.PercolatedSearch{},
+	},
+}
+
+func TestParsePercolatedSearches(t *testing.T) {
+	for i, test := range parsePercolatedSearchesTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		assert.Equal(t, parsePercolatedSearches(test.hits), test.expectedSearches, "wrong percolated searches")
+	}
+}
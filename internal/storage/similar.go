@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/simhash"
+)
+
+const (
+	// Documents sharing most bands with the signature are checked for exact distance, near
+	// duplicates share at least Bands - distance bands, so they are ranked first
+	similarCandidatesSize	= 200
+	// Number of hits grouped when near duplicates are collapsed, collapsed hits are replaced
+	// with following ones, so page stays full unless most hits are duplicates
+	nearDuplicateWindowSize	= 50
+	// ES default size of keyword search
+	keywordResultsSize		= 10
+)
+
+// storedDocument is the source of indexed documents with fields computed by server, bands
+// of SimHash are only needed to find similar documents and indexing time to copy documents
+// indexed during reindex, so they aren't returned by search.
+type storedDocument struct {
+	models.Document
+	Embedding		[]float32	`json:"embedding,omitempty"`
+	SimHash			string		`json:"simhash"`
+	SimHashBands	[]string	`json:"simhash_bands,omitempty"`
+	IndexedAt		time.Time	`json:"indexed_at"`
+}
+
+func newStoredDocument(document models.Document, embedding []float32, indexedAt time.Time) storedDocument {
+	signature := simhash.Compute(document.Title + "\n" + document.Text)
+	return storedDocument{
+		Document: document,
+		Embedding: embedding,
+		SimHash: simhash.Format(signature),
+		SimHashBands: simhash.BandTokens(signature),
+		IndexedAt: indexedAt,
+	}
+}
+
+// GetDocument returns nil if index has no document with the id.
+func (es *ElasticSearchClient) GetDocument(ctx context.Context, indexName string, id string) (*models.SearchHit, error) {
+	result, err := es.Client.Get(indexName, id).SourceExcludes_(EmbeddingProperty, SimHashBandsProperty).Do(ctx)
+	if err != nil {
+		log.Errorf("Error getting document %s from index %s: %s", id, indexName, err)
+		return nil, err
+	}
+	if !result.Found {
+		return nil, nil
+	}
+
+	var document models.SearchHit
+	if err := json.Unmarshal(result.Source_, &document); err != nil {
+		log.Errorf("Error unmarshalling document %s of index %s: %s", id, indexName, err)
+		return nil, err
+	}
+	document.Id = result.Id_
+	return &document, nil
+}
+
+// SimilarDocuments finds candidates sharing bands with the signature and returns those
+// within distance, closest first. Documents indexed before SimHash was supported have
+// no bands and aren't found.
+func (es *ElasticSearchClient) SimilarDocuments(ctx context.Context, similarRequest *models.SimilarDocumentsRequest) ([]models.SimilarDocument, error) {
+	size := similarCandidatesSize
+	request := &search.Request{
+		Query: buildSimilarQuery(similarRequest),
+		Source_: &types.SourceFilter{Excludes: []string{EmbeddingProperty, SimHashBandsProperty}},
+		Size: &size,
+	}
+
+	searchResult, err := es.Client.Search().Index(similarRequest.Index).Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("Error searching documents similar to %s in index %s: %s", similarRequest.Id, similarRequest.Index, err)
+		return nil, err
+	}
+
+	return filterSimilar(parseHits(searchResult.Hits.Hits), similarRequest), nil
+}
+
+// MoreLikeThis searches documents sharing significant terms of title and text with the
+// document or text of the request, the document itself isn't returned.
+func (es *ElasticSearchClient) MoreLikeThis(ctx context.Context, moreLikeThisRequest *models.MoreLikeThisRequest) (*models.SearchResponse, error) {
+	request := &search.Request{
+		Query: buildMoreLikeThisQuery(moreLikeThisRequest),
+		Source_: &types.SourceFilter{Excludes: []string{EmbeddingProperty, SimHashBandsProperty}},
+	}
+
+	searchResult, err := es.Client.Search().Index(moreLikeThisRequest.Index).Request(request).Do(ctx)
+	if err != nil {
+		log.Errorf("Error searching documents like %q in index %s: %s", moreLikeThisRequest.Id+moreLikeThisRequest.Text, moreLikeThisRequest.Index, err)
+		return nil, err
+	}
+
+	var total int64
+	if searchResult.Hits.Total != nil {
+		total = searchResult.Hits.Total.Value
+	}
+
+	return &models.SearchResponse{Total: total, Documents: parseHits(searchResult.Hits.Hits)}, nil
+}
+
+func buildMoreLikeThisQuery(moreLikeThisRequest *models.MoreLikeThisRequest) *types.Query {
+	var like types.Like = moreLikeThisRequest.Text
+	if moreLikeThisRequest.Id != "" {
+		index, id := moreLikeThisRequest.Index, moreLikeThisRequest.Id
+		like = types.LikeDocument{Index_: &index, Id_: &id}
+	}
+
+	return &types.Query{MoreLikeThis: &types.MoreLikeThisQuery{
+		Fields: []string{"title", "text"},
+		Like: []types.Like{like},
+		MinTermFreq: moreLikeThisRequest.MinTermFreq,
+		MaxQueryTerms: moreLikeThisRequest.MaxQueryTerms,
+	}}
+}
+
+// buildSimilarQuery scores documents by the number of shared bands.
+func buildSimilarQuery(similarRequest *models.SimilarDocumentsRequest) *types.Query {
+	bands := []types.Query{}
+	for _, token := range simhash.BandTokens(similarRequest.SimHash) {
+		bands = append(bands, types.Query{ConstantScore: &types.ConstantScoreQuery{
+			Filter: &types.Query{Term: map[string]types.TermQuery{SimHashBandsProperty: {Value: token}}},
+		}})
+	}
+
+	return &types.Query{Bool: &types.BoolQuery{
+		Should: bands,
+		MustNot: []types.Query{{Ids: &types.IdsQuery{Values: []string{similarRequest.Id}}}},
+		MinimumShouldMatch: 1,
+	}}
+}
+
+func filterSimilar(documents []models.SearchHit, similarRequest *models.SimilarDocumentsRequest) []models.SimilarDocument {
+	similar := []models.SimilarDocument{}
+	for _, document := range documents {
+		signature, err := simhash.Parse(document.SimHash)
+		if err != nil {
+			continue
+		}
+		distance := simhash.Distance(signature, similarRequest.SimHash)
+		if distance <= similarRequest.Distance {
+			similar = append(similar, models.SimilarDocument{SearchHit: document, Distance: distance})
+		}
+	}
+
+	sort.SliceStable(similar, func(i, j int) bool {
+		return similar[i].Distance < similar[j].Distance
+	})
+	if len(similar) > similarRequest.Size {
+		similar = similar[:similarRequest.Size]
+	}
+	return similar
+}
+
+// collapseNearDuplicates keeps the first hit of each group of near duplicates and lists
+// ids of the rest in it. Hits without SimHash are never collapsed, hits of different indexes
+// aren't compared as their ids could clash.
+func collapseNearDuplicates(documents []models.SearchHit, distance int) []models.SearchHit {
+	collapsed := []models.SearchHit{}
+	// signatures of collapsed documents, nil if document has no valid SimHash
+	signatures := []*uint64{}
+	for _, document := range documents {
+		var signature *uint64
+		if parsed, err := simhash.Parse(document.SimHash); err == nil {
+			signature = &parsed
+		}
+
+		group := -1
+		for i := range collapsed {
+			if signature != nil && signatures[i] != nil && collapsed[i].Index == document.Index && simhash.Distance(*signatures[i], *signature) <= distance {
+				group = i
+				break
+			}
+		}
+
+		if group >= 0 {
+			collapsed[group].NearDuplicates = append(collapsed[group].NearDuplicates, document.Id)
+			continue
+		}
+		collapsed = append(collapsed, document)
+		signatures = append(signatures, signature)
+	}
+	return collapsed
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+func TestNewStoredDocument(t *testing.T) {
+	indexedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	marshaledDocument, err := json.Marshal(newStoredDocument(models.Document{Title: "test", Text: "test"}, []float32{1, 0}, indexedAt))
+	if err != nil {
+		t.Fatalf("Unable to marshal document, error: %s\n", err)
+	}
+
+	var stored map[string]any
+	if err := json.Unmarshal(marshaledDocument, &stored); err != nil {
+		t.Fatalf("Unable to unmarshal document, error: %s\n", err)
+	}
+	assert.Equal(t, len(stored["simhash"].(string)), 16, "wrong simhash length")
+	assert.Equal(t, len(stored["simhash_bands"].([]any)), 8, "wrong number of simhash bands")
+	assert.Equal(t, stored["title"], "test", "wrong title")
+	assert.Equal(t, stored["embedding"], []any{1.0, 0.0}, "wrong embedding")
+	assert.Equal(t, stored["indexed_at"], "2024-05-01T12:00:00Z", "wrong indexing time")
+}
+
+func TestBuildSimilarQuery(t *testing.T) {
+	marshaledQuery, err := json.Marshal(buildSimilarQuery(&models.SimilarDocumentsRequest{Id: "a", SimHash: 0x0123456789abcdef}))
+	if err != nil {
+		t.Fatalf("Unable to marshal query, error: %s\n", err)
+	}
+
+	band := func(token string) string {
+		return fmt.Sprintf(`{"constant_score":{"filter":{"term":{"simhash_bands":{"value":"%s"}}}}}`, token)
+	}
+	expectedQuery := `{"bool":{"minimum_should_match":1,"must_not":[{"ids":{"values":["a"]}}],"should":[` +
+		band("0ef") + "," + band("1cd") + "," + band("2ab") + "," + band("389") + "," +
+		band("467") + "," + band("545") + "," + band("623") + "," + band("701") + `]}}`
+	assert.Equal(t, string(marshaledQuery), expectedQuery, "wrong similar query")
+}
+
+func TestFilterSimilar(t *testing.T) {
+	documents := []models.SearchHit{
+		{Document: models.Document{Id: "far"}, SimHash: "00000000000000ff"},
+		{Document: models.Document{Id: "close"}, SimHash: "0000000000000001"},
+		{Document: models.Document{Id: "old"}},
+		{Document: models.Document{Id: "closer"}, SimHash: "0000000000000000"},
+		{Document: models.Document{Id: "close2"}, SimHash: "0000000000000100"},
+	}
+	similar := filterSimilar(documents, &models.SimilarDocumentsRequest{SimHash: 0, Distance: 3, Size: 2})
+
+	assert.Equal(t, similar, []models.SimilarDocument{
+		{SearchHit: models.SearchHit{Document: models.Document{Id: "closer"}, SimHash: "0000000000000000"}, Distance: 0},
+		{SearchHit: models.SearchHit{Document: models.Document{Id: "close"}, SimHash: "0000000000000001"}, Distance: 1},
+	}, "wrong similar documents")
+}
+
+var collapseNearDuplicatesTests = []struct {
+	testName			string
+	documents			[]models.SearchHit
+	expectedDocuments	[]models.SearchHit
+}{
+	{
+		testName: "Collapse near duplicates into the first hit",
+		documents: []models.SearchHit{
+			{Document: models.Document{Id: "a"}, Index: "test", SimHash: "0000000000000000"},
+			{Document: models.Document{Id: "b"}, Index: "test", SimHash: "ffffffffffffffff"},
+			{Document: models.Document{Id: "c"}, Index: "test", SimHash: "0000000000000003"},
+			{Document: models.Document{Id: "d"}, Index: "test", SimHash: "fffffffffffffff0"},
+		},
+		expectedDocuments: []models.SearchHit{
+			{Document: models.Document{Id: "a"}, Index: "test", SimHash: "0000000000000000", NearDuplicates: []string{"c"}},
+			{Document: models.Document{Id: "b"}, Index: "test", SimHash: "ffffffffffffffff"},
+			{Document: models.Document{Id: "d"}, Index: "test", SimHash: "fffffffffffffff0"},
+		},
+	},
+	{
+		testName: "Keep hits without simhash and hits of other indexes",
+		documents: []models.SearchHit{
+			{Document: models.Document{Id: "a"}, Index: "test"},
+			{Document: models.Document{Id: "b"}, Index: "test"},
+			{Document: models.Document{Id: "c"}, Index: "test", SimHash: "0000000000000000"},
+			{Document: models.Document{Id: "c"}, Index: "other", SimHash: "0000000000000000"},
+		},
+		expectedDocuments: []models.SearchHit{
+			{Document: models.Document{Id: "a"}, Index: "test"},
+			{Document: models.Document{Id: "b"}, Index: "test"},
+			{Document: models.Document{Id: "c"}, Index: "test", SimHash: "0000000000000000"},
+			{Document: models.Document{Id: "c"}, Index: "other", SimHash: "0000000000000000"},
+		},
+	},
+}
+
+func TestCollapseNearDuplicates(t *testing.T) {
+	for i, test := range collapseNearDuplicatesTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		assert.Equal(t, collapseNearDuplicates(test.documents, 3), test.expectedDocuments, "wrong collapsed documents")
+	}
+}
+
+var buildMoreLikeThisQueryTests = []struct {
+	testName		string
+	request			*models.MoreLikeThisRequest
+	expectedQuery	string
+}{
+	{
+		testName: "Like document of the same index",
+		request: &models.MoreLikeThisRequest{Index: "test", Id: "a"},
+		expectedQuery: `{"more_like_this":{"fields":["title","text"],"like":[{"_id":"a","_index":"test"}]}}`,
+	},
+	{
+		testName: "Like free text with tuning parameters",
+		request: &models.MoreLikeThisRequest{Index: "test", Text: "city budget", MinTermFreq: &testMinTermFreq, MaxQueryTerms: &testMaxQueryTerms},
+		expectedQuery: `{"more_like_this":{"fields":["title","text"],"like":["city budget"],"max_query_terms":12,"min_term_freq":1}}`,
+	},
+}
+
+var (
+	testMinTermFreq		= 1
+	testMaxQueryTerms	= 12
+)
+
+func TestBuildMoreLikeThisQuery(t *testing.T) {
+	for i, test := range buildMoreLikeThisQueryTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledQuery, err := json.Marshal(buildMoreLikeThisQuery(test.request))
+		if err != nil {
+			t.Fatalf("Unable to marshal query, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledQuery), test.expectedQuery, "wrong more like this query")
+	}
+}
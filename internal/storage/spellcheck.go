@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/suggestmode"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const didYouMeanSuggester = "did_you_mean"
+
+// Words of query text, operators and separators between them are kept by correction
+var queryWordRegexp = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// Suggestions are kept only if corrected query matches some documents
+const didYouMeanCollateQuery = `{"multi_match":{"query":"{{suggestion}}","fields":["title","text"],"operator":"and"}}`
+
+// buildDidYouMeanSuggester corrects plain text queries with phrase suggester. Candidates are
+// taken from title suggest subfield, it isn't stemmed, so suggestions are real words.
+// Search DSL has no single query text to correct and knn search doesn't depend on spelling.
+func buildDidYouMeanSuggester(searchRequest *models.DocumentSearchRequest) *types.Suggester {
+	if searchRequest.Search != nil || searchRequest.DidYouMeanMaxHits < 0 || searchRequest.Mode == models.SearchModeKnn {
+		return nil
+	}
+
+	field := "title." + SuggestSubfield
+	size := 1
+	maxErrors := types.Float64(2)
+	collateQuery := didYouMeanCollateQuery
+
+	return &types.Suggester{
+		Suggesters: map[string]types.FieldSuggester{
+			didYouMeanSuggester: {
+				Text: &searchRequest.Query,
+				Phrase: &types.PhraseSuggester{
+					Field: field,
+					Size: &size,
+					MaxErrors: &maxErrors,
+					DirectGenerator: []types.DirectGenerator{
+						{Field: field, SuggestMode: &suggestmode.Always},
+					},
+					Collate: &types.PhraseSuggestCollate{
+						Query: types.PhraseSuggestCollateQuery{Source: &collateQuery},
+					},
+				},
+			},
+		},
+	}
+}
+
+// parseDidYouMean returns the best suggestion if search found at most maxHits documents.
+func parseDidYouMean(suggest map[string][]types.Suggest, total int64, maxHits int) string {
+	if total > int64(maxHits) {
+		return ""
+	}
+
+	for _, entry := range suggest[didYouMeanSuggester] {
+		phraseSuggest, ok := entry.(*types.PhraseSuggest)
+		if !ok || len(phraseSuggest.Options) == 0 {
+			continue
+		}
+		return phraseSuggest.Options[0].Text
+	}
+	return ""
+}
+
+// CorrectQuery replaces misspelled words of query with words of did you mean suggestion, so
+// operators, phrases and groups of query are kept. Suggestion is made of analyzed query words,
+// false is returned if they don't match words of query one to one.
+func CorrectQuery(query string, suggestion string) (string, bool) {
+	wordLocations := queryWordRegexp.FindAllStringIndex(query, -1)
+	suggestedWords := strings.Fields(suggestion)
+	if len(wordLocations) == 0 || len(wordLocations) != len(suggestedWords) {
+		return "", false
+	}
+
+	var corrected strings.Builder
+	last := 0
+	for i, location := range wordLocations {
+		corrected.WriteString(query[last:location[0]])
+		word := query[location[0]:location[1]]
+		if strings.EqualFold(word, suggestedWords[i]) {
+			corrected.WriteString(word)
+		} else {
+			corrected.WriteString(suggestedWords[i])
+		}
+		last = location[1]
+	}
+	corrected.WriteString(query[last:])
+
+	return corrected.String(), true
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildDidYouMeanSuggesterTests = []struct {
+	testName			string
+	searchRequest		*models.DocumentSearchRequest
+	expectedSuggester	string
+}{
+	{
+		testName: "Phrase suggester corrects query against title",
+		searchRequest: &models.DocumentSearchRequest{Query: "wireles mose", DidYouMeanMaxHits: 5},
+		expectedSuggester: `{"did_you_mean":{"phrase":{"collate":{"query":{"source":"{\"multi_match\":{\"query\":\"{{suggestion}}\",\"fields\":[\"title\",\"text\"],\"operator\":\"and\"}}"}},"direct_generator":[{"field":"title.suggest","suggest_mode":"always"}],"field":"title.suggest","max_errors":2,"size":1},"text":"wireles mose"}}`,
+	},
+	{
+		testName: "No suggester for search DSL",
+		searchRequest: &models.DocumentSearchRequest{Search: &models.SearchClause{Term: &models.TermClause{Field: "tags", Value: "news"}}},
+		expectedSuggester: `null`,
+	},
+	{
+		testName: "No suggester when suggestions are disabled",
+		searchRequest: &models.DocumentSearchRequest{Query: "wireles mose", DidYouMeanMaxHits: -1},
+		expectedSuggester: `null`,
+	},
+}
+
+func TestBuildDidYouMeanSuggester(t *testing.T) {
+	for i, test := range buildDidYouMeanSuggesterTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledSuggester, err := json.Marshal(buildDidYouMeanSuggester(test.searchRequest))
+		if err != nil {
+			t.Fatalf("Unable to marshal suggester, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledSuggester), test.expectedSuggester, "wrong suggester")
+	}
+}
+
+var parseDidYouMeanTests = []struct {
+	testName			string
+	total				int64
+	maxHits				int
+	expectedDidYouMean	string
+}{
+	{
+		testName: "Return best suggestion for low result search",
+		total: 1,
+		maxHits: 5,
+		expectedDidYouMean: "wireless mouse",
+	},
+	{
+		testName: "Skip suggestion when search found enough documents",
+		total: 6,
+		maxHits: 5,
+		expectedDidYouMean: "",
+	},
+}
+
+func TestParseDidYouMean(t *testing.T) {
+	esResponse := `{
+		"took": 1,
+		"timed_out": false,
+		"_shards": {"total": 1, "successful": 1, "skipped": 0, "failed": 0},
+		"hits": {"hits": []},
+		"suggest": {
+			"phrase#did_you_mean": [{
+				"text": "wireles mose",
+				"offset": 0,
+				"length": 12,
+				"options": [{"text": "wireless mouse", "score": 0.4}, {"text": "wireless mose", "score": 0.1}]
+			}]
+		}
+	}`
+
+	response := search.NewResponse()
+	if err := json.Unmarshal([]byte(esResponse), response); err != nil {
+		t.Fatalf("Unable to unmarshal ES response, error: %s\n", err)
+	}
+
+	for i, test := range parseDidYouMeanTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		assert.Equal(t, parseDidYouMean(response.Suggest, test.total, test.maxHits), test.expectedDidYouMean, "wrong did you mean")
+	}
+}
+
+var correctQueryTests = []struct {
+	testName			string
+	query				string
+	suggestion			string
+	expectedQuery		string
+	expectedCorrected	bool
+}{
+	{
+		testName: "Replace misspelled words",
+		query: "wireles mose",
+		suggestion: "wireless mouse",
+		expectedQuery: "wireless mouse",
+		expectedCorrected: true,
+	},
+	{
+		testName: "Keep operators, phrases and groups",
+		query: `"wireles mose" AND (pad OR -mat*)`,
+		suggestion: "wireless mouse and pad or mat",
+		expectedQuery: `"wireless mouse" AND (pad OR -mat*)`,
+		expectedCorrected: true,
+	},
+	{
+		testName: "Don't correct when words don't match",
+		query: "title:wireles",
+		suggestion: "title:wireless",
+		expectedQuery: "",
+		expectedCorrected: false,
+	},
+}
+
+func TestCorrectQuery(t *testing.T) {
+	for i, test := range correctQueryTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		query, corrected := CorrectQuery(test.query, test.suggestion)
+
+		assert.Equal(t, query, test.expectedQuery, "wrong corrected query")
+		assert.Equal(t, corrected, test.expectedCorrected, "wrong corrected flag")
+	}
+}
@@ -2,20 +2,71 @@ package storage
 
 import (
 	"context"
+	"time"
+
 	"github.com/xavesen/search-api/internal/models"
 )
 
 type DocumentStorage interface {
-	SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) ([]models.Document, error)
+	SearchQuery(ctx context.Context, searchRequest *models.DocumentSearchRequest) (*models.SearchResponse, error)
+	Suggest(ctx context.Context, suggestRequest *models.SuggestRequest) ([]models.Suggestion, error)
 	IndexExists(ctx context.Context, indexName string) (bool, error)
-	NewIndex(ctx context.Context, indexName string) error
+	NewIndex(ctx context.Context, index *models.IndexMetadata) error
+	UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error
+	UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error
+	IndexDocuments(ctx context.Context, indexName string, documents []models.Document, embeddings [][]float32, refreshPolicy string, dedupe string) ([]models.DocumentIndexingResult, error)
+	GetDocument(ctx context.Context, indexName string, id string) (*models.SearchHit, error)
+	SimilarDocuments(ctx context.Context, similarRequest *models.SimilarDocumentsRequest) ([]models.SimilarDocument, error)
+	MoreLikeThis(ctx context.Context, moreLikeThisRequest *models.MoreLikeThisRequest) (*models.SearchResponse, error)
+	NewIndexVersion(ctx context.Context, index *models.IndexMetadata) error
+	StartReindex(ctx context.Context, sourceIndex string, destIndex string) (string, error)
+	GetReindexProgress(ctx context.Context, esTaskId string) (*models.ReindexProgress, error)
+	CatchUpReindex(ctx context.Context, sourceIndex string, destIndex string, since time.Time, overwrite bool) error
+	SetIndexWriteBlock(ctx context.Context, physicalName string, blocked bool) error
+	SwapIndexAlias(ctx context.Context, alias string, oldIndex string, newIndex string) error
+	DeleteIndex(ctx context.Context, physicalName string) error
 }
 
 type UserStorage interface {
 	CheckUserIndexRights(ctx context.Context, userId string, indexId string) (bool, error)
+	FilterUserIndexes(ctx context.Context, userId string, indexNames []string) ([]string, error)
 	AddIndexToUser(ctx context.Context, userId string, indexName string) error
 	GetUserInfoByLogin(ctx context.Context, login string) (*models.User, error)
 	SetRefreshToken(ctx context.Context, userId string, refreshToken string) error
 	GetUserInfoById(ctx context.Context, userId string) (*models.User, error)
 	CheckIfTokenBlacklisted(ctx context.Context, token string) (bool, error)
-}
\ No newline at end of file
+}
+
+type IndexStorage interface {
+	CreateIndexMetadata(ctx context.Context, index *models.IndexMetadata) error
+	DeleteIndexMetadata(ctx context.Context, indexName string) error
+	GetIndexMetadata(ctx context.Context, indexName string) (*models.IndexMetadata, error)
+	GetIndexesMetadata(ctx context.Context, indexNames []string) ([]models.IndexMetadata, error)
+	UpdateIndexSchema(ctx context.Context, indexName string, schema *models.IndexSchema) error
+	UpdateIndexAnalysis(ctx context.Context, indexName string, analysis *models.IndexAnalysis) error
+	UpdateIndexFuzzySettings(ctx context.Context, indexName string, fuzzy *models.FuzzySettings) error
+	UpdateIndexDedupe(ctx context.Context, indexName string, dedupe string) error
+	UpdateIndexVersion(ctx context.Context, indexName string, version int, schema *models.IndexSchema, analysis *models.IndexAnalysis) error
+	CreateReindexTask(ctx context.Context, task *models.ReindexTask) error
+	GetReindexTask(ctx context.Context, id string) (*models.ReindexTask, error)
+	GetRunningReindexTasks(ctx context.Context, indexName string) ([]models.ReindexTask, error)
+	UpdateReindexTask(ctx context.Context, task *models.ReindexTask) error
+}
+
+type SavedSearchStorage interface {
+	CreateSavedSearch(ctx context.Context, savedSearch *models.SavedSearch) error
+	GetSavedSearch(ctx context.Context, id string) (*models.SavedSearch, error)
+	PercolateDocuments(ctx context.Context, indexName string, documents []models.Document) ([]models.PercolatedSearch, error)
+	SaveMatches(ctx context.Context, matches []models.SavedSearchMatch) error
+	GetMatches(ctx context.Context, savedSearchId string, size int) (*models.SavedSearchMatchesResponse, error)
+}
+
+type WebhookStorage interface {
+	CreateWebhook(ctx context.Context, webhook *models.Webhook) error
+	GetWebhook(ctx context.Context, id string) (*models.Webhook, error)
+	GetIndexWebhooks(ctx context.Context, indexName string) ([]models.Webhook, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error)
+	GetDeliveries(ctx context.Context, webhookId string, size int) ([]models.WebhookDelivery, error)
+}
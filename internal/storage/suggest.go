@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"github.com/elastic/go-elasticsearch/v8/typedapi/core/search"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types/enums/textquerytype"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// SuggestCollapseField returns field suggestions of the index are collapsed by. Title of indexes
+// with metadata is mapped by the service with keyword subfield, indexes created before metadata
+// was stored are mapped by ES and title may be unmapped, ES rejects collapse on such field.
+func SuggestCollapseField(index *models.IndexMetadata) string {
+	if index == nil {
+		return ""
+	}
+	return "title." + KeywordSubfield
+}
+
+// buildSuggestRequest matches prefix against shingles of title suggest subfield, the last term
+// is matched as prefix. Hits are collapsed by title if collapse field is known, so documents with
+// equal titles are suggested once, and only title is fetched without counting total hits to keep it fast.
+func buildSuggestRequest(suggestRequest *models.SuggestRequest) *search.Request {
+	suggestField := "title." + SuggestSubfield
+	size := suggestRequest.Size
+	trackTotalHits := false
+
+	request := &search.Request{
+		Query: &types.Query{
+			MultiMatch: &types.MultiMatchQuery{
+				Query: suggestRequest.Prefix,
+				Type: &textquerytype.Boolprefix,
+				Fields: []string{suggestField, suggestField + "._2gram", suggestField + "._3gram"},
+			},
+		},
+		Source_: &types.SourceFilter{Includes: []string{"title"}},
+		Size: &size,
+		TrackTotalHits: trackTotalHits,
+	}
+	if suggestRequest.CollapseField != "" {
+		request.Collapse = &types.FieldCollapse{Field: suggestRequest.CollapseField}
+	}
+
+	return request
+}
@@ -0,0 +1,40 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildSuggestRequestTests = []struct {
+	testName		string
+	suggestRequest	*models.SuggestRequest
+	expectedRequest	string
+}{
+	{
+		testName: "Prefix is matched against title shingles and hits are collapsed by title",
+		suggestRequest: &models.SuggestRequest{Index: "test", Prefix: "wireless mo", Size: 5, CollapseField: "title.keyword"},
+		expectedRequest: `{"collapse":{"field":"title.keyword"},"query":{"multi_match":{"fields":["title.suggest","title.suggest._2gram","title.suggest._3gram"],"query":"wireless mo","type":"bool_prefix"}},"size":5,"_source":{"includes":["title"]},"track_total_hits":false}`,
+	},
+	{
+		testName: "Hits aren't collapsed without collapse field",
+		suggestRequest: &models.SuggestRequest{Index: "test", Prefix: "wireless mo", Size: 5},
+		expectedRequest: `{"query":{"multi_match":{"fields":["title.suggest","title.suggest._2gram","title.suggest._3gram"],"query":"wireless mo","type":"bool_prefix"}},"size":5,"_source":{"includes":["title"]},"track_total_hits":false}`,
+	},
+}
+
+func TestBuildSuggestRequest(t *testing.T) {
+	for i, test := range buildSuggestRequestTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		marshaledRequest, err := json.Marshal(buildSuggestRequest(test.suggestRequest))
+		if err != nil {
+			t.Fatalf("Unable to marshal request, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledRequest), test.expectedRequest, "wrong suggest request")
+	}
+}
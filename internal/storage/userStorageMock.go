@@ -12,6 +12,7 @@ type UserStorageMock struct {
 	IndexRightsError		error
 	AddIndexError 			error
 	IndexAccess				bool
+	UserIndexes				[]string
 	User 					*models.User
 	GetUserErr				error
 	SetRefreshTokenErr		error
@@ -25,6 +26,14 @@ func (us *UserStorageMock) CheckUserIndexRights(ctx context.Context, userId stri
 	return us.IndexAccess, us.IndexRightsError
 }
 
+func (us *UserStorageMock) FilterUserIndexes(ctx context.Context, userId string, indexNames []string) ([]string, error) {
+	if us.IndexRightsError != nil {
+		return nil, us.IndexRightsError
+	}
+
+	return filterIndexes(us.UserIndexes, indexNames), nil
+}
+
 func (us *UserStorageMock) AddIndexToUser(ctx context.Context, userId string, indexName string) error {
 	return us.AddIndexError
 }
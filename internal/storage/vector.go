@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"sort"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	// Number of documents returned by knn and hybrid searches
+	vectorResultsSize	= 10
+	// Candidates considered on each shard, more candidates improve accuracy at the cost of speed
+	knnNumCandidates	= 100
+	// Number of top documents of each ranking fused in hybrid mode
+	rrfWindowSize		= 50
+	// Constant k of reciprocal rank fusion, it lowers influence of top ranks
+	rrfRankConstant		= 60
+)
+
+func buildKnnSearch(searchRequest *models.DocumentSearchRequest, k int) []types.KnnSearch {
+	numCandidates := knnNumCandidates
+	if numCandidates < k {
+		numCandidates = k
+	}
+
+	return []types.KnnSearch{
+		{
+			Field: EmbeddingProperty,
+			QueryVector: searchRequest.QueryVector,
+			K: &k,
+			NumCandidates: &numCandidates,
+		},
+	}
+}
+
+// fuseRankings merges rankings with reciprocal rank fusion: each document gets sum of
+// 1 / (k + rank) over rankings it appears in. Documents are identified by index and id,
+// equal scores keep order of first appearance.
+func fuseRankings(rankings ...[]models.SearchHit) []models.SearchHit {
+	type fusedDocument struct {
+		document	models.SearchHit
+		score		float64
+		position	int
+	}
+
+	fused := map[string]*fusedDocument{}
+	for _, ranking := range rankings {
+		for rank, document := range ranking {
+			key := document.Index + "/" + document.Id
+			fusedDoc, ok := fused[key]
+			if !ok {
+				fusedDoc = &fusedDocument{document: document, position: len(fused)}
+				fused[key] = fusedDoc
+			}
+			fusedDoc.score += 1 / float64(rrfRankConstant+rank+1)
+		}
+	}
+
+	fusedDocs := make([]*fusedDocument, 0, len(fused))
+	for _, fusedDoc := range fused {
+		fusedDocs = append(fusedDocs, fusedDoc)
+	}
+	sort.Slice(fusedDocs, func(i, j int) bool {
+		if fusedDocs[i].score != fusedDocs[j].score {
+			return fusedDocs[i].score > fusedDocs[j].score
+		}
+		return fusedDocs[i].position < fusedDocs[j].position
+	})
+
+	documents := make([]models.SearchHit, 0, len(fusedDocs))
+	for _, fusedDoc := range fusedDocs {
+		documents = append(documents, fusedDoc.document)
+	}
+	return documents
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var buildKnnSearchRequestTests = []struct {
+	testName		string
+	searchRequest	*models.DocumentSearchRequest
+	size			*int
+	expectedRequest	string
+}{
+	{
+		testName: "Knn search without query and suggester",
+		searchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless mouse", Mode: models.SearchModeKnn, QueryVector: []float32{0.6, 0.8}},
+		expectedRequest: `{"knn":[{"field":"embedding","k":10,"num_candidates":100,"query_vector":[0.6,0.8]}],"_source":{"excludes":["embedding","simhash_bands"]}}`,
+	},
+	{
+		testName: "Knn search with rrf window size",
+		searchRequest: &models.DocumentSearchRequest{Index: "test", Query: "wireless mouse", Mode: models.SearchModeKnn, QueryVector: []float32{1, 0}},
+		size: &[]int{rrfWindowSize}[0],
+		expectedRequest: `{"knn":[{"field":"embedding","k":50,"num_candidates":100,"query_vector":[1,0]}],"size":50,"_source":{"excludes":["embedding","simhash_bands"]}}`,
+	},
+}
+
+func TestBuildKnnSearchRequest(t *testing.T) {
+	for i, test := range buildKnnSearchRequestTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		request, err := buildSearchRequest(test.searchRequest, test.size)
+		if err != nil {
+			t.Fatalf("Unable to build search request, error: %s\n", err)
+		}
+
+		marshaledRequest, err := json.Marshal(request)
+		if err != nil {
+			t.Fatalf("Unable to marshal search request, error: %s\n", err)
+		}
+
+		assert.Equal(t, string(marshaledRequest), test.expectedRequest, "wrong search request")
+	}
+}
+
+var fuseRankingsTests = []struct {
+	testName		string
+	rankings		[][]models.SearchHit
+	expectedIds		[]string
+}{
+	{
+		testName: "Documents found by both rankings go first",
+		rankings: [][]models.SearchHit{
+			{{Document: models.Document{Id: "1"}, Index: "test"}, {Document: models.Document{Id: "2"}, Index: "test"}, {Document: models.Document{Id: "3"}, Index: "test"}},
+			{{Document: models.Document{Id: "4"}, Index: "test"}, {Document: models.Document{Id: "3"}, Index: "test"}},
+		},
+		expectedIds: []string{"3", "1", "4", "2"},
+	},
+	{
+		testName: "Equal scores keep order of first appearance",
+		rankings: [][]models.SearchHit{
+			{{Document: models.Document{Id: "1"}, Index: "test"}},
+			{{Document: models.Document{Id: "2"}, Index: "test"}},
+		},
+		expectedIds: []string{"1", "2"},
+	},
+	{
+		testName: "Same id in different indexes are different documents",
+		rankings: [][]models.SearchHit{
+			{{Document: models.Document{Id: "1"}, Index: "first"}},
+			{{Document: models.Document{Id: "1"}, Index: "second"}},
+		},
+		expectedIds: []string{"1", "1"},
+	},
+	{
+		testName: "Empty rankings",
+		rankings: [][]models.SearchHit{{}, {}},
+		expectedIds: []string{},
+	},
+}
+
+func TestFuseRankings(t *testing.T) {
+	for i, test := range fuseRankingsTests {
+		fmt.Printf("Running test #%d: %s\n", i+1, test.testName)
+
+		ids := []string{}
+		for _, document := range fuseRankings(test.rankings...) {
+			ids = append(ids, document.Id)
+		}
+
+		assert.Equal(t, ids, test.expectedIds, "wrong fused ranking")
+	}
+}
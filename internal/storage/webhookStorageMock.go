@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/xavesen/search-api/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebhookStorageMock keeps deliveries in memory, it is safe for concurrent use as deliveries
+// are updated from delivery goroutines.
+type WebhookStorageMock struct {
+	CreateError		error
+	GetError		error
+	Webhooks		[]models.Webhook
+	Deliveries		[]models.WebhookDelivery
+	DeliveriesSize	int
+	mutex			sync.Mutex
+}
+
+func (ws *WebhookStorageMock) CreateWebhook(ctx context.Context, webhook *models.Webhook) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	if ws.CreateError != nil {
+		return ws.CreateError
+	}
+
+	webhook.Id = fmt.Sprintf("w%d", len(ws.Webhooks)+1)
+	ws.Webhooks = append(ws.Webhooks, *webhook)
+	return nil
+}
+
+func (ws *WebhookStorageMock) GetWebhook(ctx context.Context, id string) (*models.Webhook, error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	if ws.GetError != nil {
+		return nil, ws.GetError
+	}
+
+	for _, webhook := range ws.Webhooks {
+		if webhook.Id == id {
+			return &webhook, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (ws *WebhookStorageMock) GetIndexWebhooks(ctx context.Context, indexName string) ([]models.Webhook, error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	if ws.GetError != nil {
+		return nil, ws.GetError
+	}
+
+	webhooks := []models.Webhook{}
+	for _, webhook := range ws.Webhooks {
+		if webhook.Index == indexName {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return webhooks, nil
+}
+
+func (ws *WebhookStorageMock) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	delivery.Id = fmt.Sprintf("d%d", len(ws.Deliveries)+1)
+	ws.Deliveries = append(ws.Deliveries, *delivery)
+	return nil
+}
+
+func (ws *WebhookStorageMock) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	for i := range ws.Deliveries {
+		if ws.Deliveries[i].Id == delivery.Id {
+			ws.Deliveries[i] = *delivery
+			return nil
+		}
+	}
+	return mongo.ErrNoDocuments
+}
+
+func (ws *WebhookStorageMock) GetDelivery(ctx context.Context, id string) (*models.WebhookDelivery, error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	for _, delivery := range ws.Deliveries {
+		if delivery.Id == id {
+			return &delivery, nil
+		}
+	}
+	return nil, mongo.ErrNoDocuments
+}
+
+func (ws *WebhookStorageMock) GetDeliveries(ctx context.Context, webhookId string, size int) ([]models.WebhookDelivery, error) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.DeliveriesSize = size
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range ws.Deliveries {
+		if delivery.WebhookId == webhookId {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
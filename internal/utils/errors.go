@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/typedapi/types"
+	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Error codes are part of the API contract, clients rely on them instead of messages
+const (
+	CodeInvalidPayload		= "INVALID_PAYLOAD"
+	CodeValidationFailed	= "VALIDATION_FAILED"
+	CodeInvalidParameter	= "INVALID_PARAMETER"
+	CodeInvalidQuery		= "INVALID_QUERY"
+	CodePayloadTooLarge		= "PAYLOAD_TOO_LARGE"
+	CodeUnsupportedMediaType	= "UNSUPPORTED_MEDIA_TYPE"
+	CodeUnauthorized		= "UNAUTHORIZED"
+	CodeTokenExpired		= "TOKEN_EXPIRED"
+	CodeTokenBlacklisted	= "TOKEN_BLACKLISTED"
+	CodeIndexNotFound		= "INDEX_NOT_FOUND"
+	CodeIndexAlreadyExists	= "INDEX_ALREADY_EXISTS"
+	CodeIndexNotConfigurable	= "INDEX_NOT_CONFIGURABLE"
+	CodeReindexInProgress	= "REINDEX_IN_PROGRESS"
+	CodeNotFound			= "NOT_FOUND"
+	CodeEmbeddingsUnavailable	= "EMBEDDINGS_UNAVAILABLE"
+	CodeSavedSearchesUnavailable	= "SAVED_SEARCHES_UNAVAILABLE"
+	CodeTooManyRequests		= "TOO_MANY_REQUESTS"
+	CodeTimeout				= "TIMEOUT"
+	CodeInternal			= "INTERNAL_ERROR"
+)
+
+const (
+	esIndexNotFound				= "index_not_found_exception"
+	esResourceAlreadyExists		= "resource_already_exists_exception"
+)
+
+// ES error types returned with 400 status when search query can't be parsed
+var esQueryErrors = []string{
+	"search_phase_execution_exception",
+	"query_shard_exception",
+	"parse_exception",
+	"x_content_parse_exception",
+	"parsing_exception",
+}
+
+type APIError struct {
+	Status		int		`json:"-"`
+	Code		string	`json:"code"`
+	Message		string	`json:"message"`
+	Details		any		`json:"details,omitempty"`
+	RequestId	string	`json:"request_id,omitempty"`
+}
+
+func NewAPIError(status int, code string, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// WithDetails returns a copy of the error with details set, so predefined errors aren't modified.
+func (e *APIError) WithDetails(details any) *APIError {
+	withDetails := *e
+	withDetails.Details = details
+	return &withDetails
+}
+
+var (
+	ErrInvalidPayload		= NewAPIError(http.StatusBadRequest, CodeInvalidPayload, "Invalid request payload")
+	ErrValidationFailed		= NewAPIError(http.StatusBadRequest, CodeValidationFailed, "Invalid request payload")
+	ErrPayloadTooLarge		= NewAPIError(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, "Request payload is too large")
+	ErrUnauthorized			= NewAPIError(http.StatusUnauthorized, CodeUnauthorized, "Unauthorized")
+	ErrTokenExpired			= NewAPIError(http.StatusUnauthorized, CodeTokenExpired, "Token has expired, refresh it or login again")
+	ErrRefreshTokenExpired	= NewAPIError(http.StatusUnauthorized, CodeTokenExpired, "Refresh token has expired")
+	ErrTokenBlacklisted		= NewAPIError(http.StatusUnauthorized, CodeTokenBlacklisted, "Token is blacklisted")
+	ErrIndexNotFound		= NewAPIError(http.StatusForbidden, CodeIndexNotFound, "Index doesn't exist or you don't have access to it")
+	ErrIndexAlreadyExists	= NewAPIError(http.StatusConflict, CodeIndexAlreadyExists, "Index with such name already exists")
+	ErrInvalidQuery			= NewAPIError(http.StatusBadRequest, CodeInvalidQuery, "Search query is invalid")
+	ErrNotFound				= NewAPIError(http.StatusNotFound, CodeNotFound, "Resource not found")
+	ErrTooManyRequests		= NewAPIError(http.StatusTooManyRequests, CodeTooManyRequests, "Too many requests, try again later")
+	ErrTimeout				= NewAPIError(http.StatusGatewayTimeout, CodeTimeout, "Request timed out")
+	ErrEmbeddingsUnavailable	= NewAPIError(http.StatusServiceUnavailable, CodeEmbeddingsUnavailable, "Embeddings aren't available, vector search and indexing to indexes with vectors are disabled")
+	ErrSavedSearchesUnavailable	= NewAPIError(http.StatusServiceUnavailable, CodeSavedSearchesUnavailable, "Saved searches aren't available, queued documents are indexed outside of the service and can't be evaluated")
+	ErrInternal				= NewAPIError(http.StatusInternalServerError, CodeInternal, "Internal server error")
+)
+
+// MapError converts errors returned by storages and queues to API errors,
+// everything that isn't recognized is an internal error.
+func MapError(err error) *APIError {
+	var apiError *APIError
+	if errors.As(err, &apiError) {
+		return apiError
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrNotFound
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+
+	var esError *types.ElasticsearchError
+	if errors.As(err, &esError) {
+		switch {
+		case esError.ErrorCause.Type == esIndexNotFound:
+			return ErrIndexNotFound
+		case esError.ErrorCause.Type == esResourceAlreadyExists:
+			return ErrIndexAlreadyExists
+		case esError.Status == http.StatusTooManyRequests:
+			return ErrTooManyRequests
+		case esError.Status == http.StatusBadRequest && isESQueryError(esError.ErrorCause.Type):
+			if len(esError.ErrorCause.RootCause) > 0 && esError.ErrorCause.RootCause[0].Reason != nil {
+				return ErrInvalidQuery.WithDetails(map[string]string{"reason": *esError.ErrorCause.RootCause[0].Reason})
+			}
+			return ErrInvalidQuery
+		}
+	}
+
+	return ErrInternal
+}
+
+func isESQueryError(errorType string) bool {
+	for _, queryErrorType := range esQueryErrors {
+		if errorType == queryErrorType {
+			return true
+		}
+	}
+	return false
+}
+
+type problemDetails struct {
+	Type		string		`json:"type"`
+	Title		string		`json:"title"`
+	Status		int			`json:"status"`
+	Detail		string		`json:"detail"`
+	Instance	string		`json:"instance"`
+	Code		string		`json:"code"`
+	Details		any			`json:"details,omitempty"`
+	RequestId	string		`json:"request_id,omitempty"`
+}
+
+// WriteError writes mapped error as RFC 7807 problem details if client accepts
+// application/problem+json and as usual Response otherwise.
+func WriteError(w http.ResponseWriter, r *http.Request, err error) error {
+	apiError := *MapError(err)
+	apiError.RequestId = RequestIdFromContext(r.Context())
+
+	if apiError.Status >= http.StatusInternalServerError {
+		log.Errorf("Responding with %s to request %s: %s", apiError.Code, apiError.RequestId, err)
+	}
+
+	if acceptsProblemJSON(r) {
+		problem := problemDetails{
+			Type: "urn:search-api:error:" + apiError.Code,
+			Title: http.StatusText(apiError.Status),
+			Status: apiError.Status,
+			Detail: apiError.Message,
+			Instance: r.URL.Path,
+			Code: apiError.Code,
+			Details: apiError.Details,
+			RequestId: apiError.RequestId,
+		}
+
+		w.Header().Add("Content-Type", ContentTypeProblemJSON)
+		w.WriteHeader(apiError.Status)
+		return json.NewEncoder(w).Encode(problem)
+	}
+
+	resp := Response{
+		Success: false,
+		ErrorMessage: apiError.Message,
+		Error: &apiError,
+	}
+	if fieldErrors, ok := apiError.Details.([]models.FieldError); ok {
+		resp.Errors = fieldErrors
+	}
+
+	return writeResponse(w, apiError.Status, resp)
+}
+
+func acceptsProblemJSON(r *http.Request) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, _ := strings.Cut(strings.TrimSpace(accepted), ";")
+		if mediaType == ContentTypeProblemJSON {
+			return true
+		}
+	}
+
+	return false
+}
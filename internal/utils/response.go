@@ -1,10 +1,12 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/xavesen/search-api/internal/models"
 )
 
 type ContextKey string
@@ -12,23 +14,38 @@ type ContextKey string
 const ContextKeyReqId ContextKey = "requestId"
 const ContextKeyUserId ContextKey = "userId"
 
+func RequestIdFromContext(ctx context.Context) string {
+	requestId, _ := ctx.Value(ContextKeyReqId).(string)
+	return requestId
+}
+
 type Response struct {
-	Success			bool	`json:"success"`
-	ErrorMessage	string	`json:"errorMessage"`
-	Data			any		`json:"data"`
+	Success			bool				`json:"success"`
+	ErrorMessage	string				`json:"errorMessage"`
+	Error			*APIError			`json:"error,omitempty"`
+	Errors			[]models.FieldError	`json:"errors,omitempty"`
+	Data			any					`json:"data"`
 }
 
 func WriteJSON(w http.ResponseWriter, r *http.Request, statusCode int, success bool, errorMessage string, data any) error {
-	log.Info("Responding to request")
-	
 	resp := Response{
 		Success: success,
 		ErrorMessage: errorMessage,
 		Data: data,
 	}
 
+	return writeResponse(w, statusCode, resp)
+}
+
+func WriteValidationError(w http.ResponseWriter, r *http.Request, fieldErrors []models.FieldError) error {
+	return WriteError(w, r, ErrValidationFailed.WithDetails(fieldErrors))
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, resp Response) error {
+	log.Info("Responding to request")
+
 	w.Header().Add("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 
 	return json.NewEncoder(w).Encode(resp)
-}
\ No newline at end of file
+}
@@ -26,5 +26,8 @@ func (tom *TokenOperatorMock) ValidateToken(tokenStr string, key []byte) (bool,
 	if tom.ValidateErr != nil {
 		return false, nil, tom.ValidateErr
 	}
+	if tom.TokenValid && tom.ReturnedToken == nil {
+		return true, &jwt.Token{Claims: jwt.RegisteredClaims{Subject: "1"}}, nil
+	}
 	return tom.TokenValid, tom.ReturnedToken, nil
 }
\ No newline at end of file
@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxAggregations		= 20
+	maxTermsSize		= 1000
+	maxAggregationRanges	= 100
+)
+
+// Characters ES doesn't allow in aggregation names
+const forbiddenAggregationNameChars = "[]>"
+
+var calendarIntervals = []string{"minute", "1m", "hour", "1h", "day", "1d", "week", "1w", "month", "1M", "quarter", "1q", "year", "1y"}
+
+func validateAggregations(request *models.DocumentSearchRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	fieldError := func(field string, message string) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: message})
+	}
+
+	if len(request.Aggregations) > maxAggregations {
+		fieldError("aggregations", fmt.Sprintf("at most %d aggregations are allowed", maxAggregations))
+		return fieldErrors
+	}
+
+	for _, name := range sortedKeys(request.Aggregations) {
+		path := "aggregations." + name
+		if name == "" || strings.ContainsAny(name, forbiddenAggregationNameChars) {
+			fieldError(path, fmt.Sprintf("aggregation name must be non empty and must not contain any of %q", forbiddenAggregationNameChars))
+			continue
+		}
+		aggregation := request.Aggregations[name]
+		fieldErrors = append(fieldErrors, validateAggregation(path, &aggregation)...)
+	}
+
+	for _, name := range sortedKeys(request.PostFilters) {
+		path := "post_filters." + name
+		if _, ok := request.Aggregations[name]; !ok {
+			fieldError(path, "post filter must belong to one of requested aggregations")
+			continue
+		}
+		clause := request.PostFilters[name]
+		fieldErrors = append(fieldErrors, validateSearchClause(path, &clause, 1)...)
+	}
+
+	return fieldErrors
+}
+
+func validateAggregation(path string, aggregation *models.Aggregation) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	fieldError := func(field string, message string) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: message})
+	}
+
+	set := 0
+	for _, isSet := range []bool{aggregation.Terms != nil, aggregation.DateHistogram != nil, aggregation.Range != nil, aggregation.Cardinality != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		fieldError(path, "exactly one of terms, date_histogram, range or cardinality is required")
+		return fieldErrors
+	}
+
+	switch {
+	case aggregation.Terms != nil:
+		termsPath := path + ".terms"
+		fieldErrors = append(fieldErrors, validateSearchField(termsPath+".field", aggregation.Terms.Field)...)
+		if aggregation.Terms.Size != nil && (*aggregation.Terms.Size < 1 || *aggregation.Terms.Size > maxTermsSize) {
+			fieldError(termsPath+".size", fmt.Sprintf("size must be between 1 and %d", maxTermsSize))
+		}
+	case aggregation.DateHistogram != nil:
+		dateHistogramPath := path + ".date_histogram"
+		fieldErrors = append(fieldErrors, validateSearchField(dateHistogramPath+".field", aggregation.DateHistogram.Field)...)
+		if !contains(calendarIntervals, aggregation.DateHistogram.CalendarInterval) {
+			fieldError(dateHistogramPath+".calendar_interval", fmt.Sprintf("calendar_interval must be one of %s", strings.Join(calendarIntervals, ", ")))
+		}
+	case aggregation.Range != nil:
+		rangePath := path + ".range"
+		fieldErrors = append(fieldErrors, validateSearchField(rangePath+".field", aggregation.Range.Field)...)
+		if len(aggregation.Range.Ranges) == 0 || len(aggregation.Range.Ranges) > maxAggregationRanges {
+			fieldError(rangePath+".ranges", fmt.Sprintf("from 1 to %d ranges are required", maxAggregationRanges))
+		}
+		for i, aggregationRange := range aggregation.Range.Ranges {
+			if aggregationRange.From == nil && aggregationRange.To == nil {
+				fieldError(fmt.Sprintf("%s.ranges[%d]", rangePath, i), "at least one of from or to is required")
+			}
+		}
+	case aggregation.Cardinality != nil:
+		fieldErrors = append(fieldErrors, validateSearchField(path+".cardinality.field", aggregation.Cardinality.Field)...)
+	}
+
+	return fieldErrors
+}
+
+// sortedKeys keeps field errors order stable for maps.
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
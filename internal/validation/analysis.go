@@ -0,0 +1,78 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxStopwords		= 1000
+	maxSynonymRules		= 10000
+	maxSynonymRuleBytes	= 1024
+)
+
+func validateIndexAnalysis(path string, analysis *models.IndexAnalysis) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if analysis.Language != "" && !contains(models.AnalysisLanguages, analysis.Language) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".language", Message: fmt.Sprintf("language must be one of %s", strings.Join(models.AnalysisLanguages, ", "))})
+	}
+
+	if len(analysis.Stopwords) > maxStopwords {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".stopwords", Message: fmt.Sprintf("at most %d stopwords are allowed", maxStopwords)})
+	}
+	for i, stopword := range analysis.Stopwords {
+		if strings.TrimSpace(stopword) == "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: fmt.Sprintf("%s.stopwords[%d]", path, i), Message: "stopword must not be empty"})
+		}
+	}
+
+	return append(fieldErrors, validateSynonyms(path+".synonyms", analysis.Synonyms)...)
+}
+
+func (v *Validator) ValidateUpdateSynonymsRequest(request *models.UpdateSynonymsRequest) []models.FieldError {
+	return validateSynonyms("synonyms", request.Synonyms)
+}
+
+// validateSynonyms checks rules in Solr format: comma separated equivalent terms
+// or explicit mapping like "tv, television => television".
+func validateSynonyms(path string, synonyms []string) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if len(synonyms) > maxSynonymRules {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path, Message: fmt.Sprintf("at most %d synonym rules are allowed", maxSynonymRules)})
+		return fieldErrors
+	}
+
+	for i, rule := range synonyms {
+		rulePath := fmt.Sprintf("%s[%d]", path, i)
+		if len(rule) > maxSynonymRuleBytes {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: rulePath, Message: fmt.Sprintf("synonym rule must not be longer than %d bytes", maxSynonymRuleBytes)})
+			continue
+		}
+
+		sides := strings.Split(rule, "=>")
+		switch {
+		case len(sides) > 2:
+			fieldErrors = append(fieldErrors, models.FieldError{Field: rulePath, Message: "synonym rule must contain at most one =>"})
+		case len(sides) == 2 && (countTerms(sides[0]) == 0 || countTerms(sides[1]) == 0):
+			fieldErrors = append(fieldErrors, models.FieldError{Field: rulePath, Message: "both sides of => must contain terms"})
+		case len(sides) == 1 && countTerms(rule) < 2:
+			fieldErrors = append(fieldErrors, models.FieldError{Field: rulePath, Message: "synonym rule must contain at least two comma separated terms"})
+		}
+	}
+
+	return fieldErrors
+}
+
+func countTerms(terms string) int {
+	count := 0
+	for _, term := range strings.Split(terms, ",") {
+		if strings.TrimSpace(term) != "" {
+			count++
+		}
+	}
+	return count
+}
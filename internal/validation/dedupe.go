@@ -0,0 +1,23 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+func (v *Validator) ValidateUpdateDedupeRequest(request *models.UpdateDedupeRequest) []models.FieldError {
+	if request.Dedupe == "" {
+		return []models.FieldError{{Field: "dedupe", Message: "dedupe is required"}}
+	}
+	return validateDedupePolicy("dedupe", request.Dedupe)
+}
+
+// validateDedupePolicy checks policy set at path, empty policy means index default.
+func validateDedupePolicy(path string, policy string) []models.FieldError {
+	if policy != "" && !contains(models.DedupePolicies, policy) {
+		return []models.FieldError{{Field: path, Message: fmt.Sprintf("dedupe must be one of %s", strings.Join(models.DedupePolicies, ", "))}}
+	}
+	return []models.FieldError{}
+}
@@ -0,0 +1,64 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxPrefixLength		= 20
+	maxMaxExpansions	= 200
+)
+
+var fuzzinessValues = []string{models.FuzzinessAuto, "0", "1", "2"}
+
+func (v *Validator) ValidateUpdateFuzzyRequest(request *models.FuzzySettings) []models.FieldError {
+	return validateFuzzySettings("", request)
+}
+
+// validateFuzzySettings checks settings nested in path, empty path means top level fields.
+func validateFuzzySettings(path string, settings *models.FuzzySettings) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	if path != "" {
+		path += "."
+	}
+
+	if settings.Fuzziness != "" && !contains(fuzzinessValues, strings.ToUpper(settings.Fuzziness)) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + "fuzziness", Message: fmt.Sprintf("fuzziness must be one of %s", strings.Join(fuzzinessValues, ", "))})
+	}
+
+	if settings.PrefixLength != nil && (*settings.PrefixLength < 0 || *settings.PrefixLength > maxPrefixLength) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + "prefix_length", Message: fmt.Sprintf("prefix_length must be from 0 to %d", maxPrefixLength)})
+	}
+
+	if settings.MaxExpansions != nil && (*settings.MaxExpansions < 1 || *settings.MaxExpansions > maxMaxExpansions) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + "max_expansions", Message: fmt.Sprintf("max_expansions must be from 1 to %d", maxMaxExpansions)})
+	}
+
+	return fieldErrors
+}
+
+// validateSearchFuzzySettings rejects fuzzy settings for syntaxes they aren't applied to,
+// query_string and simple syntax have their own ~ operator.
+func validateSearchFuzzySettings(request *models.DocumentSearchRequest) []models.FieldError {
+	if request.Search != nil || request.Syntax == "" {
+		return validateFuzzySettings("", &request.FuzzySettings)
+	}
+
+	fieldErrors := []models.FieldError{}
+	for _, setting := range []struct {
+		field	string
+		isSet	bool
+	}{
+		{"fuzziness", request.Fuzziness != ""},
+		{"prefix_length", request.PrefixLength != nil},
+		{"max_expansions", request.MaxExpansions != nil},
+	} {
+		if setting.isSet {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: setting.field, Message: fmt.Sprintf("%s is not supported with %s syntax, use ~ operator", setting.field, request.Syntax)})
+		}
+	}
+	return fieldErrors
+}
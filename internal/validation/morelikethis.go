@@ -0,0 +1,34 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxMinTermFreq		= 100
+	maxMaxQueryTerms	= 100
+)
+
+func (v *Validator) ValidateMoreLikeThisRequest(request *models.MoreLikeThisRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	hasId, hasText := strings.TrimSpace(request.Id) != "", strings.TrimSpace(request.Text) != ""
+	if !hasId && !hasText {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "id", Message: "id or text is required"})
+	}
+	if hasId && hasText {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "text", Message: "text can't be used together with id"})
+	}
+
+	if request.MinTermFreq != nil && (*request.MinTermFreq < 1 || *request.MinTermFreq > maxMinTermFreq) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "min_term_freq", Message: fmt.Sprintf("min_term_freq must be from 1 to %d", maxMinTermFreq)})
+	}
+	if request.MaxQueryTerms != nil && (*request.MaxQueryTerms < 1 || *request.MaxQueryTerms > maxMaxQueryTerms) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "max_query_terms", Message: fmt.Sprintf("max_query_terms must be from 1 to %d", maxMaxQueryTerms)})
+	}
+
+	return fieldErrors
+}
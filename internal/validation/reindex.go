@@ -0,0 +1,20 @@
+package validation
+
+import (
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// ValidateReindexRequest checks new settings only, unlike schema updates reindexing can
+// change types of existing fields.
+func (v *Validator) ValidateReindexRequest(request *models.ReindexRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if request.Schema != nil {
+		fieldErrors = append(fieldErrors, ValidateIndexSchema("schema", request.Schema)...)
+	}
+	if request.Analysis != nil {
+		fieldErrors = append(fieldErrors, validateIndexAnalysis("analysis", request.Analysis)...)
+	}
+
+	return fieldErrors
+}
@@ -0,0 +1,121 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxSortFields		= 5
+	maxSourceFields		= 50
+)
+
+var (
+	sortOrders			= []string{"", models.SortOrderAsc, models.SortOrderDesc}
+	sortableFieldTypes	= []string{
+		models.FieldTypeKeyword,
+		models.FieldTypeDate,
+		models.FieldTypeLong,
+		models.FieldTypeInteger,
+		models.FieldTypeFloat,
+		models.FieldTypeDouble,
+		models.FieldTypeBoolean,
+	}
+)
+
+// validateResultsOptions checks sort and source filter options which don't depend on index schema.
+func validateResultsOptions(request *models.DocumentSearchRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	fieldError := func(field string, message string) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: message})
+	}
+
+	if len(request.Sort) > maxSortFields {
+		fieldError("sort", fmt.Sprintf("at most %d sort fields are allowed", maxSortFields))
+	}
+	sortFields := map[string]bool{}
+	for i, sortField := range request.Sort {
+		path := fmt.Sprintf("sort[%d]", i)
+		if sortField.Field == "" {
+			fieldError(path+".field", "field is required")
+		} else if sortFields[sortField.Field] {
+			fieldError(path+".field", "field is already sorted by")
+		}
+		sortFields[sortField.Field] = true
+		if !contains(sortOrders, sortField.Order) {
+			fieldError(path+".order", fmt.Sprintf("order must be %s or %s", models.SortOrderAsc, models.SortOrderDesc))
+		}
+	}
+
+	if request.Source != nil {
+		sourceLists := []struct {
+			name	string
+			fields	[]string
+		}{
+			{"includes", request.Source.Includes},
+			{"excludes", request.Source.Excludes},
+		}
+		for _, sourceList := range sourceLists {
+			path := "_source." + sourceList.name
+			if len(sourceList.fields) > maxSourceFields {
+				fieldError(path, fmt.Sprintf("at most %d fields are allowed", maxSourceFields))
+			}
+			for i, field := range sourceList.fields {
+				if strings.TrimSpace(field) == "" {
+					fieldError(fmt.Sprintf("%s[%d]", path, i), "field must not be empty")
+				}
+			}
+		}
+
+		if request.SnippetOnly && contains(request.Source.Includes, "text") {
+			fieldError("_source.includes", "text can't be included in snippet_only mode")
+		}
+	}
+
+	return fieldErrors
+}
+
+// ValidateSortFields checks that documents can be sorted by fields, only _score, title and
+// schema fields of sortable types are allowed as dynamically mapped fields may be text.
+func ValidateSortFields(schema *models.IndexSchema, sort []models.SortField) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	for i, sortField := range sort {
+		if sortField.Field == models.SortFieldScore || sortField.Field == "title" {
+			continue
+		}
+
+		if !isSortableSchemaField(schema, sortField.Field) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: fmt.Sprintf("sort[%d].field", i), Message: "field must be _score, title or sortable schema field"})
+		}
+	}
+
+	return fieldErrors
+}
+
+// isSortableSchemaField resolves path like fields.author.name through object fields,
+// fields inside nested ones can't be sorted by without nested sort options.
+func isSortableSchemaField(schema *models.IndexSchema, path string) bool {
+	names := strings.Split(path, ".")
+	if schema == nil || len(names) < 2 || names[0] != "fields" {
+		return false
+	}
+
+	schemaFields := schema.Fields
+	for i, name := range names[1:] {
+		schemaField, ok := schemaFields[name]
+		if !ok {
+			return false
+		}
+		if i == len(names)-2 {
+			return contains(sortableFieldTypes, schemaField.Type)
+		}
+		if schemaField.Type != models.FieldTypeObject {
+			return false
+		}
+		schemaFields = schemaField.Properties
+	}
+	return false
+}
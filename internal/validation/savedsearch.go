@@ -0,0 +1,37 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+func (v *Validator) ValidateSavedSearchRequest(request *models.SavedSearchRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if strings.TrimSpace(request.Name) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "name", Message: "name is required"})
+	} else if len(request.Name) > models.MaxSavedSearchNameLen {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "name", Message: fmt.Sprintf("name must be at most %d bytes", models.MaxSavedSearchNameLen)})
+	}
+
+	if request.WebhookURL != "" && !isHTTPURL(request.WebhookURL) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "webhook_url", Message: "webhook_url must be absolute http or https url"})
+	} else if v.isForbiddenWebhookURL(request.WebhookURL) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "webhook_url", Message: "webhook_url must not point to loopback, private or link-local address"})
+	}
+
+	if request.Search != nil {
+		if request.Query != "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "search", Message: "search can't be combined with query"})
+		}
+		return append(fieldErrors, validateSearchClause("search", request.Search, 1)...)
+	}
+
+	if strings.TrimSpace(request.Query) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "query", Message: "query or search is required"})
+	}
+
+	return fieldErrors
+}
@@ -0,0 +1,259 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const (
+	maxSchemaFields		= 100
+	maxSchemaDepth		= 3
+)
+
+var schemaFieldNameRegexp = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+
+var schemaFieldTypes = []string{
+	models.FieldTypeKeyword,
+	models.FieldTypeText,
+	models.FieldTypeDate,
+	models.FieldTypeLong,
+	models.FieldTypeInteger,
+	models.FieldTypeFloat,
+	models.FieldTypeDouble,
+	models.FieldTypeBoolean,
+	models.FieldTypeObject,
+	models.FieldTypeNested,
+}
+
+// Date formats accepted by ES default strict_date_optional_time format
+var dateLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02"}
+
+// ValidateIndexSchema checks schema definition sent on index creation or update.
+func ValidateIndexSchema(path string, schema *models.IndexSchema) []models.FieldError {
+	if len(schema.Fields) == 0 {
+		return []models.FieldError{{Field: path + ".fields", Message: "at least one field is required"}}
+	}
+
+	fieldErrors := validateSchemaFields(path+".fields", schema.Fields, 1)
+	if countSchemaFields(schema.Fields) > maxSchemaFields {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".fields", Message: fmt.Sprintf("at most %d fields are allowed", maxSchemaFields)})
+	}
+
+	return fieldErrors
+}
+
+func validateSchemaFields(path string, fields map[string]models.SchemaField, depth int) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	fieldError := func(field string, message string) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: message})
+	}
+
+	for _, name := range sortedKeys(fields) {
+		field := fields[name]
+		fieldPath := path + "." + name
+		if !schemaFieldNameRegexp.MatchString(name) {
+			fieldError(fieldPath, "field name must start with a letter and contain only letters, digits, _ and - up to 64 characters")
+			continue
+		}
+		if !contains(schemaFieldTypes, field.Type) {
+			fieldError(fieldPath+".type", fmt.Sprintf("type must be one of %s", strings.Join(schemaFieldTypes, ", ")))
+			continue
+		}
+
+		isObject := field.Type == models.FieldTypeObject || field.Type == models.FieldTypeNested
+		switch {
+		case isObject && depth >= maxSchemaDepth:
+			fieldError(fieldPath, fmt.Sprintf("fields can't be nested deeper than %d levels", maxSchemaDepth))
+		case isObject && len(field.Properties) == 0:
+			fieldError(fieldPath+".properties", fmt.Sprintf("properties are required for %s fields", field.Type))
+		case isObject:
+			fieldErrors = append(fieldErrors, validateSchemaFields(fieldPath+".properties", field.Properties, depth+1)...)
+		case len(field.Properties) > 0:
+			fieldError(fieldPath+".properties", "properties are allowed only for object and nested fields")
+		}
+	}
+
+	return fieldErrors
+}
+
+// ValidateSchemaUpdate allows only additive changes: new fields can be added,
+// existing ones must keep their types.
+func ValidateSchemaUpdate(path string, current map[string]models.SchemaField, update map[string]models.SchemaField) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	for _, name := range sortedKeys(update) {
+		existing, ok := current[name]
+		if !ok {
+			continue
+		}
+		fieldPath := path + "." + name
+		if existing.Type != update[name].Type {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: fieldPath + ".type", Message: fmt.Sprintf("type of existing field can't be changed from %s", existing.Type)})
+			continue
+		}
+		fieldErrors = append(fieldErrors, ValidateSchemaUpdate(fieldPath+".properties", existing.Properties, update[name].Properties)...)
+	}
+
+	return fieldErrors
+}
+
+// ValidateDocumentsFields checks documents' fields against index schema,
+// without schema any fields are accepted.
+func (v *Validator) ValidateDocumentsFields(schema *models.IndexSchema, documents []models.Document) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	if schema == nil {
+		return fieldErrors
+	}
+
+	for i, document := range documents {
+		fieldErrors = append(fieldErrors, v.ValidateDocumentFields(fmt.Sprintf("documents[%d]", i), schema, &document)...)
+	}
+
+	return fieldErrors
+}
+
+// ValidateDocumentFields checks fields of single document against index schema.
+func (v *Validator) ValidateDocumentFields(path string, schema *models.IndexSchema, document *models.Document) []models.FieldError {
+	if schema == nil {
+		return []models.FieldError{}
+	}
+	return validateFieldValues(path + ".fields", schema.Fields, document.Fields)
+}
+
+func validateFieldValues(path string, schemaFields map[string]models.SchemaField, values map[string]any) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	for _, name := range sortedKeys(values) {
+		fieldPath := path + "." + name
+		schemaField, ok := schemaFields[name]
+		if !ok {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: fieldPath, Message: "field is not defined in index schema"})
+			continue
+		}
+
+		// ES accepts arrays of values for any field type
+		if array, ok := values[name].([]any); ok {
+			for i, value := range array {
+				fieldErrors = append(fieldErrors, validateFieldValue(fmt.Sprintf("%s[%d]", fieldPath, i), schemaField, value)...)
+			}
+			continue
+		}
+		fieldErrors = append(fieldErrors, validateFieldValue(fieldPath, schemaField, values[name])...)
+	}
+
+	return fieldErrors
+}
+
+func validateFieldValue(path string, schemaField models.SchemaField, value any) []models.FieldError {
+	fieldError := func(message string) []models.FieldError {
+		return []models.FieldError{{Field: path, Message: message}}
+	}
+
+	if value == nil {
+		return []models.FieldError{}
+	}
+
+	switch schemaField.Type {
+	case models.FieldTypeKeyword, models.FieldTypeText:
+		if _, ok := value.(string); !ok {
+			return fieldError("value must be a string")
+		}
+	case models.FieldTypeDate:
+		if !isDate(value) {
+			return fieldError("value must be a date in ISO 8601 format or milliseconds since epoch")
+		}
+	case models.FieldTypeLong, models.FieldTypeInteger:
+		number, ok := integerValue(value)
+		if !ok {
+			return fieldError("value must be an integer")
+		}
+		if schemaField.Type == models.FieldTypeInteger && (number < math.MinInt32 || number > math.MaxInt32) {
+			return fieldError("value is out of integer range")
+		}
+	case models.FieldTypeFloat, models.FieldTypeDouble:
+		if !isNumber(value) {
+			return fieldError("value must be a number")
+		}
+	case models.FieldTypeBoolean:
+		if _, ok := value.(bool); !ok {
+			return fieldError("value must be a boolean")
+		}
+	case models.FieldTypeObject, models.FieldTypeNested:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return fieldError("value must be an object")
+		}
+		return validateFieldValues(path, schemaField.Properties, object)
+	}
+
+	return []models.FieldError{}
+}
+
+// integerValue returns value of integer field. Requests are decoded to json.Number, so
+// large values stay exact, while extractors give int64 and float64.
+func integerValue(value any) (int64, bool) {
+	switch value := value.(type) {
+	case json.Number:
+		if number, err := value.Int64(); err == nil {
+			return number, true
+		}
+		// integers may be written like 1e3 or 2.0
+		number, err := value.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return integerValue(number)
+	case float64:
+		if value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+			return 0, false
+		}
+		return int64(value), true
+	case int:
+		return int64(value), true
+	case int64:
+		return value, true
+	}
+	return 0, false
+}
+
+func isNumber(value any) bool {
+	switch value := value.(type) {
+	case json.Number:
+		_, err := value.Float64()
+		return err == nil
+	case float64, int, int64:
+		return true
+	}
+	return false
+}
+
+func isDate(value any) bool {
+	switch value := value.(type) {
+	case json.Number:
+		_, ok := integerValue(value)
+		return ok
+	case float64:
+		return value == math.Trunc(value)
+	case string:
+		for _, layout := range dateLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func countSchemaFields(fields map[string]models.SchemaField) int {
+	count := len(fields)
+	for _, field := range fields {
+		count += countSchemaFields(field.Properties)
+	}
+	return count
+}
@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const maxSearchClauseDepth = 10
+
+var (
+	queryOperators		= []string{"", "and", "or"}
+	multiMatchTypes		= []string{"", "best_fields", "most_fields", "cross_fields", "phrase", "phrase_prefix"}
+)
+
+func validateSearchClause(path string, clause *models.SearchClause, depth int) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+	fieldError := func(field string, message string) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: message})
+	}
+
+	if depth > maxSearchClauseDepth {
+		fieldError(path, fmt.Sprintf("search clauses can't be nested deeper than %d levels", maxSearchClauseDepth))
+		return fieldErrors
+	}
+
+	set := 0
+	for _, isSet := range []bool{clause.Match != nil, clause.MatchPhrase != nil, clause.MultiMatch != nil, clause.Bool != nil, clause.Term != nil, clause.Range != nil, clause.Prefix != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		fieldError(path, "exactly one of match, match_phrase, multi_match, bool, term, range or prefix is required")
+		return fieldErrors
+	}
+
+	switch {
+	case clause.Match != nil:
+		fieldErrors = append(fieldErrors, validateMatchClause(path+".match", clause.Match)...)
+	case clause.MatchPhrase != nil:
+		fieldErrors = append(fieldErrors, validateMatchClause(path+".match_phrase", clause.MatchPhrase)...)
+	case clause.MultiMatch != nil:
+		multiMatchPath := path + ".multi_match"
+		if strings.TrimSpace(clause.MultiMatch.Query) == "" {
+			fieldError(multiMatchPath+".query", "query is required")
+		}
+		if len(clause.MultiMatch.Fields) == 0 {
+			fieldError(multiMatchPath+".fields", "at least one field is required")
+		}
+		for i, field := range clause.MultiMatch.Fields {
+			fieldErrors = append(fieldErrors, validateSearchField(fmt.Sprintf("%s.fields[%d].field", multiMatchPath, i), field.Field)...)
+		}
+		if !contains(multiMatchTypes, clause.MultiMatch.Type) {
+			fieldError(multiMatchPath+".type", fmt.Sprintf("type must be one of %s", strings.Join(multiMatchTypes[1:], ", ")))
+		}
+		if !contains(queryOperators, clause.MultiMatch.Operator) {
+			fieldError(multiMatchPath+".operator", "operator must be and or or")
+		}
+	case clause.Bool != nil:
+		boolPath := path + ".bool"
+		if len(clause.Bool.Must) + len(clause.Bool.Should) + len(clause.Bool.MustNot) + len(clause.Bool.Filter) == 0 {
+			fieldError(boolPath, "at least one of must, should, must_not or filter is required")
+		}
+		occurrences := []struct {
+			name	string
+			clauses	[]models.SearchClause
+		}{
+			{"must", clause.Bool.Must},
+			{"should", clause.Bool.Should},
+			{"must_not", clause.Bool.MustNot},
+			{"filter", clause.Bool.Filter},
+		}
+		for _, occurrence := range occurrences {
+			for i := range occurrence.clauses {
+				fieldErrors = append(fieldErrors, validateSearchClause(fmt.Sprintf("%s.%s[%d]", boolPath, occurrence.name, i), &occurrence.clauses[i], depth+1)...)
+			}
+		}
+	case clause.Term != nil:
+		termPath := path + ".term"
+		fieldErrors = append(fieldErrors, validateSearchField(termPath+".field", clause.Term.Field)...)
+		switch clause.Term.Value.(type) {
+		case string, float64, json.Number, bool:
+		default:
+			fieldError(termPath+".value", "value must be a string, number or boolean")
+		}
+	case clause.Range != nil:
+		rangePath := path + ".range"
+		fieldErrors = append(fieldErrors, validateSearchField(rangePath+".field", clause.Range.Field)...)
+		if clause.Range.Gt == nil && clause.Range.Gte == nil && clause.Range.Lt == nil && clause.Range.Lte == nil {
+			fieldError(rangePath, "at least one of gt, gte, lt or lte is required")
+		}
+	case clause.Prefix != nil:
+		prefixPath := path + ".prefix"
+		fieldErrors = append(fieldErrors, validateSearchField(prefixPath+".field", clause.Prefix.Field)...)
+		if clause.Prefix.Value == "" {
+			fieldError(prefixPath+".value", "value is required")
+		}
+	}
+
+	return fieldErrors
+}
+
+func validateMatchClause(path string, clause *models.MatchClause) []models.FieldError {
+	fieldErrors := validateSearchField(path+".field", clause.Field)
+
+	if strings.TrimSpace(clause.Query) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".query", Message: "query is required"})
+	}
+	if !contains(queryOperators, clause.Operator) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".operator", Message: "operator must be and or or"})
+	}
+
+	return fieldErrors
+}
+
+// validateSearchField rejects empty field names and ES internal fields like _id or _index.
+func validateSearchField(path string, field string) []models.FieldError {
+	if field == "" {
+		return []models.FieldError{{Field: path, Message: "field is required"}}
+	}
+	if strings.HasPrefix(field, "_") {
+		return []models.FieldError{{Field: path, Message: "internal fields can't be searched"}}
+	}
+
+	return []models.FieldError{}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package validation
+
+import (
+	"fmt"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+func (v *Validator) ValidateCSVMapping(mapping *models.CSVMapping) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if mapping.Title == "" && len(mapping.Text) == 0 {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "csv_mapping", Message: "title or text column is required"})
+	}
+
+	for i, column := range mapping.Text {
+		if column == "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: fmt.Sprintf("csv_mapping.text[%d]", i), Message: "column is required"})
+		}
+	}
+
+	for field, column := range mapping.Fields {
+		if field == "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "csv_mapping.fields", Message: "field name is required"})
+		} else if column == "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "csv_mapping.fields." + field, Message: "column is required"})
+		}
+	}
+
+	return fieldErrors
+}
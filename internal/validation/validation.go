@@ -0,0 +1,227 @@
+package validation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+const maxIndexNameBytes = 255
+
+// Limit of indexes listed in one search request, all user's indexes can be searched with *
+const maxSearchIndexes = 20
+
+// Characters elasticsearch doesn't allow in index names
+const forbiddenIndexNameChars = "\\/*?\"<>| ,#:"
+
+// New indexes are aliases over physical indexes named index~v<version>, so their names
+// leave room for the suffix and can't contain its separator
+const (
+	indexVersionSeparator		= "~"
+	maxIndexVersionSuffixBytes	= 12
+)
+
+// Validator checks request payloads, zero limits mean there is no limit.
+type Validator struct {
+	MaxDocumentSize			int
+	MaxDocumentsPerRequest	int
+	// ReservedIndexNames are used by the service itself and can't be created by users
+	ReservedIndexNames		[]string
+	// AllowPrivateWebhooks accepts webhook urls with loopback and private hosts
+	AllowPrivateWebhooks	bool
+	// ExternalIngestion means queued documents are indexed outside of the service, so
+	// webhooks can't subscribe to ingestion events
+	ExternalIngestion		bool
+}
+
+func (v *Validator) ValidateDocumentsForIndexing(request *models.DocumentsForIndexing) []models.FieldError {
+	fieldErrors := ValidateIndexName("index_name", request.Index)
+
+	if len(request.Documents) == 0 {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "documents", Message: "at least one document is required"})
+	} else if v.MaxDocumentsPerRequest > 0 && len(request.Documents) > v.MaxDocumentsPerRequest {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "documents", Message: fmt.Sprintf("at most %d documents are allowed per request", v.MaxDocumentsPerRequest)})
+	}
+
+	for i, document := range request.Documents {
+		fieldErrors = append(fieldErrors, v.ValidateDocument(fmt.Sprintf("documents[%d]", i), &document)...)
+	}
+
+	return fieldErrors
+}
+
+// ValidateDocument checks single document, path is prepended to fields of returned errors.
+func (v *Validator) ValidateDocument(path string, document *models.Document) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if strings.TrimSpace(document.Title) == "" && strings.TrimSpace(document.Text) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path, Message: "title or text is required"})
+	}
+
+	if v.MaxDocumentSize > 0 {
+		marshaledDocument, _ := json.Marshal(document)
+		if len(marshaledDocument) > v.MaxDocumentSize {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: path, Message: fmt.Sprintf("document size exceeds %d bytes", v.MaxDocumentSize)})
+		}
+	}
+
+	return fieldErrors
+}
+
+func (v *Validator) ValidateDocumentSearchRequest(request *models.DocumentSearchRequest) []models.FieldError {
+	fieldErrors := validateSearchIndexes(request)
+	fieldErrors = append(fieldErrors, validateAggregations(request)...)
+	fieldErrors = append(fieldErrors, validateSearchFuzzySettings(request)...)
+	fieldErrors = append(fieldErrors, validateResultsOptions(request)...)
+	fieldErrors = append(fieldErrors, validateSearchMode(request)...)
+
+	if request.Search != nil {
+		if request.Query != "" || request.Syntax != "" || request.DefaultOperator != "" {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "search", Message: "search can't be combined with query, syntax and default_operator"})
+		}
+		if request.AutoCorrect {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "auto_correct", Message: "auto_correct is allowed only with query"})
+		}
+		return append(fieldErrors, validateSearchClause("search", request.Search, 1)...)
+	}
+
+	if strings.TrimSpace(request.Query) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "query", Message: "query is required"})
+	}
+
+	if request.Syntax != "" && request.Syntax != models.SyntaxQueryString && request.Syntax != models.SyntaxSimple {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "syntax", Message: fmt.Sprintf("syntax must be %s or %s", models.SyntaxQueryString, models.SyntaxSimple)})
+	}
+
+	if request.DefaultOperator != "" {
+		if request.Syntax != models.SyntaxSimple {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "default_operator", Message: "default_operator is allowed only with simple syntax"})
+		} else if !strings.EqualFold(request.DefaultOperator, models.DefaultOperatorAnd) && !strings.EqualFold(request.DefaultOperator, models.DefaultOperatorOr) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "default_operator", Message: fmt.Sprintf("default_operator must be %s or %s", models.DefaultOperatorAnd, models.DefaultOperatorOr)})
+		}
+	}
+
+	return fieldErrors
+}
+
+// validateSearchIndexes checks that either single index or list of indexes is searched.
+func validateSearchIndexes(request *models.DocumentSearchRequest) []models.FieldError {
+	if len(request.Indexes) == 0 {
+		return ValidateIndexName("index_name", request.Index)
+	}
+
+	fieldErrors := []models.FieldError{}
+	if request.Index != "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "index_names", Message: "index_names can't be combined with index_name"})
+	}
+	if len(request.Indexes) > maxSearchIndexes {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "index_names", Message: fmt.Sprintf("at most %d indexes can be searched at once", maxSearchIndexes)})
+	}
+
+	if contains(request.Indexes, models.AllIndexes) {
+		if len(request.Indexes) > 1 {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "index_names", Message: fmt.Sprintf("%s can't be combined with index names", models.AllIndexes)})
+		}
+		return fieldErrors
+	}
+
+	listed := map[string]bool{}
+	for i, index := range request.Indexes {
+		field := fmt.Sprintf("index_names[%d]", i)
+		if listed[index] {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: "index is already listed"})
+		}
+		listed[index] = true
+		fieldErrors = append(fieldErrors, ValidateIndexName(field, index)...)
+	}
+
+	return fieldErrors
+}
+
+func (v *Validator) ValidateSuggestRequest(request *models.SuggestRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if strings.TrimSpace(request.Prefix) == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "prefix", Message: "prefix is required"})
+	} else if len(request.Prefix) > models.MaxSuggestPrefixLen {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "prefix", Message: fmt.Sprintf("prefix must be at most %d bytes", models.MaxSuggestPrefixLen)})
+	}
+
+	return fieldErrors
+}
+
+func (v *Validator) ValidateCreateIndexRequest(request *models.CreateIndexRequest) []models.FieldError {
+	fieldErrors := ValidateIndexName("index_name", request.Index)
+	if contains(v.ReservedIndexNames, request.Index) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "index_name", Message: "index name is reserved"})
+	}
+	if len(fieldErrors) == 0 {
+		if strings.Contains(request.Index, indexVersionSeparator) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "index_name", Message: fmt.Sprintf("index name must not contain %q", indexVersionSeparator)})
+		} else if len(request.Index) > maxIndexNameBytes-maxIndexVersionSuffixBytes {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: "index_name", Message: fmt.Sprintf("index name must not be longer than %d bytes", maxIndexNameBytes-maxIndexVersionSuffixBytes)})
+		}
+	}
+	if request.Schema != nil {
+		fieldErrors = append(fieldErrors, ValidateIndexSchema("schema", request.Schema)...)
+	}
+	if request.Analysis != nil {
+		fieldErrors = append(fieldErrors, validateIndexAnalysis("analysis", request.Analysis)...)
+	}
+	if request.Fuzzy != nil {
+		fieldErrors = append(fieldErrors, validateFuzzySettings("fuzzy", request.Fuzzy)...)
+	}
+	if request.Vector != nil {
+		fieldErrors = append(fieldErrors, validateVectorSettings("vector", request.Vector)...)
+	}
+	fieldErrors = append(fieldErrors, validateDedupePolicy("dedupe", request.Dedupe)...)
+
+	return fieldErrors
+}
+
+func (v *Validator) ValidateLoginRequest(request *models.LoginRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if request.Login == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "login", Message: "login is required"})
+	}
+	if request.Password == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "password", Message: "password is required"})
+	}
+
+	return fieldErrors
+}
+
+func (v *Validator) ValidateRefreshRequest(request *models.RefreshRequest) []models.FieldError {
+	if request.RefreshToken == "" {
+		return []models.FieldError{{Field: "refresh_token", Message: "refresh_token is required"}}
+	}
+
+	return []models.FieldError{}
+}
+
+// ValidateIndexName enforces elasticsearch index naming rules.
+func ValidateIndexName(field string, name string) []models.FieldError {
+	fieldError := func(message string) []models.FieldError {
+		return []models.FieldError{{Field: field, Message: message}}
+	}
+
+	switch {
+	case name == "":
+		return fieldError("index name is required")
+	case len(name) > maxIndexNameBytes:
+		return fieldError(fmt.Sprintf("index name must not be longer than %d bytes", maxIndexNameBytes))
+	case name == "." || name == "..":
+		return fieldError("index name must not be . or ..")
+	case strings.ToLower(name) != name:
+		return fieldError("index name must be lowercase")
+	case strings.ContainsAny(name[:1], "-_+"):
+		return fieldError("index name must not start with -, _ or +")
+	case strings.ContainsAny(name, forbiddenIndexNameChars):
+		return fieldError(fmt.Sprintf("index name must not contain any of %q", forbiddenIndexNameChars))
+	}
+
+	return []models.FieldError{}
+}
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+var searchModes = []string{models.SearchModeKeyword, models.SearchModeKnn, models.SearchModeHybrid}
+
+func validateVectorSettings(path string, settings *models.VectorSettings) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if settings.Similarity != "" && !contains(models.VectorSimilarities, settings.Similarity) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".similarity", Message: fmt.Sprintf("similarity must be one of %s", strings.Join(models.VectorSimilarities, ", "))})
+	}
+	if settings.Dimensions != 0 {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: path + ".dimensions", Message: "dimensions are set from embedder and can't be specified"})
+	}
+
+	return fieldErrors
+}
+
+// validateSearchMode rejects options vector modes don't support, knn search matches
+// only the query text while hybrid also can't aggregate or post filter fused results.
+func validateSearchMode(request *models.DocumentSearchRequest) []models.FieldError {
+	if request.Mode == "" || request.Mode == models.SearchModeKeyword {
+		return []models.FieldError{}
+	}
+	if !contains(searchModes, request.Mode) {
+		return []models.FieldError{{Field: "mode", Message: fmt.Sprintf("mode must be one of %s", strings.Join(searchModes, ", "))}}
+	}
+
+	fieldErrors := []models.FieldError{}
+	for _, option := range []struct {
+		field	string
+		isSet	bool
+		modes	[]string
+	}{
+		{"search", request.Search != nil, searchModes[1:]},
+		{"syntax", request.Syntax != "", searchModes[1:]},
+		{"sort", len(request.Sort) > 0, searchModes[1:]},
+		{"auto_correct", request.AutoCorrect, searchModes[1:]},
+		{"aggregations", len(request.Aggregations) > 0, []string{models.SearchModeHybrid}},
+		{"post_filters", len(request.PostFilters) > 0, []string{models.SearchModeHybrid}},
+	} {
+		if option.isSet && contains(option.modes, request.Mode) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: option.field, Message: fmt.Sprintf("%s is not supported in %s mode", option.field, request.Mode)})
+		}
+	}
+	return fieldErrors
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/outbound"
+)
+
+func (v *Validator) ValidateWebhookRequest(request *models.WebhookRequest) []models.FieldError {
+	fieldErrors := []models.FieldError{}
+
+	if request.URL == "" {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "url", Message: "url is required"})
+	} else if !isHTTPURL(request.URL) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "url", Message: "url must be absolute http or https url"})
+	} else if v.isForbiddenWebhookURL(request.URL) {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "url", Message: "url must not point to loopback, private or link-local address"})
+	}
+
+	if len(request.Secret) < models.MinWebhookSecretLen || len(request.Secret) > models.MaxWebhookSecretLen {
+		message := fmt.Sprintf("secret must be from %d to %d bytes", models.MinWebhookSecretLen, models.MaxWebhookSecretLen)
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "secret", Message: message})
+	}
+
+	if len(request.Events) == 0 {
+		fieldErrors = append(fieldErrors, models.FieldError{Field: "events", Message: "at least one event is required"})
+	}
+	for i, event := range request.Events {
+		field := fmt.Sprintf("events[%d]", i)
+		if !contains(models.WebhookEvents, event) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: fmt.Sprintf("unknown event %s", event)})
+		} else if contains(request.Events[:i], event) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: fmt.Sprintf("duplicate event %s", event)})
+		} else if v.ExternalIngestion && contains(models.IngestionEvents, event) {
+			fieldErrors = append(fieldErrors, models.FieldError{Field: field, Message: fmt.Sprintf("event %s isn't available, queued documents are indexed outside of the service", event)})
+		}
+	}
+
+	return fieldErrors
+}
+
+func isHTTPURL(rawURL string) bool {
+	parsedURL, err := url.Parse(rawURL)
+	return err == nil && (parsedURL.Scheme == "http" || parsedURL.Scheme == "https") && parsedURL.Host != ""
+}
+
+// isForbiddenWebhookURL reports urls of http or https webhooks pointing to the host or its
+// internal networks, names are checked again after resolving when webhook is called.
+func (v *Validator) isForbiddenWebhookURL(rawURL string) bool {
+	if v.AllowPrivateWebhooks {
+		return false
+	}
+
+	parsedURL, err := url.Parse(rawURL)
+	return err == nil && outbound.IsForbiddenHost(parsedURL.Hostname())
+}
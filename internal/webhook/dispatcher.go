@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+const (
+	HeaderEvent		= "X-Webhook-Event"
+	HeaderDelivery	= "X-Webhook-Delivery"
+	HeaderTimestamp	= "X-Webhook-Timestamp"
+	// HeaderSignature is sha256= followed by hex HMAC-SHA256 of timestamp, dot and body
+	HeaderSignature	= "X-Webhook-Signature"
+)
+
+// Longest pause between delivery attempts
+const maxBackoff = 10 * time.Minute
+
+// Dispatcher delivers events to webhooks subscribed to them. Deliveries run in background
+// with retries and are recorded after each attempt, deliveries interrupted by restart or
+// Stop stay pending and can be redelivered.
+type Dispatcher struct {
+	storage		storage.WebhookStorage
+	client		*http.Client
+	maxAttempts	int
+	backoff		time.Duration
+	// stopped is closed by Stop to interrupt pauses between attempts
+	stopped		chan struct{}
+	stopOnce	sync.Once
+	wg			sync.WaitGroup
+}
+
+// NewDispatcher delivers events with client, it is expected to have timeout and to refuse
+// addresses webhooks must not reach.
+func NewDispatcher(webhookStorage storage.WebhookStorage, client *http.Client, maxAttempts int, backoff time.Duration) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Dispatcher{
+		storage: webhookStorage,
+		client: client,
+		maxAttempts: maxAttempts,
+		backoff: backoff,
+		stopped: make(chan struct{}),
+	}
+}
+
+// Sign returns signature of payload sent at timestamp, receivers compute it with their
+// copy of the secret and compare to HeaderSignature.
+func Sign(secret string, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatch sends event to webhooks of the index subscribed to it, errors are only logged
+// as events are side effects of already finished operations. Nil dispatcher of server
+// without webhooks sends nothing.
+func (d *Dispatcher) Dispatch(ctx context.Context, indexName string, eventType string, data any) {
+	if d == nil {
+		return
+	}
+
+	webhooks, err := d.storage.GetIndexWebhooks(ctx, indexName)
+	if err != nil {
+		log.Errorf("Error getting webhooks of index %s to dispatch %s event: %s", indexName, eventType, err)
+		return
+	}
+
+	var payload []byte
+	event := models.WebhookEvent{
+		Id: primitive.NewObjectID().Hex(),
+		Type: eventType,
+		Index: indexName,
+		CreatedAt: time.Now().UTC(),
+		Data: data,
+	}
+
+	for _, webhook := range webhooks {
+		if !subscribed(&webhook, eventType) {
+			continue
+		}
+
+		if payload == nil {
+			payload, err = json.Marshal(event)
+			if err != nil {
+				log.Errorf("Error marshalling %s event of index %s: %s", eventType, indexName, err)
+				return
+			}
+		}
+
+		delivery := &models.WebhookDelivery{
+			WebhookId: webhook.Id,
+			EventId: event.Id,
+			Event: eventType,
+			Payload: string(payload),
+		}
+		d.start(ctx, webhook, delivery)
+	}
+}
+
+// Redeliver sends payload of delivery once again as new delivery.
+func (d *Dispatcher) Redeliver(ctx context.Context, webhook *models.Webhook, original *models.WebhookDelivery) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		WebhookId: webhook.Id,
+		EventId: original.EventId,
+		Event: original.Event,
+		Payload: original.Payload,
+		RedeliveryOf: original.Id,
+	}
+	if err := d.create(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	d.deliverInBackground(*webhook, *delivery)
+	return delivery, nil
+}
+
+// Stop makes deliveries waiting to retry give up, attempts in progress aren't interrupted.
+func (d *Dispatcher) Stop() {
+	d.stopOnce.Do(func() {
+		close(d.stopped)
+	})
+}
+
+// Wait blocks until all started deliveries finish.
+func (d *Dispatcher) Wait() {
+	d.wg.Wait()
+}
+
+func subscribed(webhook *models.Webhook, eventType string) bool {
+	for _, event := range webhook.Events {
+		if event == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dispatcher) start(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery) {
+	if err := d.create(ctx, delivery); err != nil {
+		return
+	}
+	d.deliverInBackground(webhook, *delivery)
+}
+
+func (d *Dispatcher) create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	delivery.Status = models.DeliveryStatusPending
+	delivery.CreatedAt = time.Now().UTC()
+	delivery.UpdatedAt = delivery.CreatedAt
+
+	err := d.storage.CreateDelivery(ctx, delivery)
+	if err != nil {
+		log.Errorf("Error recording delivery of %s event to webhook %s: %s", delivery.Event, delivery.WebhookId, err)
+	}
+	return err
+}
+
+// deliverInBackground doesn't use request context, deliveries outlive requests that caused them.
+func (d *Dispatcher) deliverInBackground(webhook models.Webhook, delivery models.WebhookDelivery) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.deliver(context.Background(), &webhook, &delivery)
+	}()
+}
+
+// deliver makes attempts until webhook responds with 2xx, pause between attempts doubles.
+// Delivery stopped during pause stays pending.
+func (d *Dispatcher) deliver(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) {
+	for delivery.Attempts < d.maxAttempts {
+		if delivery.Attempts > 0 && !d.pause(backoff(d.backoff, delivery.Attempts)) {
+			log.Infof("Delivery %s of %s event to webhook %s is stopped after %d attempts", delivery.Id, delivery.Event, webhook.Id, delivery.Attempts)
+			return
+		}
+
+		delivery.Attempts++
+		delivery.ResponseStatus, delivery.Error = 0, ""
+		status, err := d.post(ctx, webhook, delivery)
+		delivery.ResponseStatus = status
+		if err == nil {
+			delivery.Status = models.DeliveryStatusSucceeded
+		} else {
+			delivery.Error = err.Error()
+			if delivery.Attempts >= d.maxAttempts {
+				delivery.Status = models.DeliveryStatusFailed
+			}
+			log.Warningf("Error delivering %s event to webhook %s, attempt %d of %d: %s", delivery.Event, webhook.Id, delivery.Attempts, d.maxAttempts, err)
+		}
+
+		delivery.UpdatedAt = time.Now().UTC()
+		if err := d.storage.UpdateDelivery(ctx, delivery); err != nil {
+			log.Errorf("Error recording attempt of delivery %s to webhook %s: %s", delivery.Id, webhook.Id, err)
+		}
+		if delivery.Status != models.DeliveryStatusPending {
+			return
+		}
+	}
+}
+
+// pause returns false if dispatcher is stopped before duration passes.
+func (d *Dispatcher) pause(duration time.Duration) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.stopped:
+		return false
+	}
+}
+
+func backoff(base time.Duration, attempts int) time.Duration {
+	pause := base
+	for i := 1; i < attempts && pause < maxBackoff; i++ {
+		pause *= 2
+	}
+	if pause > maxBackoff {
+		pause = maxBackoff
+	}
+	return pause
+}
+
+func (d *Dispatcher) post(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.Id)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(webhook.Secret, timestamp, payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// body is drained, so connection can be reused
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
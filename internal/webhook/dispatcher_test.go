@@ -0,0 +1,225 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/magiconair/properties/assert"
+	"github.com/xavesen/search-api/internal/models"
+	"github.com/xavesen/search-api/internal/outbound"
+	"github.com/xavesen/search-api/internal/storage"
+)
+
+const testSecret = "0123456789abcdef"
+
+type receivedRequest struct {
+	header	http.Header
+	body	[]byte
+}
+
+// newReceiver starts webhook receiver answering with statuses in order, the last status
+// is repeated once statuses run out.
+func newReceiver(statuses ...int) (*httptest.Server, func() []receivedRequest) {
+	var mutex sync.Mutex
+	received := []receivedRequest{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mutex.Lock()
+		received = append(received, receivedRequest{header: r.Header, body: body})
+		status := statuses[len(statuses)-1]
+		if len(received) <= len(statuses) {
+			status = statuses[len(received)-1]
+		}
+		mutex.Unlock()
+
+		w.WriteHeader(status)
+	}))
+
+	return server, func() []receivedRequest {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]receivedRequest{}, received...)
+	}
+}
+
+func TestDispatchRetriesUntilSuccess(t *testing.T) {
+	receiver, received := newReceiver(http.StatusInternalServerError, http.StatusBadGateway, http.StatusNoContent)
+	defer receiver.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventDocumentsQueued}},
+	}}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 5, time.Millisecond)
+
+	dispatcher.Dispatch(context.TODO(), "test", models.EventDocumentsQueued, &models.DocumentsEventData{RequestId: "r1", Documents: 2})
+	dispatcher.Wait()
+
+	requests := received()
+	assert.Equal(t, len(requests), 3, "wrong number of attempts")
+	assert.Equal(t, len(webhookStorage.Deliveries), 1, "retries must be recorded in the same delivery")
+
+	delivery := webhookStorage.Deliveries[0]
+	assert.Equal(t, delivery.Status, models.DeliveryStatusSucceeded, "wrong delivery status")
+	assert.Equal(t, delivery.Attempts, 3, "wrong number of recorded attempts")
+	assert.Equal(t, delivery.ResponseStatus, http.StatusNoContent, "wrong recorded response status")
+	assert.Equal(t, delivery.Error, "", "error of earlier attempt must be cleared")
+	assert.Equal(t, string(requests[2].body), delivery.Payload, "wrong delivered payload")
+
+	var event models.WebhookEvent
+	if err := json.Unmarshal(requests[2].body, &event); err != nil {
+		t.Fatalf("Unable to unmarshal delivered event, error: %s\n", err)
+	}
+	assert.Equal(t, event.Type, models.EventDocumentsQueued, "wrong event type")
+	assert.Equal(t, event.Index, "test", "wrong event index")
+	assert.Equal(t, event.Id, delivery.EventId, "wrong event id")
+	assert.Equal(t, requests[2].header.Get(HeaderEvent), models.EventDocumentsQueued, "wrong event header")
+	assert.Equal(t, requests[2].header.Get(HeaderDelivery), delivery.Id, "wrong delivery header")
+}
+
+func TestDispatchSignature(t *testing.T) {
+	receiver, received := newReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventIndexCreated}},
+	}}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 1, time.Millisecond)
+
+	dispatcher.Dispatch(context.TODO(), "test", models.EventIndexCreated, nil)
+	dispatcher.Wait()
+
+	requests := received()
+	if len(requests) != 1 {
+		t.Fatalf("Expected 1 request, got %d\n", len(requests))
+	}
+	header := requests[0].header
+	assert.Equal(t, header.Get(HeaderSignature), Sign(testSecret, header.Get(HeaderTimestamp), requests[0].body), "signature must verify with the secret")
+	assert.Equal(t, header.Get(HeaderSignature) != Sign("another secret!!", header.Get(HeaderTimestamp), requests[0].body), true, "signature must depend on the secret")
+	assert.Equal(t, header.Get(HeaderSignature) != Sign(testSecret, "0", requests[0].body), true, "signature must depend on the timestamp")
+}
+
+func TestDispatchAllAttemptsFail(t *testing.T) {
+	receiver, received := newReceiver(http.StatusServiceUnavailable)
+	defer receiver.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventIngestionFailed}},
+	}}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 3, time.Millisecond)
+
+	dispatcher.IngestionFailed(context.TODO(), "test", "r1", 4, errors.New("cluster unavailable"))
+	dispatcher.Wait()
+
+	assert.Equal(t, len(received()), 3, "wrong number of attempts")
+	delivery := webhookStorage.Deliveries[0]
+	assert.Equal(t, delivery.Status, models.DeliveryStatusFailed, "wrong delivery status")
+	assert.Equal(t, delivery.Attempts, 3, "wrong number of recorded attempts")
+	assert.Equal(t, delivery.ResponseStatus, http.StatusServiceUnavailable, "wrong recorded response status")
+	assert.Equal(t, delivery.Error, "webhook responded with status 503", "wrong recorded error")
+}
+
+func TestDispatchEventFiltering(t *testing.T) {
+	receiver, received := newReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventDocumentsIndexed}},
+		{Id: "w2", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventIngestionFailed}},
+		{Id: "w3", Index: "other", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventDocumentsIndexed, models.EventIngestionFailed}},
+	}}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 1, time.Millisecond)
+
+	dispatcher.DocumentsIndexed(context.TODO(), "test", "r1", []models.DocumentIndexingResult{
+		{Position: 0, Id: "d1", Result: models.IndexingResultCreated},
+		{Position: 1, Id: "d2", Result: models.IndexingResultUpdated},
+	})
+	dispatcher.Wait()
+
+	assert.Equal(t, len(received()), 1, "only subscribed webhooks of the index must be notified")
+	assert.Equal(t, webhookStorage.Deliveries[0].WebhookId, "w1", "wrong notified webhook")
+
+	dispatcher.DocumentsIndexed(context.TODO(), "test", "r2", []models.DocumentIndexingResult{
+		{Position: 0, Id: "d1", Result: models.IndexingResultCreated},
+		{Position: 1, Result: models.IndexingResultFailed, Error: "mapper_parsing_exception"},
+	})
+	dispatcher.Wait()
+
+	assert.Equal(t, len(webhookStorage.Deliveries), 3, "partially failed batch must be reported with both events")
+	var event struct {
+		Data	models.DocumentsEventData	`json:"data"`
+	}
+	if err := json.Unmarshal([]byte(webhookStorage.Deliveries[2].Payload), &event); err != nil {
+		t.Fatalf("Unable to unmarshal delivered event, error: %s\n", err)
+	}
+	assert.Equal(t, webhookStorage.Deliveries[2].WebhookId, "w2", "wrong notified webhook")
+	assert.Equal(t, event.Data, models.DocumentsEventData{
+		RequestId: "r2",
+		Documents: 2,
+		Created: 1,
+		Failed: 1,
+		Failures: []models.DocumentIndexingResult{{Position: 1, Result: models.IndexingResultFailed, Error: "mapper_parsing_exception"}},
+	}, "wrong ingestion failure data")
+}
+
+func TestRedeliver(t *testing.T) {
+	receiver, received := newReceiver(http.StatusOK)
+	defer receiver.Close()
+
+	webhook := models.Webhook{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventIndexCreated}}
+	webhookStorage := &storage.WebhookStorageMock{
+		Webhooks: []models.Webhook{webhook},
+		Deliveries: []models.WebhookDelivery{
+			{Id: "d1", WebhookId: "w1", EventId: "e1", Event: models.EventIndexCreated, Payload: `{"id":"e1"}`, Status: models.DeliveryStatusFailed, Attempts: 5},
+		},
+	}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 5, time.Millisecond)
+
+	redelivery, err := dispatcher.Redeliver(context.TODO(), &webhook, &webhookStorage.Deliveries[0])
+	if err != nil {
+		t.Fatalf("Unable to redeliver, error: %s\n", err)
+	}
+	dispatcher.Wait()
+
+	assert.Equal(t, redelivery.Id, "d2", "redelivery must be recorded as new delivery")
+	assert.Equal(t, redelivery.RedeliveryOf, "d1", "wrong redelivered delivery")
+
+	requests := received()
+	assert.Equal(t, len(requests), 1, "wrong number of attempts")
+	assert.Equal(t, string(requests[0].body), `{"id":"e1"}`, "original payload must be redelivered")
+	assert.Equal(t, webhookStorage.Deliveries[0].Status, models.DeliveryStatusFailed, "original delivery must not change")
+	assert.Equal(t, webhookStorage.Deliveries[1].Status, models.DeliveryStatusSucceeded, "wrong redelivery status")
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, backoff(time.Second, 1), time.Second, "wrong pause after first attempt")
+	assert.Equal(t, backoff(time.Second, 3), 4*time.Second, "pause must double after each attempt")
+	assert.Equal(t, backoff(time.Second, 100), maxBackoff, "pause must be capped")
+}
+
+func TestStopInterruptsBackoff(t *testing.T) {
+	receiver, received := newReceiver(http.StatusServiceUnavailable)
+	defer receiver.Close()
+
+	webhookStorage := &storage.WebhookStorageMock{Webhooks: []models.Webhook{
+		{Id: "w1", Index: "test", URL: receiver.URL, Secret: testSecret, Events: []string{models.EventIngestionFailed}},
+	}}
+	dispatcher := NewDispatcher(webhookStorage, outbound.NewClient(time.Second, true), 3, time.Hour)
+
+	dispatcher.IngestionFailed(context.TODO(), "test", "r1", 4, errors.New("cluster unavailable"))
+	for len(received()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	dispatcher.Stop()
+	dispatcher.Wait()
+
+	assert.Equal(t, len(received()), 1, "delivery must not be retried after stop")
+	assert.Equal(t, webhookStorage.Deliveries[0].Status, models.DeliveryStatusPending, "stopped delivery must stay pending")
+}
@@ -0,0 +1,48 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/xavesen/search-api/internal/models"
+)
+
+// DocumentsIndexed reports indexed batch, documents that failed separately are also
+// reported with ingestion.failed event.
+func (d *Dispatcher) DocumentsIndexed(ctx context.Context, indexName string, requestId string, results []models.DocumentIndexingResult) {
+	data := models.DocumentsEventData{RequestId: requestId, Documents: len(results)}
+	for _, result := range results {
+		switch result.Result {
+		case models.IndexingResultCreated:
+			data.Created++
+		case models.IndexingResultUpdated:
+			data.Updated++
+		case models.IndexingResultSkipped:
+			data.Skipped++
+		case models.IndexingResultFailed:
+			data.Failed++
+			data.Failures = append(data.Failures, result)
+		}
+	}
+
+	d.Dispatch(ctx, indexName, models.EventDocumentsIndexed, &models.DocumentsEventData{
+		RequestId: data.RequestId,
+		Documents: data.Documents,
+		Created: data.Created,
+		Updated: data.Updated,
+		Failed: data.Failed,
+		Skipped: data.Skipped,
+	})
+	if data.Failed > 0 {
+		d.Dispatch(ctx, indexName, models.EventIngestionFailed, &data)
+	}
+}
+
+// IngestionFailed reports batch that wasn't indexed at all.
+func (d *Dispatcher) IngestionFailed(ctx context.Context, indexName string, requestId string, documents int, err error) {
+	d.Dispatch(ctx, indexName, models.EventIngestionFailed, &models.DocumentsEventData{
+		RequestId: requestId,
+		Documents: documents,
+		Failed: documents,
+		Error: err.Error(),
+	})
+}